@@ -2,14 +2,20 @@ package main
 
 import (
 	"log"
+	"time"
 	_ "tradesimulator/docs" // Import generated docs
 	"tradesimulator/internal/config"
+	marketDAO "tradesimulator/internal/dao/market"
 	"tradesimulator/internal/dao/simulation"
 	"tradesimulator/internal/dao/trading"
 	"tradesimulator/internal/database"
+	simulationEngine "tradesimulator/internal/engines/simulation"
+	tradingEngine "tradesimulator/internal/engines/trading"
 	"tradesimulator/internal/handlers"
 	wsHandlers "tradesimulator/internal/handlers/websocket"
 	"tradesimulator/internal/integrations/binance"
+	"tradesimulator/internal/integrations/marketdata"
+	"tradesimulator/internal/models"
 	"tradesimulator/internal/services"
 	"tradesimulator/internal/services/market"
 
@@ -65,34 +71,198 @@ func main() {
 		c.Next()
 	})
 
+	// Configure display precision for monetary values
+	models.SetValueDecimals(cfg.ValueDecimals)
+	for asset, decimals := range cfg.QuoteAssetDecimals {
+		models.SetQuoteAssetDecimals(asset, decimals)
+	}
+
+	// Configure how realized P&L is matched against open lots
+	costBasisMethod, err := models.ParseCostBasisMethod(cfg.CostBasisMethod)
+	if err != nil {
+		log.Fatalf("Invalid COST_BASIS_METHOD: %v", err)
+	}
+	models.SetCostBasisMethod(costBasisMethod)
+
 	// Initialize integrations
 	binanceClient := binance.NewBinanceService()
+	if cfg.BinanceRequestLog {
+		binanceClient.EnableRequestLog(cfg.BinanceRequestLogSize)
+		log.Printf("Binance request logging enabled (keeping last %d requests)", cfg.BinanceRequestLogSize)
+	}
+	providerName, err := marketdata.ParseProvider(cfg.MarketDataProvider)
+	if err != nil {
+		log.Fatalf("Invalid MARKET_DATA_PROVIDER: %v", err)
+	}
+	var marketDataProvider marketdata.MarketDataProvider = binanceClient
+	if providerName == marketdata.ProviderCSV {
+		csvProvider, err := marketdata.NewCSVMarketDataProvider(cfg.MarketDataCSVPath, cfg.MarketDataCSVInterval)
+		if err != nil {
+			log.Fatalf("Invalid MARKET_DATA_CSV_PATH: %v", err)
+		}
+		marketDataProvider = csvProvider
+		log.Printf("Serving market data from %s at %s", cfg.MarketDataCSVPath, cfg.MarketDataCSVInterval)
+	} else {
+		if err := binanceClient.SetSupportedSymbols(binance.ParseSupportedSymbols(cfg.SupportedSymbols)); err != nil {
+			log.Fatalf("Invalid SUPPORTED_SYMBOLS: %v", err)
+		}
+		if dropped := binanceClient.VerifySupportedSymbols(); len(dropped) > 0 {
+			log.Printf("Binance does not list %v as USDT pairs, dropped from the supported symbols", dropped)
+		}
+		if len(binanceClient.GetSupportedSymbols()) == 0 {
+			log.Fatalf("Invalid SUPPORTED_SYMBOLS: none of the configured symbols are listed by Binance")
+		}
+	}
+
+	// Serve imported candle series alongside the provider's symbols
+	importedProvider, err := marketdata.NewImportedMarketDataProvider(marketDataProvider, marketDAO.NewImportedCandleDAO(database.GetDB()))
+	if err != nil {
+		log.Printf("Imported candles unavailable, imports disabled: %v", err)
+	} else {
+		marketDataProvider = importedProvider
+	}
+	log.Printf("Supported symbols: %v", marketDataProvider.GetSupportedSymbols())
 
 	// Initialize services
-	marketDataService := market.NewMarketDataService(binanceClient)
+	marketDataService := market.NewMarketDataService(marketDataProvider)
 
 	// Initialize handlers
 	healthHandler := handlers.NewHealthHandler()
-	marketHandler := handlers.NewMarketHandler(marketDataService)
+	cutoffRegistry := services.NewMarketCutoffRegistry()
+	marketHandler := handlers.NewMarketHandler(marketDataService, cutoffRegistry)
 
 	// Initialize DAOs
 	simulationDAO := simulation.NewSimulationDAO(database.GetDB())
 	orderDAO := trading.NewOrderDAO(database.GetDB())
 	tradeDAO := trading.NewTradeDAO(database.GetDB())
 	positionDAO := trading.NewPositionDAO(database.GetDB())
+	annotationDAO := simulation.NewAnnotationDAO(database.GetDB())
+
+	// Simulations left running or paused by a previous process have no engine, stop them so they can be resumed
+	// For now, use default user ID 1
+	if recovered, err := simulationEngine.RecoverInterruptedSimulations(simulationDAO, 1); err != nil {
+		log.Printf("Failed to recover interrupted simulations: %v", err)
+	} else if recovered > 0 {
+		log.Printf("Stopped %d simulations interrupted by a server restart", recovered)
+	}
 
 	// Initialize portfolio service
-	portfolioService := services.NewPortfolioService()
+	portfolioService := services.NewPortfolioService(positionDAO)
 
 	// Initialize order service (for REST API endpoints)
 	orderService := services.NewOrderService(orderDAO, tradeDAO)
 
 	// Initialize WebSocket handler with dependencies (handlers will be created internally)
-	wsHandler := wsHandlers.NewWebSocketHandler(binanceClient, portfolioService, simulationDAO, orderDAO, tradeDAO, positionDAO, orderService)
+	wsHandler := wsHandlers.NewWebSocketHandler(marketDataProvider, portfolioService, simulationDAO, orderDAO, tradeDAO, positionDAO, orderService)
+	disconnectBehavior, err := wsHandlers.ParseDisconnectBehavior(cfg.SimulationDisconnectBehavior)
+	if err != nil {
+		log.Fatalf("Invalid SIMULATION_DISCONNECT_BEHAVIOR: %v", err)
+	}
+	wsHandler.SetDisconnectBehavior(disconnectBehavior)
+	pausedOrderBehavior, err := wsHandlers.ParsePausedOrderBehavior(cfg.PausedOrderBehavior)
+	if err != nil {
+		log.Fatalf("Invalid PAUSED_ORDER_BEHAVIOR: %v", err)
+	}
+	wsHandler.SetPausedOrderBehavior(pausedOrderBehavior)
+	stopOrderBehavior, err := simulationEngine.ParseStopOrderBehavior(cfg.StopOrderBehavior)
+	if err != nil {
+		log.Fatalf("Invalid STOP_ORDER_BEHAVIOR: %v", err)
+	}
+	wsHandler.SetStopOrderBehavior(stopOrderBehavior)
+	wsHandler.SetOrderEventRetry(wsHandlers.OrderEventRetry{
+		BacklogSize: cfg.OrderEventBacklogSize,
+		Attempts:    cfg.OrderEventRetryAttempts,
+	})
+	heartbeat := wsHandlers.Heartbeat{
+		PingInterval: time.Duration(cfg.WebSocketPingIntervalSeconds) * time.Second,
+		PongTimeout:  time.Duration(cfg.WebSocketPongTimeoutSeconds) * time.Second,
+	}
+	if err := heartbeat.Validate(); err != nil {
+		log.Fatalf("Invalid WS_PING_INTERVAL_SECONDS or WS_PONG_TIMEOUT_SECONDS: %v", err)
+	}
+	wsHandler.SetHeartbeat(heartbeat)
+	if cfg.WebSocketReadLimitBytes <= 0 {
+		log.Fatalf("Invalid WS_READ_LIMIT_BYTES: %d must be positive", cfg.WebSocketReadLimitBytes)
+	}
+	wsHandler.SetReadLimit(int64(cfg.WebSocketReadLimitBytes))
+	feeSchedule, err := tradingEngine.ParseFeeSchedule(cfg.FeeSchedule)
+	if err != nil {
+		log.Fatalf("Invalid FEE_SCHEDULE: %v", err)
+	}
+	wsHandler.SetFeeSchedule(feeSchedule)
+	defaultFees, err := tradingEngine.NewFeeTier(cfg.MakerFeeRate, cfg.TakerFeeRate)
+	if err != nil {
+		log.Fatalf("Invalid MAKER_FEE_RATE or TAKER_FEE_RATE: %v", err)
+	}
+	wsHandler.SetDefaultFeeTier(defaultFees)
+	feeRounding, err := tradingEngine.ParseFeeRounding(cfg.FeeRounding)
+	if err != nil {
+		log.Fatalf("Invalid FEE_ROUNDING: %v", err)
+	}
+	if cfg.FeeDecimals < 0 {
+		log.Fatalf("Invalid FEE_DECIMALS: %d must not be negative", cfg.FeeDecimals)
+	}
+	wsHandler.SetFeeRounding(feeRounding, cfg.FeeDecimals)
+	wsHandler.SetMaxPendingLimitOrders(cfg.MaxPendingLimitOrders)
+	wsHandler.SetValuationConcurrency(cfg.ValuationConcurrency)
+	wsHandler.SetMaxSpeed(cfg.MaxSimulationSpeed)
+	wsHandler.SetSmoothPlayback(cfg.SmoothPlaybackCandlesPerTick)
+	if cfg.DataBatchCandles < 0 || cfg.DataBatchCandles > simulationEngine.MaxDataBatchCandles {
+		log.Fatalf("Invalid DATA_BATCH_CANDLES: %d must be between 0 and %d", cfg.DataBatchCandles, simulationEngine.MaxDataBatchCandles)
+	}
+	wsHandler.SetDataBatchSize(cfg.DataBatchCandles)
+	wsHandler.SetClampMarketData(cfg.ClampMarketDataToSimTime)
+	wsHandler.SetMarketCutoffRegistry(cutoffRegistry)
+	wsHandler.SetEquitySampling(cfg.EquitySampleIntervalMs, cfg.MaxEquitySamples)
+	if cfg.TradingStatsEnabled {
+		wsHandler.SetTradingStatsInterval(cfg.TradingStatsIntervalCandles)
+	}
 
 	// Initialize REST API handlers
-	simulationHandler := handlers.NewSimulationHandler(simulationDAO)
-	orderHandler := handlers.NewOrderHandler(orderService, portfolioService)
+	replayService := services.NewReplayService(simulationDAO, tradeDAO, annotationDAO, marketDataService)
+	if cfg.ArchiveSimulationCandles {
+		wsHandler.SetCandleArchiver(replayService)
+	}
+	metricsService := services.NewMetricsService(simulationDAO, tradeDAO, marketDataService)
+	annotationService := services.NewAnnotationService(annotationDAO, simulationDAO, tradeDAO)
+	simulationHandler := handlers.NewSimulationHandler(simulationDAO, replayService, metricsService, annotationService, orderService, portfolioService, wsHandler, wsHandler)
+	orderHandler := handlers.NewOrderHandler(orderService, portfolioService, wsHandler)
+	portfolioHandler := handlers.NewPortfolioHandler(portfolioService, orderService, simulationDAO, wsHandler)
+	backtestHandler := handlers.NewBacktestHandler(wsHandler, metricsService)
+	adminHandler := handlers.NewAdminHandler(marketDataService, wsHandler, wsHandler)
+	configHandler, err := handlers.NewConfigHandler(handlers.ServerConfig{
+		Environment:        cfg.Environment,
+		MarketDataProvider: string(providerName),
+		Intervals:          marketDataProvider.GetSupportedIntervals(),
+		Limits: handlers.ServerLimits{
+			MaxSimulationSpeed:    cfg.MaxSimulationSpeed,
+			MaxHistoricalCandles:  handlers.MaxHistoricalLimit,
+			MaxReplayCandles:      services.MaxReplayCandles,
+			MaxExtraIntervals:     simulationEngine.MaxExtraIntervals,
+			MaxExtraSymbols:       simulationEngine.MaxExtraSymbols,
+			MaxWarmupBars:         simulationEngine.MaxWarmupBars,
+			MaxPendingLimitOrders: cfg.MaxPendingLimitOrders,
+			MaxLeverage:           tradingEngine.MaxLeverage,
+			MaxOrderTagLength:     models.MaxOrderTagLength,
+			MaxAnnotationLength:   services.MaxAnnotationLength,
+			WebSocketReadLimit:    cfg.WebSocketReadLimitBytes,
+		},
+		Fees: handlers.ServerFees{
+			Default:  defaultFees,
+			Schedule: feeSchedule,
+			Rounding: feeRounding,
+			Decimals: cfg.FeeDecimals,
+		},
+		CostBasisMethod:              string(costBasisMethod),
+		ValueDecimals:                cfg.ValueDecimals,
+		SimulationDisconnectBehavior: string(disconnectBehavior),
+		PausedOrderBehavior:          string(pausedOrderBehavior),
+		StopOrderBehavior:            string(stopOrderBehavior),
+		ClampMarketDataToSimTime:     cfg.ClampMarketDataToSimTime,
+	})
+	if err != nil {
+		log.Fatalf("Failed to build server config: %v", err)
+	}
 
 	// Health check endpoint
 	r.GET("/health", healthHandler.Health)
@@ -110,6 +280,7 @@ func main() {
 	api := r.Group("/api/v1")
 	{
 		api.GET("/health", healthHandler.Health)
+		api.GET("/config", configHandler.GetConfig)
 
 		// Market data endpoints
 		market := api.Group("/market")
@@ -117,15 +288,27 @@ func main() {
 			market.GET("/historical", marketHandler.GetHistoricalData)
 			market.GET("/symbols", marketHandler.GetSupportedSymbols)
 			market.GET("/earliest-time/:symbol", marketHandler.GetEarliestTime)
+			market.GET("/candle", marketHandler.GetCandleAt)
+			market.GET("/random-start", marketHandler.GetRandomStart)
+			market.GET("/prices", marketHandler.GetLatestPrices)
+			market.GET("/watchlist", marketHandler.GetWatchlist)
+			market.POST("/prefetch", marketHandler.PrefetchData)
+			market.GET("/prefetch/:id", marketHandler.GetPrefetchStatus)
+			market.POST("/import", marketHandler.ImportCandles)
 		}
 
 		// Simulation endpoints
 		handlers.RegisterSimulationRoutes(api, simulationHandler)
 
+		// Admin and diagnostics endpoints
+		handlers.RegisterAdminRoutes(api, adminHandler, cfg.AdminAPIToken)
+
 		// Order and portfolio endpoints
 		orders := api.Group("/orders")
 		{
 			orders.GET("", orderHandler.GetOrders)
+			orders.GET("/:id/trades", orderHandler.GetOrderTrades)
+			orders.DELETE("/:id", orderHandler.CancelOrder)
 		}
 
 		trades := api.Group("/trades")
@@ -137,6 +320,10 @@ func main() {
 		{
 			positions.GET("", orderHandler.GetPositions)
 		}
+
+		api.GET("/portfolio", portfolioHandler.GetPortfolio)
+
+		api.POST("/backtests", backtestHandler.RunBacktest)
 	}
 
 	// Start server
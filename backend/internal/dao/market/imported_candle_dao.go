@@ -0,0 +1,79 @@
+package market
+
+import (
+	"fmt"
+
+	"tradesimulator/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// importBatchSize is the number of candles inserted per statement when saving a series
+const importBatchSize = 1000
+
+// ImportedSeries summarizes one imported symbol and interval
+type ImportedSeries struct {
+	Symbol      string `json:"symbol"`
+	Interval    string `json:"interval"`
+	CandleCount int    `json:"candleCount"`
+	StartTime   int64  `json:"startTime"` // Start time of the first candle in milliseconds
+	EndTime     int64  `json:"endTime"`   // Start time of the last candle in milliseconds
+}
+
+// ImportedCandleDAO handles database operations for imported candle series
+type ImportedCandleDAO struct {
+	db *gorm.DB
+}
+
+// ImportedCandleDAOInterface defines the contract for imported candle data access
+type ImportedCandleDAOInterface interface {
+	ReplaceSeries(symbol, interval string, candles []models.ImportedCandle) error
+	GetSeries(symbol, interval string) ([]models.ImportedCandle, error)
+	ListSeries() ([]ImportedSeries, error)
+}
+
+// NewImportedCandleDAO creates a new imported candle DAO instance
+func NewImportedCandleDAO(db *gorm.DB) ImportedCandleDAOInterface {
+	return &ImportedCandleDAO{
+		db: db,
+	}
+}
+
+// ReplaceSeries stores the candles of a symbol and interval, replacing any earlier import of that series
+func (dao *ImportedCandleDAO) ReplaceSeries(symbol, interval string, candles []models.ImportedCandle) error {
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(&models.ImportedCandle{Symbol: symbol, Interval: interval}).Delete(&models.ImportedCandle{}).Error; err != nil {
+			return fmt.Errorf("failed to replace imported candles: %w", err)
+		}
+		if len(candles) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(candles, importBatchSize).Error; err != nil {
+			return fmt.Errorf("failed to save imported candles: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetSeries gets the imported candles of a symbol and interval ordered by start time
+func (dao *ImportedCandleDAO) GetSeries(symbol, interval string) ([]models.ImportedCandle, error) {
+	var candles []models.ImportedCandle
+	if err := dao.db.Where(&models.ImportedCandle{Symbol: symbol, Interval: interval}).Order("start_time ASC").Find(&candles).Error; err != nil {
+		return nil, fmt.Errorf("failed to get imported candles: %w", err)
+	}
+	return candles, nil
+}
+
+// ListSeries summarizes every imported series ordered by symbol and interval
+func (dao *ImportedCandleDAO) ListSeries() ([]ImportedSeries, error) {
+	var series []ImportedSeries
+	err := dao.db.Model(&models.ImportedCandle{}).
+		Select(`symbol, "interval", COUNT(*) AS candle_count, MIN(start_time) AS start_time, MAX(start_time) AS end_time`).
+		Group(`symbol, "interval"`).
+		Order(`symbol ASC, "interval" ASC`).
+		Scan(&series).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list imported series: %w", err)
+	}
+	return series, nil
+}
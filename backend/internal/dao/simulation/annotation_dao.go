@@ -0,0 +1,54 @@
+package simulation
+
+import (
+	"fmt"
+
+	"tradesimulator/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AnnotationDAO handles database operations for simulation annotations
+type AnnotationDAO struct {
+	db *gorm.DB
+}
+
+// AnnotationDAOInterface defines the contract for annotation data access
+type AnnotationDAOInterface interface {
+	Create(annotation *models.Annotation) error
+	GetBySimulation(simulationID uint) ([]models.Annotation, error)
+	Delete(simulationID, annotationID uint) (bool, error)
+}
+
+// NewAnnotationDAO creates a new annotation DAO instance
+func NewAnnotationDAO(db *gorm.DB) AnnotationDAOInterface {
+	return &AnnotationDAO{
+		db: db,
+	}
+}
+
+// Create creates a new annotation record
+func (dao *AnnotationDAO) Create(annotation *models.Annotation) error {
+	if err := dao.db.Create(annotation).Error; err != nil {
+		return fmt.Errorf("failed to create annotation: %w", err)
+	}
+	return nil
+}
+
+// GetBySimulation gets all annotations of a simulation ordered by simulation time
+func (dao *AnnotationDAO) GetBySimulation(simulationID uint) ([]models.Annotation, error) {
+	var annotations []models.Annotation
+	if err := dao.db.Where("simulation_id = ?", simulationID).Order("sim_time ASC, id ASC").Find(&annotations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get annotations: %w", err)
+	}
+	return annotations, nil
+}
+
+// Delete deletes an annotation of a simulation, reporting whether it existed
+func (dao *AnnotationDAO) Delete(simulationID, annotationID uint) (bool, error) {
+	result := dao.db.Where("simulation_id = ?", simulationID).Delete(&models.Annotation{}, annotationID)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to delete annotation: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
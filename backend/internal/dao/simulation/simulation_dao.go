@@ -2,6 +2,7 @@ package simulation
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 
@@ -11,8 +12,82 @@ import (
 
 // ExtraConfig represents additional simulation configuration
 type ExtraConfig struct {
-	Speed     int    `json:"speed,omitempty"`
-	Timeframe string `json:"timeframe,omitempty"`
+	Speed               int      `json:"speed,omitempty"`
+	Timeframe           string   `json:"timeframe,omitempty"`
+	MarketFillPrice     string   `json:"market_fill_price,omitempty"` // "last_close" or "next_open"
+	StrictLimitFills    bool     `json:"strict_limit_fills,omitempty"`
+	ClampToAffordable   bool     `json:"clamp_to_affordable,omitempty"`
+	FillAtLimitPrice    bool     `json:"fill_at_limit_price,omitempty"`
+	VolumeParticipation float64  `json:"volume_participation,omitempty"`
+	VolumeScale         float64  `json:"volume_scale,omitempty"`
+	FeeRate             *float64 `json:"fee_rate,omitempty"`         // Unset when the simulation used the server's fee rates
+	LimitFillPrice      string   `json:"limit_fill_price,omitempty"` // "same_candle" or "next_open"
+	WarmupCandles       int      `json:"warmup_candles,omitempty"`
+	WarmupDurationMs    int64    `json:"warmup_duration_ms,omitempty"`
+	WarmupBars          int      `json:"warmup_bars,omitempty"` // Base candles preloaded from before the start time
+	Blind               bool     `json:"blind,omitempty"`
+	Intervals           []string `json:"intervals,omitempty"` // Extra intervals streamed alongside the base candles
+	EndTime             int64    `json:"end_time,omitempty"`  // Simulation time at which the run completes
+	Symbols             []string `json:"symbols,omitempty"`   // Extra symbols played alongside the main symbol
+
+	Leverage              float64 `json:"leverage,omitempty"` // Above 1 for futures mode simulations
+	MaintenanceMarginRate float64 `json:"maintenance_margin_rate,omitempty"`
+	AllowShort            bool    `json:"allow_short,omitempty"` // Sells may exceed holdings and open shorts
+	SlippageMode          string  `json:"slippage_mode,omitempty"`
+	SlippageBps           float64 `json:"slippage_bps,omitempty"`
+	MinPartialFill        float64 `json:"min_partial_fill,omitempty"`
+	MinPartialFillMode    string  `json:"min_partial_fill_mode,omitempty"` // "fraction" or "quantity"
+
+	Loop                 bool `json:"loop,omitempty"`                    // Replay from the start time at the end
+	ResetPortfolioOnLoop bool `json:"reset_portfolio_on_loop,omitempty"` // Each replay is a new simulation record
+	AggregateCandles     bool `json:"aggregate_candles,omitempty"`       // Display interval candles built on the server
+}
+
+// ParseExtraConfig decodes the extra configs stored on a simulation record
+func ParseExtraConfig(simulation *models.Simulation) (*ExtraConfig, error) {
+	extraConfig := &ExtraConfig{}
+	if simulation.ExtraConfigs == "" {
+		return extraConfig, nil
+	}
+	if err := json.Unmarshal([]byte(simulation.ExtraConfigs), extraConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse extra configs: %w", err)
+	}
+	return extraConfig, nil
+}
+
+// SimulationSort orders a user's simulation list
+type SimulationSort string
+
+const (
+	SimulationSortNewest     SimulationSort = "newest"      // Most recently created first (default)
+	SimulationSortOldest     SimulationSort = "oldest"      // Least recently created first
+	SimulationSortPnLDesc    SimulationSort = "pnl_desc"    // Highest P&L (total value minus initial funding) first
+	SimulationSortPnLAsc     SimulationSort = "pnl_asc"     // Lowest P&L first
+	SimulationSortReturnDesc SimulationSort = "return_desc" // Highest return relative to initial funding first
+	SimulationSortReturnAsc  SimulationSort = "return_asc"  // Lowest return first
+)
+
+// simulationSortOrders maps each sort to its ORDER BY clause
+// P&L and returns are stored along with the total value, so simulations that never recorded one sort last
+var simulationSortOrders = map[SimulationSort]string{
+	SimulationSortNewest:     "created_at DESC",
+	SimulationSortOldest:     "created_at ASC",
+	SimulationSortPnLDesc:    "pnl DESC NULLS LAST, created_at DESC",
+	SimulationSortPnLAsc:     "pnl ASC NULLS LAST, created_at DESC",
+	SimulationSortReturnDesc: "return_pct DESC NULLS LAST, created_at DESC",
+	SimulationSortReturnAsc:  "return_pct ASC NULLS LAST, created_at DESC",
+}
+
+// ParseSimulationSort validates a simulation list sort, defaulting to newest first
+func ParseSimulationSort(value string) (SimulationSort, error) {
+	if value == "" {
+		return SimulationSortNewest, nil
+	}
+	sort := SimulationSort(value)
+	if _, ok := simulationSortOrders[sort]; !ok {
+		return "", fmt.Errorf("unknown sort %q (expected newest, oldest, pnl_desc, pnl_asc, return_desc or return_asc)", value)
+	}
+	return sort, nil
 }
 
 // SimulationDAO handles database operations for simulation records
@@ -25,11 +100,18 @@ type SimulationDAOInterface interface {
 	CreateSimulationRecord(userID uint, symbol string, startSimTime, endSimTime int64, initialFunding float64, mode models.SimulationMode, extraConfig *ExtraConfig) (*models.Simulation, error)
 	UpdateSimulationStatus(simulationID uint, status models.SimulationStatus) error
 	UpdateSimulationStatusWithDetails(simulationID uint, status models.SimulationStatus, endSimTime int64, totalValue *float64) error
+	UpdateLastSimTime(simulationID uint, lastSimTime int64) error
 	GetSimulationByID(simulationID uint) (*models.Simulation, error)
-	GetUserSimulations(userID uint, limit, offset int) ([]models.Simulation, error)
+	GetUserSimulations(userID uint, sort SimulationSort, limit, offset int) ([]models.Simulation, error)
 	GetRunningSimulation(userID uint) (*models.Simulation, error)
 	DeleteSimulation(simulationID uint) error
 	GetSimulationStats(simulationID uint) (map[string]interface{}, error)
+	RecordEquitySample(simulationID uint, simTime int64, cash, positionValue float64) error
+	GetEquitySamples(simulationID uint) ([]models.EquitySample, error)
+	GetEquitySampleSummary(simulationID uint) (count int, lastSimTime int64, err error)
+	ThinEquitySamples(simulationID uint) (int, error)
+	SaveCandleArchive(archive *models.CandleArchive) error
+	GetCandleArchive(simulationID uint) (*models.CandleArchive, error)
 }
 
 // NewSimulationDAO creates a new simulation DAO instance
@@ -90,7 +172,24 @@ func (s *SimulationDAO) UpdateSimulationStatus(simulationID uint, status models.
 	return nil
 }
 
+// UpdateLastSimTime checkpoints how far a playing simulation has got, leaving its status and end time alone
+func (s *SimulationDAO) UpdateLastSimTime(simulationID uint, lastSimTime int64) error {
+	result := s.db.Model(&models.Simulation{}).
+		Where("id = ?", simulationID).
+		Update("last_sim_time", lastSimTime)
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to update simulation last sim time: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("simulation record not found: %d", simulationID)
+	}
+	return nil
+}
+
 // UpdateSimulationStatusWithDetails updates simulation status along with end time and total value
+// An end time is also the latest simulation time reached, so it moves the last sim time with it
 func (s *SimulationDAO) UpdateSimulationStatusWithDetails(simulationID uint, status models.SimulationStatus, endSimTime int64, totalValue *float64) error {
 	updates := map[string]interface{}{
 		"status": status,
@@ -98,11 +197,14 @@ func (s *SimulationDAO) UpdateSimulationStatusWithDetails(simulationID uint, sta
 
 	if endSimTime != 0 {
 		updates["end_sim_time"] = endSimTime
+		updates["last_sim_time"] = endSimTime
 	}
 
-	// Update total_value if provided (for any status)
+	// Update total_value if provided (for any status), keeping the stored P&L and return in step with it
 	if totalValue != nil {
 		updates["total_value"] = *totalValue
+		updates["pnl"] = gorm.Expr("? - initial_funding", *totalValue)
+		updates["return_pct"] = gorm.Expr("(? - initial_funding) / NULLIF(initial_funding, 0) * 100", *totalValue)
 	}
 
 	result := s.db.Model(&models.Simulation{}).
@@ -138,11 +240,16 @@ func (s *SimulationDAO) GetSimulationByID(simulationID uint) (*models.Simulation
 	return &simulation, nil
 }
 
-// GetUserSimulations retrieves all simulations for a user
-func (s *SimulationDAO) GetUserSimulations(userID uint, limit, offset int) ([]models.Simulation, error) {
+// GetUserSimulations retrieves all simulations for a user in the given order
+func (s *SimulationDAO) GetUserSimulations(userID uint, sort SimulationSort, limit, offset int) ([]models.Simulation, error) {
+	order, ok := simulationSortOrders[sort]
+	if !ok {
+		order = simulationSortOrders[SimulationSortNewest]
+	}
+
 	var simulations []models.Simulation
 	query := s.db.Where("user_id = ?", userID).
-		Order("created_at DESC")
+		Order(order)
 
 	if limit > 0 {
 		query = query.Limit(limit)
@@ -201,6 +308,30 @@ func (s *SimulationDAO) DeleteSimulation(simulationID uint) error {
 		return fmt.Errorf("failed to delete positions: %w", err)
 	}
 
+	// Delete position history
+	if err := tx.Where("simulation_id = ?", simulationID).Delete(&models.PositionSnapshot{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete position snapshots: %w", err)
+	}
+
+	// Delete annotations
+	if err := tx.Where("simulation_id = ?", simulationID).Delete(&models.Annotation{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete annotations: %w", err)
+	}
+
+	// Delete recorded equity samples
+	if err := tx.Where("simulation_id = ?", simulationID).Delete(&models.EquitySample{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete equity samples: %w", err)
+	}
+
+	// Delete archived candles
+	if err := tx.Where("simulation_id = ?", simulationID).Delete(&models.CandleArchive{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete candle archive: %w", err)
+	}
+
 	// Delete the simulation record itself
 	if err := tx.Delete(&models.Simulation{}, simulationID).Error; err != nil {
 		tx.Rollback()
@@ -223,11 +354,19 @@ func (s *SimulationDAO) GetSimulationStats(simulationID uint) (map[string]interf
 		return nil, err
 	}
 
+	// Monetary values are rounded for display; the stored record keeps full precision
+	const quoteAsset = "USDT"
+	var totalValue *float64
+	if simulation.TotalValue != nil {
+		rounded := models.RoundValue(quoteAsset, *simulation.TotalValue)
+		totalValue = &rounded
+	}
+
 	stats := map[string]interface{}{
 		"simulation_id":   simulation.ID,
 		"symbol":          simulation.Symbol,
-		"initial_funding": simulation.InitialFunding,
-		"total_value":     simulation.TotalValue,
+		"initial_funding": models.RoundValue(quoteAsset, simulation.InitialFunding),
+		"total_value":     totalValue,
 		"status":          simulation.Status,
 		"start_time":      simulation.StartSimTime,
 		"end_time":        simulation.EndSimTime,
@@ -235,12 +374,18 @@ func (s *SimulationDAO) GetSimulationStats(simulationID uint) (map[string]interf
 		"updated_at":      simulation.UpdatedAt,
 	}
 
-	// Calculate P&L if simulation is completed
+	// Report the stored P&L, calculating it for records whose total value predates the stored columns
 	if simulation.TotalValue != nil {
 		pnl := *simulation.TotalValue - simulation.InitialFunding
+		if simulation.PnL != nil {
+			pnl = *simulation.PnL
+		}
 		pnlPercentage := (pnl / simulation.InitialFunding) * 100
-		stats["pnl"] = pnl
-		stats["pnl_percentage"] = pnlPercentage
+		if simulation.ReturnPct != nil {
+			pnlPercentage = *simulation.ReturnPct
+		}
+		stats["pnl"] = models.RoundValue(quoteAsset, pnl)
+		stats["pnl_percentage"] = models.RoundPercent(pnlPercentage)
 	}
 
 	// Count orders and trades
@@ -251,11 +396,157 @@ func (s *SimulationDAO) GetSimulationStats(simulationID uint) (map[string]interf
 	stats["order_count"] = orderCount
 	stats["trade_count"] = tradeCount
 
+	// Realize P&L over the trade history with the configured cost basis method
+	var trades []models.Trade
+	if err := s.db.Where("simulation_id = ?", simulationID).Order("executed_at ASC, id ASC").Find(&trades).Error; err != nil {
+		return nil, fmt.Errorf("failed to get trades: %w", err)
+	}
+	ledger := models.NewCostBasisLedger(models.GetCostBasisMethod())
+	var realizedPnL float64
+	for i := range trades {
+		realized, _ := ledger.Apply(&trades[i])
+		realizedPnL += realized
+	}
+	stats["realized_pnl"] = models.RoundValue(quoteAsset, realizedPnL)
+	stats["cost_basis_method"] = ledger.Method()
+
+	// Compare each fill with the price its order intended, limit or expected market price
+	var orders []models.Order
+	if err := s.db.Where("simulation_id = ?", simulationID).Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("failed to get orders: %w", err)
+	}
+	ordersByID := make(map[uint]*models.Order, len(orders))
+	for i := range orders {
+		ordersByID[orders[i].ID] = &orders[i]
+	}
+	var priceImprovement, measuredNotional float64
+	var improvedTrades, slippedTrades int
+	for i := range trades {
+		order, ok := ordersByID[trades[i].OrderID]
+		if !ok {
+			continue
+		}
+		improvement, ok := trades[i].PriceImprovement(order)
+		if !ok {
+			continue
+		}
+		priceImprovement += improvement
+		measuredNotional += trades[i].Quantity * *order.IntendedPrice()
+		if improvement > 0 {
+			improvedTrades++
+		} else if improvement < 0 {
+			slippedTrades++
+		}
+	}
+	stats["price_improvement"] = models.RoundValue(quoteAsset, priceImprovement)
+	stats["price_improved_trades"] = improvedTrades
+	stats["slipped_trades"] = slippedTrades
+	if measuredNotional > 0 {
+		stats["price_improvement_bps"] = models.RoundPercent(priceImprovement / measuredNotional * 10000)
+	}
+
 	// Parse extra configs
 	var extraConfig ExtraConfig
 	if err := json.Unmarshal([]byte(simulation.ExtraConfigs), &extraConfig); err == nil {
 		stats["extra_config"] = extraConfig
+		if extraConfig.FeeRate != nil {
+			stats["fee_rate"] = *extraConfig.FeeRate
+		}
 	}
 
 	return stats, nil
-}
\ No newline at end of file
+}
+
+// RecordEquitySample stores the portfolio value at a simulation time, split into cash and position value
+func (s *SimulationDAO) RecordEquitySample(simulationID uint, simTime int64, cash, positionValue float64) error {
+	sample := &models.EquitySample{
+		SimulationID:  simulationID,
+		SimTime:       simTime,
+		TotalValue:    cash + positionValue,
+		Cash:          &cash,
+		PositionValue: &positionValue,
+	}
+	if err := s.db.Create(sample).Error; err != nil {
+		return fmt.Errorf("failed to record equity sample: %w", err)
+	}
+	return nil
+}
+
+// GetEquitySamples retrieves the recorded equity samples of a simulation, oldest first
+func (s *SimulationDAO) GetEquitySamples(simulationID uint) ([]models.EquitySample, error) {
+	var samples []models.EquitySample
+	if err := s.db.Where("simulation_id = ?", simulationID).Order("sim_time ASC, id ASC").Find(&samples).Error; err != nil {
+		return nil, fmt.Errorf("failed to get equity samples: %w", err)
+	}
+	return samples, nil
+}
+
+// GetEquitySampleSummary returns the number of equity samples of a simulation and the time of the latest one
+func (s *SimulationDAO) GetEquitySampleSummary(simulationID uint) (int, int64, error) {
+	var summary struct {
+		Count       int
+		LastSimTime int64
+	}
+	err := s.db.Model(&models.EquitySample{}).
+		Select("COUNT(*) AS count, COALESCE(MAX(sim_time), 0) AS last_sim_time").
+		Where("simulation_id = ?", simulationID).
+		Scan(&summary).Error
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to summarize equity samples: %w", err)
+	}
+	return summary.Count, summary.LastSimTime, nil
+}
+
+// ThinEquitySamples halves the equity samples of a simulation by deleting every second one
+// The first and latest samples are kept so the curve still spans the whole run
+// Returns the number of samples remaining
+func (s *SimulationDAO) ThinEquitySamples(simulationID uint) (int, error) {
+	err := s.db.Exec(`
+		DELETE FROM equity_samples
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT id,
+					ROW_NUMBER() OVER (ORDER BY sim_time ASC, id ASC) AS position,
+					COUNT(*) OVER () AS total
+				FROM equity_samples
+				WHERE simulation_id = ?
+			) numbered
+			WHERE position % 2 = 0 AND position < total
+		)`, simulationID).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to thin equity samples: %w", err)
+	}
+
+	count, _, err := s.GetEquitySampleSummary(simulationID)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("Thinned equity samples for simulation %d to %d", simulationID, count)
+	return count, nil
+}
+
+// SaveCandleArchive stores the candle archive of a simulation, replacing any earlier archive
+func (s *SimulationDAO) SaveCandleArchive(archive *models.CandleArchive) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("simulation_id = ?", archive.SimulationID).Delete(&models.CandleArchive{}).Error; err != nil {
+			return fmt.Errorf("failed to replace candle archive: %w", err)
+		}
+		if err := tx.Create(archive).Error; err != nil {
+			return fmt.Errorf("failed to save candle archive: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetCandleArchive retrieves the candle archive of a simulation, or nil if none was stored
+func (s *SimulationDAO) GetCandleArchive(simulationID uint) (*models.CandleArchive, error) {
+	var archive models.CandleArchive
+	err := s.db.Where("simulation_id = ?", simulationID).First(&archive).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candle archive: %w", err)
+	}
+	return &archive, nil
+}
@@ -0,0 +1,170 @@
+package simulation_test
+
+import (
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	simulationDAO "tradesimulator/internal/dao/simulation"
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func TestParseSimulationSort(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    simulationDAO.SimulationSort
+		wantErr bool
+	}{
+		{value: "", want: simulationDAO.SimulationSortNewest},
+		{value: "pnl_desc", want: simulationDAO.SimulationSortPnLDesc},
+		{value: "pnl_asc", want: simulationDAO.SimulationSortPnLAsc},
+		{value: "return_desc", want: simulationDAO.SimulationSortReturnDesc},
+		{value: "pnl", wantErr: true},
+		{value: "PNL_DESC", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := simulationDAO.ParseSimulationSort(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSimulationSort(%q) = %q, %v", tt.value, got, err)
+		}
+	}
+}
+
+// The P&L sorts order by the stored P&L, runs without one last and ties newest first
+func TestGetUserSimulationsPnLOrder(t *testing.T) {
+	tests := []struct {
+		sort simulationDAO.SimulationSort
+		want string
+	}{
+		{sort: simulationDAO.SimulationSortPnLDesc, want: "ORDER BY pnl DESC NULLS LAST, created_at DESC"},
+		{sort: simulationDAO.SimulationSortPnLAsc, want: "ORDER BY pnl ASC NULLS LAST, created_at DESC"},
+		{sort: "unknown", want: "ORDER BY created_at DESC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.sort), func(t *testing.T) {
+			db := testutil.NewDB()
+			var query string
+			if err := db.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+				query = tx.Statement.SQL.String()
+			}); err != nil {
+				t.Fatalf("register callback: %v", err)
+			}
+
+			if _, err := simulationDAO.NewSimulationDAO(db).GetUserSimulations(1, tt.sort, 10, 0); err != nil {
+				t.Fatalf("GetUserSimulations: %v", err)
+			}
+			if !strings.Contains(query, tt.want) {
+				t.Errorf("query %q doesn't contain %q", query, tt.want)
+			}
+		})
+	}
+}
+
+// postgresDAO returns a DAO on the migrated database in TEST_DATABASE_URL, inside a transaction rolled back when the
+// test ends, and skips the test without one
+func postgresDAO(t *testing.T) simulationDAO.SimulationDAOInterface {
+	t.Helper()
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	tx := db.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+	return simulationDAO.NewSimulationDAO(tx)
+}
+
+// Finalizing a simulation with its total value stores the P&L and return in the columns migration 011 added
+func TestFinalizationStoresPnLInPostgres(t *testing.T) {
+	dao := postgresDAO(t)
+
+	simulation, err := dao.CreateSimulationRecord(1, "BTCUSDT", 0, 0, 10_000, models.SimulationModeSpot, nil)
+	if err != nil {
+		t.Fatalf("CreateSimulationRecord: %v", err)
+	}
+	if err := dao.UpdateSimulationStatusWithDetails(simulation.ID, models.SimulationStatusCompleted, 1, ptr(8_500)); err != nil {
+		t.Fatalf("UpdateSimulationStatusWithDetails: %v", err)
+	}
+
+	record, err := dao.GetSimulationByID(simulation.ID)
+	if err != nil {
+		t.Fatalf("GetSimulationByID: %v", err)
+	}
+	if record.PnL == nil || *record.PnL != -1_500 {
+		t.Errorf("P&L = %v, want -1500", record.PnL)
+	}
+	if record.ReturnPct == nil || *record.ReturnPct != -15 {
+		t.Errorf("return = %v, want -15%%", record.ReturnPct)
+	}
+}
+
+func TestGetUserSimulationsSortedByPnLInPostgres(t *testing.T) {
+	dao := postgresDAO(t)
+
+	// Final values 9000, 12000 and 10500 on 10000 funding, and one still running without a P&L
+	ids := map[uint]string{}
+	for _, run := range []struct {
+		name       string
+		totalValue *float64
+	}{
+		{name: "loss", totalValue: ptr(9_000)},
+		{name: "best", totalValue: ptr(12_000)},
+		{name: "running"},
+		{name: "gain", totalValue: ptr(10_500)},
+	} {
+		simulation, err := dao.CreateSimulationRecord(1, "BTCUSDT", 0, 0, 10_000, models.SimulationModeSpot, nil)
+		if err != nil {
+			t.Fatalf("CreateSimulationRecord: %v", err)
+		}
+		if run.totalValue != nil {
+			if err := dao.UpdateSimulationStatusWithDetails(simulation.ID, models.SimulationStatusCompleted, 0, run.totalValue); err != nil {
+				t.Fatalf("UpdateSimulationStatusWithDetails: %v", err)
+			}
+		}
+		ids[simulation.ID] = run.name
+	}
+
+	tests := []struct {
+		sort simulationDAO.SimulationSort
+		want []string
+	}{
+		{sort: simulationDAO.SimulationSortPnLDesc, want: []string{"best", "gain", "loss", "running"}},
+		{sort: simulationDAO.SimulationSortPnLAsc, want: []string{"loss", "gain", "best", "running"}},
+	}
+	for _, tt := range tests {
+		simulations, err := dao.GetUserSimulations(1, tt.sort, 0, 0)
+		if err != nil {
+			t.Fatalf("GetUserSimulations: %v", err)
+		}
+		var got []string
+		for _, simulation := range simulations {
+			if name, ok := ids[simulation.ID]; ok {
+				got = append(got, name)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s order = %v, want %v", tt.sort, got, tt.want)
+		}
+	}
+}
+
+func ptr(value float64) *float64 {
+	return &value
+}
@@ -18,6 +18,9 @@ type OrderDAOInterface interface {
 	Update(order *models.Order) error
 	GetByID(orderID uint) (*models.Order, error)
 	GetUserOrders(userID, simulationID uint, limit int) ([]models.Order, error)
+	GetUserOrdersByTag(userID, simulationID uint, tag string, limit int) ([]models.Order, error)
+	GetPendingOrders(userID, simulationID uint) ([]models.Order, error)
+	GetSimulationOpenOrders(simulationID uint) ([]models.Order, error)
 	CreateWithTx(tx *gorm.DB, order *models.Order) error
 	UpdateWithTx(tx *gorm.DB, order *models.Order) error
 }
@@ -70,6 +73,46 @@ func (dao *OrderDAO) GetUserOrders(userID, simulationID uint, limit int) ([]mode
 	return orders, nil
 }
 
+// GetUserOrdersByTag gets a user's orders in a specific simulation carrying the given tag
+func (dao *OrderDAO) GetUserOrdersByTag(userID, simulationID uint, tag string, limit int) ([]models.Order, error) {
+	var orders []models.Order
+	query := dao.db.Where("user_id = ? AND simulation_id = ? AND tag = ?", userID, simulationID, tag).Order("created_at DESC")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if err := query.Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("failed to get orders by tag: %w", err)
+	}
+
+	return orders, nil
+}
+
+// GetPendingOrders gets a user's open orders in a specific simulation, oldest first
+func (dao *OrderDAO) GetPendingOrders(userID, simulationID uint) ([]models.Order, error) {
+	var orders []models.Order
+	if err := dao.db.Where("user_id = ? AND simulation_id = ? AND status IN ?", userID, simulationID, models.OpenOrderStatuses).
+		Order("created_at ASC").Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("failed to get pending orders: %w", err)
+	}
+	return orders, nil
+}
+
+// GetSimulationOpenOrders gets every user's open orders in a specific simulation, or in all simulations when
+// simulationID is 0, oldest first
+func (dao *OrderDAO) GetSimulationOpenOrders(simulationID uint) ([]models.Order, error) {
+	var orders []models.Order
+	query := dao.db.Where("status IN ?", models.OpenOrderStatuses).Order("created_at ASC")
+	if simulationID > 0 {
+		query = query.Where("simulation_id = ?", simulationID)
+	}
+	if err := query.Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("failed to get open orders: %w", err)
+	}
+	return orders, nil
+}
+
 // CreateWithTx creates a new order record within a transaction
 func (dao *OrderDAO) CreateWithTx(tx *gorm.DB, order *models.Order) error {
 	if err := tx.Create(order).Error; err != nil {
@@ -3,11 +3,15 @@ package trading
 import (
 	"fmt"
 	"log"
+	"math"
 
 	"tradesimulator/internal/models"
 	"gorm.io/gorm"
 )
 
+// positionQuantityEpsilon treats smaller remaining quantities as a closed position
+const positionQuantityEpsilon = 1e-9
+
 // PositionDAO handles database operations for positions
 type PositionDAO struct {
 	db *gorm.DB
@@ -26,6 +30,8 @@ type PositionDAOInterface interface {
 	DeleteWithTx(tx *gorm.DB, position *models.Position) error
 	GetPositionWithTx(tx *gorm.DB, userID, simulationID uint, symbol, baseCurrency string) (*models.Position, error)
 	UpdateOrCreatePosition(tx *gorm.DB, userID uint, simulationID *uint, symbol string, baseCurrency string, quantityChange, price, fee float64) error
+	RecordSnapshotWithTx(tx *gorm.DB, userID uint, simulationID *uint, symbol, baseCurrency string, orderID uint, simTime int64) error
+	GetSnapshots(simulationID uint, symbol string) ([]models.PositionSnapshot, error)
 	CreateInitialUSDTPosition(userID uint, simulationID *uint, initialFunding float64) error
 }
 
@@ -124,55 +130,151 @@ func (dao *PositionDAO) GetPositionWithTx(tx *gorm.DB, userID, simulationID uint
 
 // UpdateOrCreatePosition updates or creates a position within a transaction (extracted from order service)
 func (dao *PositionDAO) UpdateOrCreatePosition(tx *gorm.DB, userID uint, simulationID *uint, symbol string, baseCurrency string, quantityChange, price, fee float64) error {
+	// Positions are always simulation-scoped; a nil ID would never match and create duplicates
+	if simulationID == nil {
+		return fmt.Errorf("simulation ID is required to update %s position", symbol)
+	}
+
 	var position models.Position
 	err := tx.Where("user_id = ? AND symbol = ? AND base_currency = ? AND simulation_id = ?", userID, symbol, baseCurrency, simulationID).First(&position).Error
 
 	if err == gorm.ErrRecordNotFound {
-		// Create new position
-		position = models.Position{
-			UserID:       userID,
-			SimulationID: simulationID,
-			Symbol:       symbol,
-			BaseCurrency: baseCurrency,
-			Quantity:     quantityChange,
-			AveragePrice: price,
-			TotalCost:    (quantityChange * price) + fee,
-		}
+		position = NewPositionFromFill(userID, simulationID, symbol, baseCurrency, quantityChange, price, fee)
 		return tx.Create(&position).Error
 	} else if err != nil {
 		return err
-	} else {
-		// Update existing position
-		newQuantity := position.Quantity + quantityChange
+	}
+
+	if !ApplyPositionChange(&position, quantityChange, price, fee) {
+		// Cash spent in full, delete it
+		return tx.Delete(&position).Error
+	}
+	return tx.Save(&position).Error
+}
+
+// NewPositionFromFill builds the position opened by a first fill, the average price includes the opening fee like
+// every later fill
+func NewPositionFromFill(userID uint, simulationID *uint, symbol, baseCurrency string, quantityChange, price, fee float64) models.Position {
+	position := models.Position{
+		UserID:       userID,
+		SimulationID: simulationID,
+		Symbol:       symbol,
+		BaseCurrency: baseCurrency,
+		Quantity:     quantityChange,
+		AveragePrice: price,
+		TotalCost:    (quantityChange * price) + fee,
+	}
+	if quantityChange != 0 {
+		position.AveragePrice = position.TotalCost / quantityChange
+	}
+	return position
+}
 
+// ApplyPositionChange applies a fill to an existing position in memory
+// It returns false when a USDT position is spent in full, which is deleted rather than kept at zero
+func ApplyPositionChange(position *models.Position, quantityChange, price, fee float64) bool {
+	newQuantity := position.Quantity + quantityChange
+
+	if position.Symbol == "USDT" {
 		if newQuantity == 0 {
-			// Position closed, delete it
-			return tx.Delete(&position).Error
-		} else if symbol == "USDT" {
-			// For USDT positions, just update quantity (price always 1, no average price calculation needed)
-			position.Quantity = newQuantity
-			position.TotalCost = newQuantity // For USDT, total cost = quantity since price = 1
-		} else if (position.Quantity > 0 && quantityChange > 0) || (position.Quantity < 0 && quantityChange < 0) {
-			// Same direction, update average price
-			newTotalCost := position.TotalCost + (quantityChange * price) + fee
-			newAveragePrice := newTotalCost / newQuantity
-
-			position.Quantity = newQuantity
-			position.AveragePrice = newAveragePrice
-			position.TotalCost = newTotalCost
-		} else {
-			// Opposite direction, just update quantity
-			position.Quantity = newQuantity
-			// Keep existing average price and update total cost proportionally
-			position.TotalCost = position.AveragePrice * newQuantity
+			return false
 		}
+		// For USDT positions, just update quantity (price always 1, no average price calculation needed)
+		position.Quantity = newQuantity
+		position.TotalCost = newQuantity // For USDT, total cost = quantity since price = 1
+	} else if position.IsFlat() {
+		// Reopening a closed position, its realized P&L carries over
+		position.Quantity = newQuantity
+		position.TotalCost = (quantityChange * price) + fee
+		position.AveragePrice = position.TotalCost / newQuantity
+	} else if (position.Quantity > 0 && quantityChange > 0) || (position.Quantity < 0 && quantityChange < 0) {
+		// Same direction, update average price
+		newTotalCost := position.TotalCost + (quantityChange * price) + fee
+		newAveragePrice := newTotalCost / newQuantity
 
-		return tx.Save(&position).Error
+		position.Quantity = newQuantity
+		position.AveragePrice = newAveragePrice
+		position.TotalCost = newTotalCost
+	} else {
+		reducePosition(position, quantityChange, price, fee)
 	}
+	return true
 }
 
-// CreateInitialUSDTPosition creates an initial USDT position for a new user (extracted from order service)
+// RecordSnapshotWithTx copies a position's current state into its history within a transaction
+// The copy is a single INSERT ... SELECT so recording adds no round trip to read the position back
+func (dao *PositionDAO) RecordSnapshotWithTx(tx *gorm.DB, userID uint, simulationID *uint, symbol, baseCurrency string, orderID uint, simTime int64) error {
+	if simulationID == nil {
+		return fmt.Errorf("simulation ID is required to record %s position snapshot", symbol)
+	}
+
+	err := tx.Exec(`
+		INSERT INTO position_snapshots (simulation_id, symbol, sim_time, order_id, quantity, average_price, total_cost, realized_pnl, created_at)
+		SELECT simulation_id, symbol, ?, ?, quantity, average_price, total_cost, realized_pnl, NOW()
+		FROM positions
+		WHERE user_id = ? AND simulation_id = ? AND symbol = ? AND base_currency = ?`,
+		simTime, orderID, userID, *simulationID, symbol, baseCurrency).Error
+	if err != nil {
+		return fmt.Errorf("failed to record position snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetSnapshots gets the position snapshots of a simulation in simulation time order, for one symbol when symbol
+// is not empty
+func (dao *PositionDAO) GetSnapshots(simulationID uint, symbol string) ([]models.PositionSnapshot, error) {
+	query := dao.db.Where("simulation_id = ?", simulationID)
+	if symbol != "" {
+		query = query.Where("symbol = ?", symbol)
+	}
+
+	var snapshots []models.PositionSnapshot
+	if err := query.Order("sim_time ASC, id ASC").Find(&snapshots).Error; err != nil {
+		return nil, fmt.Errorf("failed to get position snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// reducePosition applies a trade against the direction of a position, realizing P&L on the closed quantity
+// The average price already includes opening fees, so the closing fee is the only other cost; when the trade
+// crosses through zero, the closed part takes its share of the fee and the rest opens a new position at price
+func reducePosition(position *models.Position, quantityChange, price, fee float64) {
+	tradeQuantity := math.Abs(quantityChange)
+	closedQuantity := math.Min(tradeQuantity, math.Abs(position.Quantity))
+	closingFee := fee * closedQuantity / tradeQuantity
+
+	if position.Quantity > 0 {
+		position.RealizedPnL += closedQuantity*(price-position.AveragePrice) - closingFee
+	} else {
+		position.RealizedPnL += closedQuantity*(position.AveragePrice-price) - closingFee
+	}
+
+	newQuantity := position.Quantity + quantityChange
+	switch {
+	case math.Abs(newQuantity) <= positionQuantityEpsilon:
+		// Closed, kept flat so the realized P&L survives
+		position.Quantity = 0
+		position.AveragePrice = 0
+		position.TotalCost = 0
+	case (newQuantity > 0) == (position.Quantity > 0):
+		// Reduced, the remaining quantity keeps its average price
+		position.Quantity = newQuantity
+		position.TotalCost = position.AveragePrice * newQuantity
+	default:
+		// Crossed from long to short or back, the remainder opens at the trade price
+		position.Quantity = newQuantity
+		position.TotalCost = newQuantity*price + (fee - closingFee)
+		position.AveragePrice = position.TotalCost / newQuantity
+	}
+}
+
+// CreateInitialUSDTPosition creates the funding USDT position of a new simulation
+// This is the only place initial cash is created; positions without a simulation ID are rejected
 func (dao *PositionDAO) CreateInitialUSDTPosition(userID uint, simulationID *uint, initialFunding float64) error {
+	if simulationID == nil {
+		return fmt.Errorf("simulation ID is required to create initial USDT position")
+	}
+
 	position := &models.Position{
 		UserID:       userID,
 		SimulationID: simulationID,
@@ -189,4 +291,4 @@ func (dao *PositionDAO) CreateInitialUSDTPosition(userID uint, simulationID *uin
 
 	log.Printf("Created initial USDT position for user %d with balance: $%.2f", userID, position.Quantity)
 	return nil
-}
\ No newline at end of file
+}
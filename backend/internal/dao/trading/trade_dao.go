@@ -17,6 +17,9 @@ type TradeDAOInterface interface {
 	Create(trade *models.Trade) error
 	GetByID(tradeID uint) (*models.Trade, error)
 	GetUserTrades(userID, simulationID uint, limit int) ([]models.Trade, error)
+	GetUserTradesByTag(userID, simulationID uint, tag string, limit int) ([]models.Trade, error)
+	GetTradesByOrderID(orderID uint) ([]models.Trade, error)
+	GetLatestTrade(userID, simulationID uint, symbol string) (*models.Trade, error)
 	CreateWithTx(tx *gorm.DB, trade *models.Trade) error
 }
 
@@ -60,6 +63,49 @@ func (dao *TradeDAO) GetUserTrades(userID, simulationID uint, limit int) ([]mode
 	return trades, nil
 }
 
+// GetUserTradesByTag gets a user's trades in a specific simulation whose order carries the given tag
+func (dao *TradeDAO) GetUserTradesByTag(userID, simulationID uint, tag string, limit int) ([]models.Trade, error) {
+	var trades []models.Trade
+	query := dao.db.Where("user_id = ? AND simulation_id = ?", userID, simulationID).
+		Where("order_id IN (?)", dao.db.Model(&models.Order{}).Select("id").Where("simulation_id = ? AND tag = ?", simulationID, tag)).
+		Order("executed_at DESC")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if err := query.Find(&trades).Error; err != nil {
+		return nil, fmt.Errorf("failed to get trades by tag: %w", err)
+	}
+
+	return trades, nil
+}
+
+// GetLatestTrade gets a user's most recent trade of a symbol in a specific simulation, nil when there is none
+func (dao *TradeDAO) GetLatestTrade(userID, simulationID uint, symbol string) (*models.Trade, error) {
+	var trades []models.Trade
+	err := dao.db.Where("user_id = ? AND simulation_id = ? AND symbol = ?", userID, simulationID, symbol).
+		Order("executed_at DESC, id DESC").
+		Limit(1).
+		Find(&trades).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest trade: %w", err)
+	}
+	if len(trades) == 0 {
+		return nil, nil
+	}
+	return &trades[0], nil
+}
+
+// GetTradesByOrderID gets all trades executed for an order, oldest first
+func (dao *TradeDAO) GetTradesByOrderID(orderID uint) ([]models.Trade, error) {
+	var trades []models.Trade
+	if err := dao.db.Where("order_id = ?", orderID).Order("executed_at ASC, id ASC").Find(&trades).Error; err != nil {
+		return nil, fmt.Errorf("failed to get trades for order: %w", err)
+	}
+	return trades, nil
+}
+
 // CreateWithTx creates a new trade record within a transaction
 func (dao *TradeDAO) CreateWithTx(tx *gorm.DB, trade *models.Trade) error {
 	if err := tx.Create(trade).Error; err != nil {
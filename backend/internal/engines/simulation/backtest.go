@@ -0,0 +1,173 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	tradingDAO "tradesimulator/internal/dao/trading"
+	"tradesimulator/internal/engines/trading"
+	"tradesimulator/internal/models"
+)
+
+// MaxBacktestCandles caps the base candles between a backtest's start and end time, bounding the data it fetches
+const MaxBacktestCandles = 100000
+
+// BacktestStrategy is called with each base candle of a backtest, after the candle has filled pending orders
+// Returning an error stops the backtest
+type BacktestStrategy func(bt *BacktestContext, candle models.OHLCV) error
+
+// BacktestContext is what a strategy sees of the backtest it runs in
+type BacktestContext struct {
+	SimulationID   uint
+	Symbol         string
+	SimulationTime int64   // Close time of the current candle
+	CurrentPrice   float64 // Close of the current candle, the price orders are placed at
+	WarmingUp      bool    // The configured warmup hasn't elapsed; orders are accepted but clients would be refused
+
+	// Orders places orders in the backtest's simulation, for user 1 like every simulation
+	Orders trading.OrderExecutionEngineInterface
+
+	positionDAO tradingDAO.PositionDAOInterface
+}
+
+// MarketOrder executes a market order on the backtest's symbol at the current price
+func (bt *BacktestContext) MarketOrder(side models.OrderSide, quantity float64, tag string) (*models.Order, *models.Trade, error) {
+	return bt.Orders.ExecuteMarketOrder(1, bt.SimulationID, bt.Symbol, side, quantity, bt.CurrentPrice, tag, bt.SimulationTime)
+}
+
+// Position returns the quantity held of a symbol in the backtest's simulation, 0 when there is no position
+func (bt *BacktestContext) Position(symbol string) (float64, error) {
+	positions, err := bt.positionDAO.GetUserPositions(1, bt.SimulationID)
+	if err != nil {
+		return 0, err
+	}
+	for _, position := range positions {
+		if position.Symbol == symbol {
+			return position.Quantity, nil
+		}
+	}
+	return 0, nil
+}
+
+// BacktestResult is the outcome of a backtest played to its end
+type BacktestResult struct {
+	Simulation    *models.Simulation `json:"simulation"`
+	CandlesPlayed int                `json:"candlesPlayed"`
+	TradeStats    trading.TradeStats `json:"tradeStats"`
+}
+
+// RunBacktest plays a simulation from startTime to endTime with default options, calling strategy with every base
+// candle, and returns the completed simulation record
+func (se *SimulationEngine) RunBacktest(ctx context.Context, symbol, interval string, startTime, endTime int64, speed int, initialFunding float64, strategy BacktestStrategy) (*BacktestResult, error) {
+	options := SimulationOptions{Execution: trading.DefaultExecutionOptions()}
+	return se.RunBacktestWithOptions(ctx, symbol, interval, startTime, endTime, speed, initialFunding, options, strategy)
+}
+
+// RunBacktestWithOptions plays a simulation from startTime to endTime as fast as candles can be processed, calling
+// strategy with every base candle, and returns the completed simulation record
+// It runs synchronously on the caller's goroutine instead of the ticker driven loop. Speed only selects the base
+// interval and the allowed timeframes, as it does for played simulations. The simulation completes like a played
+// one when the end time or the end of data is reached, and is stopped when the strategy fails or ctx is done, e.g.
+// when the requesting client disconnects
+func (se *SimulationEngine) RunBacktestWithOptions(ctx context.Context, symbol, interval string, startTime, endTime int64, speed int, initialFunding float64, options SimulationOptions, strategy BacktestStrategy) (*BacktestResult, error) {
+	orders, ok := se.orderExecutionEngine.(trading.OrderExecutionEngineInterface)
+	if !ok {
+		return nil, fmt.Errorf("backtests require an order execution engine")
+	}
+	if strategy == nil {
+		return nil, fmt.Errorf("backtests require a strategy")
+	}
+	if endTime == 0 {
+		return nil, fmt.Errorf("backtests require an end time")
+	}
+	if err := validateEndTime(startTime, endTime); err != nil {
+		return nil, err
+	}
+	if options.Loop {
+		return nil, fmt.Errorf("backtests cannot loop")
+	}
+	if candles := (endTime - startTime) / models.GetIntervalDurationMs(baseIntervalForSpeed(speed)); candles > MaxBacktestCandles {
+		return nil, fmt.Errorf("backtest range spans %d base candles, at most %d are allowed; shorten it or raise the speed", candles, MaxBacktestCandles)
+	}
+	options.EndTime = endTime
+
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	if se.state != StateStopped {
+		return nil, fmt.Errorf("simulation already running")
+	}
+	if _, err := se.startUnsafe(symbol, interval, startTime, speed, initialFunding, options); err != nil {
+		return nil, err
+	}
+
+	bt := &BacktestContext{
+		SimulationID: se.currentSimulationID,
+		Symbol:       se.symbol,
+		Orders:       orders,
+		positionDAO:  se.positionDAO,
+	}
+	played, err := se.playBacktestUnsafe(ctx, bt, strategy)
+	if err != nil {
+		se.settleOpenOrdersOnStopUnsafe()
+		se.updateSimulationStatusWithPortfolioValue(models.SimulationStatusStopped)
+		se.deactivateMarketCutoff()
+		se.state = StateStopped
+		return nil, err
+	}
+	se.completeSimulationUnsafe("Backtest completed")
+
+	simulationRecord, err := se.simulationDAO.GetSimulationByID(bt.SimulationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backtest simulation: %w", err)
+	}
+	log.Printf("Backtest %d completed after %d base candles", bt.SimulationID, played)
+
+	return &BacktestResult{
+		Simulation:    simulationRecord,
+		CandlesPlayed: played,
+		TradeStats:    orders.GetTradeStats(),
+	}, nil
+}
+
+// playBacktestUnsafe processes base candles one after another until the end time or the end of data, loading data
+// inline as the buffer runs out, and returns how many were played (caller must hold lock)
+// It stops when either ctx or the engine's own context is done
+func (se *SimulationEngine) playBacktestUnsafe(ctx context.Context, bt *BacktestContext, strategy BacktestStrategy) (int, error) {
+	played := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return played, fmt.Errorf("backtest cancelled: %w", err)
+		}
+		if err := se.ctx.Err(); err != nil {
+			return played, fmt.Errorf("backtest cancelled: %w", err)
+		}
+
+		if se.currentIndex >= len(se.baseDataset) {
+			if se.noMoreDataAvailable || se.reachedEndTimeUnsafe() {
+				return played, nil
+			}
+			if err := se.loadMoreHistoricalData(ctx); err != nil {
+				return played, fmt.Errorf("failed to load backtest data: %w", err)
+			}
+			continue
+		}
+
+		baseCandle := se.baseDataset[se.currentIndex]
+		if !se.withinEndTime(baseCandle) {
+			se.currentSimTime = se.options.EndTime
+			return played, nil
+		}
+		se.currentSimTime = baseCandle.EndTime
+		se.processBaseCandle(baseCandle)
+		played++
+
+		bt.SimulationTime = se.currentSimTime
+		bt.CurrentPrice = se.currentPrice
+		bt.WarmingUp = !se.warmupComplete
+		if err := strategy(bt, baseCandle); err != nil {
+			return played, fmt.Errorf("backtest strategy failed at %d: %w", baseCandle.EndTime, err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package simulation
+
+import (
+	"fmt"
+	"math"
+
+	"tradesimulator/internal/models"
+)
+
+// Built-in backtest strategies, selectable by name over the REST API
+const (
+	StrategyBuyAndHold   = "buy_and_hold"
+	StrategySMACrossover = "sma_crossover"
+)
+
+// Position sizing modes of built-in strategies
+const (
+	SizingCompounding = "compounding" // Buy with all cash, reinvesting realized gains
+	SizingFixed       = "fixed"       // Buy with at most the cash spent on the first buy, keeping gains aside
+)
+
+// BacktestStrategyConfig selects a built-in strategy and its parameters
+type BacktestStrategyConfig struct {
+	Name       string `json:"name"`
+	FastPeriod int    `json:"fastPeriod,omitempty"` // sma_crossover fast average length in base candles, defaults to 10
+	SlowPeriod int    `json:"slowPeriod,omitempty"` // sma_crossover slow average length in base candles, defaults to 30
+	Sizing     string `json:"sizing,omitempty"`     // compounding or fixed, defaults to compounding
+}
+
+// NewBacktestStrategy builds a fresh instance of a built-in strategy, which must not be shared between backtests
+// Built-in strategies can buy with all available cash, so backtests running them should clamp market buys to the
+// affordable quantity, and they don't trade until the warmup has elapsed
+func NewBacktestStrategy(config BacktestStrategyConfig) (BacktestStrategy, error) {
+	sizing := &positionSizing{}
+	switch config.Sizing {
+	case "", SizingCompounding:
+	case SizingFixed:
+		sizing.fixed = true
+	default:
+		return nil, fmt.Errorf("unknown sizing: %s, must be %s or %s", config.Sizing, SizingCompounding, SizingFixed)
+	}
+
+	switch config.Name {
+	case StrategyBuyAndHold:
+		return buyAndHold(sizing), nil
+	case StrategySMACrossover:
+		fast, slow := config.FastPeriod, config.SlowPeriod
+		if fast == 0 {
+			fast = 10
+		}
+		if slow == 0 {
+			slow = 30
+		}
+		if fast < 1 || slow <= fast {
+			return nil, fmt.Errorf("invalid sma_crossover periods: fast %d, slow %d, fast must be at least 1 and below slow", fast, slow)
+		}
+		return smaCrossover(fast, slow, sizing), nil
+	default:
+		return nil, fmt.Errorf("unknown strategy: %s", config.Name)
+	}
+}
+
+// buyAndHold buys once the warmup has elapsed and holds until the backtest ends
+// Its single buy spends all cash under either sizing
+func buyAndHold(sizing *positionSizing) BacktestStrategy {
+	bought := false
+	return func(bt *BacktestContext, candle models.OHLCV) error {
+		if bought || bt.WarmingUp {
+			return nil
+		}
+		bought = true
+		return buy(bt, sizing, "buy_and_hold")
+	}
+}
+
+// smaCrossover buys when the fast close average crosses above the slow one and sells the whole position when it
+// crosses back below
+func smaCrossover(fast, slow int, sizing *positionSizing) BacktestStrategy {
+	closes := make([]float64, 0, slow)
+	var wasAbove, primed bool
+	return func(bt *BacktestContext, candle models.OHLCV) error {
+		closes = append(closes, candle.Close)
+		if len(closes) > slow {
+			closes = closes[1:]
+		}
+		if len(closes) < slow {
+			return nil
+		}
+
+		above := average(closes[slow-fast:]) > average(closes)
+		crossed := primed && above != wasAbove
+		wasAbove, primed = above, true
+		if !crossed || bt.WarmingUp {
+			return nil
+		}
+
+		if above {
+			return buy(bt, sizing, "sma_crossover")
+		}
+		held, err := bt.Position(bt.Symbol)
+		if err != nil {
+			return fmt.Errorf("failed to get position: %w", err)
+		}
+		if held <= 0 {
+			return nil
+		}
+		_, _, err = bt.MarketOrder(models.OrderSideSell, held, "sma_crossover")
+		return err
+	}
+}
+
+// positionSizing decides how much of the simulation's cash a built-in strategy spends on a buy
+type positionSizing struct {
+	fixed bool
+	stake float64 // Cash spent on the first buy, the most fixed sizing spends on any later one
+}
+
+// spend returns the cash to buy with out of the current cash balance
+// Compounding spends it all, so realized gains grow later positions; fixed spends the first buy's stake, or all
+// cash when losses left less than that
+func (ps *positionSizing) spend(cash float64) float64 {
+	if !ps.fixed {
+		return cash
+	}
+	if ps.stake == 0 {
+		ps.stake = cash
+	}
+	return math.Min(cash, ps.stake)
+}
+
+// buy places a market buy for as much as the sized share of the USDT balance covers at the current price
+func buy(bt *BacktestContext, sizing *positionSizing, tag string) error {
+	cash, err := bt.Position("USDT")
+	if err != nil {
+		return fmt.Errorf("failed to get cash balance: %w", err)
+	}
+	if cash <= 0 || bt.CurrentPrice <= 0 {
+		return nil
+	}
+	_, _, err = bt.MarketOrder(models.OrderSideBuy, sizing.spend(cash)/bt.CurrentPrice, tag)
+	return err
+}
+
+func average(values []float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,140 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"tradesimulator/internal/engines/trading"
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/testutil"
+)
+
+// A backtest stops at the next candle once its context is cancelled, and its simulation is recorded as stopped
+func TestBacktestStopsWhenContextCancelled(t *testing.T) {
+	te := newTestEngine(t)
+	te.marketData.SetCandles("BTCUSDT", "1d", flatCandles("1d", 600, 100))
+	te.SetMaxSpeed(86400 * 2000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var simulationID uint
+	calls := 0
+	strategy := func(bt *BacktestContext, candle models.OHLCV) error {
+		simulationID = bt.SimulationID
+		calls++
+		if calls == 10 {
+			cancel()
+		}
+		return nil
+	}
+
+	endTime := testStartTime + 500*models.GetIntervalDurationMs("1d")
+	options := SimulationOptions{Execution: trading.DefaultExecutionOptions()}
+	_, err := te.RunBacktestWithOptions(ctx, "BTCUSDT", "1d", testStartTime, endTime, 86400*2000, 10000, options, strategy)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunBacktestWithOptions error = %v, want context.Canceled", err)
+	}
+	if calls != 10 {
+		t.Errorf("strategy called %d times, want 10", calls)
+	}
+	if state := te.GetStatus().State; state != string(StateStopped) {
+		t.Errorf("engine state = %s, want stopped", state)
+	}
+	record, err := te.simulationDAO.GetSimulationByID(simulationID)
+	if err != nil {
+		t.Fatalf("GetSimulationByID: %v", err)
+	}
+	if record.Status != models.SimulationStatusStopped {
+		t.Errorf("status = %s, want stopped", record.Status)
+	}
+}
+
+// Cancelling a backtest while a data load is waiting to retry a failed fetch ends the wait instead of sitting out
+// the backoff and retrying
+func TestBacktestDataRetryStopsWhenContextCancelled(t *testing.T) {
+	te := newTestEngine(t)
+	te.marketData.SetCandles("BTCUSDT", "1d", flatCandles("1d", 600, 100))
+	te.SetMaxSpeed(86400 * 2000)
+	te.SetDataBatchSize(50)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var loaded int
+	strategy := func(bt *BacktestContext, candle models.OHLCV) error {
+		if loaded == 0 {
+			// Every load after the initial one fails
+			loaded = len(te.marketData.Requests())
+			te.marketData.FailAfter(0, errors.New("binance unavailable"))
+			time.AfterFunc(100*time.Millisecond, cancel)
+		}
+		return nil
+	}
+
+	endTime := testStartTime + 500*models.GetIntervalDurationMs("1d")
+	options := SimulationOptions{Execution: trading.DefaultExecutionOptions()}
+	started := time.Now()
+	_, err := te.RunBacktestWithOptions(ctx, "BTCUSDT", "1d", testStartTime, endTime, 86400*2000, 10000, options, strategy)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunBacktestWithOptions error = %v, want context.Canceled", err)
+	}
+	// The first retry would wait 2s
+	if elapsed := time.Since(started); elapsed >= time.Second {
+		t.Errorf("backtest took %v to stop, want it to end the retry wait", elapsed)
+	}
+	if retries := len(te.marketData.Requests()) - loaded; retries != 1 {
+		t.Errorf("made %d failing requests, want 1 and no retry after cancelling", retries)
+	}
+}
+
+// Compounding sizing reinvests a round trip's gain in the next buy while fixed sizing keeps buying with the first
+// buy's stake
+func TestBacktestStrategySizing(t *testing.T) {
+	// With fast 1 and slow 2 the averages cross whenever the close changes direction: buy at 100, sell at 150,
+	// buy again at 110 and end at 220
+	candles := testutil.Candles(testStartTime, "1d", 100, 90, 100, 200, 150, 100, 110, 220)
+	endTime := testStartTime + int64(len(candles))*models.GetIntervalDurationMs("1d")
+
+	tests := []struct {
+		sizing string
+		want   float64
+	}{
+		{sizing: SizingCompounding, want: 30000}, // 15000 reinvested, doubling
+		{sizing: SizingFixed, want: 25000},       // 10000 reinvested, doubling, beside 5000 of gains
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sizing, func(t *testing.T) {
+			te := newTestEngine(t)
+			te.marketData.SetCandles("BTCUSDT", "1d", candles)
+			te.SetMaxSpeed(86400 * 2000)
+
+			strategy, err := NewBacktestStrategy(BacktestStrategyConfig{Name: StrategySMACrossover, FastPeriod: 1, SlowPeriod: 2, Sizing: tt.sizing})
+			if err != nil {
+				t.Fatalf("NewBacktestStrategy: %v", err)
+			}
+			// Market buys are clamped as they are for backtests over the REST API
+			noFee := 0.0
+			options := SimulationOptions{Execution: trading.ExecutionOptions{FeeRate: &noFee, ClampToAffordable: true}}
+			result, err := te.RunBacktestWithOptions(context.Background(), "BTCUSDT", "1d", testStartTime, endTime, 86400*2000, 10000, options, strategy)
+			if err != nil {
+				t.Fatalf("RunBacktestWithOptions: %v", err)
+			}
+
+			if result.TradeStats.TradeCount != 3 {
+				t.Errorf("made %d trades, want 3", result.TradeStats.TradeCount)
+			}
+			if total := result.Simulation.TotalValue; total == nil || math.Abs(*total-tt.want) > 1e-6 {
+				t.Errorf("final value = %v, want %v", total, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBacktestStrategyRejectsUnknownSizing(t *testing.T) {
+	if _, err := NewBacktestStrategy(BacktestStrategyConfig{Name: StrategyBuyAndHold, Sizing: "martingale"}); err == nil {
+		t.Error("NewBacktestStrategy accepted an unknown sizing")
+	}
+}
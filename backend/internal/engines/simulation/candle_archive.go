@@ -0,0 +1,38 @@
+package simulation
+
+import (
+	"log"
+
+	"tradesimulator/internal/models"
+)
+
+// CandleArchiver stores the candle series of a finished simulation for offline review
+type CandleArchiver interface {
+	ArchiveCandles(simulationID uint) error
+}
+
+// SetCandleArchiver sets the archiver called when a simulation stops or completes (nil disables archiving)
+func (se *SimulationEngine) SetCandleArchiver(archiver CandleArchiver) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	se.candleArchiver = archiver
+}
+
+// archiveCandles archives the simulation's candles in the background once its end time is recorded
+// Paused simulations are skipped since their range still grows when resumed
+func (se *SimulationEngine) archiveCandles(simulationID uint, status models.SimulationStatus) {
+	if se.candleArchiver == nil {
+		return
+	}
+	if status != models.SimulationStatusStopped && status != models.SimulationStatusCompleted {
+		return
+	}
+
+	archiver := se.candleArchiver
+	go func() {
+		if err := archiver.ArchiveCandles(simulationID); err != nil {
+			log.Printf("Failed to archive candles for simulation %d: %v", simulationID, err)
+		}
+	}()
+}
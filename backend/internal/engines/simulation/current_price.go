@@ -0,0 +1,51 @@
+package simulation
+
+import (
+	"fmt"
+)
+
+// CurrentPrice is the latest price of a symbol at the simulation's current time
+type CurrentPrice struct {
+	Symbol    string  `json:"symbol"`
+	Price     float64 `json:"price"`     // Close of the last processed candle
+	PriceTime int64   `json:"priceTime"` // Close time of that candle in milliseconds
+	SimTime   int64   `json:"simTime"`
+}
+
+// GetCurrentPrice returns the latest price of a symbol the running simulation plays, the main symbol when empty
+// A paused simulation reports the close of the last candle processed before it paused
+func (se *SimulationEngine) GetCurrentPrice(symbol string) (CurrentPrice, error) {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	if se.state != StatePlaying && se.state != StatePaused {
+		return CurrentPrice{}, fmt.Errorf("simulation not running")
+	}
+	if symbol == "" {
+		symbol = se.symbol
+	}
+
+	current := CurrentPrice{Symbol: symbol, SimTime: se.currentSimTime}
+	if symbol == se.symbol {
+		current.Price = se.currentPrice
+		current.PriceTime = se.currentPriceTime
+	} else {
+		found := false
+		for _, feed := range se.symbolFeeds {
+			if feed.symbol == symbol {
+				current.Price = feed.price
+				current.PriceTime = feed.priceTime
+				found = true
+				break
+			}
+		}
+		if !found {
+			return CurrentPrice{}, fmt.Errorf("symbol %s not in simulation", symbol)
+		}
+	}
+
+	if current.Price <= 0 {
+		return CurrentPrice{}, fmt.Errorf("no price available for %s yet", symbol)
+	}
+	return current, nil
+}
@@ -0,0 +1,62 @@
+package simulation
+
+import (
+	"fmt"
+
+	"tradesimulator/internal/models"
+)
+
+// validateEndTime checks an optional simulation end time against the start time (0 means no end time)
+func validateEndTime(startTime, endTime int64) error {
+	if endTime < 0 {
+		return fmt.Errorf("invalid end time: %d, must not be negative", endTime)
+	}
+	if endTime != 0 && endTime <= startTime {
+		return fmt.Errorf("invalid end time: %d, must be after the start time %d", endTime, startTime)
+	}
+	return nil
+}
+
+// reachedEndTimeUnsafe reports whether a bounded simulation has played up to its end time (caller must hold lock)
+func (se *SimulationEngine) reachedEndTimeUnsafe() bool {
+	return se.options.EndTime > 0 && se.currentSimTime >= se.options.EndTime
+}
+
+// withinEndTime reports whether a base candle closes by the simulation's end time, always true without one
+func (se *SimulationEngine) withinEndTime(candle models.OHLCV) bool {
+	return se.options.EndTime == 0 || candle.EndTime <= se.options.EndTime
+}
+
+// dataEndTime returns the end time to pass to historical data requests, nil when the simulation has none
+// so requests keep fetching until data runs out
+func (se *SimulationEngine) dataEndTime() *int64 {
+	return dataEndTimeOf(se.options.EndTime)
+}
+
+// dataEndTimeOf returns an end time option as a historical data request bound, nil for 0 (no end time)
+func dataEndTimeOf(endTime int64) *int64 {
+	if endTime == 0 {
+		return nil
+	}
+	return &endTime
+}
+
+// progressUnsafe returns how far the simulation has run as a percentage between 0 and 100 (caller must hold lock)
+// With an end time it is the elapsed share of the simulated time range, otherwise the share of the buffered
+// candles already played
+func (se *SimulationEngine) progressUnsafe() float64 {
+	var progress float64
+	if endTime := se.options.EndTime; endTime > se.startTime {
+		progress = float64(se.currentSimTime-se.startTime) / float64(endTime-se.startTime) * 100
+	} else if len(se.baseDataset) > 0 {
+		progress = float64(se.currentIndex) / float64(len(se.baseDataset)) * 100
+	}
+
+	switch {
+	case progress < 0:
+		progress = 0
+	case progress > 100:
+		progress = 100
+	}
+	return models.RoundPercent(progress)
+}
@@ -0,0 +1,85 @@
+package simulation
+
+import (
+	"testing"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/types"
+)
+
+func TestValidateEndTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		endTime int64
+		wantErr bool
+	}{
+		{name: "no end time", endTime: 0},
+		{name: "after the start", endTime: testStartTime + 1},
+		{name: "at the start", endTime: testStartTime, wantErr: true},
+		{name: "negative", endTime: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := validateEndTime(testStartTime, tt.endTime); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateEndTime() error = %v, want error %t", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// A bounded simulation completes on the candle closing at its end time and never requests data past it
+func TestSimulationCompletesAtEndTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		lastIndex int // Index of the candle closing at the end time
+	}{
+		{name: "within the first batch", lastIndex: 5},
+		{name: "after background loads", lastIndex: 249},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := newTestEngine(t)
+			candles := flatCandles("1d", 1000, 100)
+			te.marketData.SetCandles("BTCUSDT", "1d", candles)
+			te.maxBufferSize = 300
+			te.SetDataBatchSize(100)
+			te.SetMaxSpeed(86400 * 2000)
+
+			endTime := candles[tt.lastIndex].EndTime
+			if err := te.Start("BTCUSDT", "1d", testStartTime, 86400*2000, 10000, SimulationOptions{EndTime: endTime}); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			simulationID := te.GetStatus().SimulationID
+
+			waitFor(t, "the simulation to complete", func() bool {
+				return te.GetStatus().State == string(StateStopped)
+			})
+
+			updates := te.client.Messages(types.SimulationUpdate)
+			if len(updates) != tt.lastIndex+1 {
+				t.Fatalf("played %d candles, want %d", len(updates), tt.lastIndex+1)
+			}
+			last := updates[len(updates)-1].Data.(SimulationUpdateData)
+			if last.BaseCandle.StartTime != candles[tt.lastIndex].StartTime {
+				t.Errorf("last candle starts at %d, want %d", last.BaseCandle.StartTime, candles[tt.lastIndex].StartTime)
+			}
+
+			record, err := te.simulationDAO.GetSimulationByID(simulationID)
+			if err != nil {
+				t.Fatalf("GetSimulationByID: %v", err)
+			}
+			if record.Status != models.SimulationStatusCompleted {
+				t.Errorf("simulation status = %s, want %s", record.Status, models.SimulationStatusCompleted)
+			}
+
+			for i, request := range te.marketData.Requests() {
+				if request.EndTime == nil || *request.EndTime != endTime {
+					t.Errorf("request %d isn't bounded by the %d end time", i, endTime)
+				}
+				if request.StartTime != nil && *request.StartTime > endTime {
+					t.Errorf("requested data from %d, past the %d end time", *request.StartTime, endTime)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package simulation
+
+import (
+	"log"
+)
+
+const (
+	// DefaultEquitySampleIntervalMs is the simulation time between equity samples (one hour)
+	DefaultEquitySampleIntervalMs = 60 * 60 * 1000
+	// MinEquitySampleIntervalMs is the shortest accepted sampling interval, one base candle at the finest base interval
+	MinEquitySampleIntervalMs = 60 * 1000
+	// DefaultMaxEquitySamples bounds the samples stored per simulation
+	DefaultMaxEquitySamples = 2000
+)
+
+// SetEquitySampling sets the simulation time between equity samples and the most samples stored per simulation
+// An interval of 0 disables sampling; once a simulation reaches maxSamples its samples are thinned
+// by half and the interval doubled, so long runs keep an evenly spaced curve of bounded size
+func (se *SimulationEngine) SetEquitySampling(intervalMs int64, maxSamples int) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	if intervalMs > 0 && intervalMs < MinEquitySampleIntervalMs {
+		intervalMs = MinEquitySampleIntervalMs
+	}
+	if intervalMs < 0 {
+		intervalMs = 0
+	}
+	if maxSamples < 2 {
+		maxSamples = DefaultMaxEquitySamples
+	}
+	se.equitySampleIntervalMs = intervalMs
+	se.maxEquitySamples = maxSamples
+}
+
+// startEquitySamplingUnsafe records the initial funding as the first sample of a new simulation (caller must hold lock)
+func (se *SimulationEngine) startEquitySamplingUnsafe(startTime int64, initialFunding float64) {
+	se.equitySampleStepMs = se.equitySampleIntervalMs
+	se.equitySampleCount = 0
+	se.lastEquitySampleTime = startTime
+
+	if se.equitySampleIntervalMs <= 0 {
+		return
+	}
+	if err := se.simulationDAO.RecordEquitySample(se.currentSimulationID, startTime, initialFunding, 0); err != nil {
+		log.Printf("Failed to record initial equity sample for simulation %d: %v", se.currentSimulationID, err)
+		return
+	}
+	se.equitySampleCount = 1
+}
+
+// restoreEquitySamplingUnsafe continues sampling a resumed simulation from its stored samples (caller must hold lock)
+func (se *SimulationEngine) restoreEquitySamplingUnsafe(startTime int64) {
+	se.equitySampleStepMs = se.equitySampleIntervalMs
+	se.equitySampleCount = 0
+	se.lastEquitySampleTime = se.currentPriceTime
+
+	if se.equitySampleIntervalMs <= 0 {
+		return
+	}
+
+	count, lastSimTime, err := se.simulationDAO.GetEquitySampleSummary(se.currentSimulationID)
+	if err != nil {
+		log.Printf("Failed to load equity samples for simulation %d: %v", se.currentSimulationID, err)
+		return
+	}
+	se.equitySampleCount = count
+	if lastSimTime > 0 {
+		se.lastEquitySampleTime = lastSimTime
+	}
+
+	// Recover the step earlier thinning left the samples at
+	for se.equitySampleStepMs > 0 && (se.lastEquitySampleTime-startTime)/se.equitySampleStepMs >= int64(se.maxEquitySamples) {
+		se.equitySampleStepMs *= 2
+	}
+}
+
+// maybeRecordEquitySample samples the portfolio value once the sampling step has elapsed (caller must hold lock)
+func (se *SimulationEngine) maybeRecordEquitySample() {
+	if se.equitySampleStepMs <= 0 || se.currentSimulationID == 0 {
+		return
+	}
+	if se.currentPriceTime-se.lastEquitySampleTime < se.equitySampleStepMs {
+		return
+	}
+
+	cash, positionValue, err := se.calculatePortfolioBreakdown(se.currentPrice, se.currentSimulationID, se.symbol)
+	if err != nil {
+		log.Printf("Failed to value portfolio for equity sample: %v", err)
+		return
+	}
+	if err := se.simulationDAO.RecordEquitySample(se.currentSimulationID, se.currentPriceTime, cash, positionValue); err != nil {
+		log.Printf("Failed to record equity sample for simulation %d: %v", se.currentSimulationID, err)
+		return
+	}
+	se.lastEquitySampleTime = se.currentPriceTime
+	se.equitySampleCount++
+
+	if se.equitySampleCount < se.maxEquitySamples {
+		return
+	}
+
+	count, err := se.simulationDAO.ThinEquitySamples(se.currentSimulationID)
+	if err != nil {
+		log.Printf("Failed to thin equity samples for simulation %d: %v", se.currentSimulationID, err)
+		return
+	}
+	se.equitySampleCount = count
+	se.equitySampleStepMs *= 2
+}
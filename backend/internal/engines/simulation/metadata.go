@@ -0,0 +1,64 @@
+package simulation
+
+import (
+	"tradesimulator/internal/models"
+)
+
+// minTimeframeSteps are the display timeframes in ascending order; at a given speed the smallest allowed timeframe
+// is the largest one whose duration in seconds doesn't exceed the speed
+var minTimeframeSteps = []struct {
+	name    string
+	seconds float64
+}{
+	{"1m", 60},
+	{"5m", 300},
+	{"15m", 900},
+	{"1h", 3600},
+	{"4h", 14400},
+	{"1d", 86400},
+}
+
+// IntervalInfo is a supported candle interval and its duration
+type IntervalInfo struct {
+	Interval   string `json:"interval"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// TimeframeRule is the smallest timeframe allowed from a speed upwards, until the next rule's speed
+type TimeframeRule struct {
+	MinSpeed     int    `json:"minSpeed"`
+	MinTimeframe string `json:"minTimeframe"`
+}
+
+// SimulationMetadata describes the symbols, intervals and limits a simulation can be started with
+type SimulationMetadata struct {
+	Symbols           []string        `json:"symbols"`
+	Intervals         []IntervalInfo  `json:"intervals"`
+	MaxSpeed          int             `json:"maxSpeed"`
+	TimeframeRules    []TimeframeRule `json:"timeframeRules"` // Ascending by minSpeed
+	MaxExtraIntervals int             `json:"maxExtraIntervals"`
+	MaxExtraSymbols   int             `json:"maxExtraSymbols"`
+}
+
+// GetMetadata returns the symbols, intervals and speed and timeframe constraints accepted by Start
+func (se *SimulationEngine) GetMetadata() SimulationMetadata {
+	intervals := se.marketData.GetSupportedIntervals()
+	metadata := SimulationMetadata{
+		Symbols:           se.marketData.GetSupportedSymbols(),
+		Intervals:         make([]IntervalInfo, 0, len(intervals)),
+		MaxSpeed:          se.maxSpeed,
+		TimeframeRules:    make([]TimeframeRule, 0, len(minTimeframeSteps)),
+		MaxExtraIntervals: MaxExtraIntervals,
+		MaxExtraSymbols:   MaxExtraSymbols,
+	}
+	for _, interval := range intervals {
+		metadata.Intervals = append(metadata.Intervals, IntervalInfo{Interval: interval, DurationMs: models.GetIntervalDurationMs(interval)})
+	}
+
+	// Speeds below the first step still allow the smallest timeframe
+	metadata.TimeframeRules = append(metadata.TimeframeRules, TimeframeRule{MinSpeed: 1, MinTimeframe: minTimeframeSteps[0].name})
+	for _, step := range minTimeframeSteps[1:] {
+		metadata.TimeframeRules = append(metadata.TimeframeRules, TimeframeRule{MinSpeed: int(step.seconds), MinTimeframe: step.name})
+	}
+	return metadata
+}
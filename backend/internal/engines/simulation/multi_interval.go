@@ -0,0 +1,177 @@
+package simulation
+
+import (
+	"fmt"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/types"
+)
+
+// MaxExtraIntervals is the most additional intervals a simulation can stream
+const MaxExtraIntervals = 4
+
+// IntervalUpdateData is sent each time a candle of a subscribed interval completes
+type IntervalUpdateData struct {
+	Symbol         string       `json:"symbol"`
+	Interval       string       `json:"interval"`
+	Candle         models.OHLCV `json:"candle"`
+	SimulationTime int64        `json:"simulationTime"`
+}
+
+// intervalAggregator builds candles of one interval from the streamed base candles
+type intervalAggregator struct {
+	interval   string
+	durationMs int64
+	current    *models.OHLCV // Candle being built, nil between candles
+	partial    bool          // Current candle is missing base candles from its start
+}
+
+func newIntervalAggregator(interval string) *intervalAggregator {
+	return &intervalAggregator{
+		interval:   interval,
+		durationMs: models.GetIntervalDurationMs(interval),
+	}
+}
+
+// add folds a base candle into the current candle and returns it once the interval has completed
+func (ia *intervalAggregator) add(baseCandle models.OHLCV) *models.OHLCV {
+	ia.fold(baseCandle)
+	if baseCandle.EndTime < ia.current.EndTime {
+		return nil
+	}
+
+	completed := ia.current
+	ia.current = nil
+	if ia.partial {
+		return nil // Simulations starting mid-candle only stream the interval's first full candle onwards
+	}
+	completed.IsComplete = true
+	return completed
+}
+
+// snapshot folds a base candle into the current candle and returns a copy of it so far, complete once the base
+// candle closes the interval
+// Unlike add, it also returns the candle a simulation started partway through, so displays have no gap
+func (ia *intervalAggregator) snapshot(baseCandle models.OHLCV) models.OHLCV {
+	ia.fold(baseCandle)
+	candle := *ia.current
+	if baseCandle.EndTime >= ia.current.EndTime {
+		candle.IsComplete = true
+		ia.current = nil
+	}
+	return candle
+}
+
+// fold merges a base candle into the current candle, starting a new one at the interval's boundaries
+func (ia *intervalAggregator) fold(baseCandle models.OHLCV) {
+	startTime := models.CalculateCandleStartTime(baseCandle.StartTime, ia.interval)
+
+	// A gap in the base data leaves the previous candle unfinished, drop it rather than mislabel it
+	if ia.current != nil && ia.current.StartTime != startTime {
+		ia.current = nil
+	}
+
+	if ia.current == nil {
+		ia.partial = baseCandle.StartTime != startTime
+		ia.current = &models.OHLCV{
+			StartTime: startTime,
+			EndTime:   startTime + ia.durationMs - 1,
+			Open:      baseCandle.Open,
+			High:      baseCandle.High,
+			Low:       baseCandle.Low,
+			Close:     baseCandle.Close,
+			Volume:    baseCandle.Volume,
+		}
+	} else {
+		if baseCandle.High > ia.current.High {
+			ia.current.High = baseCandle.High
+		}
+		if baseCandle.Low < ia.current.Low {
+			ia.current.Low = baseCandle.Low
+		}
+		ia.current.Close = baseCandle.Close
+		ia.current.Volume += baseCandle.Volume
+	}
+}
+
+// validateExtraIntervals checks that every extra interval can be built from the base interval at the given speed
+func (se *SimulationEngine) validateExtraIntervals(intervals []string, speed int) error {
+	if len(intervals) > MaxExtraIntervals {
+		return fmt.Errorf("too many intervals: %d, at most %d are supported", len(intervals), MaxExtraIntervals)
+	}
+
+	seen := make(map[string]bool, len(intervals))
+	for _, interval := range intervals {
+		if seen[interval] {
+			return fmt.Errorf("duplicate interval: %s", interval)
+		}
+		seen[interval] = true
+
+		if !se.marketData.ValidateInterval(interval) {
+			return fmt.Errorf("invalid interval: %s", interval)
+		}
+		if !se.isTimeframeAllowed(interval, speed) {
+			return fmt.Errorf("interval %s not allowed at %dx speed. Use %s or higher", interval, speed, se.getMinAllowedTimeframe(speed))
+		}
+	}
+	return nil
+}
+
+// resetIntervalAggregators starts fresh candles for the simulation's extra intervals, and for its display interval
+// when candles are aggregated (caller must hold lock)
+func (se *SimulationEngine) resetIntervalAggregators() {
+	se.intervalAggregators = nil
+	for _, interval := range se.options.Intervals {
+		se.intervalAggregators = append(se.intervalAggregators, newIntervalAggregator(interval))
+	}
+
+	se.displayAggregator = nil
+	if se.options.AggregateCandles && se.interval != "" {
+		se.displayAggregator = newIntervalAggregator(se.interval)
+	}
+}
+
+// displayCandle feeds a base candle to the display interval and returns that interval's candle so far, nil when
+// candles aren't aggregated or the current base interval doesn't divide the display interval (caller must hold lock)
+func (se *SimulationEngine) displayCandle(baseCandle models.OHLCV) *models.OHLCV {
+	aggregator := se.displayAggregator
+	if aggregator == nil {
+		return nil
+	}
+
+	baseDurationMs := models.GetIntervalDurationMs(se.baseInterval)
+	if aggregator.durationMs < baseDurationMs || aggregator.durationMs%baseDurationMs != 0 {
+		aggregator.current = nil
+		return nil
+	}
+
+	candle := aggregator.snapshot(baseCandle)
+	return &candle
+}
+
+// sendIntervalUpdates feeds a base candle to each extra interval and sends the candles it completes (caller must hold lock)
+// Intervals that the current base interval doesn't divide evenly, e.g. after a speed change, are skipped
+func (se *SimulationEngine) sendIntervalUpdates(baseCandle models.OHLCV) {
+	if len(se.intervalAggregators) == 0 {
+		return
+	}
+
+	baseDurationMs := models.GetIntervalDurationMs(se.baseInterval)
+	for _, aggregator := range se.intervalAggregators {
+		if aggregator.durationMs < baseDurationMs || aggregator.durationMs%baseDurationMs != 0 {
+			aggregator.current = nil
+			continue
+		}
+
+		candle := aggregator.add(baseCandle)
+		if candle == nil || se.client == nil {
+			continue
+		}
+		se.client.SendMessage(types.IntervalUpdate, IntervalUpdateData{
+			Symbol:         se.symbol,
+			Interval:       aggregator.interval,
+			Candle:         *candle,
+			SimulationTime: se.currentSimTime,
+		})
+	}
+}
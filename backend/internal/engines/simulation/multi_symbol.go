@@ -0,0 +1,220 @@
+package simulation
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/types"
+)
+
+// MaxExtraSymbols is the most additional symbols a simulation can trade alongside its main symbol
+const MaxExtraSymbols = 4
+
+// symbolFeedReloadCandles is how few unplayed candles an extra symbol keeps buffered before more are fetched
+const symbolFeedReloadCandles = 10
+
+// symbolFeed streams the base candles of an extra symbol on the simulation clock
+type symbolFeed struct {
+	symbol     string
+	dataset    []models.OHLCV
+	index      int     // Position of the next candle to play
+	price      float64 // Close of the last played candle
+	priceTime  int64
+	noMoreData bool
+}
+
+// validateExtraSymbols checks that each extra symbol is supported and differs from the main symbol and the others
+func (se *SimulationEngine) validateExtraSymbols(symbol string, symbols []string) error {
+	if len(symbols) > MaxExtraSymbols {
+		return fmt.Errorf("too many symbols: %d, at most %d can be added", len(symbols), MaxExtraSymbols)
+	}
+
+	supported := make(map[string]bool)
+	for _, supportedSymbol := range se.marketData.GetSupportedSymbols() {
+		supported[supportedSymbol] = true
+	}
+
+	seen := map[string]bool{symbol: true}
+	for _, extra := range symbols {
+		if seen[extra] {
+			return fmt.Errorf("duplicate symbol: %s", extra)
+		}
+		seen[extra] = true
+
+		if !supported[extra] {
+			return fmt.Errorf("unsupported symbol: %q", extra)
+		}
+	}
+	return nil
+}
+
+// normalizeSymbols returns the canonical form of each user-supplied extra symbol
+func normalizeSymbols(symbols []string) []string {
+	if len(symbols) == 0 {
+		return nil
+	}
+	normalized := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		normalized[i] = models.NormalizeSymbol(symbol)
+	}
+	return normalized
+}
+
+// loadSymbolFeeds fetches base candles for each extra symbol from fetchStart up to endTime and positions every
+// feed at positionTime, priced at the close of the last candle completed by then
+func (se *SimulationEngine) loadSymbolFeeds(symbols []string, fetchStart, positionTime int64, endTime *int64) ([]*symbolFeed, error) {
+	feeds := make([]*symbolFeed, 0, len(symbols))
+	for _, symbol := range symbols {
+		dataset, err := se.loadHistoricalDataset(symbol, se.baseInterval, fetchStart, endTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load base dataset for %s: %w", symbol, err)
+		}
+
+		feed := &symbolFeed{symbol: symbol, dataset: dataset}
+		feed.index = sort.Search(len(dataset), func(i int) bool {
+			return dataset[i].EndTime > positionTime
+		})
+		if feed.index > 0 {
+			previous := dataset[feed.index-1]
+			feed.price = previous.Close
+			feed.priceTime = previous.EndTime
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, nil
+}
+
+// reloadSymbolFeedsUnsafe refetches the extra symbols at the current base interval around the current time (caller must hold lock)
+// Used whenever the main dataset is replaced, so every symbol stays on the same clock and base interval
+func (se *SimulationEngine) reloadSymbolFeedsUnsafe() error {
+	if len(se.options.Symbols) == 0 {
+		return nil
+	}
+
+	fetchStart := se.currentSimTime - seekLookbackCandles*models.GetIntervalDurationMs(se.baseInterval)
+	feeds, err := se.loadSymbolFeeds(se.options.Symbols, fetchStart, se.currentSimTime, se.dataEndTime())
+	if err != nil {
+		return err
+	}
+	se.symbolFeeds = feeds
+	return nil
+}
+
+// advanceSymbolFeedsUnsafe plays every extra symbol candle completed by untilTime, filling that symbol's orders
+// and streaming the candle as a simulation_update for the symbol (caller must hold lock)
+func (se *SimulationEngine) advanceSymbolFeedsUnsafe(untilTime int64) {
+	for _, feed := range se.symbolFeeds {
+		for {
+			if len(feed.dataset)-feed.index < symbolFeedReloadCandles {
+				se.loadMoreSymbolData(feed)
+			}
+			if feed.index >= len(feed.dataset) || feed.dataset[feed.index].EndTime > untilTime {
+				break
+			}
+
+			candle := feed.dataset[feed.index]
+			feed.price = candle.Close
+			feed.priceTime = candle.EndTime
+			feed.index++
+
+			if se.orderExecutionEngine != nil {
+				if trades, err := se.orderExecutionEngine.ProcessCandle(feed.symbol, candle); err != nil {
+					log.Printf("Error processing pending %s orders at price %.8f: %v", feed.symbol, candle.Close, err)
+				} else if len(trades) > 0 {
+					log.Printf("Processed %d pending %s order executions at price %.8f", len(trades), feed.symbol, candle.Close)
+				}
+			}
+
+			if se.client != nil {
+				se.client.SendMessage(types.SimulationUpdate, SimulationUpdateData{
+					Symbol:         feed.symbol,
+					BaseCandle:     candle,
+					SimulationTime: se.currentSimTime,
+					Progress:       se.progressUnsafe(),
+					State:          string(se.state),
+					Speed:          se.speed,
+				})
+			}
+		}
+	}
+}
+
+// loadMoreSymbolData appends the next batch of an extra symbol's candles, dropping played ones beyond the buffer size
+// Extra symbols load inline on the simulation loop, as they only fetch when their buffer is nearly played out
+func (se *SimulationEngine) loadMoreSymbolData(feed *symbolFeed) {
+	if feed.noMoreData || len(feed.dataset) == 0 {
+		return
+	}
+	if se.options.EndTime > 0 && feed.dataset[len(feed.dataset)-1].EndTime >= se.options.EndTime {
+		return
+	}
+
+	startTime := feed.dataset[len(feed.dataset)-1].StartTime + 1
+	data, err := se.fetchDataBatch(feed.symbol, se.baseInterval, startTime, se.dataEndTime())
+	if err != nil {
+		log.Printf("Failed to load more %s data: %v", feed.symbol, err)
+		return
+	}
+	if len(data) == 0 {
+		log.Printf("No more historical data available for %s", feed.symbol)
+		feed.noMoreData = true
+		return
+	}
+
+	feed.dataset = append(feed.dataset, data...)
+	if excess := len(feed.dataset) - se.maxBufferSize; excess > 0 && se.maxBufferSize > 0 {
+		if excess > feed.index {
+			excess = feed.index
+		}
+		feed.dataset = feed.dataset[excess:]
+		feed.index -= excess
+	}
+}
+
+// currentPricesUnsafe returns the latest played close of the main symbol and every extra symbol (caller must hold lock)
+// Symbols that haven't played a candle yet are left out
+func (se *SimulationEngine) currentPricesUnsafe() map[string]float64 {
+	prices := make(map[string]float64, len(se.symbolFeeds)+1)
+	if se.currentPrice > 0 {
+		prices[se.symbol] = se.currentPrice
+	}
+	for _, feed := range se.symbolFeeds {
+		if feed.price > 0 {
+			prices[feed.symbol] = feed.price
+		}
+	}
+	return prices
+}
+
+// PriceFor returns the latest price of a symbol the simulation plays, 0 for other symbols or before its first candle
+func (s SimulationStatus) PriceFor(symbol string) float64 {
+	if symbol == s.Symbol {
+		return s.CurrentPrice
+	}
+	return s.Prices[symbol]
+}
+
+// Plays reports whether the simulation streams the symbol, either as its main symbol or an extra one
+func (s SimulationStatus) Plays(symbol string) bool {
+	if symbol == s.Symbol {
+		return true
+	}
+	for _, extra := range s.Symbols {
+		if extra == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentPrices returns the latest price of every symbol the simulation plays
+func (s SimulationStatus) CurrentPrices() map[string]float64 {
+	prices := make(map[string]float64, len(s.Prices)+1)
+	for symbol, price := range s.Prices {
+		prices[symbol] = price
+	}
+	prices[s.Symbol] = s.CurrentPrice
+	return prices
+}
@@ -0,0 +1,60 @@
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	simulationDAO "tradesimulator/internal/dao/simulation"
+	"tradesimulator/internal/models"
+)
+
+// checkpointInterval is how often a playing simulation stores its simulation time, bounding what a server restart loses
+const checkpointInterval = 30 * time.Second
+
+// maybeCheckpointUnsafe stores the last processed candle time as the simulation's last sim time once the checkpoint interval has passed (caller must hold lock)
+// The end time is left for when the simulation stops; recovery stops it at the checkpoint, so a simulation interrupted by a restart resumes close to where it was
+func (se *SimulationEngine) maybeCheckpointUnsafe() {
+	if se.currentSimulationID == 0 || se.currentPriceTime == 0 {
+		return
+	}
+	if time.Since(se.lastCheckpointAt) < checkpointInterval {
+		return
+	}
+
+	if err := se.simulationDAO.UpdateLastSimTime(se.currentSimulationID, se.currentPriceTime); err != nil {
+		log.Printf("Failed to checkpoint simulation %d: %v", se.currentSimulationID, err)
+	}
+	se.lastCheckpointAt = time.Now()
+}
+
+// RecoverInterruptedSimulations stops the simulations a previous server process left running or paused
+// Engines only live as long as the process, so these records have no engine behind them. Each is stopped at its last
+// checkpointed time (its start time if none was stored), after which a client can continue it with a resume carrying
+// the simulation ID: symbol, speed, timeframe and options come from the stored config and pending orders are reloaded
+func RecoverInterruptedSimulations(dao simulationDAO.SimulationDAOInterface, userID uint) (int, error) {
+	recovered := 0
+	for {
+		simulation, err := dao.GetRunningSimulation(userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return recovered, nil
+		}
+		if err != nil {
+			return recovered, fmt.Errorf("failed to find interrupted simulations: %w", err)
+		}
+
+		resumeTime := simulation.LastSimTime
+		if resumeTime == 0 {
+			resumeTime = simulation.StartSimTime
+		}
+		if err := dao.UpdateSimulationStatusWithDetails(simulation.ID, models.SimulationStatusStopped, resumeTime, nil); err != nil {
+			return recovered, fmt.Errorf("failed to stop interrupted simulation %d: %w", simulation.ID, err)
+		}
+
+		log.Printf("Stopped simulation %d interrupted by a server restart at simulation time %d", simulation.ID, resumeTime)
+		recovered++
+	}
+}
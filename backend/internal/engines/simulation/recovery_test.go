@@ -0,0 +1,76 @@
+package simulation
+
+import (
+	"testing"
+
+	"tradesimulator/internal/models"
+)
+
+// Checkpoints go to the last sim time, leaving the end time for when the simulation stops
+func TestCheckpointWritesLastSimTime(t *testing.T) {
+	te := newTestEngine(t)
+	record, err := te.simulationDAO.CreateSimulationRecord(1, "BTCUSDT", testStartTime, 0, 10000, models.SimulationModeSpot, nil)
+	if err != nil {
+		t.Fatalf("CreateSimulationRecord: %v", err)
+	}
+
+	te.mu.Lock()
+	te.currentSimulationID = record.ID
+	te.currentPriceTime = testStartTime + 3_600_000
+	te.maybeCheckpointUnsafe()
+	// Within the checkpoint interval nothing is written
+	te.currentPriceTime = testStartTime + 7_200_000
+	te.maybeCheckpointUnsafe()
+	te.mu.Unlock()
+
+	stored, err := te.simulationDAO.GetSimulationByID(record.ID)
+	if err != nil {
+		t.Fatalf("GetSimulationByID: %v", err)
+	}
+	if stored.LastSimTime != testStartTime+3_600_000 {
+		t.Errorf("last sim time = %d, want the first checkpoint %d", stored.LastSimTime, testStartTime+3_600_000)
+	}
+	if stored.EndSimTime != 0 || stored.Status != models.SimulationStatusRunning {
+		t.Errorf("end time %d and status %s, want 0 and running", stored.EndSimTime, stored.Status)
+	}
+}
+
+// Interrupted simulations are stopped at their last checkpoint, or their start time without one
+func TestRecoverInterruptedSimulations(t *testing.T) {
+	te := newTestEngine(t)
+	checkpointed, err := te.simulationDAO.CreateSimulationRecord(1, "BTCUSDT", testStartTime, 0, 10000, models.SimulationModeSpot, nil)
+	if err != nil {
+		t.Fatalf("CreateSimulationRecord: %v", err)
+	}
+	if err := te.simulationDAO.UpdateLastSimTime(checkpointed.ID, testStartTime+3_600_000); err != nil {
+		t.Fatalf("UpdateLastSimTime: %v", err)
+	}
+	fresh, err := te.simulationDAO.CreateSimulationRecord(1, "ETHUSDT", testStartTime, 0, 10000, models.SimulationModeSpot, nil)
+	if err != nil {
+		t.Fatalf("CreateSimulationRecord: %v", err)
+	}
+
+	recovered, err := RecoverInterruptedSimulations(te.simulationDAO, 1)
+	if err != nil {
+		t.Fatalf("RecoverInterruptedSimulations: %v", err)
+	}
+	if recovered != 2 {
+		t.Errorf("recovered %d simulations, want 2", recovered)
+	}
+
+	for _, tt := range []struct {
+		id   uint
+		want int64
+	}{
+		{id: checkpointed.ID, want: testStartTime + 3_600_000},
+		{id: fresh.ID, want: testStartTime},
+	} {
+		stored, err := te.simulationDAO.GetSimulationByID(tt.id)
+		if err != nil {
+			t.Fatalf("GetSimulationByID: %v", err)
+		}
+		if stored.Status != models.SimulationStatusStopped || stored.EndSimTime != tt.want {
+			t.Errorf("simulation %d is %s at %d, want stopped at %d", tt.id, stored.Status, stored.EndSimTime, tt.want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package simulation
+
+import (
+	"fmt"
+	"log"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/types"
+)
+
+// ReplayRestartedData is sent when a looping simulation reaches its end and replays from the start time
+type ReplayRestartedData struct {
+	SimulationID         uint  `json:"simulationID"`
+	PreviousSimulationID uint  `json:"previousSimulationID,omitempty"` // The completed record when the portfolio was reset
+	LoopCount            int   `json:"loopCount"`
+	StartTime            int64 `json:"startTime"`
+	PortfolioReset       bool  `json:"portfolioReset"`
+}
+
+// restartReplayUnsafe rewinds a looping simulation to its start time (caller must hold lock)
+// With ResetPortfolioOnLoop the finished pass is completed and the replay continues as a new simulation record
+// with the same settings and initial funding; otherwise the record, portfolio and open orders carry on
+// It reports false when the replay couldn't restart, leaving the simulation to complete as usual
+func (se *SimulationEngine) restartReplayUnsafe() bool {
+	baseDataset, err := se.loadHistoricalDataset(se.symbol, se.baseInterval, se.startTime, se.dataEndTime())
+	if err != nil {
+		log.Printf("Failed to restart replay of simulation %d: %v", se.currentSimulationID, err)
+		se.sendErrorMessage("Failed to restart replay", err.Error())
+		return false
+	}
+	symbolFeeds, err := se.loadSymbolFeeds(se.options.Symbols, se.startTime, se.startTime, se.dataEndTime())
+	if err != nil {
+		log.Printf("Failed to restart replay of simulation %d: %v", se.currentSimulationID, err)
+		se.sendErrorMessage("Failed to restart replay", err.Error())
+		return false
+	}
+
+	previousSimulationID := se.currentSimulationID
+	if se.options.ResetPortfolioOnLoop {
+		if err := se.startLoopRecordUnsafe(); err != nil {
+			log.Printf("Failed to restart replay of simulation %d: %v", previousSimulationID, err)
+			se.sendErrorMessage("Failed to restart replay", err.Error())
+			return false
+		}
+	} else {
+		// Simulation time repeats from here, which a single equity curve can't hold, so only the first pass is sampled
+		se.equitySampleStepMs = 0
+	}
+
+	se.baseDataset = baseDataset
+	se.symbolFeeds = symbolFeeds
+	se.currentIndex = 0
+	se.currentSimTime = se.startTime
+	se.currentPriceTime = se.startTime
+	se.currentPrice = 0
+	se.isLoadingData = false
+	se.noMoreDataAvailable = false
+	se.lastDataLoadTime = baseDataset[len(baseDataset)-1].StartTime
+	se.resetIntervalAggregators()
+	se.resetSmoothPlaybackClock()
+	// Activate rather than advance, as the cutoff moves back to the start time
+	se.activateMarketCutoff()
+	se.loopCount++
+
+	log.Printf("Simulation %d replaying from %d, loop %d (portfolio reset: %t)",
+		se.currentSimulationID, se.startTime, se.loopCount, se.options.ResetPortfolioOnLoop)
+	if se.client != nil {
+		data := ReplayRestartedData{
+			SimulationID:   se.currentSimulationID,
+			LoopCount:      se.loopCount,
+			StartTime:      se.startTime,
+			PortfolioReset: se.options.ResetPortfolioOnLoop,
+		}
+		if se.options.ResetPortfolioOnLoop {
+			data.PreviousSimulationID = previousSimulationID
+		}
+		se.client.SendMessage(types.ReplayRestarted, data)
+	}
+	se.sendStatusUpdateUnsafe(fmt.Sprintf("Replay restarted, loop %d", se.loopCount))
+	return true
+}
+
+// startLoopRecordUnsafe completes the current simulation record and continues in a new one funded like it (caller must hold lock)
+func (se *SimulationEngine) startLoopRecordUnsafe() error {
+	previous, err := se.simulationDAO.GetSimulationByID(se.currentSimulationID)
+	if err != nil {
+		return fmt.Errorf("failed to get simulation record: %w", err)
+	}
+
+	se.settleOpenOrdersOnStopUnsafe()
+	se.updateSimulationStatusWithPortfolioValue(models.SimulationStatusCompleted)
+	se.deactivateMarketCutoff()
+
+	extraConfig := extraConfigFromOptions(se.speed, se.interval, se.options)
+	record, err := se.simulationDAO.CreateSimulationRecord(1, se.symbol, se.startTime, 0, previous.InitialFunding, se.options.mode(), extraConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create simulation record: %w", err)
+	}
+	se.currentSimulationID = record.ID
+
+	// Create initial USDT position for the simulation (use user ID 1 as default for simulation)
+	if previous.InitialFunding > 0 {
+		if err := se.positionDAO.CreateInitialUSDTPosition(1, &record.ID, previous.InitialFunding); err != nil {
+			return fmt.Errorf("failed to create initial USDT position: %w", err)
+		}
+	}
+
+	// Orders left open on the completed record must not fill into the new one
+	if se.orderExecutionEngine != nil {
+		if err := se.orderExecutionEngine.ResetOrderBook(record.ID); err != nil {
+			log.Printf("Failed to reset order book for simulation %d: %v", record.ID, err)
+		}
+	}
+
+	se.candlesProcessed = 0
+	se.warmupComplete = se.options.WarmupCandles == 0 && se.options.WarmupDurationMs == 0
+	se.resetTradingStatsUnsafe(se.startTime)
+	se.startEquitySamplingUnsafe(se.startTime, previous.InitialFunding)
+	return nil
+}
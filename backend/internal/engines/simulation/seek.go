@@ -0,0 +1,146 @@
+package simulation
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"tradesimulator/internal/models"
+)
+
+// seekLookbackCandles is how many base candles before the target a fresh fetch starts at,
+// so the candle that closed just before the target is available to price the new position
+const seekLookbackCandles = 2
+
+// SeekTo moves a running simulation to targetSimTime without restarting it
+// Playback continues with the first base candle closing after the target, and the current price becomes the close of the
+// candle before it; data is fetched afresh when the target lies outside the buffer
+// Orders, trades and positions are not rewound, and blind simulations can only seek forward
+func (se *SimulationEngine) SeekTo(targetSimTime int64) error {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	if se.state != StatePlaying && se.state != StatePaused {
+		return fmt.Errorf("simulation not running")
+	}
+	if targetSimTime < se.startTime {
+		return fmt.Errorf("cannot seek to %d, before the simulation start time %d", targetSimTime, se.startTime)
+	}
+	if targetSimTime > time.Now().UnixMilli() {
+		return fmt.Errorf("cannot seek to %d, past the latest market data", targetSimTime)
+	}
+	if se.options.EndTime > 0 && targetSimTime > se.options.EndTime {
+		return fmt.Errorf("cannot seek to %d, past the simulation end time %d", targetSimTime, se.options.EndTime)
+	}
+	if se.options.Blind && targetSimTime < se.currentSimTime {
+		return fmt.Errorf("blind simulations cannot seek backwards")
+	}
+
+	if err := se.seekToUnsafe(targetSimTime); err != nil {
+		return err
+	}
+	se.sendStatusUpdateUnsafe(fmt.Sprintf("Simulation moved to %d", targetSimTime))
+	return nil
+}
+
+// StepBack moves a running simulation back by steps base candles kept in the buffer, to replay them
+// Playback continues with the earliest stepped back candle and the current price becomes the close of the candle
+// before it, so that candle must still be buffered; up to 100 played candles are kept (see cleanupOldData)
+// Like SeekTo, orders, trades and positions are not rewound
+func (se *SimulationEngine) StepBack(steps int) error {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	if se.state != StatePlaying && se.state != StatePaused {
+		return fmt.Errorf("simulation not running")
+	}
+	if se.options.Blind {
+		return fmt.Errorf("blind simulations cannot step backwards")
+	}
+	if steps <= 0 {
+		return fmt.Errorf("invalid steps: %d, must be positive", steps)
+	}
+
+	retained := se.retainedStepsUnsafe()
+	if steps > retained {
+		return fmt.Errorf("cannot step back %d candles, only %d are retained", steps, retained)
+	}
+
+	targetSimTime := se.baseDataset[se.currentIndex-steps-1].EndTime
+	if err := se.seekToUnsafe(targetSimTime); err != nil {
+		return err
+	}
+	se.sendStatusUpdateUnsafe(fmt.Sprintf("Simulation stepped back %d candles", steps))
+	return nil
+}
+
+// retainedStepsUnsafe returns how many played base candles StepBack can return to: every buffered candle before the
+// current index except the first, which prices the position, and none starting before the simulation start time
+// (caller must hold lock)
+func (se *SimulationEngine) retainedStepsUnsafe() int {
+	retained := 0
+	for i := min(se.currentIndex, len(se.baseDataset)) - 1; i > 0; i-- {
+		if se.baseDataset[i].StartTime < se.startTime {
+			break
+		}
+		retained++
+	}
+	return retained
+}
+
+// seekToUnsafe moves playback to targetSimTime, fetching data when the buffer doesn't cover it (caller must hold lock)
+func (se *SimulationEngine) seekToUnsafe(targetSimTime int64) error {
+	index, ok := seekIndex(se.baseDataset, targetSimTime)
+	if !ok {
+		// Fetch a fresh buffer starting shortly before the target
+		fetchStart := targetSimTime - seekLookbackCandles*models.GetIntervalDurationMs(se.baseInterval)
+		dataset, err := se.loadHistoricalDataset(se.symbol, se.baseInterval, fetchStart, se.dataEndTime())
+		if err != nil {
+			return fmt.Errorf("failed to load data for seek: %w", err)
+		}
+		if dataset[len(dataset)-1].EndTime <= targetSimTime {
+			return fmt.Errorf("no market data available after %d", targetSimTime)
+		}
+
+		se.baseDataset = dataset
+		se.isLoadingData = false
+		se.noMoreDataAvailable = false
+		se.lastDataLoadTime = dataset[len(dataset)-1].StartTime
+		index, _ = seekIndex(se.baseDataset, targetSimTime)
+	}
+
+	se.currentIndex = index
+	se.currentSimTime = targetSimTime
+	if index > 0 {
+		previous := se.baseDataset[index-1]
+		se.currentPrice = previous.Close
+		se.currentPriceTime = previous.EndTime
+	} else {
+		se.currentPrice = se.baseDataset[index].Open
+		se.currentPriceTime = targetSimTime
+	}
+
+	if err := se.reloadSymbolFeedsUnsafe(); err != nil {
+		return fmt.Errorf("failed to load data for seek: %w", err)
+	}
+
+	se.advanceMarketCutoff()
+	se.resetIntervalAggregators()
+	se.resetSmoothPlaybackClock()
+
+	log.Printf("Simulation %d seeked to %d, index %d of %d buffered candles", se.currentSimulationID, targetSimTime, index, len(se.baseDataset))
+	return nil
+}
+
+// seekIndex finds the first buffered candle closing after targetSimTime
+// It reports false when the buffer doesn't cover the target with a candle on both sides, so the price can't be set
+func seekIndex(dataset []models.OHLCV, targetSimTime int64) (int, bool) {
+	if len(dataset) == 0 || dataset[0].EndTime > targetSimTime {
+		return 0, false
+	}
+	index := sort.Search(len(dataset), func(i int) bool {
+		return dataset[i].EndTime > targetSimTime
+	})
+	return index, index < len(dataset)
+}
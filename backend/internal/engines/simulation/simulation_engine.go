@@ -9,12 +9,31 @@ import (
 
 	simulationDAO "tradesimulator/internal/dao/simulation"
 	tradingDAO "tradesimulator/internal/dao/trading"
-	"tradesimulator/internal/integrations/binance"
+	"tradesimulator/internal/engines/trading"
+	"tradesimulator/internal/integrations/marketdata"
 	"tradesimulator/internal/models"
 	"tradesimulator/internal/services"
 	"tradesimulator/internal/types"
 )
 
+const (
+	// maxDataBatchSize is the most candles Binance returns per request
+	maxDataBatchSize = 1000
+	// maxDataBatchSpanMs bounds the market time loaded per batch, so coarse base intervals
+	// (4h, 1d) don't pull years of data that a replay will rarely reach
+	maxDataBatchSpanMs = 90 * 24 * 60 * 60 * 1000
+	// minDataBatchSize keeps batches large enough that loading isn't triggered every few ticks
+	minDataBatchSize = 60
+	// MaxDataBatchCandles bounds a configured batch size to the default in-memory buffer; batches
+	// above maxDataBatchSize are loaded with consecutive range requests
+	MaxDataBatchCandles = 5000
+
+	// DefaultMaxSpeed is the default speed cap: one week of market time per real second
+	DefaultMaxSpeed = 7 * 24 * 60 * 60
+	// minTickerInterval is the fastest the simulation loop ticks; higher speeds advance more per tick
+	minTickerInterval = 10 * time.Millisecond
+)
+
 // ClientMessageSender interface for sending messages to a specific client
 type ClientMessageSender interface {
 	SendMessage(messageType types.MessageType, data interface{})
@@ -43,7 +62,7 @@ type SimulationEngine struct {
 	startTime      int64 // Start time in milliseconds
 	ctx            context.Context
 	cancel         context.CancelFunc
-	binanceService *binance.BinanceService
+	marketData     marketdata.MarketDataProvider
 
 	// Base data streaming support
 	baseInterval     string         // Optimal base interval (1m, 5m, etc.)
@@ -71,19 +90,140 @@ type SimulationEngine struct {
 	positionDAO         tradingDAO.PositionDAOInterface      // DAO for managing positions
 
 	// Order execution integration
-	orderExecutionEngine interface {
-		ProcessPriceUpdate(symbol string, currentPrice float64, simulationTime int64) ([]*models.Trade, error)
-		LoadPendingOrders(simulationID uint) error
-	} // Order execution engine for processing limit orders
+	orderExecutionEngine OrderProcessor // Order execution engine for processing pending orders
+	options              SimulationOptions
+
+	// Warmup tracking (orders are rejected until warmup completes)
+	candlesProcessed int  // Base candles processed since start
+	warmupComplete   bool // Whether the configured warmup has elapsed
+
+	// Number of symbol prices fetched in parallel when valuing the portfolio
+	valuationConcurrency int
+
+	// Highest accepted speed in market seconds per real second
+	maxSpeed int
+
+	// Replays restarted from the start time by a looping simulation
+	loopCount int
+
+	// Base candles loaded per data batch (0 sizes batches by the base interval)
+	dataBatchCandles int
+
+	// Shared registry enforcing the future-data cutoff for market endpoints
+	cutoffRegistry  *services.MarketCutoffRegistry
+	clampMarketData bool // Register a cutoff for every simulation, not only blind ones
+
+	// Periodic trading_stats messages every tradingStatsInterval base candles (0 disables)
+	tradingStatsInterval     int
+	candlesSinceTradingStats int
+	tradingStatsStartTime    int64 // Market time trades per minute is measured from
+
+	// Portfolio value sampling stored as the simulation's equity curve
+	equitySampleIntervalMs int64 // Configured simulation time between samples (0 disables)
+	maxEquitySamples       int   // Samples kept per simulation before thinning
+	equitySampleStepMs     int64 // Current interval, doubled each time the samples are thinned
+	equitySampleCount      int
+	lastEquitySampleTime   int64
+
+	// Candle builders for the simulation's extra intervals, and for its display interval when candles are aggregated
+	intervalAggregators []*intervalAggregator
+	displayAggregator   *intervalAggregator
+
+	// Base candle streams of the simulation's extra symbols, in the order they were requested
+	symbolFeeds []*symbolFeed
+
+	lastCheckpointAt time.Time // Wall clock time the simulation time was last stored
+
+	// Smooth playback emits a steady number of base candles per tick (0 uses time based ticks)
+	smoothCandlesPerTick int
+	smoothBatch          int       // Candles per tick after raising it to respect minTickerInterval
+	smoothClockStart     time.Time // Real time the emitted candle count is measured from
+	smoothClockCandles   int       // Candles emitted since smoothClockStart
+
+	// Stores the candles of stopped and completed simulations for offline review (nil disables)
+	candleArchiver CandleArchiver
+
+	// What happens to open orders when the simulation stops or completes
+	stopOrderBehavior StopOrderBehavior
+}
+
+// OrderProcessor is the part of the order execution engine driven by the simulation loop
+type OrderProcessor interface {
+	ProcessCandle(symbol string, candle models.OHLCV) ([]*models.Trade, error)
+	LoadPendingOrders(simulationID uint) error
+	CancelOpenOrders(userID, simulationID uint) ([]*models.Order, error)
+	SetExecutionOptions(options trading.ExecutionOptions)
+	GetTradeStats() trading.TradeStats
+	GetUnrealizedPnL(prices map[string]float64) float64
+	ResetTradeStats(simulationID uint) error
+	ResetOrderBook(simulationID uint) error
+}
+
+// SimulationOptions holds optional per-simulation settings supplied when starting a simulation
+type SimulationOptions struct {
+	Execution trading.ExecutionOptions
+
+	// Warmup streams candles without allowing trades until both limits are reached (0 disables)
+	WarmupCandles    int   // Number of base candles to process before trading
+	WarmupDurationMs int64 // Market time in milliseconds to elapse before trading
+
+	// Base candles before the start time sent to the client to prime indicators, never traded on (0 disables)
+	WarmupBars int
+
+	// Blind mode stops market data endpoints from serving data past the simulation time
+	Blind bool
+
+	// Additional intervals streamed as completed candles alongside the base candles (empty for single interval)
+	Intervals []string
+
+	// Simulation time at which the run completes, also used for progress (0 runs until data runs out)
+	EndTime int64
+
+	// Additional symbols played on the same clock as the main symbol and tradable in the same portfolio
+	Symbols []string
+
+	// Loop replays from the start time whenever the run reaches its end time or the end of data, and
+	// ResetPortfolioOnLoop starts each replay as a new simulation record with the initial funding
+	Loop                 bool
+	ResetPortfolioOnLoop bool
+
+	// AggregateCandles rolls base candles up to the display interval on the server, for clients that don't aggregate
+	AggregateCandles bool
+}
+
+// mode returns futures mode for leveraged simulations and spot otherwise
+func (so SimulationOptions) mode() models.SimulationMode {
+	if so.Execution.Leveraged() {
+		return models.SimulationModeFuture
+	}
+	return models.SimulationModeSpot
+}
+
+// Validate checks the options and fills in defaults for empty values
+func (so *SimulationOptions) Validate() error {
+	if so.WarmupCandles < 0 {
+		return fmt.Errorf("invalid warmup candles: %d, must not be negative", so.WarmupCandles)
+	}
+	if so.WarmupDurationMs < 0 {
+		return fmt.Errorf("invalid warmup duration: %d, must not be negative", so.WarmupDurationMs)
+	}
+	if so.WarmupBars < 0 || so.WarmupBars > MaxWarmupBars {
+		return fmt.Errorf("invalid warmup bars: %d, must be between 0 and %d", so.WarmupBars, MaxWarmupBars)
+	}
+	if so.ResetPortfolioOnLoop && !so.Loop {
+		return fmt.Errorf("resetting the portfolio on loop requires loop to be enabled")
+	}
+	return so.Execution.Validate()
 }
 
 type SimulationUpdateData struct {
-	Symbol         string       `json:"symbol"`
-	BaseCandle     models.OHLCV `json:"baseCandle"` // Single complete base candle
-	SimulationTime int64        `json:"simulationTime"`
-	Progress       float64      `json:"progress"` // 0-100%
-	State          string       `json:"state"`
-	Speed          int          `json:"speed"`
+	Symbol         string        `json:"symbol"`
+	BaseCandle     models.OHLCV  `json:"baseCandle"`              // Single complete base candle
+	DisplayCandle  *models.OHLCV `json:"displayCandle,omitempty"` // Display interval candle so far, when candles are aggregated
+	SimulationTime int64         `json:"simulationTime"`
+	Progress       float64       `json:"progress"` // 0-100%
+	State          string        `json:"state"`
+	Speed          int           `json:"speed"`
 }
 
 type SimulationStatus struct {
@@ -91,39 +231,78 @@ type SimulationStatus struct {
 	Symbol           string  `json:"symbol"`
 	Interval         string  `json:"interval"`
 	Speed            int     `json:"speed"`
-	Progress         float64 `json:"progress"`
+	Progress         float64 `json:"progress"` // 0-100%, time-based when the simulation has an end time
 	StartTime        int64   `json:"startTime"`
+	EndTime          int64   `json:"endTime,omitempty"`
 	CurrentPriceTime int64   `json:"currentPriceTime"`
 	CurrentPrice     float64 `json:"currentPrice"`
 	SimulationID     uint    `json:"simulationID"`
 	IsRunning        bool    `json:"isRunning"`
 	SimulationTime   int64   `json:"simulationTime"`
 	Message          string  `json:"message"`
+
+	WarmingUp              bool  `json:"warmingUp"`
+	WarmupCandlesRemaining int   `json:"warmupCandlesRemaining,omitempty"`
+	WarmupEndTime          int64 `json:"warmupEndTime,omitempty"` // Simulation time when the warmup duration elapses
+
+	Blind     bool     `json:"blind,omitempty"`
+	Intervals []string `json:"intervals,omitempty"` // Extra intervals streamed as interval_update messages
+
+	Loop      bool `json:"loop,omitempty"`
+	LoopCount int  `json:"loopCount,omitempty"` // Replays restarted from the start time so far
+
+	AggregateCandles bool `json:"aggregateCandles,omitempty"` // simulation_update carries the display interval candle
+
+	Symbols []string           `json:"symbols,omitempty"` // Extra symbols played alongside symbol
+	Prices  map[string]float64 `json:"prices,omitempty"`  // Latest close of every symbol in a multi-symbol simulation
+
+	Data DataStatus `json:"data"`
 }
 
-func NewSimulationEngine(client ClientMessageSender, binanceService *binance.BinanceService, portfolioService *services.PortfolioService, simDAO simulationDAO.SimulationDAOInterface, positionDAO tradingDAO.PositionDAOInterface, orderEngine interface {
-	ProcessPriceUpdate(symbol string, currentPrice float64, simulationTime int64) ([]*models.Trade, error)
-	LoadPendingOrders(simulationID uint) error
-}) *SimulationEngine {
+// DataStatus reports the engine's historical data loading, to tell a mid-load pause from a stall or the end of data
+type DataStatus struct {
+	IsLoadingData       bool    `json:"isLoadingData"`
+	NoMoreDataAvailable bool    `json:"noMoreDataAvailable"` // The exchange returned no candles past the buffer
+	BufferedCandles     int     `json:"bufferedCandles"`
+	CandlesAhead        int     `json:"candlesAhead"` // Buffered base candles not yet played
+	MaxBufferSize       int     `json:"maxBufferSize"`
+	BufferFillPct       float64 `json:"bufferFillPct"`
+	LastDataLoadTime    int64   `json:"lastDataLoadTime"` // Start time of the last loaded candle in milliseconds
+}
+
+// WarmupCompleteData is sent once the configured warmup has elapsed
+type WarmupCompleteData struct {
+	SimulationID     uint  `json:"simulationID"`
+	CandlesProcessed int   `json:"candlesProcessed"`
+	SimulationTime   int64 `json:"simulationTime"`
+}
+
+func NewSimulationEngine(client ClientMessageSender, marketData marketdata.MarketDataProvider, portfolioService *services.PortfolioService, simDAO simulationDAO.SimulationDAOInterface, positionDAO tradingDAO.PositionDAOInterface, orderEngine OrderProcessor) *SimulationEngine {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &SimulationEngine{
-		state:                StateStopped,
-		speed:                1,
-		client:               client,
-		stopChan:             make(chan struct{}),
-		ctx:                  ctx,
-		cancel:               cancel,
-		binanceService:       binanceService,
-		speedChangeChan:      make(chan int, 1),
-		timeframeChangeChan:  make(chan string, 1),
-		dataLoadThreshold:    0.8,  // Load more data when 80% consumed
-		maxBufferSize:        5000, // Keep max 5000 candles in memory
-		dataLoadChan:         make(chan bool, 1),
-		simulationDAO:        simDAO,
-		portfolioService:     portfolioService,
-		positionDAO:          positionDAO,
-		orderExecutionEngine: orderEngine,
+		state:                  StateStopped,
+		stopOrderBehavior:      StopOrderCancel,
+		speed:                  1,
+		client:                 client,
+		stopChan:               make(chan struct{}),
+		ctx:                    ctx,
+		cancel:                 cancel,
+		marketData:             marketData,
+		speedChangeChan:        make(chan int, 1),
+		timeframeChangeChan:    make(chan string, 1),
+		dataLoadThreshold:      0.8,  // Load more data when 80% consumed
+		maxBufferSize:          5000, // Keep max 5000 candles in memory
+		dataLoadChan:           make(chan bool, 1),
+		simulationDAO:          simDAO,
+		portfolioService:       portfolioService,
+		positionDAO:            positionDAO,
+		orderExecutionEngine:   orderEngine,
+		options:                SimulationOptions{Execution: trading.DefaultExecutionOptions()},
+		valuationConcurrency:   DefaultValuationConcurrency,
+		maxSpeed:               DefaultMaxSpeed,
+		equitySampleIntervalMs: DefaultEquitySampleIntervalMs,
+		maxEquitySamples:       DefaultMaxEquitySamples,
 	}
 }
 
@@ -134,7 +313,7 @@ func (se *SimulationEngine) SetClient(client ClientMessageSender) {
 	se.client = client
 }
 
-func (se *SimulationEngine) Start(symbol, interval string, startTime int64, speed int, initialFunding float64) error {
+func (se *SimulationEngine) Start(symbol, interval string, startTime int64, speed int, initialFunding float64, options SimulationOptions) error {
 	se.mu.Lock()
 	defer se.mu.Unlock()
 
@@ -142,15 +321,52 @@ func (se *SimulationEngine) Start(symbol, interval string, startTime int64, spee
 		return fmt.Errorf("simulation already running")
 	}
 
-	// Validate speed (allow any positive integer)
-	if speed <= 0 {
-		return fmt.Errorf("invalid speed: %d, must be positive", speed)
+	warmupBars, err := se.startUnsafe(symbol, interval, startTime, speed, initialFunding, options)
+	if err != nil {
+		return err
+	}
+
+	se.sendWarmupBarsUnsafe(warmupBars)
+
+	// Send initial status update
+	se.sendStatusUpdateUnsafe("Simulation started")
+
+	// Start the simulation goroutine
+	go se.runSimulation()
+
+	return nil
+}
+
+// startUnsafe validates the start settings, loads the data and creates the simulation record, leaving the simulation
+// playing at its start time; it returns the preloaded warmup bars for the client (caller must hold lock)
+func (se *SimulationEngine) startUnsafe(symbol, interval string, startTime int64, speed int, initialFunding float64, options SimulationOptions) ([]models.OHLCV, error) {
+	symbol = models.NormalizeSymbol(symbol)
+	options.Symbols = normalizeSymbols(options.Symbols)
+
+	if err := se.validateSpeed(speed); err != nil {
+		return nil, err
+	}
+
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := validateEndTime(startTime, options.EndTime); err != nil {
+		return nil, err
+	}
+
+	if err := se.validateExtraIntervals(options.Intervals, speed); err != nil {
+		return nil, err
+	}
+
+	if err := se.validateExtraSymbols(symbol, options.Symbols); err != nil {
+		return nil, err
 	}
 
 	// Validate timeframe is compatible with speed
 	if !se.isTimeframeAllowed(interval, speed) {
 		minAllowed := se.getMinAllowedTimeframe(speed)
-		return fmt.Errorf("timeframe %s not allowed at %dx speed. Use %s or higher", interval, speed, minAllowed)
+		return nil, fmt.Errorf("timeframe %s not allowed at %dx speed. Use %s or higher", interval, speed, minAllowed)
 	}
 
 	// Determine optimal base interval for progressive updates
@@ -159,14 +375,25 @@ func (se *SimulationEngine) Start(symbol, interval string, startTime int64, spee
 	se.speed = speed
 	se.baseInterval = se.getOptimalBaseInterval()
 
-	// Load base interval dataset for progressive candle building
-	baseDataset, err := se.loadHistoricalDataset(symbol, se.baseInterval, startTime)
+	// Load base interval dataset for progressive candle building, the options aren't applied yet
+	baseDataset, err := se.loadHistoricalDataset(symbol, se.baseInterval, startTime, dataEndTimeOf(options.EndTime))
 	if err != nil {
-		return fmt.Errorf("failed to load base dataset: %w", err)
+		return nil, fmt.Errorf("failed to load base dataset: %w", err)
 	}
 
 	if len(baseDataset) == 0 {
-		return fmt.Errorf("no historical data available from start time")
+		return nil, fmt.Errorf("no historical data available from start time")
+	}
+
+	// Preload history before the start time so indicators aren't cold when the replay begins
+	warmupBars, err := se.loadWarmupBars(symbol, startTime, options.WarmupBars)
+	if err != nil {
+		return nil, err
+	}
+
+	symbolFeeds, err := se.loadSymbolFeeds(options.Symbols, startTime, startTime, dataEndTimeOf(options.EndTime))
+	if err != nil {
+		return nil, err
 	}
 
 	// Reset all time-related state for new simulation
@@ -181,26 +408,31 @@ func (se *SimulationEngine) Start(symbol, interval string, startTime int64, spee
 
 	// Set new simulation data
 	se.baseDataset = baseDataset
+	se.symbolFeeds = symbolFeeds
 	se.startTime = startTime
 	se.currentSimTime = startTime
 	se.currentPriceTime = startTime
 	se.state = StatePlaying
 
 	// Create simulation record
-	extraConfig := &simulationDAO.ExtraConfig{
-		Speed:     speed,
-		Timeframe: interval,
-	}
-	simulationRecord, err := se.simulationDAO.CreateSimulationRecord(1, symbol, startTime, 0, initialFunding, models.SimulationModeSpot, extraConfig)
+	extraConfig := extraConfigFromOptions(speed, interval, options)
+	simulationRecord, err := se.simulationDAO.CreateSimulationRecord(1, symbol, startTime, 0, initialFunding, options.mode(), extraConfig)
 	if err != nil {
-		return fmt.Errorf("failed to create simulation record: %w", err)
+		return nil, fmt.Errorf("failed to create simulation record: %w", err)
 	}
 	se.currentSimulationID = simulationRecord.ID
+	se.loopCount = 0
+	se.applyOptions(options)
+	se.resetIntervalAggregators()
+	se.candlesProcessed = 0
+	se.warmupComplete = options.WarmupCandles == 0 && options.WarmupDurationMs == 0
+	se.resetTradingStatsUnsafe(startTime)
+	se.startEquitySamplingUnsafe(startTime, initialFunding)
 
 	// Create initial USDT position for the simulation (use user ID 1 as default for simulation)
 	if initialFunding > 0 {
 		if err := se.positionDAO.CreateInitialUSDTPosition(1, &simulationRecord.ID, initialFunding); err != nil {
-			return fmt.Errorf("failed to create initial USDT position: %w", err)
+			return nil, fmt.Errorf("failed to create initial USDT position: %w", err)
 		}
 		log.Printf("Created initial USDT position with funding: $%.2f", initialFunding)
 	}
@@ -213,6 +445,9 @@ func (se *SimulationEngine) Start(symbol, interval string, startTime int64, spee
 		}
 	}
 
+	// Registered once nothing can fail, as a failed start never deactivates it
+	se.activateMarketCutoff()
+
 	// Initialize continuous data loading state
 	se.isLoadingData = false
 	se.noMoreDataAvailable = false
@@ -222,23 +457,18 @@ func (se *SimulationEngine) Start(symbol, interval string, startTime int64, spee
 		se.lastDataLoadTime = startTime
 	}
 
-	log.Printf("Starting simulation: %s %s from %d with %d base candles (%s) at %dx speed",
-		symbol, interval, startTime, len(baseDataset), se.baseInterval, speed)
+	log.Printf("Starting simulation: %s %s from %d with %d base candles (%s) and %d warmup bars at %dx speed",
+		symbol, interval, startTime, len(baseDataset), se.baseInterval, len(warmupBars), speed)
 
-	// Send initial status update
-	se.sendStatusUpdateUnsafe("Simulation started")
-
-	// Start the simulation goroutine
-	go se.runSimulation()
-
-	return nil
+	return warmupBars, nil
 }
 
-func (se *SimulationEngine) loadHistoricalDataset(symbol, interval string, startTime int64) ([]models.OHLCV, error) {
-	// Use binance service to fetch historical data with incomplete candle support
+// loadHistoricalDataset fetches the first batch of base candles from startTime, none past endTime when it is set
+func (se *SimulationEngine) loadHistoricalDataset(symbol, interval string, startTime int64, endTime *int64) ([]models.OHLCV, error) {
+	// Use the market data provider to fetch historical data with incomplete candle support
 	startTimeMs := startTime
 
-	data, err := se.binanceService.GetHistoricalData(symbol, interval, 1000, &startTimeMs, nil, false)
+	data, err := se.fetchDataBatch(symbol, interval, startTimeMs, endTime)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch historical data: %w", err)
 	}
@@ -253,22 +483,27 @@ func (se *SimulationEngine) loadHistoricalDataset(symbol, interval string, start
 }
 
 func (se *SimulationEngine) runSimulation() {
+	// The loop keeps its own ticker; se.ticker is only shared under the lock so Stop can halt it
+	se.mu.Lock()
 	se.tickerInterval = se.getOptimalTickerInterval()
-	se.ticker = time.NewTicker(se.tickerInterval)
-	defer se.ticker.Stop()
+	ticker := time.NewTicker(se.tickerInterval)
+	se.ticker = ticker
+	currentInterval := se.tickerInterval
+	se.mu.Unlock()
+	defer func() { ticker.Stop() }()
 
-	log.Printf("Simulation goroutine started with ticker interval: %v", se.getOptimalTickerInterval())
+	log.Printf("Simulation goroutine started with ticker interval: %v", currentInterval)
 
-	currentInterval := se.tickerInterval
 	for {
 		select {
-		case <-se.ticker.C:
+		case <-ticker.C:
 			se.mu.Lock()
 			// Check if ticker interval needs to be updated
 			if currentInterval != se.tickerInterval {
 				currentInterval = se.tickerInterval
-				se.ticker.Stop()
-				se.ticker = time.NewTicker(se.tickerInterval)
+				ticker.Stop()
+				ticker = time.NewTicker(se.tickerInterval)
+				se.ticker = ticker
 				log.Printf("Ticker recreated with new interval: %v", se.tickerInterval)
 			}
 
@@ -278,16 +513,26 @@ func (se *SimulationEngine) runSimulation() {
 
 				if se.processNextBaseUpdate() {
 					// Base candle processed and broadcasted
+				} else if se.reachedEndTimeUnsafe() {
+					log.Printf("Simulation reached its end time %d", se.options.EndTime)
+					if se.options.Loop && se.restartReplayUnsafe() {
+						se.mu.Unlock()
+						continue
+					}
+
+					se.completeSimulationUnsafe("Simulation completed - reached end time")
+					se.mu.Unlock()
+					return
 				} else {
 					// Reached end of dataset - but don't stop immediately if we're loading more data
 					if !se.isLoadingData {
 						log.Printf("Simulation reached end of base dataset")
+						if se.options.Loop && se.restartReplayUnsafe() {
+							se.mu.Unlock()
+							continue
+						}
 
-						// Complete simulation record with final portfolio value
-						se.updateSimulationStatusWithPortfolioValue(models.SimulationStatusCompleted)
-
-						se.state = StateStopped
-						se.sendStatusUpdateUnsafe("Simulation completed - reached end of data")
+						se.completeSimulationUnsafe("Simulation completed - reached end of data")
 						se.mu.Unlock()
 						return
 					}
@@ -338,6 +583,17 @@ func (se *SimulationEngine) runSimulation() {
 	}
 }
 
+// completeSimulationUnsafe settles open orders, completes the simulation record with its final portfolio value and
+// stops the simulation (caller must hold lock)
+func (se *SimulationEngine) completeSimulationUnsafe(message string) {
+	se.settleOpenOrdersOnStopUnsafe()
+	se.updateSimulationStatusWithPortfolioValue(models.SimulationStatusCompleted)
+	se.deactivateMarketCutoff()
+
+	se.state = StateStopped
+	se.sendStatusUpdateUnsafe(message)
+}
+
 // processNextBaseUpdate advances simulation time and processes base candles
 func (se *SimulationEngine) processNextBaseUpdate() bool {
 	// Only process updates when actively playing
@@ -345,13 +601,21 @@ func (se *SimulationEngine) processNextBaseUpdate() bool {
 		return true // Don't process updates if not playing, but don't end simulation
 	}
 
+	if se.smoothCandlesPerTick > 0 {
+		return se.processSmoothBaseUpdate()
+	}
+
 	// Calculate how much market time to advance based on ticker interval and speed
-	tickerIntervalMs := se.tickerInterval.Milliseconds() // Convert to milliseconds
-	marketMsPerRealSecond := int64(se.speed * 1000)      // speed in market seconds, convert to ms
-	marketMsPerUpdate := (marketMsPerRealSecond * tickerIntervalMs) / 1000
+	// Microsecond precision keeps sub-second tickers at high speeds (4h/1d base) from drifting
+	tickerIntervalUs := se.tickerInterval.Microseconds()
+	marketMsPerRealSecond := int64(se.speed) * 1000 // speed in market seconds, convert to ms
+	marketMsPerUpdate := (marketMsPerRealSecond * tickerIntervalUs) / 1000000
 
-	// Advance simulation time with millisecond precision (only when playing)
+	// Advance simulation time with millisecond precision (only when playing), never past the end time
 	se.currentSimTime += marketMsPerUpdate
+	if se.options.EndTime > 0 && se.currentSimTime > se.options.EndTime {
+		se.currentSimTime = se.options.EndTime
+	}
 
 	// Process all candles that are ready to be broadcast
 	for se.currentIndex < len(se.baseDataset) {
@@ -359,50 +623,63 @@ func (se *SimulationEngine) processNextBaseUpdate() bool {
 
 		// Check if this base candle's end time is now <= current simulation time
 		if baseCandle.EndTime <= se.currentSimTime {
-			// Update current price and price time
-			se.currentPrice = baseCandle.Close
-			se.currentPriceTime = baseCandle.EndTime
-
-			// Process limit orders at the new price (before sending to client)
-			if se.orderExecutionEngine != nil {
-				if trades, err := se.orderExecutionEngine.ProcessPriceUpdate(se.symbol, se.currentPrice, se.currentPriceTime); err != nil {
-					log.Printf("Error processing limit orders at price %.8f: %v", se.currentPrice, err)
-				} else if len(trades) > 0 {
-					log.Printf("Processed %d limit order executions at price %.8f", len(trades), se.currentPrice)
-				}
-			}
-
-			// Send this base candle to client
-			se.sendBaseCandle(baseCandle)
-			se.currentIndex++
+			se.processBaseCandle(baseCandle)
 		} else {
 			// No more candles ready, break out of loop
 			break
 		}
 	}
 
-	// If no more base candles available, end simulation
-	if se.currentIndex >= len(se.baseDataset) {
+	// If the end time is reached or no more base candles are available, end simulation
+	if se.reachedEndTimeUnsafe() || se.currentIndex >= len(se.baseDataset) {
 		return false
 	}
 
 	return true
 }
 
-// sendBaseCandle sends a single base candle to the client for frontend aggregation
+// processBaseCandle fills pending orders against a completed base candle and streams it (caller must hold lock)
+func (se *SimulationEngine) processBaseCandle(baseCandle models.OHLCV) {
+	// Update current price and price time
+	se.currentPrice = baseCandle.Close
+	se.currentPriceTime = baseCandle.EndTime
+	se.advanceMarketCutoff()
+
+	// Process pending orders against the completed candle (before sending to client)
+	if se.orderExecutionEngine != nil {
+		if trades, err := se.orderExecutionEngine.ProcessCandle(se.symbol, baseCandle); err != nil {
+			log.Printf("Error processing pending orders at price %.8f: %v", se.currentPrice, err)
+		} else if len(trades) > 0 {
+			log.Printf("Processed %d pending order executions at price %.8f", len(trades), se.currentPrice)
+		}
+	}
+
+	// Send this base candle to client
+	se.sendBaseCandle(baseCandle)
+	se.sendIntervalUpdates(baseCandle)
+	se.advanceSymbolFeedsUnsafe(baseCandle.EndTime)
+	se.currentIndex++
+	se.candlesProcessed++
+	se.checkWarmupComplete()
+	se.maybeSendTradingStats()
+	se.maybeRecordEquitySample()
+	se.maybeCheckpointUnsafe()
+}
+
+// sendBaseCandle sends a single base candle to the client for frontend aggregation, along with the display interval
+// candle it belongs to when the server aggregates
 func (se *SimulationEngine) sendBaseCandle(baseCandle models.OHLCV) {
+	displayCandle := se.displayCandle(baseCandle)
 	if se.client == nil {
 		return // No client to send to
 	}
 
-	// Progress calculation placeholder - will be time-based in future
-	progress := float64(0)
-
 	updateData := SimulationUpdateData{
 		Symbol:         se.symbol,
 		BaseCandle:     baseCandle,
+		DisplayCandle:  displayCandle,
 		SimulationTime: se.currentSimTime,
-		Progress:       progress,
+		Progress:       se.progressUnsafe(),
 		State:          string(se.state),
 		Speed:          se.speed,
 	}
@@ -452,45 +729,135 @@ func (se *SimulationEngine) GetStatus() SimulationStatus {
 	se.mu.RLock()
 	defer se.mu.RUnlock()
 
-	// Progress calculation placeholder - will be time-based in future
-	progress := float64(0)
+	return se.getStatusUnsafe()
+}
 
-	return SimulationStatus{
-		State:            string(se.state),
-		Symbol:           se.symbol,
-		Interval:         se.interval,
-		Speed:            se.speed,
-		Progress:         progress,
-		StartTime:        se.startTime,
-		CurrentPriceTime: se.currentPriceTime,
-		CurrentPrice:     se.currentPrice,
-		SimulationID:     se.currentSimulationID,
-		IsRunning:        se.state == StatePlaying || se.state == StatePaused,
-		SimulationTime:   se.currentSimTime,
+// EngineDiagnostics reports in-memory engine state that the simulation record doesn't reflect
+type EngineDiagnostics struct {
+	BufferedCandles int `json:"bufferedCandles"` // Base candles held in memory
+	MaxBufferSize   int `json:"maxBufferSize"`
+	CurrentIndex    int `json:"currentIndex"` // Position of the next base candle in the buffer
+}
+
+// GetDiagnostics returns the engine's in-memory buffer state
+func (se *SimulationEngine) GetDiagnostics() EngineDiagnostics {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	return EngineDiagnostics{
+		BufferedCandles: len(se.baseDataset),
+		MaxBufferSize:   se.maxBufferSize,
+		CurrentIndex:    se.currentIndex,
+	}
+}
+
+// getDataStatusUnsafe reports the data loading state (caller must hold lock)
+// Background loads fetch without the lock and only take it to append, so a load in progress shows as loading
+func (se *SimulationEngine) getDataStatusUnsafe() DataStatus {
+	status := DataStatus{
+		IsLoadingData:       se.isLoadingData,
+		NoMoreDataAvailable: se.noMoreDataAvailable,
+		BufferedCandles:     len(se.baseDataset),
+		MaxBufferSize:       se.maxBufferSize,
+		LastDataLoadTime:    se.lastDataLoadTime,
 	}
+	if ahead := len(se.baseDataset) - se.currentIndex; ahead > 0 {
+		status.CandlesAhead = ahead
+	}
+	if se.maxBufferSize > 0 {
+		status.BufferFillPct = models.RoundPercent(float64(len(se.baseDataset)) / float64(se.maxBufferSize) * 100)
+	}
+	return status
+}
+
+// GetRecentCandles returns up to limit of the most recently processed base candles, oldest first
+func (se *SimulationEngine) GetRecentCandles(limit int) []models.OHLCV {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	end := se.currentIndex
+	if end > len(se.baseDataset) {
+		end = len(se.baseDataset)
+	}
+	start := end - limit
+	if start < 0 {
+		start = 0
+	}
+
+	candles := make([]models.OHLCV, end-start)
+	copy(candles, se.baseDataset[start:end])
+	return candles
 }
 
 // getStatusUnsafe returns status without acquiring locks (caller must hold lock)
 func (se *SimulationEngine) getStatusUnsafe() SimulationStatus {
-	// Progress calculation placeholder - will be time-based in future
-	progress := float64(0)
-
-	return SimulationStatus{
+	status := SimulationStatus{
 		State:            string(se.state),
 		Symbol:           se.symbol,
 		Interval:         se.interval,
 		Speed:            se.speed,
-		Progress:         progress,
+		Progress:         se.progressUnsafe(),
 		StartTime:        se.startTime,
+		EndTime:          se.options.EndTime,
 		CurrentPriceTime: se.currentPriceTime,
 		CurrentPrice:     se.currentPrice,
 		SimulationID:     se.currentSimulationID,
 		IsRunning:        se.state == StatePlaying || se.state == StatePaused,
 		SimulationTime:   se.currentSimTime,
+		Blind:            se.options.Blind,
+		Intervals:        se.options.Intervals,
+		Loop:             se.options.Loop,
+		LoopCount:        se.loopCount,
+		AggregateCandles: se.options.AggregateCandles,
+		Data:             se.getDataStatusUnsafe(),
+	}
+	if len(se.options.Symbols) > 0 {
+		status.Symbols = se.options.Symbols
+		status.Prices = se.currentPricesUnsafe()
+	}
+
+	if status.IsRunning && !se.warmupComplete {
+		status.WarmingUp = true
+		if remaining := se.options.WarmupCandles - se.candlesProcessed; remaining > 0 {
+			status.WarmupCandlesRemaining = remaining
+		}
+		if se.options.WarmupDurationMs > 0 {
+			status.WarmupEndTime = se.startTime + se.options.WarmupDurationMs
+		}
+	}
+
+	return status
+}
+
+// checkWarmupComplete marks the warmup finished once both limits are reached and notifies the client
+func (se *SimulationEngine) checkWarmupComplete() {
+	if se.warmupComplete {
+		return
+	}
+	if se.candlesProcessed < se.options.WarmupCandles {
+		return
+	}
+	if se.currentPriceTime < se.startTime+se.options.WarmupDurationMs {
+		return
+	}
+
+	se.warmupComplete = true
+	log.Printf("Warmup complete after %d candles at %d", se.candlesProcessed, se.currentPriceTime)
+
+	if se.client != nil {
+		se.client.SendMessage(types.WarmupComplete, WarmupCompleteData{
+			SimulationID:     se.currentSimulationID,
+			CandlesProcessed: se.candlesProcessed,
+			SimulationTime:   se.currentPriceTime,
+		})
 	}
 }
 
 func (se *SimulationEngine) getOptimalTickerInterval() time.Duration {
+	if se.smoothCandlesPerTick > 0 {
+		return se.smoothTickerInterval()
+	}
+
 	// Get base interval duration in seconds
 	baseIntervalDurationMs := models.GetIntervalDurationMs(se.baseInterval)
 	baseIntervalSeconds := float64(baseIntervalDurationMs) / 1000.0
@@ -501,15 +868,22 @@ func (se *SimulationEngine) getOptimalTickerInterval() time.Duration {
 	// Calculate how much of a base candle we consume per real second
 	baseCandlesPerSecond := marketSecondsPerRealSecond / baseIntervalSeconds
 
-	// Calculate ticker interval
+	// Calculate ticker interval, clamped so extreme speeds don't spin the loop
 	tickerInterval := time.Duration(float64(time.Second) / baseCandlesPerSecond)
+	if tickerInterval < minTickerInterval {
+		tickerInterval = minTickerInterval
+	}
 	return tickerInterval
 
 }
 
 // getOptimalBaseInterval determines the best base interval for fetching data
 func (se *SimulationEngine) getOptimalBaseInterval() string {
+	return baseIntervalForSpeed(se.speed)
+}
 
+// baseIntervalForSpeed returns the largest base interval whose duration in seconds doesn't exceed speed
+func baseIntervalForSpeed(speed int) string {
 	// Available timeframes supported by Binance API in ascending order
 	timeframes := []string{"1m", "5m", "15m", "1h", "4h", "1d"}
 
@@ -519,7 +893,7 @@ func (se *SimulationEngine) getOptimalBaseInterval() string {
 		intervalDurationMs := models.GetIntervalDurationMs(tf)
 		intervalDurationSeconds := intervalDurationMs / 1000
 
-		if se.speed >= int(intervalDurationSeconds) {
+		if speed >= int(intervalDurationSeconds) {
 			baseInterval = tf
 		} else {
 			break // Since timeframes are in ascending order, we can break early
@@ -528,28 +902,35 @@ func (se *SimulationEngine) getOptimalBaseInterval() string {
 	return baseInterval
 }
 
+// dataBatchSize returns how many base candles to load per batch, the configured count or else the interval's default
+func (se *SimulationEngine) dataBatchSize(baseInterval string) int {
+	if se.dataBatchCandles > 0 {
+		return se.dataBatchCandles
+	}
+	return defaultDataBatchSize(baseInterval)
+}
+
+// defaultDataBatchSize returns how many base candles to load per request for a base interval
+// Fine intervals use full 1000-candle batches; coarse ones are limited to maxDataBatchSpanMs of market time
+func defaultDataBatchSize(baseInterval string) int {
+	batchSize := int(maxDataBatchSpanMs / models.GetIntervalDurationMs(baseInterval))
+	if batchSize > maxDataBatchSize {
+		return maxDataBatchSize
+	}
+	if batchSize < minDataBatchSize {
+		return minDataBatchSize
+	}
+	return batchSize
+}
+
 // getMinAllowedTimeframe calculates minimum allowed display timeframe based on speed
 func (se *SimulationEngine) getMinAllowedTimeframe(speed int) string {
 	// Speed is in seconds: how many market seconds per real second
 	marketSecondsPerRealSecond := float64(speed)
 
-	// Find the largest timeframe that's <= marketSecondsPerRealSecond
-	// Available timeframes in ascending order (in seconds)
-	timeframes := []struct {
-		name    string
-		seconds float64
-	}{
-		{"1m", 60},
-		{"5m", 300},
-		{"15m", 900},
-		{"1h", 3600},
-		{"4h", 14400},
-		{"1d", 86400},
-	}
-
 	// Find the largest timeframe that's <= marketSecondsPerRealSecond
 	minTimeframe := "1m" // default to smallest if no match
-	for _, tf := range timeframes {
+	for _, tf := range minTimeframeSteps {
 		if tf.seconds <= marketSecondsPerRealSecond {
 			minTimeframe = tf.name
 		}
@@ -598,6 +979,7 @@ func (se *SimulationEngine) Resume() error {
 
 	// Handle normal resume from paused state
 	se.state = StatePlaying
+	se.resetSmoothPlaybackClock()
 
 	// Update simulation record status
 	se.updateSimulationStatus(models.SimulationStatusRunning)
@@ -615,8 +997,11 @@ func (se *SimulationEngine) Stop() error {
 		return nil // Already stopped
 	}
 
+	se.settleOpenOrdersOnStopUnsafe()
+
 	// Calculate final portfolio value and complete simulation record
 	se.updateSimulationStatusWithPortfolioValue(models.SimulationStatusStopped)
+	se.deactivateMarketCutoff()
 
 	se.state = StateStopped
 	// Keep simulation status for display until next start
@@ -638,12 +1023,14 @@ func (se *SimulationEngine) Stop() error {
 	return nil
 }
 
+// SetSpeed changes the playback speed, handing the change to the simulation loop while playing
+// The write lock serializes callers so replacing a queued change can't block on the channel
 func (se *SimulationEngine) SetSpeed(speed int) error {
-	se.mu.RLock()
-	defer se.mu.RUnlock()
+	se.mu.Lock()
+	defer se.mu.Unlock()
 
-	if speed <= 0 {
-		return fmt.Errorf("invalid speed: %d, must be positive", speed)
+	if err := se.validateSpeed(speed); err != nil {
+		return err
 	}
 
 	// If simulation is running, send speed change to goroutine via channel
@@ -660,8 +1047,18 @@ func (se *SimulationEngine) SetSpeed(speed int) error {
 			se.speedChangeChan <- speed
 			log.Printf("Speed change request replaced: %dx", speed)
 		}
+	} else if se.state == StatePaused {
+		// The loop isn't consuming candles while paused, so switch the base data here
+		if err := se.handleSpeedChange(speed); err != nil {
+			return err
+		}
+		se.sendStatusUpdateUnsafe(fmt.Sprintf("Speed changed to %dx", speed))
+		return nil
 	} else {
-		// If not running, update speed directly
+		// If not running, update speed directly; the next start loads data at this base interval
+		se.speed = speed
+		se.baseInterval = se.getOptimalBaseInterval()
+		se.tickerInterval = se.getOptimalTickerInterval()
 		log.Printf("Speed updated directly to %dx (simulation not running)", speed)
 
 		// Send updated status for direct updates
@@ -672,15 +1069,85 @@ func (se *SimulationEngine) SetSpeed(speed int) error {
 	return nil
 }
 
+// SetMaxSpeed sets the highest speed accepted by Start, SetSpeed and resume
+func (se *SimulationEngine) SetMaxSpeed(maxSpeed int) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	if maxSpeed <= 0 {
+		maxSpeed = DefaultMaxSpeed
+	}
+	se.maxSpeed = maxSpeed
+}
+
+// SetDataBatchSize sets how many base candles each data load fetches (0 sizes batches by the base interval)
+// Counts above Binance's 1000 candle limit are fetched with consecutive range requests, up to MaxDataBatchCandles
+func (se *SimulationEngine) SetDataBatchSize(candles int) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	if candles < 0 {
+		candles = 0
+	}
+	if candles > MaxDataBatchCandles {
+		candles = MaxDataBatchCandles
+	}
+	se.dataBatchCandles = candles
+}
+
+// fetchDataBatch loads a batch of the configured size for interval from startTime (caller must hold lock)
+func (se *SimulationEngine) fetchDataBatch(symbol, interval string, startTime int64, endTime *int64) ([]models.OHLCV, error) {
+	return fetchCandles(se.marketData, symbol, interval, startTime, endTime, se.dataBatchSize(interval))
+}
+
+// fetchCandles loads up to batchSize candles from startTime in requests of at most maxDataBatchSize
+// A short response ends the batch early, as the provider has no more data in range
+func fetchCandles(marketData marketdata.MarketDataProvider, symbol, interval string, startTime int64, endTime *int64, batchSize int) ([]models.OHLCV, error) {
+	remaining := batchSize
+	var batch []models.OHLCV
+	for remaining > 0 {
+		limit := min(remaining, maxDataBatchSize)
+		data, err := marketData.GetHistoricalData(symbol, interval, limit, &startTime, endTime, false)
+		if err != nil {
+			if len(batch) > 0 {
+				// Keep the candles already fetched; the next load resumes after them
+				log.Printf("Stopped loading %s %s batch after %d candles: %v", symbol, interval, len(batch), err)
+				return batch, nil
+			}
+			return nil, err
+		}
+
+		batch = append(batch, data...)
+		if len(data) < limit {
+			break
+		}
+		remaining -= len(data)
+		startTime = data[len(data)-1].StartTime + 1
+	}
+	return batch, nil
+}
+
+// validateSpeed checks a requested speed against the positive range and the configured cap
+func (se *SimulationEngine) validateSpeed(speed int) error {
+	if speed <= 0 {
+		return fmt.Errorf("invalid speed: %d, must be positive", speed)
+	}
+	if speed > se.maxSpeed {
+		return fmt.Errorf("invalid speed: %d exceeds the maximum of %d", speed, se.maxSpeed)
+	}
+	return nil
+}
+
 // GetMinAllowedTimeframeForSpeed exposes the min timeframe calculation for frontend
 func (se *SimulationEngine) GetMinAllowedTimeframeForSpeed(speed int) string {
 	return se.getMinAllowedTimeframe(speed)
 }
 
 // SetTimeframe changes the timeframe during simulation
+// Like SetSpeed it holds the write lock so concurrent callers can't block on the channel
 func (se *SimulationEngine) SetTimeframe(newTimeframe string) error {
-	se.mu.RLock()
-	defer se.mu.RUnlock()
+	se.mu.Lock()
+	defer se.mu.Unlock()
 
 	// Validate timeframe is allowed for current speed
 	if !se.isTimeframeAllowed(newTimeframe, se.speed) {
@@ -704,6 +1171,9 @@ func (se *SimulationEngine) SetTimeframe(newTimeframe string) error {
 		}
 	} else {
 		// If not running, update timeframe directly
+		if err := se.handleTimeframeChange(newTimeframe); err != nil {
+			return err
+		}
 		log.Printf("Timeframe updated directly to %s (simulation not running)", newTimeframe)
 
 		// Send updated status for direct updates
@@ -741,7 +1211,7 @@ func (se *SimulationEngine) handleSpeedChange(newSpeed int) error {
 		log.Printf("Loading new base data from aligned time %d (aligned: %d, current price time: %d)",
 			loadStartTime, alignedStartTime, se.currentPriceTime)
 
-		newBaseDataset, err := se.binanceService.GetHistoricalData(se.symbol, se.baseInterval, 1000, &loadStartTime, nil, false)
+		newBaseDataset, err := se.fetchDataBatch(se.symbol, se.baseInterval, loadStartTime, se.dataEndTime())
 		if err != nil {
 			// Revert changes on error
 			se.speed = oldSpeed
@@ -751,6 +1221,7 @@ func (se *SimulationEngine) handleSpeedChange(newSpeed int) error {
 		}
 
 		se.baseDataset = newBaseDataset
+		se.isLoadingData = false       // A load still fetching the old interval is discarded when it finishes
 		se.noMoreDataAvailable = false // Reset since we have new data
 
 		// Find current position in new base dataset
@@ -764,6 +1235,11 @@ func (se *SimulationEngine) handleSpeedChange(newSpeed int) error {
 		}
 		se.currentIndex = newIndex
 
+		// Extra symbols switch to the new base interval too
+		if err := se.reloadSymbolFeedsUnsafe(); err != nil {
+			log.Printf("Failed to reload extra symbols at base interval %s: %v", se.baseInterval, err)
+		}
+
 		log.Printf("Repositioned to index %d in new base dataset (next candle: %d-%d)",
 			se.currentIndex,
 			func() int64 {
@@ -800,7 +1276,10 @@ func (se *SimulationEngine) handleTimeframeChange(newTimeframe string) error {
 	oldInterval := se.interval
 	se.interval = newTimeframe
 
-	// No progressive candle state to reset
+	// Aggregated display candles start over at the new interval
+	if se.options.AggregateCandles {
+		se.displayAggregator = newIntervalAggregator(newTimeframe)
+	}
 
 	log.Printf("Timeframe change completed: %s -> %s (base: %s unchanged)", oldInterval, newTimeframe, se.baseInterval)
 	return nil
@@ -811,6 +1290,7 @@ func (se *SimulationEngine) Cleanup() {
 	defer se.mu.Unlock()
 
 	se.cancel()
+	se.deactivateMarketCutoff()
 
 	if se.ticker != nil {
 		se.ticker.Stop()
@@ -848,6 +1328,8 @@ func (se *SimulationEngine) updateSimulationStatusWithPortfolioValue(status mode
 			// Update with calculated portfolio value
 			if err := se.simulationDAO.UpdateSimulationStatusWithDetails(se.currentSimulationID, status, endSimTime, &totalValue); err != nil {
 				log.Printf("Failed to update simulation with portfolio calculation to %s: %v", status, err)
+			} else {
+				se.archiveCandles(simulationID, status)
 			}
 		}
 	} else {
@@ -855,6 +1337,9 @@ func (se *SimulationEngine) updateSimulationStatusWithPortfolioValue(status mode
 		var err error
 		if endSimTime != 0 {
 			err = se.simulationDAO.UpdateSimulationStatusWithDetails(se.currentSimulationID, status, endSimTime, nil)
+			if err == nil {
+				se.archiveCandles(simulationID, status)
+			}
 		} else {
 			err = se.simulationDAO.UpdateSimulationStatus(se.currentSimulationID, status)
 		}
@@ -876,71 +1361,145 @@ func (se *SimulationEngine) updateSimulationStatus(status models.SimulationStatu
 
 // calculateCurrentPortfolioValue calculates portfolio value without acquiring internal locks
 func (se *SimulationEngine) calculateCurrentPortfolioValue(currentPrice float64, simulationID uint, symbol string) (float64, error) {
+	cash, positionValue, err := se.calculatePortfolioBreakdown(currentPrice, simulationID, symbol)
+	if err != nil {
+		return 0, err
+	}
+	return cash + positionValue, nil
+}
+
+// calculatePortfolioBreakdown values the portfolio split into USDT cash and the market value of held positions
+func (se *SimulationEngine) calculatePortfolioBreakdown(currentPrice float64, simulationID uint, symbol string) (cash, positionValue float64, err error) {
 	// Use portfolio service to get positions for current simulation
 	positions, err := se.portfolioService.GetUserPositions(1, simulationID) // Pass simulationID directly
 	if err != nil {
-		return 0, fmt.Errorf("failed to get positions: %w", err)
+		return 0, 0, fmt.Errorf("failed to get positions: %w", err)
 	}
 
-	var totalValue float64
+	// Extra simulation symbols use their latest played candle, other held symbols are valued at their own
+	// price at the current simulation time
+	knownPrices := se.currentPricesUnsafe()
+	var otherSymbols []string
 	for _, position := range positions {
-		var marketValue float64
+		if position.Symbol != "USDT" && position.Symbol != symbol && position.Quantity != 0 && knownPrices[position.Symbol] == 0 {
+			otherSymbols = append(otherSymbols, position.Symbol)
+		}
+	}
+	otherPrices, err := se.fetchPricesAt(otherSymbols, se.currentPriceTime)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get prices for held symbols: %w", err)
+	}
+	for heldSymbol, price := range knownPrices {
+		if _, fetched := otherPrices[heldSymbol]; !fetched {
+			otherPrices[heldSymbol] = price
+		}
+	}
 
+	for _, position := range positions {
 		if position.Symbol == "USDT" {
 			// USDT is always worth 1:1
-			marketValue = position.Quantity
+			cash += position.Quantity
 		} else if position.Symbol == symbol {
 			// Use current price for the simulation symbol
-			marketValue = position.Quantity * currentPrice
+			positionValue += position.MarketValue(currentPrice)
 		} else {
-			// For other symbols, assume 0 value (shouldn't happen in single-symbol simulation)
-			marketValue = 0
+			positionValue += position.MarketValue(otherPrices[position.Symbol])
 		}
-
-		totalValue += marketValue
 	}
 
-	return totalValue, nil
+	return cash, positionValue, nil
 }
 
-// loadMoreHistoricalData loads additional historical data from the last loaded timestamp
-func (se *SimulationEngine) loadMoreHistoricalData() error {
-	if se.isLoadingData {
+// dataLoad is a fetch of the candles following the buffer, captured under the lock so it can run without it
+type dataLoad struct {
+	symbol     string
+	interval   string
+	startTime  int64
+	endTime    *int64
+	batchSize  int
+	marketData marketdata.MarketDataProvider
+}
+
+// loadMoreHistoricalData loads additional historical data from the last loaded timestamp (caller must hold lock)
+// It holds the lock for the whole fetch; the simulation loop loads in the background with checkDataLoadingNeeded
+// Retries stop once ctx is done
+func (se *SimulationEngine) loadMoreHistoricalData(ctx context.Context) error {
+	load, ok := se.beginDataLoadUnsafe()
+	if !ok {
 		return nil // Already loading data
 	}
+	newData, err := load.fetch(ctx)
+	return se.finishDataLoadUnsafe(load, newData, err)
+}
 
+// beginDataLoadUnsafe marks a load as in progress and captures what it fetches (caller must hold lock)
+// It returns false when a load is already in progress
+func (se *SimulationEngine) beginDataLoadUnsafe() (dataLoad, bool) {
+	if se.isLoadingData {
+		return dataLoad{}, false
+	}
 	se.isLoadingData = true
-	defer func() { se.isLoadingData = false }()
 
-	// Calculate start time for next data chunk (use last candle's timestamp + 1ms)
-	var startTimeMs int64
+	return dataLoad{
+		symbol:     se.symbol,
+		interval:   se.baseInterval,
+		startTime:  se.nextDataLoadStartUnsafe(),
+		endTime:    se.dataEndTime(),
+		batchSize:  se.dataBatchSize(se.baseInterval),
+		marketData: se.marketData,
+	}, true
+}
+
+// nextDataLoadStartUnsafe returns the time the next load fetches from, just after the last buffered candle
+// (caller must hold lock)
+func (se *SimulationEngine) nextDataLoadStartUnsafe() int64 {
 	if len(se.baseDataset) > 0 {
-		lastCandle := se.baseDataset[len(se.baseDataset)-1]
-		startTimeMs = lastCandle.StartTime + 1
-	} else {
-		// Fallback to last known time
-		startTimeMs = se.lastDataLoadTime
+		return se.baseDataset[len(se.baseDataset)-1].StartTime + 1
 	}
+	// Fallback to last known time
+	return se.lastDataLoadTime
+}
 
-	// Fetch new data chunk with retry logic
+// fetch loads the candles with retries; it touches no engine state, so it runs without the lock
+// It gives up without another attempt once ctx is done, also cutting a retry wait short
+func (load dataLoad) fetch(ctx context.Context) ([]models.OHLCV, error) {
 	var newData []models.OHLCV
 	var err error
 	maxRetries := 3
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		newData, err = se.binanceService.GetHistoricalData(se.symbol, se.baseInterval, 1000, &startTimeMs, nil, false)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("data loading cancelled before attempt %d: %w", attempt, ctxErr)
+		}
+		newData, err = fetchCandles(load.marketData, load.symbol, load.interval, load.startTime, load.endTime, load.batchSize)
 		if err == nil {
-			break
+			return newData, nil
 		}
 
 		if attempt < maxRetries {
 			waitTime := time.Duration(attempt) * 2 * time.Second // Exponential backoff: 2s, 4s, 6s
 			log.Printf("Data loading attempt %d failed: %v. Retrying in %v...", attempt, err, waitTime)
-			time.Sleep(waitTime)
+			select {
+			case <-ctx.Done():
+			case <-time.After(waitTime):
+			}
 		}
 	}
+	return nil, fmt.Errorf("failed to load more historical data after %d attempts: %w", maxRetries, err)
+}
+
+// finishDataLoadUnsafe appends the fetched candles to the buffer and ends the load (caller must hold lock)
+// Candles are dropped when the buffer was replaced while they were fetched, by a seek, a replay restart or a
+// base interval change, as they no longer follow it
+func (se *SimulationEngine) finishDataLoadUnsafe(load dataLoad, newData []models.OHLCV, err error) error {
+	if load.symbol != se.symbol || load.interval != se.baseInterval || load.startTime != se.nextDataLoadStartUnsafe() {
+		// The replacement reset the flag, and a newer load may be running
+		log.Printf("Discarding %d %s candles loaded for a replaced buffer", len(newData), load.interval)
+		return nil
+	}
+	se.isLoadingData = false
 
 	if err != nil {
-		return fmt.Errorf("failed to load more historical data after %d attempts: %w", maxRetries, err)
+		return err
 	}
 
 	if len(newData) == 0 {
@@ -956,8 +1515,6 @@ func (se *SimulationEngine) loadMoreHistoricalData() error {
 	log.Printf("Loaded %d historical candles for %s %s starting from %d to %d",
 		len(newData), se.baseInterval, se.baseInterval, newData[0].EndTime, newData[len(newData)-1].StartTime)
 
-	// displayDataset loading removed - no longer needed
-
 	// Perform memory cleanup if needed
 	se.cleanupOldData()
 
@@ -993,7 +1550,7 @@ func (se *SimulationEngine) cleanupOldData() {
 	}
 }
 
-// checkDataLoadingNeeded checks if more data loading is needed based on current position
+// checkDataLoadingNeeded checks if more data loading is needed based on current position (caller must hold lock)
 func (se *SimulationEngine) checkDataLoadingNeeded() {
 	if se.isLoadingData || se.noMoreDataAvailable {
 		return // Already loading or no more data available
@@ -1004,11 +1561,26 @@ func (se *SimulationEngine) checkDataLoadingNeeded() {
 		return
 	}
 
+	// Bounded simulations stop loading once the buffer covers the end time
+	if lastCandle := se.baseDataset[len(se.baseDataset)-1]; se.options.EndTime > 0 && lastCandle.EndTime >= se.options.EndTime {
+		return
+	}
+
 	progress := float64(se.currentIndex) / float64(len(se.baseDataset))
 	if progress >= se.dataLoadThreshold {
-		// Trigger background data loading
+		load, ok := se.beginDataLoadUnsafe()
+		if !ok {
+			return
+		}
+
+		// Fetch in the background without the lock, then take it to append the candles
 		go func() {
-			if err := se.loadMoreHistoricalData(); err != nil {
+			newData, err := load.fetch(se.ctx)
+			se.mu.Lock()
+			err = se.finishDataLoadUnsafe(load, newData, err)
+			se.mu.Unlock()
+
+			if err != nil {
 				log.Printf("Failed to load more data: %v", err)
 				// Notify simulation loop about data loading failure
 				select {
@@ -1053,23 +1625,49 @@ func (se *SimulationEngine) ResumeStopped(simulationID uint, speed int, interval
 
 	// Set simulation parameters
 	se.currentSimulationID = simulationID
+	se.loopCount = 0
 	se.symbol = simulationRecord.Symbol
+	se.startTime = simulationRecord.StartSimTime
+
+	extraConfig, err := simulationDAO.ParseExtraConfig(simulationRecord)
+	if err != nil {
+		log.Printf("Failed to parse extra config for simulation %d, using defaults: %v", simulationID, err)
+		extraConfig = &simulationDAO.ExtraConfig{}
+	}
 
 	// Use provided speed/interval, or fall back to simulation record if not provided
 	if speed > 0 {
+		if err := se.validateSpeed(speed); err != nil {
+			return err
+		}
 		se.speed = speed
+	} else if extraConfig.Speed > 0 {
+		se.speed = extraConfig.Speed
+		if se.speed > se.maxSpeed {
+			se.speed = se.maxSpeed // Cap may have been lowered since the simulation was saved
+		}
 	} else {
-		// Extract speed from simulation record's extra config if available
 		se.speed = 60 // Default fallback
 	}
 
 	if interval != "" {
 		se.interval = interval
+	} else if extraConfig.Timeframe != "" {
+		se.interval = extraConfig.Timeframe
 	} else {
-		// Extract interval from simulation record if available, fallback to 1h
 		se.interval = "1h"
 	}
 
+	// Restore per-simulation options recorded at start
+	options := optionsFromExtraConfig(extraConfig)
+	if err := options.Validate(); err != nil {
+		log.Printf("Invalid stored options for simulation %d, using defaults: %v", simulationID, err)
+		options = SimulationOptions{Execution: trading.DefaultExecutionOptions()}
+	}
+	se.applyOptions(options)
+	se.resetIntervalAggregators()
+	se.warmupComplete = true // Warmup only applies to the start of a replay
+
 	// Recalculate base interval and ticker based on speed
 	se.baseInterval = se.getOptimalBaseInterval()
 	se.tickerInterval = se.getOptimalTickerInterval()
@@ -1079,7 +1677,7 @@ func (se *SimulationEngine) ResumeStopped(simulationID uint, speed int, interval
 	se.currentSimTime = simulationRecord.EndSimTime
 
 	// Load historical data from the end time
-	baseDataset, err := se.loadHistoricalDataset(se.symbol, se.baseInterval, simulationRecord.EndSimTime)
+	baseDataset, err := se.loadHistoricalDataset(se.symbol, se.baseInterval, simulationRecord.EndSimTime, se.dataEndTime())
 	if err != nil {
 		return fmt.Errorf("failed to load historical data for resume: %w", err)
 	}
@@ -1092,6 +1690,10 @@ func (se *SimulationEngine) ResumeStopped(simulationID uint, speed int, interval
 	se.baseDataset = baseDataset
 	se.currentIndex = 0 // Start from beginning of new dataset
 
+	if err := se.reloadSymbolFeedsUnsafe(); err != nil {
+		return fmt.Errorf("failed to load historical data for resume: %w", err)
+	}
+
 	// Reset data loading state
 	se.isLoadingData = false
 	se.noMoreDataAvailable = false
@@ -1107,6 +1709,9 @@ func (se *SimulationEngine) ResumeStopped(simulationID uint, speed int, interval
 
 	// Update state to playing
 	se.state = StatePlaying
+	se.activateMarketCutoff()
+	se.resetTradingStatsUnsafe(simulationRecord.StartSimTime)
+	se.restoreEquitySamplingUnsafe(simulationRecord.StartSimTime)
 
 	// Update simulation record status
 	se.updateSimulationStatus(models.SimulationStatusRunning)
@@ -1120,3 +1725,119 @@ func (se *SimulationEngine) ResumeStopped(simulationID uint, speed int, interval
 
 	return nil
 }
+
+// applyOptions stores per-simulation options and pushes execution settings to the order engine
+func (se *SimulationEngine) applyOptions(options SimulationOptions) {
+	se.options = options
+	if se.orderExecutionEngine != nil {
+		se.orderExecutionEngine.SetExecutionOptions(options.Execution)
+	}
+}
+
+// extraConfigFromOptions builds the config stored on a simulation record from its start settings
+func extraConfigFromOptions(speed int, interval string, options SimulationOptions) *simulationDAO.ExtraConfig {
+	return &simulationDAO.ExtraConfig{
+		Speed:                speed,
+		Timeframe:            interval,
+		MarketFillPrice:      string(options.Execution.MarketFillPrice),
+		StrictLimitFills:     options.Execution.StrictLimitFills,
+		ClampToAffordable:    options.Execution.ClampToAffordable,
+		FillAtLimitPrice:     options.Execution.FillAtLimitPrice,
+		VolumeParticipation:  options.Execution.VolumeParticipation,
+		VolumeScale:          options.Execution.VolumeScale,
+		FeeRate:              options.Execution.FeeRate,
+		LimitFillPrice:       string(options.Execution.LimitFillPrice),
+		WarmupCandles:        options.WarmupCandles,
+		WarmupDurationMs:     options.WarmupDurationMs,
+		WarmupBars:           options.WarmupBars,
+		Blind:                options.Blind,
+		Intervals:            options.Intervals,
+		EndTime:              options.EndTime,
+		Symbols:              options.Symbols,
+		Loop:                 options.Loop,
+		ResetPortfolioOnLoop: options.ResetPortfolioOnLoop,
+		AggregateCandles:     options.AggregateCandles,
+
+		Leverage:              options.Execution.Leverage,
+		MaintenanceMarginRate: options.Execution.MaintenanceMarginRate,
+		AllowShort:            options.Execution.AllowShort,
+		SlippageMode:          string(options.Execution.SlippageMode),
+		SlippageBps:           options.Execution.SlippageBps,
+		MinPartialFill:        options.Execution.MinPartialFill,
+		MinPartialFillMode:    string(options.Execution.MinPartialFillMode),
+	}
+}
+
+// optionsFromExtraConfig rebuilds simulation options from a stored simulation record config
+func optionsFromExtraConfig(extraConfig *simulationDAO.ExtraConfig) SimulationOptions {
+	return SimulationOptions{
+		Execution: trading.ExecutionOptions{
+			MarketFillPrice:     trading.MarketFillPrice(extraConfig.MarketFillPrice),
+			StrictLimitFills:    extraConfig.StrictLimitFills,
+			ClampToAffordable:   extraConfig.ClampToAffordable,
+			FillAtLimitPrice:    extraConfig.FillAtLimitPrice,
+			VolumeParticipation: extraConfig.VolumeParticipation,
+			VolumeScale:         extraConfig.VolumeScale,
+			FeeRate:             extraConfig.FeeRate,
+			LimitFillPrice:      trading.LimitFillPrice(extraConfig.LimitFillPrice),
+
+			Leverage:              extraConfig.Leverage,
+			MaintenanceMarginRate: extraConfig.MaintenanceMarginRate,
+			AllowShort:            extraConfig.AllowShort,
+			SlippageMode:          trading.SlippageMode(extraConfig.SlippageMode),
+			SlippageBps:           extraConfig.SlippageBps,
+			MinPartialFill:        extraConfig.MinPartialFill,
+			MinPartialFillMode:    trading.MinPartialFillMode(extraConfig.MinPartialFillMode),
+		},
+		WarmupCandles:    extraConfig.WarmupCandles,
+		WarmupDurationMs: extraConfig.WarmupDurationMs,
+		WarmupBars:       extraConfig.WarmupBars,
+		Blind:            extraConfig.Blind,
+		Intervals:        extraConfig.Intervals,
+		EndTime:          extraConfig.EndTime,
+		Symbols:          extraConfig.Symbols,
+
+		Loop:                 extraConfig.Loop,
+		ResetPortfolioOnLoop: extraConfig.ResetPortfolioOnLoop,
+		AggregateCandles:     extraConfig.AggregateCandles,
+	}
+}
+
+// SetMarketCutoffRegistry sets the registry used to keep market endpoints from serving data past
+// the simulation time. Blind simulations always register; clampAll registers every simulation
+func (se *SimulationEngine) SetMarketCutoffRegistry(registry *services.MarketCutoffRegistry, clampAll bool) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.cutoffRegistry = registry
+	se.clampMarketData = clampAll
+}
+
+// restrictsMarketData reports whether the current simulation limits market data to its time (caller must hold lock)
+func (se *SimulationEngine) restrictsMarketData() bool {
+	return se.cutoffRegistry != nil && (se.options.Blind || se.clampMarketData)
+}
+
+// activateMarketCutoff registers the current simulation's cutoff if it restricts market data (caller must hold lock)
+func (se *SimulationEngine) activateMarketCutoff() {
+	if !se.restrictsMarketData() || se.currentSimulationID == 0 {
+		return
+	}
+	se.cutoffRegistry.Activate(se.currentSimulationID, se.currentPriceTime)
+	log.Printf("Market data for simulation %d cut off at %d (blind: %t)", se.currentSimulationID, se.currentPriceTime, se.options.Blind)
+}
+
+// advanceMarketCutoff moves the cutoff to the latest completed candle (caller must hold lock)
+func (se *SimulationEngine) advanceMarketCutoff() {
+	if !se.restrictsMarketData() {
+		return
+	}
+	se.cutoffRegistry.Advance(se.currentSimulationID, se.currentPriceTime)
+}
+
+// deactivateMarketCutoff lifts the current simulation's cutoff (caller must hold lock)
+func (se *SimulationEngine) deactivateMarketCutoff() {
+	if se.cutoffRegistry == nil || se.currentSimulationID == 0 {
+		return
+	}
+	se.cutoffRegistry.Deactivate(se.currentSimulationID)
+}
@@ -0,0 +1,625 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tradesimulator/internal/engines/trading"
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/services"
+	"tradesimulator/internal/testutil"
+	"tradesimulator/internal/types"
+)
+
+// testStartTime is aligned to every base interval up to 1d
+const testStartTime = int64(1_704_067_200_000) // 2024-01-01T00:00:00Z
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testEngine is a simulation engine backed by in-memory DAOs, a real order engine and fixed candles
+type testEngine struct {
+	*SimulationEngine
+	marketData    *testutil.MarketData
+	simulationDAO *testutil.SimulationDAO
+	positionDAO   *testutil.PositionDAO
+	orderDAO      *testutil.OrderDAO
+	tradeDAO      *testutil.TradeDAO
+	orders        trading.OrderExecutionEngineInterface
+	client        *testutil.Client
+}
+
+func newTestEngine(t *testing.T) *testEngine {
+	t.Helper()
+
+	te := &testEngine{
+		marketData:    testutil.NewMarketData(),
+		simulationDAO: testutil.NewSimulationDAO(),
+		positionDAO:   testutil.NewPositionDAO(),
+		orderDAO:      testutil.NewOrderDAO(),
+		tradeDAO:      testutil.NewTradeDAO(),
+		client:        testutil.NewClient(),
+	}
+	te.orders = trading.NewOrderExecutionEngine(te.orderDAO, te.tradeDAO, te.positionDAO, te.client, testutil.NewDB())
+	te.SimulationEngine = NewSimulationEngine(te.client, te.marketData, services.NewPortfolioService(te.positionDAO),
+		te.simulationDAO, te.positionDAO, te.orders)
+	t.Cleanup(te.Cleanup)
+	return te
+}
+
+// currentBaseInterval reads the base interval under the engine lock
+func (te *testEngine) currentBaseInterval() string {
+	te.mu.RLock()
+	defer te.mu.RUnlock()
+	return te.baseInterval
+}
+
+// flatCandles returns count candles of interval from testStartTime, all closing at price
+func flatCandles(interval string, count int, price float64) []models.OHLCV {
+	closes := make([]float64, count)
+	for i := range closes {
+		closes[i] = price
+	}
+	return testutil.Candles(testStartTime, interval, closes...)
+}
+
+// waitFor polls condition until it holds or five seconds have passed
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBaseIntervalForSpeed(t *testing.T) {
+	tests := []struct {
+		speed int
+		want  string
+	}{
+		{speed: 1, want: "1m"},
+		{speed: 59, want: "1m"},
+		{speed: 60, want: "1m"},
+		{speed: 300, want: "5m"},
+		{speed: 3599, want: "15m"},
+		{speed: 3600, want: "1h"},
+		{speed: 14399, want: "1h"},
+		{speed: 14400, want: "4h"},
+		{speed: 86399, want: "4h"},
+		{speed: 86400, want: "1d"},
+		{speed: 10_000_000, want: "1d"},
+	}
+
+	for _, tt := range tests {
+		if got := baseIntervalForSpeed(tt.speed); got != tt.want {
+			t.Errorf("baseIntervalForSpeed(%d) = %s, want %s", tt.speed, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultDataBatchSize(t *testing.T) {
+	tests := []struct {
+		baseInterval string
+		want         int
+	}{
+		{baseInterval: "1m", want: maxDataBatchSize}, // 90 days would be 129600 candles, capped at 1000
+		{baseInterval: "1h", want: maxDataBatchSize}, // 2160 candles, capped at 1000
+		{baseInterval: "4h", want: 540},              // 90 days of 4h candles
+		{baseInterval: "1d", want: 90},               // 90 days instead of ~3 years of daily candles
+		{baseInterval: "1w", want: minDataBatchSize}, // 12 weeks, raised to the minimum
+		{baseInterval: "1M", want: minDataBatchSize}, // 3 months, raised to the minimum
+	}
+
+	for _, tt := range tests {
+		if got := defaultDataBatchSize(tt.baseInterval); got != tt.want {
+			t.Errorf("defaultDataBatchSize(%s) = %d, want %d", tt.baseInterval, got, tt.want)
+		}
+	}
+}
+
+func TestDataBatchSizeOverride(t *testing.T) {
+	se := &SimulationEngine{}
+	if got := se.dataBatchSize("1d"); got != 90 {
+		t.Errorf("default 1d batch = %d, want 90", got)
+	}
+
+	se.SetDataBatchSize(250)
+	if got := se.dataBatchSize("1d"); got != 250 {
+		t.Errorf("configured 1d batch = %d, want 250", got)
+	}
+}
+
+// The configured batch size is honored by the initial load and by every later load, with counts above
+// Binance's 1000 candle limit split into consecutive range requests
+func TestDataBatchSizeHonored(t *testing.T) {
+	tests := []struct {
+		name       string
+		batchSize  int
+		wantLimits []int // Request limits of the initial load
+	}{
+		{name: "smaller than the default", batchSize: 250, wantLimits: []int{250}},
+		{name: "over one request", batchSize: 2500, wantLimits: []int{1000, 1000, 500}},
+		{name: "clamped to the maximum", batchSize: MaxDataBatchCandles + 1000, wantLimits: []int{1000, 1000, 1000, 1000, 1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := newTestEngine(t)
+			te.marketData.SetCandles("BTCUSDT", "1d", flatCandles("1d", 20000, 100))
+			te.SetMaxSpeed(86400 * 2000)
+			te.SetDataBatchSize(tt.batchSize)
+
+			if err := te.Start("BTCUSDT", "1d", testStartTime, 86400*2000, 10000, SimulationOptions{}); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			if err := te.Pause(); err != nil {
+				t.Fatalf("Pause: %v", err)
+			}
+
+			requests := te.marketData.Requests()
+			if len(requests) < len(tt.wantLimits) {
+				t.Fatalf("made %d requests, want at least %d", len(requests), len(tt.wantLimits))
+			}
+			for i, want := range tt.wantLimits {
+				if requests[i].Limit != want {
+					t.Errorf("request %d limit = %d, want %d", i, requests[i].Limit, want)
+				}
+			}
+			// Each request continues just after the last candle of the one before
+			day := models.GetIntervalDurationMs("1d")
+			for i := 1; i < len(tt.wantLimits); i++ {
+				if want := testStartTime + int64(i*1000-1)*day + 1; *requests[i].StartTime != want {
+					t.Errorf("request %d starts at %d, want %d", i, *requests[i].StartTime, want)
+				}
+			}
+
+			// A later load fetches the same count
+			waitFor(t, "background loads to finish", func() bool {
+				te.mu.Lock()
+				defer te.mu.Unlock()
+				return !te.isLoadingData
+			})
+			te.mu.Lock()
+			loaded := len(te.marketData.Requests())
+			err := te.loadMoreHistoricalData(context.Background())
+			te.mu.Unlock()
+			if err != nil {
+				t.Fatalf("loadMoreHistoricalData: %v", err)
+			}
+			requests = te.marketData.Requests()[loaded:]
+			if len(requests) != len(tt.wantLimits) {
+				t.Fatalf("later load made %d requests, want %d", len(requests), len(tt.wantLimits))
+			}
+			for i, want := range tt.wantLimits {
+				if requests[i].Limit != want {
+					t.Errorf("later request %d limit = %d, want %d", i, requests[i].Limit, want)
+				}
+			}
+		})
+	}
+}
+
+// Speeds over the cap are rejected with a clear error by Start, SetSpeed and ValidateStart, and leave no simulation
+// running
+func TestOverCapSpeedRejected(t *testing.T) {
+	te := newTestEngine(t)
+	te.marketData.SetCandles("BTCUSDT", "1d", flatCandles("1d", 50, 100))
+	te.SetMaxSpeed(86400)
+
+	err := te.Start("BTCUSDT", "1d", testStartTime, 86401, 10000, SimulationOptions{})
+	if err == nil || !strings.Contains(err.Error(), "exceeds the maximum of 86400") {
+		t.Fatalf("Start at 86401x = %v, want the over-cap error", err)
+	}
+	if status := te.GetStatus(); status.IsRunning {
+		t.Fatal("simulation running after an over-cap start")
+	}
+	if issues := ValidateStart(te.marketData, 86400, "BTCUSDT", "1d", testStartTime, 86401, 10000, SimulationOptions{}); len(issues) != 1 {
+		t.Errorf("ValidateStart issues = %v, want the over-cap speed", issues)
+	}
+
+	if err := te.Start("BTCUSDT", "1d", testStartTime, 86400, 10000, SimulationOptions{}); err != nil {
+		t.Fatalf("Start at the cap: %v", err)
+	}
+	if err := te.SetSpeed(86401); err == nil {
+		t.Error("SetSpeed over the cap succeeded")
+	}
+	if status := te.GetStatus(); status.Speed != 86400 {
+		t.Errorf("speed = %d after a rejected change, want 86400", status.Speed)
+	}
+
+	// Without a configured cap the default applies
+	te.SetMaxSpeed(0)
+	if err := te.SetSpeed(DefaultMaxSpeed + 1); err == nil {
+		t.Error("SetSpeed over the default cap succeeded")
+	}
+	if got := te.getOptimalTickerInterval(); got < minTickerInterval {
+		t.Errorf("ticker interval at 86400x = %v, below the %v minimum", got, minTickerInterval)
+	}
+}
+
+func TestOptimalTickerIntervalAtLargeBaseIntervals(t *testing.T) {
+	tests := []struct {
+		speed int
+		want  time.Duration
+	}{
+		{speed: 14400, want: time.Second},             // One 4h candle per second
+		{speed: 86400, want: time.Second},             // One 1d candle per second
+		{speed: 864000, want: 100 * time.Millisecond}, // Ten 1d candles per second
+		{speed: 86400000, want: minTickerInterval},    // Clamped instead of ticking every 1ms
+	}
+
+	for _, tt := range tests {
+		se := &SimulationEngine{speed: tt.speed, baseInterval: baseIntervalForSpeed(tt.speed)}
+		if got := se.getOptimalTickerInterval(); got != tt.want {
+			t.Errorf("ticker interval at %dx (%s base) = %v, want %v", tt.speed, se.baseInterval, got, tt.want)
+		}
+	}
+}
+
+// Run with go test -race: speed changes race the simulation loop, which reloads base data when the base interval
+// changes
+func TestSetSpeedConcurrentlyWhilePlaying(t *testing.T) {
+	te := newTestEngine(t)
+	te.marketData.SetCandles("BTCUSDT", "1m", flatCandles("1m", 3000, 100))
+	te.marketData.SetCandles("BTCUSDT", "5m", flatCandles("5m", 600, 100))
+
+	if err := te.Start("BTCUSDT", "1h", testStartTime, 60, 10000, SimulationOptions{}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	speeds := []int{1, 10, 60, 120, 300, 600}
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := te.SetSpeed(speeds[(i+j)%len(speeds)]); err != nil {
+					t.Errorf("SetSpeed: %v", err)
+				}
+				te.GetStatus()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if err := te.SetSpeed(300); err != nil {
+		t.Fatalf("SetSpeed: %v", err)
+	}
+	waitFor(t, "the last speed change", func() bool {
+		status := te.GetStatus()
+		return status.Speed == 300 && te.currentBaseInterval() == "5m"
+	})
+
+	if err := te.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+}
+
+func TestSetSpeedWhileNotPlaying(t *testing.T) {
+	te := newTestEngine(t)
+	te.marketData.SetCandles("BTCUSDT", "1m", flatCandles("1m", 3000, 100))
+	te.marketData.SetCandles("BTCUSDT", "5m", flatCandles("5m", 600, 100))
+
+	// Stopped: the speed and base interval apply to the next start
+	if err := te.SetSpeed(300); err != nil {
+		t.Fatalf("SetSpeed while stopped: %v", err)
+	}
+	if status := te.GetStatus(); status.Speed != 300 {
+		t.Errorf("speed while stopped = %d, want 300", status.Speed)
+	}
+	if base := te.currentBaseInterval(); base != "5m" {
+		t.Errorf("base interval while stopped = %s, want 5m", base)
+	}
+
+	// Paused: the change applies immediately, without waiting for playback to resume
+	if err := te.Start("BTCUSDT", "1h", testStartTime, 60, 10000, SimulationOptions{}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := te.Pause(); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if err := te.SetSpeed(300); err != nil {
+		t.Fatalf("SetSpeed while paused: %v", err)
+	}
+	if status := te.GetStatus(); status.Speed != 300 {
+		t.Errorf("speed while paused = %d, want 300", status.Speed)
+	}
+	if base := te.currentBaseInterval(); base != "5m" {
+		t.Errorf("base interval while paused = %s, want 5m", base)
+	}
+}
+
+func TestSetTimeframeWhileStopped(t *testing.T) {
+	te := newTestEngine(t)
+
+	if err := te.SetTimeframe("4h"); err != nil {
+		t.Fatalf("SetTimeframe: %v", err)
+	}
+	if status := te.GetStatus(); status.Interval != "4h" {
+		t.Errorf("interval = %s, want 4h", status.Interval)
+	}
+}
+
+// Run with go test -race: background loads fetch without the lock while the loop plays and status is read
+func TestBackgroundDataLoadingWhilePlaying(t *testing.T) {
+	te := newTestEngine(t)
+	te.marketData.SetCandles("BTCUSDT", "1d", flatCandles("1d", 1000, 100))
+	te.maxBufferSize = 300
+	te.SetDataBatchSize(100)
+	te.SetMaxSpeed(86400 * 2000)
+
+	// 20 daily candles per 10ms tick, so loads are needed every few ticks
+	if err := te.Start("BTCUSDT", "1d", testStartTime, 86400*2000, 10000, SimulationOptions{}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	waitFor(t, "the simulation to complete", func() bool {
+		return te.GetStatus().State == string(StateStopped)
+	})
+
+	if played := len(te.client.Messages(types.SimulationUpdate)); played != 1000 {
+		t.Errorf("played %d candles, want 1000", played)
+	}
+	status := te.GetStatus()
+	if status.Data.IsLoadingData {
+		t.Error("still loading after completion")
+	}
+	if status.Data.BufferedCandles > te.maxBufferSize {
+		t.Errorf("buffered %d candles, more than the %d buffer", status.Data.BufferedCandles, te.maxBufferSize)
+	}
+	if want := testStartTime + 999*models.GetIntervalDurationMs("1d"); status.Data.LastDataLoadTime != want {
+		t.Errorf("last data load time = %d, want %d", status.Data.LastDataLoadTime, want)
+	}
+}
+
+func TestDataLoadDiscardedAfterBufferReplaced(t *testing.T) {
+	te := newTestEngine(t)
+	te.marketData.SetCandles("BTCUSDT", "1m", flatCandles("1m", 200, 100))
+	te.baseInterval = "1m"
+	te.symbol = "BTCUSDT"
+	te.baseDataset = flatCandles("1m", 100, 100)
+	te.SetDataBatchSize(60)
+
+	te.mu.Lock()
+	load, ok := te.beginDataLoadUnsafe()
+	_, again := te.beginDataLoadUnsafe()
+	te.mu.Unlock()
+	if !ok {
+		t.Fatal("load not started")
+	}
+	if again {
+		t.Fatal("second load started while the first is in progress")
+	}
+	newData, err := load.fetch(context.Background())
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+
+	// A seek replaces the buffer while the candles are fetched
+	te.mu.Lock()
+	te.baseDataset = flatCandles("1m", 10, 100)
+	te.isLoadingData = false
+	err = te.finishDataLoadUnsafe(load, newData, nil)
+	te.mu.Unlock()
+	if err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	if len(te.baseDataset) != 10 {
+		t.Errorf("buffer has %d candles after a stale load, want the 10 of the replacement", len(te.baseDataset))
+	}
+}
+
+// A buy limit below the market fills once a candle's low reaches it during the replay, even though no candle closes
+// at or below the limit
+func TestLimitOrderBelowMarketFillsDuringReplay(t *testing.T) {
+	tests := []struct {
+		name      string
+		execution trading.ExecutionOptions
+	}{
+		{name: "default"},
+		{name: "strict limit fills", execution: trading.ExecutionOptions{StrictLimitFills: true}},
+		{name: "fill at limit price", execution: trading.ExecutionOptions{FillAtLimitPrice: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := newTestEngine(t)
+			candles := flatCandles("1d", 600, 200)
+			// Candle 400 wicks down to 95 and closes at 130, where the price stays
+			candles[400] = testutil.Candle(candles[400].StartTime, "1d", 200, 200, 95, 130)
+			for i := 401; i < len(candles); i++ {
+				candles[i] = testutil.Candle(candles[i].StartTime, "1d", 130, 130, 130, 130)
+			}
+			te.marketData.SetCandles("BTCUSDT", "1d", candles)
+			te.SetMaxSpeed(86400 * 2000)
+
+			if err := te.Start("BTCUSDT", "1d", testStartTime, 86400*2000, 10000, SimulationOptions{Execution: tt.execution}); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			if err := te.Pause(); err != nil {
+				t.Fatalf("Pause: %v", err)
+			}
+			status := te.GetStatus()
+			if _, err := te.orders.PlaceLimitOrder(1, status.SimulationID, "BTCUSDT", models.OrderSideBuy, 1, 100, "", status.SimulationTime); err != nil {
+				t.Fatalf("PlaceLimitOrder: %v", err)
+			}
+			if err := te.Resume(); err != nil {
+				t.Fatalf("Resume: %v", err)
+			}
+
+			waitFor(t, "the simulation to complete", func() bool {
+				return te.GetStatus().State == string(StateStopped)
+			})
+
+			trades, err := te.tradeDAO.GetUserTrades(1, status.SimulationID, 0)
+			if err != nil {
+				t.Fatalf("GetUserTrades: %v", err)
+			}
+			if len(trades) != 1 {
+				t.Fatalf("got %d trades, want the limit order's fill", len(trades))
+			}
+			if trades[0].Price != 100 {
+				t.Errorf("filled at %g, want the 100 limit", trades[0].Price)
+			}
+			if want := candles[400].EndTime; trades[0].ExecutedAt != want {
+				t.Errorf("filled at time %d, want the wick candle's close %d", trades[0].ExecutedAt, want)
+			}
+			if executed := te.client.Messages(types.OrderExecuted); len(executed) != 1 {
+				t.Errorf("sent %d order_executed messages, want 1", len(executed))
+			}
+			position, err := te.positionDAO.GetPosition(1, status.SimulationID, "BTCUSDT", "USDT")
+			if err != nil || position.Quantity != 1 {
+				t.Errorf("position = %+v (%v), want 1 BTC", position, err)
+			}
+		})
+	}
+}
+
+// Short positions count against the portfolio at the current price, as the cost of buying them back
+func TestPortfolioValueWithShortPosition(t *testing.T) {
+	te := newTestEngine(t)
+	te.marketData.SetCandles("BTCUSDT", "1d", flatCandles("1d", 50, 100))
+	te.SetMaxSpeed(86400 * 2000)
+
+	noFee := 0.0
+	options := SimulationOptions{Execution: trading.ExecutionOptions{AllowShort: true, FeeRate: &noFee}}
+	if err := te.Start("BTCUSDT", "1d", testStartTime, 86400*2000, 10000, options); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := te.Pause(); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	status := te.GetStatus()
+	if _, _, err := te.orders.ExecuteMarketOrder(1, status.SimulationID, "BTCUSDT", models.OrderSideSell, 10, 100, "", status.SimulationTime); err != nil {
+		t.Fatalf("ExecuteMarketOrder: %v", err)
+	}
+
+	te.mu.Lock()
+	cash, positionValue, err := te.calculatePortfolioBreakdown(120, status.SimulationID, "BTCUSDT")
+	te.mu.Unlock()
+	if err != nil {
+		t.Fatalf("calculatePortfolioBreakdown: %v", err)
+	}
+	if cash != 11000 || positionValue != -1200 {
+		t.Errorf("cash %g and position value %g, want 11000 and -1200", cash, positionValue)
+	}
+}
+
+// Lowercase and whitespace-padded symbols are accepted and played under their canonical name
+func TestStartNormalizesSymbol(t *testing.T) {
+	te := newTestEngine(t)
+	te.marketData.SetCandles("BTCUSDT", "1d", flatCandles("1d", 50, 100))
+	te.SetMaxSpeed(86400 * 2000)
+
+	if issues := ValidateStart(te.marketData, 86400*2000, " btcusdt ", "1d", testStartTime, 86400*2000, 10000, SimulationOptions{}); len(issues) != 0 {
+		t.Errorf("ValidateStart issues = %v, want none", issues)
+	}
+
+	if err := te.Start(" btcusdt ", "1d", testStartTime, 86400*2000, 10000, SimulationOptions{}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	status := te.GetStatus()
+	if status.Symbol != "BTCUSDT" {
+		t.Errorf("status symbol = %q, want BTCUSDT", status.Symbol)
+	}
+	record, err := te.simulationDAO.GetSimulationByID(status.SimulationID)
+	if err != nil {
+		t.Fatalf("GetSimulationByID: %v", err)
+	}
+	if record.Symbol != "BTCUSDT" {
+		t.Errorf("recorded symbol = %q, want BTCUSDT", record.Symbol)
+	}
+}
+
+// A completed simulation stores its final value with the P&L and return on it
+func TestFinalizationStoresPnL(t *testing.T) {
+	te := newTestEngine(t)
+	candles := flatCandles("1d", 600, 100)
+	for i := 300; i < len(candles); i++ {
+		candles[i] = testutil.Candle(candles[i].StartTime, "1d", 120, 120, 120, 120)
+	}
+	te.marketData.SetCandles("BTCUSDT", "1d", candles)
+	te.SetMaxSpeed(86400 * 2000)
+
+	noFee := 0.0
+	options := SimulationOptions{Execution: trading.ExecutionOptions{FeeRate: &noFee}}
+	if err := te.Start("BTCUSDT", "1d", testStartTime, 86400*2000, 10000, options); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := te.Pause(); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	status := te.GetStatus()
+	if status.SimulationTime >= candles[300].StartTime {
+		t.Fatalf("paused at %d, want before the rise", status.SimulationTime)
+	}
+	if _, _, err := te.orders.ExecuteMarketOrder(1, status.SimulationID, "BTCUSDT", models.OrderSideBuy, 50, 100, "", status.SimulationTime); err != nil {
+		t.Fatalf("ExecuteMarketOrder: %v", err)
+	}
+	if err := te.Resume(); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	waitFor(t, "the simulation to complete", func() bool {
+		return te.GetStatus().State == string(StateStopped)
+	})
+
+	record, err := te.simulationDAO.GetSimulationByID(status.SimulationID)
+	if err != nil {
+		t.Fatalf("GetSimulationByID: %v", err)
+	}
+	if record.Status != models.SimulationStatusCompleted {
+		t.Errorf("status = %s, want completed", record.Status)
+	}
+	// 5000 cash and 50 BTC at 120
+	if record.TotalValue == nil || *record.TotalValue != 11000 {
+		t.Fatalf("total value = %v, want 11000", record.TotalValue)
+	}
+	if record.PnL == nil || *record.PnL != 1000 {
+		t.Errorf("P&L = %v, want 1000", record.PnL)
+	}
+	if record.ReturnPct == nil || *record.ReturnPct != 10 {
+		t.Errorf("return = %v, want 10%%", record.ReturnPct)
+	}
+}
+
+// failingFundingDAO fails to fund new simulations
+type failingFundingDAO struct {
+	*testutil.PositionDAO
+}
+
+func (failingFundingDAO) CreateInitialUSDTPosition(userID uint, simulationID *uint, initialFunding float64) error {
+	return errors.New("database unavailable")
+}
+
+// A start that fails after creating its simulation record leaves no market cutoff behind
+func TestFailedStartLeavesNoMarketCutoff(t *testing.T) {
+	te := newTestEngine(t)
+	te.marketData.SetCandles("BTCUSDT", "1d", flatCandles("1d", 600, 100))
+	positionDAO := failingFundingDAO{te.positionDAO}
+	engine := NewSimulationEngine(te.client, te.marketData, services.NewPortfolioService(positionDAO), te.simulationDAO, positionDAO, te.orders)
+	defer engine.Cleanup()
+	engine.SetMaxSpeed(86400 * 2000)
+	registry := services.NewMarketCutoffRegistry()
+	engine.SetMarketCutoffRegistry(registry, false)
+
+	err := engine.Start("BTCUSDT", "1d", testStartTime, 86400*2000, 10000, SimulationOptions{Blind: true})
+	if err == nil || !strings.Contains(err.Error(), "initial USDT position") {
+		t.Fatalf("Start error = %v, want the funding failure", err)
+	}
+	if cutoff, active := registry.Cutoff(); active {
+		t.Errorf("market data still cut off at %d after the failed start", cutoff)
+	}
+}
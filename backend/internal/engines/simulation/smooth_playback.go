@@ -0,0 +1,99 @@
+package simulation
+
+import (
+	"log"
+	"time"
+
+	"tradesimulator/internal/models"
+)
+
+// maxSmoothCatchUpFactor bounds how many batches one tick may emit to catch up with real time
+// Falling further behind restarts the playback clock instead of bursting
+const maxSmoothCatchUpFactor = 2
+
+// SetSmoothPlayback makes the engine emit a fixed number of base candles per tick (0 disables)
+// The ticker interval is derived from the speed so throughput matches time based playback, but
+// candles are released evenly instead of in bursts whenever several become ready in one tick
+func (se *SimulationEngine) SetSmoothPlayback(candlesPerTick int) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	if candlesPerTick < 0 {
+		candlesPerTick = 0
+	}
+	se.smoothCandlesPerTick = candlesPerTick
+	if se.state != StateStopped {
+		se.tickerInterval = se.getOptimalTickerInterval()
+	}
+}
+
+// smoothCandleDuration returns the real time one base candle takes at the current speed (caller must hold lock)
+func (se *SimulationEngine) smoothCandleDuration() time.Duration {
+	speed := se.speed
+	if speed <= 0 {
+		speed = 1
+	}
+	candleDuration := time.Duration(models.GetIntervalDurationMs(se.baseInterval)) * time.Millisecond / time.Duration(speed)
+	if candleDuration <= 0 {
+		return time.Nanosecond
+	}
+	return candleDuration
+}
+
+// smoothTickerInterval sizes the batch and returns the ticker interval for smooth playback (caller must hold lock)
+// The batch grows past the configured size when the resulting interval would be below minTickerInterval
+func (se *SimulationEngine) smoothTickerInterval() time.Duration {
+	candleDuration := se.smoothCandleDuration()
+	batch := se.smoothCandlesPerTick
+	if minBatch := int((minTickerInterval + candleDuration - 1) / candleDuration); batch < minBatch {
+		batch = minBatch
+	}
+	se.smoothBatch = batch
+	se.resetSmoothPlaybackClock()
+
+	return candleDuration * time.Duration(batch)
+}
+
+// resetSmoothPlaybackClock restarts the real time reference used to pace smooth playback (caller must hold lock)
+func (se *SimulationEngine) resetSmoothPlaybackClock() {
+	se.smoothClockStart = time.Now()
+	se.smoothClockCandles = 0
+}
+
+// processSmoothBaseUpdate emits the base candles due since the playback clock started (caller must hold lock)
+// Normally that is one batch; late ticks emit a little more to keep pace, up to maxSmoothCatchUpFactor batches
+func (se *SimulationEngine) processSmoothBaseUpdate() bool {
+	if se.smoothBatch <= 0 {
+		se.tickerInterval = se.smoothTickerInterval()
+	}
+
+	due := int(time.Since(se.smoothClockStart)/se.smoothCandleDuration()) - se.smoothClockCandles
+	if maxDue := se.smoothBatch * maxSmoothCatchUpFactor; due > maxDue {
+		log.Printf("Smooth playback fell %d candles behind, restarting playback clock", due)
+		se.resetSmoothPlaybackClock()
+		due = se.smoothBatch
+	}
+
+	for processed := 0; processed < due && se.currentIndex < len(se.baseDataset); processed++ {
+		baseCandle := se.baseDataset[se.currentIndex]
+		if !se.withinEndTime(baseCandle) {
+			se.currentSimTime = se.options.EndTime
+			return false
+		}
+		se.currentSimTime = baseCandle.EndTime
+		se.processBaseCandle(baseCandle)
+		se.smoothClockCandles++
+	}
+
+	if se.reachedEndTimeUnsafe() {
+		return false
+	}
+
+	// If no more base candles available, end simulation
+	if se.currentIndex >= len(se.baseDataset) {
+		// Waiting for more data shouldn't be paid back as a burst once it arrives
+		se.resetSmoothPlaybackClock()
+		return false
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package simulation
+
+import (
+	"fmt"
+	"time"
+
+	"tradesimulator/internal/integrations/marketdata"
+	"tradesimulator/internal/models"
+)
+
+// ValidateStart runs the checks Start performs on a simulation configuration and returns every issue found
+// No engine is started and no simulation record is created; an empty result means Start would accept it
+func ValidateStart(marketData marketdata.MarketDataProvider, maxSpeed int, symbol, interval string, startTime int64, speed int, initialFunding float64, options SimulationOptions) []string {
+	if maxSpeed <= 0 {
+		maxSpeed = DefaultMaxSpeed
+	}
+	symbol = models.NormalizeSymbol(symbol)
+	options.Symbols = normalizeSymbols(options.Symbols)
+
+	// A bare engine value gives access to the same checks Start uses
+	probe := &SimulationEngine{marketData: marketData, maxSpeed: maxSpeed, speed: speed}
+
+	issues := []string{}
+	addIssue := func(err error) {
+		if err != nil {
+			issues = append(issues, err.Error())
+		}
+	}
+
+	symbolSupported := false
+	for _, supported := range marketData.GetSupportedSymbols() {
+		if supported == symbol {
+			symbolSupported = true
+			break
+		}
+	}
+	if !symbolSupported {
+		addIssue(fmt.Errorf("unsupported symbol: %q", symbol))
+	}
+	addIssue(probe.validateExtraSymbols(symbol, options.Symbols))
+
+	if initialFunding <= 0 {
+		addIssue(fmt.Errorf("initial funding must be greater than 0"))
+	}
+
+	if startTime <= 0 || startTime > time.Now().UnixMilli() {
+		addIssue(fmt.Errorf("invalid start time: %d, must be a past timestamp in milliseconds", startTime))
+	}
+	addIssue(validateEndTime(startTime, options.EndTime))
+
+	speedErr := probe.validateSpeed(speed)
+	addIssue(speedErr)
+	addIssue(options.Validate())
+
+	intervalValid := marketData.ValidateInterval(interval)
+	if !intervalValid {
+		addIssue(fmt.Errorf("invalid interval: %s", interval))
+	}
+
+	// The remaining checks depend on a usable speed
+	if speedErr != nil {
+		return issues
+	}
+
+	addIssue(probe.validateExtraIntervals(options.Intervals, speed))
+	if intervalValid && !probe.isTimeframeAllowed(interval, speed) {
+		addIssue(fmt.Errorf("timeframe %s not allowed at %dx speed. Use %s or higher", interval, speed, probe.getMinAllowedTimeframe(speed)))
+	}
+
+	// Check data availability at the base interval Start would load
+	if symbolSupported && startTime > 0 {
+		baseInterval := probe.getOptimalBaseInterval()
+		data, err := marketData.GetHistoricalData(symbol, baseInterval, 1, &startTime, nil, false)
+		switch {
+		case err != nil:
+			addIssue(fmt.Errorf("failed to check historical data: %w", err))
+		case len(data) == 0:
+			addIssue(fmt.Errorf("no historical data available from start time"))
+		}
+	}
+
+	return issues
+}
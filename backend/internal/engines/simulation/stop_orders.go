@@ -0,0 +1,44 @@
+package simulation
+
+import (
+	"fmt"
+	"log"
+)
+
+// StopOrderBehavior controls what happens to a simulation's open orders when it stops or completes
+type StopOrderBehavior string
+
+const (
+	StopOrderCancel StopOrderBehavior = "cancel" // Cancel every open order, as nothing can fill them without a running engine
+	StopOrderKeep   StopOrderBehavior = "keep"   // Leave open orders pending so they rest again when the simulation is resumed
+)
+
+// ParseStopOrderBehavior validates a stop order behavior setting
+func ParseStopOrderBehavior(value string) (StopOrderBehavior, error) {
+	switch behavior := StopOrderBehavior(value); behavior {
+	case StopOrderCancel, StopOrderKeep:
+		return behavior, nil
+	default:
+		return "", fmt.Errorf("unknown stop order behavior %q (expected cancel or keep)", value)
+	}
+}
+
+// SetStopOrderBehavior sets what happens to open orders when the simulation stops
+func (se *SimulationEngine) SetStopOrderBehavior(behavior StopOrderBehavior) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+	se.stopOrderBehavior = behavior
+}
+
+// settleOpenOrdersOnStopUnsafe applies the stop order behavior to the simulation's open orders (caller must hold lock)
+// Each cancellation is sent to the client as an order_cancelled message
+func (se *SimulationEngine) settleOpenOrdersOnStopUnsafe() {
+	if se.stopOrderBehavior != StopOrderCancel || se.orderExecutionEngine == nil || se.currentSimulationID == 0 {
+		return
+	}
+
+	// For now, use default user ID 1
+	if _, err := se.orderExecutionEngine.CancelOpenOrders(1, se.currentSimulationID); err != nil {
+		log.Printf("Failed to cancel open orders of stopped simulation %d: %v", se.currentSimulationID, err)
+	}
+}
@@ -0,0 +1,98 @@
+package simulation
+
+import (
+	"testing"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/types"
+)
+
+func TestParseStopOrderBehavior(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    StopOrderBehavior
+		wantErr bool
+	}{
+		{value: "cancel", want: StopOrderCancel},
+		{value: "keep", want: StopOrderKeep},
+		{value: "", wantErr: true},
+		{value: "Cancel", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseStopOrderBehavior(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseStopOrderBehavior(%q) = %q, %v; want %q, error %t", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestOpenOrdersOnStop(t *testing.T) {
+	tests := []struct {
+		name       string
+		behavior   StopOrderBehavior // Empty keeps the engine default
+		complete   bool              // Let the replay run out of data instead of stopping it
+		wantStatus models.OrderStatus
+	}{
+		{name: "stop cancels by default", wantStatus: models.OrderStatusCancelled},
+		{name: "completion cancels by default", complete: true, wantStatus: models.OrderStatusCancelled},
+		{name: "stop keeps when configured", behavior: StopOrderKeep, wantStatus: models.OrderStatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := newTestEngine(t)
+			te.marketData.SetCandles("BTCUSDT", "1d", flatCandles("1d", 50, 200))
+			te.SetMaxSpeed(86400 * 2000)
+			if tt.behavior != "" {
+				te.SetStopOrderBehavior(tt.behavior)
+			}
+
+			if err := te.Start("BTCUSDT", "1d", testStartTime, 86400*2000, 10000, SimulationOptions{}); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			if err := te.Pause(); err != nil {
+				t.Fatalf("Pause: %v", err)
+			}
+			status := te.GetStatus()
+			// Neither order can fill while the price stays at 200
+			var orders []*models.Order
+			for _, limit := range []float64{100, 150} {
+				order, err := te.orders.PlaceLimitOrder(1, status.SimulationID, "BTCUSDT", models.OrderSideBuy, 1, limit, "", status.SimulationTime)
+				if err != nil {
+					t.Fatalf("PlaceLimitOrder: %v", err)
+				}
+				orders = append(orders, order)
+			}
+
+			if tt.complete {
+				if err := te.Resume(); err != nil {
+					t.Fatalf("Resume: %v", err)
+				}
+				waitFor(t, "the simulation to complete", func() bool {
+					return te.GetStatus().State == string(StateStopped)
+				})
+			} else if err := te.Stop(); err != nil {
+				t.Fatalf("Stop: %v", err)
+			}
+
+			for _, order := range orders {
+				stored, err := te.orderDAO.GetByID(order.ID)
+				if err != nil {
+					t.Fatalf("GetByID(%d): %v", order.ID, err)
+				}
+				if stored.Status != tt.wantStatus {
+					t.Errorf("order %d status = %s, want %s", order.ID, stored.Status, tt.wantStatus)
+				}
+			}
+
+			wantCancelled := 0
+			if tt.wantStatus == models.OrderStatusCancelled {
+				wantCancelled = len(orders)
+			}
+			if cancelled := te.client.Messages(types.OrderCancelled); len(cancelled) != wantCancelled {
+				t.Errorf("sent %d order_cancelled messages, want %d", len(cancelled), wantCancelled)
+			}
+		})
+	}
+}
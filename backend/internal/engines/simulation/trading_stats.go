@@ -0,0 +1,92 @@
+package simulation
+
+import (
+	"log"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/types"
+)
+
+// tradingStatsQuoteAsset is the quote asset trading stats values are rounded for
+const tradingStatsQuoteAsset = "USDT"
+
+// TradingStatsData is the payload of the periodic trading_stats message
+type TradingStatsData struct {
+	SimulationID    uint    `json:"simulationId"`
+	SimulationTime  int64   `json:"simulationTime"`
+	TradeCount      int     `json:"tradeCount"`
+	TradesPerMinute float64 `json:"tradesPerMinute"` // Trades per minute of simulation time
+	ClosingTrades   int     `json:"closingTrades"`
+	WinningTrades   int     `json:"winningTrades"`
+	WinRate         float64 `json:"winRate"` // Percentage of closing trades that were profitable
+	RealizedPnL     float64 `json:"realizedPnl"`
+	UnrealizedPnL   float64 `json:"unrealizedPnl"`
+	TotalPnL        float64 `json:"totalPnl"`
+	TotalFees       float64 `json:"totalFees"`
+}
+
+// SetTradingStatsInterval sets how many base candles pass between trading_stats messages (0 disables them)
+func (se *SimulationEngine) SetTradingStatsInterval(candles int) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	if candles < 0 {
+		candles = 0
+	}
+	se.tradingStatsInterval = candles
+}
+
+// resetTradingStatsUnsafe rebuilds the trade counters for the current simulation (caller must hold lock)
+// startTime is the simulation's market start time, used for the trades per minute rate
+func (se *SimulationEngine) resetTradingStatsUnsafe(startTime int64) {
+	se.tradingStatsStartTime = startTime
+	se.candlesSinceTradingStats = 0
+	if se.orderExecutionEngine == nil {
+		return
+	}
+	if err := se.orderExecutionEngine.ResetTradeStats(se.currentSimulationID); err != nil {
+		log.Printf("Failed to rebuild trading stats for simulation %d: %v", se.currentSimulationID, err)
+	}
+}
+
+// maybeSendTradingStats sends trading stats once the configured number of candles has passed (caller must hold lock)
+func (se *SimulationEngine) maybeSendTradingStats() {
+	if se.tradingStatsInterval <= 0 || se.client == nil || se.orderExecutionEngine == nil {
+		return
+	}
+
+	se.candlesSinceTradingStats++
+	if se.candlesSinceTradingStats < se.tradingStatsInterval {
+		return
+	}
+	se.candlesSinceTradingStats = 0
+
+	se.client.SendMessage(types.TradingStats, se.buildTradingStatsUnsafe())
+}
+
+// buildTradingStatsUnsafe builds the trading stats payload from the order engine's counters (caller must hold lock)
+func (se *SimulationEngine) buildTradingStatsUnsafe() TradingStatsData {
+	stats := se.orderExecutionEngine.GetTradeStats()
+
+	// Only the simulated symbol has a current price, other open positions are left unvalued
+	unrealizedPnL := se.orderExecutionEngine.GetUnrealizedPnL(se.currentPricesUnsafe())
+
+	var tradesPerMinute float64
+	if elapsedMs := se.currentPriceTime - se.tradingStatsStartTime; elapsedMs > 0 {
+		tradesPerMinute = float64(stats.TradeCount) / (float64(elapsedMs) / 60000)
+	}
+
+	return TradingStatsData{
+		SimulationID:    se.currentSimulationID,
+		SimulationTime:  se.currentPriceTime,
+		TradeCount:      stats.TradeCount,
+		TradesPerMinute: tradesPerMinute,
+		ClosingTrades:   stats.ClosingTrades,
+		WinningTrades:   stats.WinningTrades,
+		WinRate:         models.RoundPercent(stats.WinRate()),
+		RealizedPnL:     models.RoundValue(tradingStatsQuoteAsset, stats.RealizedPnL),
+		UnrealizedPnL:   models.RoundValue(tradingStatsQuoteAsset, unrealizedPnL),
+		TotalPnL:        models.RoundValue(tradingStatsQuoteAsset, stats.RealizedPnL+unrealizedPnL),
+		TotalFees:       models.RoundValue(tradingStatsQuoteAsset, stats.TotalFees),
+	}
+}
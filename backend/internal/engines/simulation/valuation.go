@@ -0,0 +1,94 @@
+package simulation
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"tradesimulator/internal/models"
+)
+
+const (
+	// DefaultValuationConcurrency is the number of symbol prices fetched in parallel during valuation
+	DefaultValuationConcurrency = 4
+
+	valuationPriceInterval = "1m"
+
+	// Simulation times within this window of now are valued at live ticker prices
+	liveValuationWindowMs = 60 * 1000
+)
+
+// SetValuationConcurrency sets how many symbol prices are fetched in parallel when valuing the portfolio
+// Requests still pass through the Binance client's rate limiter, so this only bounds in-flight requests
+func (se *SimulationEngine) SetValuationConcurrency(concurrency int) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	if concurrency <= 0 {
+		concurrency = DefaultValuationConcurrency
+	}
+	se.valuationConcurrency = concurrency
+}
+
+// fetchPricesAt returns the close of the last completed minute before atTime for each symbol
+// Historical prices are fetched concurrently, bounded by the engine's valuation concurrency
+func (se *SimulationEngine) fetchPricesAt(symbols []string, atTime int64) (map[string]float64, error) {
+	prices := make(map[string]float64, len(symbols))
+	if len(symbols) == 0 {
+		return prices, nil
+	}
+
+	// A simulation caught up with the present can use one batched ticker request
+	if time.Now().UnixMilli()-atTime <= liveValuationWindowMs {
+		return se.marketData.GetLatestPrices(symbols)
+	}
+
+	concurrency := se.valuationConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultValuationConcurrency
+	}
+
+	// Use the last closed candle so valuation never looks past the simulation time
+	candleStartTime := models.CalculateCandleStartTime(atTime, valuationPriceInterval) - models.GetIntervalDurationMs(valuationPriceInterval)
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	semaphore := make(chan struct{}, concurrency)
+
+	for _, symbol := range symbols {
+		wg.Add(1)
+		go func(symbol string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			startTime := candleStartTime
+			data, err := se.marketData.GetHistoricalData(symbol, valuationPriceInterval, 1, &startTime, nil, false)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to get price for %s: %w", symbol, err)
+				}
+			case len(data) == 0:
+				if firstErr == nil {
+					firstErr = fmt.Errorf("no price data for %s at %d", symbol, atTime)
+				}
+			default:
+				prices[symbol] = data[0].Close
+			}
+		}(symbol)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return prices, nil
+}
@@ -0,0 +1,67 @@
+package simulation
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"tradesimulator/internal/engines/trading"
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/services"
+	"tradesimulator/internal/testutil"
+)
+
+// slowMarketData adds a fixed latency to every historical data request, like a round trip to Binance
+type slowMarketData struct {
+	*testutil.MarketData
+	latency time.Duration
+}
+
+func (m slowMarketData) GetHistoricalData(symbol, interval string, limit int, startTime, endTime *int64, enableIncomplete bool) ([]models.OHLCV, error) {
+	time.Sleep(m.latency)
+	return m.MarketData.GetHistoricalData(symbol, interval, limit, startTime, endTime, enableIncomplete)
+}
+
+// BenchmarkPortfolioValuation values a portfolio holding dozens of symbols besides the simulation's, each priced
+// with its own request, one at a time and with the default concurrency
+func BenchmarkPortfolioValuation(b *testing.B) {
+	const heldSymbols = 48
+	simulationID := uint(1)
+
+	marketData := testutil.NewMarketData()
+	positionDAO := testutil.NewPositionDAO()
+	if err := positionDAO.CreateInitialUSDTPosition(1, &simulationID, 10_000); err != nil {
+		b.Fatalf("CreateInitialUSDTPosition: %v", err)
+	}
+	for i := 0; i < heldSymbols; i++ {
+		symbol := fmt.Sprintf("COIN%dUSDT", i)
+		marketData.SetCandles(symbol, valuationPriceInterval, flatCandles(valuationPriceInterval, 10, 10))
+		if err := positionDAO.UpdateOrCreatePosition(nil, 1, &simulationID, symbol, "USDT", 1, 10, 0); err != nil {
+			b.Fatalf("UpdateOrCreatePosition: %v", err)
+		}
+	}
+
+	client := testutil.NewClient()
+	orders := trading.NewOrderExecutionEngine(testutil.NewOrderDAO(), testutil.NewTradeDAO(), positionDAO, client, testutil.NewDB())
+	engine := NewSimulationEngine(client, slowMarketData{MarketData: marketData, latency: 2 * time.Millisecond},
+		services.NewPortfolioService(positionDAO), testutil.NewSimulationDAO(), positionDAO, orders)
+	defer engine.Cleanup()
+	engine.currentPriceTime = testStartTime + 5*models.GetIntervalDurationMs(valuationPriceInterval)
+
+	for _, concurrency := range []int{1, DefaultValuationConcurrency} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			engine.SetValuationConcurrency(concurrency)
+			for i := 0; i < b.N; i++ {
+				engine.mu.Lock()
+				cash, positionValue, err := engine.calculatePortfolioBreakdown(100, simulationID, "BTCUSDT")
+				engine.mu.Unlock()
+				if err != nil {
+					b.Fatalf("calculatePortfolioBreakdown: %v", err)
+				}
+				if cash != 10_000 || positionValue != heldSymbols*10 {
+					b.Fatalf("valued cash %v and positions %v, want 10000 and %v", cash, positionValue, heldSymbols*10)
+				}
+			}
+		})
+	}
+}
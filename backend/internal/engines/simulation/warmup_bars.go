@@ -0,0 +1,57 @@
+package simulation
+
+import (
+	"fmt"
+	"log"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/types"
+)
+
+// MaxWarmupBars is the most base candles before the start time a simulation can preload
+const MaxWarmupBars = maxDataBatchSize
+
+// WarmupBarsData carries the base candles preloaded from before the start time
+// They prime indicators and charts but are never played, filled against or counted towards warmup
+type WarmupBarsData struct {
+	SimulationID uint           `json:"simulationID"`
+	Symbol       string         `json:"symbol"`
+	Interval     string         `json:"interval"` // Base interval of the candles
+	Candles      []models.OHLCV `json:"candles"`  // Ascending, all ending before the start time
+}
+
+// loadWarmupBars fetches up to count base candles ending before startTime, fewer if the history is shorter
+func (se *SimulationEngine) loadWarmupBars(symbol string, startTime int64, count int) ([]models.OHLCV, error) {
+	if count == 0 {
+		return nil, nil
+	}
+
+	endTime := startTime - 1
+	data, err := se.marketData.GetHistoricalData(symbol, se.baseInterval, count, nil, &endTime, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch warmup bars: %w", err)
+	}
+
+	// Drop a candle still open at the start time, its close is after the replay begins
+	for len(data) > 0 && data[len(data)-1].EndTime >= startTime {
+		data = data[:len(data)-1]
+	}
+	if len(data) < count {
+		log.Printf("Only %d of %d warmup bars available before %d for %s %s", len(data), count, startTime, symbol, se.baseInterval)
+	}
+	return data, nil
+}
+
+// sendWarmupBarsUnsafe sends the preloaded pre-start candles to the client (caller must hold lock)
+func (se *SimulationEngine) sendWarmupBarsUnsafe(candles []models.OHLCV) {
+	if se.client == nil || len(candles) == 0 {
+		return
+	}
+
+	se.client.SendMessage(types.WarmupBars, WarmupBarsData{
+		SimulationID: se.currentSimulationID,
+		Symbol:       se.symbol,
+		Interval:     se.baseInterval,
+		Candles:      candles,
+	})
+}
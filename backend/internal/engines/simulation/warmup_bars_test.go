@@ -0,0 +1,69 @@
+package simulation
+
+import (
+	"testing"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/testutil"
+	"tradesimulator/internal/types"
+)
+
+// Warmup bars are loaded from before the start time and sent to the client, but the replay and trading start at
+// the start time
+func TestWarmupBarsLoadedButNotPlayed(t *testing.T) {
+	te := newTestEngine(t)
+	day := models.GetIntervalDurationMs("1d")
+	// Pre-start candles trade at 50, far below anything played
+	var candles []models.OHLCV
+	for i := int64(20); i > 0; i-- {
+		candles = append(candles, testutil.Candle(testStartTime-i*day, "1d", 50, 50, 50, 50))
+	}
+	te.marketData.SetCandles("BTCUSDT", "1d", append(candles, flatCandles("1d", 50, 100)...))
+	te.SetMaxSpeed(86400 * 2000)
+
+	options := SimulationOptions{WarmupBars: 10}
+	if err := te.Start("BTCUSDT", "1d", testStartTime, 86400*2000, 10000, options); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := te.Pause(); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	status := te.GetStatus()
+	if _, err := te.orders.PlaceLimitOrder(1, status.SimulationID, "BTCUSDT", models.OrderSideBuy, 1, 60, "", status.SimulationTime); err != nil {
+		t.Fatalf("PlaceLimitOrder: %v", err)
+	}
+	if err := te.Resume(); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	waitFor(t, "the simulation to complete", func() bool {
+		return te.GetStatus().State == string(StateStopped)
+	})
+
+	messages := te.client.Messages(types.WarmupBars)
+	if len(messages) != 1 {
+		t.Fatalf("sent %d warmup_bars messages, want 1", len(messages))
+	}
+	warmup := messages[0].Data.(WarmupBarsData).Candles
+	if len(warmup) != 10 {
+		t.Fatalf("sent %d warmup bars, want 10", len(warmup))
+	}
+	if first, last := warmup[0].StartTime, warmup[len(warmup)-1].EndTime; first != testStartTime-10*day || last != testStartTime-1 {
+		t.Errorf("warmup bars run from %d to %d, want the 10 days before the start time", first, last)
+	}
+
+	updates := te.client.Messages(types.SimulationUpdate)
+	if len(updates) == 0 {
+		t.Fatal("no candles played")
+	}
+	if first := updates[0].Data.(SimulationUpdateData).BaseCandle.StartTime; first != testStartTime {
+		t.Errorf("first played candle starts at %d, want the start time %d", first, testStartTime)
+	}
+	for _, update := range updates {
+		if candle := update.Data.(SimulationUpdateData).BaseCandle; candle.StartTime < testStartTime {
+			t.Fatalf("played the pre-start candle at %d", candle.StartTime)
+		}
+	}
+	if trades, _ := te.tradeDAO.GetUserTrades(1, status.SimulationID, 0); len(trades) != 0 {
+		t.Errorf("got %d trades, want the 60 limit never reached by played candles", len(trades))
+	}
+}
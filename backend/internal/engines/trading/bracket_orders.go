@@ -0,0 +1,145 @@
+package trading
+
+import (
+	"fmt"
+	"log"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/types"
+)
+
+// BracketOrderOptions holds the entry and exit prices of a bracket order
+type BracketOrderOptions struct {
+	EntryPrice      float64 // Limit price of the entry, 0 enters at market
+	TakeProfitPrice float64
+	StopLossPrice   float64
+	CurrentPrice    float64
+	QueueEntry      bool // Fill a market entry at the next candle open, for orders placed while paused
+}
+
+// PlaceBracketOrder places an entry order that, once fully filled, is closed by a take-profit or a stop-loss
+// The exits are placed for the filled quantity on the opposite side, linked to the entry through their parent
+// order ID, and whichever fills first cancels the other. The stop-loss is placed first, so when one candle
+// reaches both exits the stop-loss is assumed to have been hit first
+func (oe *OrderExecutionEngine) PlaceBracketOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity float64, options BracketOrderOptions, tag string, simulationTime int64) (*models.Order, *models.Trade, error) {
+	referencePrice := options.EntryPrice
+	if referencePrice <= 0 {
+		referencePrice = options.CurrentPrice
+	}
+	if err := validateBracketPrices(side, referencePrice, options.TakeProfitPrice, options.StopLossPrice); err != nil {
+		return nil, nil, err
+	}
+
+	params := models.OrderParameters{
+		TakeProfitPrice: &options.TakeProfitPrice,
+		StopLossPrice:   &options.StopLossPrice,
+	}
+	if options.EntryPrice > 0 {
+		params.LimitPrice = &options.EntryPrice
+		order, err := oe.placeRestingLimitOrder(userID, simulationID, symbol, side, quantity, params, tag, simulationTime)
+		return order, nil, err
+	}
+	if options.QueueEntry {
+		order, err := oe.queueMarketOrder(userID, simulationID, symbol, side, quantity, options.CurrentPrice, params, tag, simulationTime)
+		return order, nil, err
+	}
+	return oe.executeMarketOrder(userID, simulationID, symbol, side, quantity, options.CurrentPrice, params, tag, simulationTime)
+}
+
+// validateBracketPrices checks a bracket's exits lie on either side of its entry price: a buy takes profit above
+// the entry and stops out below it, a sell the other way round
+func validateBracketPrices(side models.OrderSide, entryPrice, takeProfitPrice, stopLossPrice float64) error {
+	if entryPrice <= 0 {
+		return fmt.Errorf("invalid entry price: %f", entryPrice)
+	}
+	if takeProfitPrice <= 0 || stopLossPrice <= 0 {
+		return fmt.Errorf("bracket orders require positive take-profit and stop-loss prices")
+	}
+	if side == models.OrderSideBuy && (takeProfitPrice <= entryPrice || stopLossPrice >= entryPrice) {
+		return fmt.Errorf("buy bracket needs take-profit above and stop-loss below the entry price %.8f", entryPrice)
+	}
+	if side == models.OrderSideSell && (takeProfitPrice >= entryPrice || stopLossPrice <= entryPrice) {
+		return fmt.Errorf("sell bracket needs take-profit below and stop-loss above the entry price %.8f", entryPrice)
+	}
+	return nil
+}
+
+// isBracketEntry reports whether an order is the entry of a bracket, carrying the prices of its exits
+func isBracketEntry(order *models.Order) bool {
+	if order.Type != models.OrderTypeMarket && order.Type != models.OrderTypeLimit {
+		return false
+	}
+	return order.OrderParams.TakeProfitPrice != nil && order.OrderParams.StopLossPrice != nil
+}
+
+// settleLinkedOrders follows up a fill: a fully filled bracket entry places its exits, and a filled exit
+// cancels the other one
+func (oe *OrderExecutionEngine) settleLinkedOrders(order *models.Order) {
+	if order.Status != models.OrderStatusExecuted {
+		return // Exits are only placed for the full entry quantity
+	}
+	if order.OrderParams.ParentOrderID != nil {
+		oe.cancelBracketSiblings(order)
+		return
+	}
+	if isBracketEntry(order) {
+		oe.placeBracketExits(order)
+	}
+}
+
+// placeBracketExits places the stop-loss and take-profit closing a filled bracket entry
+// A take-profit that can't be placed leaves the stop-loss on its own rather than the position unprotected
+func (oe *OrderExecutionEngine) placeBracketExits(entry *models.Order) {
+	exitSide := models.OrderSideSell
+	if entry.Side == models.OrderSideSell {
+		exitSide = models.OrderSideBuy
+	}
+	simulationTime := entry.PlacedAt
+	if entry.ExecutedAt != nil {
+		simulationTime = *entry.ExecutedAt
+	}
+
+	parentID := entry.ID
+	stopLoss := models.OrderParameters{StopLossPrice: entry.OrderParams.StopLossPrice, ParentOrderID: &parentID}
+	if _, err := oe.placeProtectiveOrder(models.OrderTypeStopLoss, entry.UserID, *entry.SimulationID, entry.Symbol, exitSide, entry.FilledQuantity, stopLoss, entry.Tag, simulationTime); err != nil {
+		log.Printf("Failed to place stop-loss of bracket order %d: %v", entry.ID, err)
+		return
+	}
+
+	takeProfit := models.OrderParameters{TakeProfitPrice: entry.OrderParams.TakeProfitPrice, ParentOrderID: &parentID}
+	if _, err := oe.placeProtectiveOrder(models.OrderTypeTakeProfit, entry.UserID, *entry.SimulationID, entry.Symbol, exitSide, entry.FilledQuantity, takeProfit, entry.Tag, simulationTime); err != nil {
+		log.Printf("Failed to place take-profit of bracket order %d: %v", entry.ID, err)
+	}
+}
+
+// cancelBracketSiblings cancels the pending exits sharing a filled exit's parent order
+func (oe *OrderExecutionEngine) cancelBracketSiblings(filled *models.Order) {
+	parentID := *filled.OrderParams.ParentOrderID
+
+	oe.mu.Lock()
+	var siblings []uint
+	for _, order := range oe.protectiveOrders {
+		if order.ID != filled.ID && order.OrderParams.ParentOrderID != nil && *order.OrderParams.ParentOrderID == parentID {
+			siblings = append(siblings, order.ID)
+		}
+	}
+	oe.mu.Unlock()
+
+	for _, orderID := range siblings {
+		if _, err := oe.CancelOrder(orderID); err != nil {
+			log.Printf("Failed to cancel order %d of bracket order %d: %v", orderID, parentID, err)
+		}
+	}
+}
+
+// cancelUntrackedOrder cancels an order the engine already stopped watching, such as a triggered protective order
+func (oe *OrderExecutionEngine) cancelUntrackedOrder(order *models.Order) {
+	order.Status = models.OrderStatusCancelled
+	if err := oe.orderDAO.Update(order); err != nil {
+		log.Printf("Failed to cancel %s order %d: %v", order.Type, order.ID, err)
+		return
+	}
+
+	log.Printf("Cancelled %s order %d", order.Type, order.ID)
+	oe.sendOrderUpdate(types.OrderCancelled, order, nil)
+}
@@ -0,0 +1,41 @@
+package trading
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/types"
+)
+
+// CancelOpenOrders cancels every pending or partially filled order of a simulation, notifying the client of each
+// Orders the engine isn't watching, e.g. left pending by an earlier run, are cancelled in the database only
+// Partially filled orders keep their fills. Returns the cancelled orders, stopping at the first order that can't be cancelled
+func (oe *OrderExecutionEngine) CancelOpenOrders(userID, simulationID uint) ([]*models.Order, error) {
+	openOrders, err := oe.orderDAO.GetPendingOrders(userID, simulationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open orders: %w", err)
+	}
+
+	cancelled := make([]*models.Order, 0, len(openOrders))
+	for i := range openOrders {
+		order, err := oe.CancelOrder(openOrders[i].ID)
+		if errors.Is(err, ErrOrderNotTracked) {
+			order = &openOrders[i]
+			order.Status = models.OrderStatusCancelled
+			if err = oe.orderDAO.Update(order); err == nil {
+				oe.sendOrderUpdate(types.OrderCancelled, order, nil)
+			}
+		}
+		if err != nil {
+			return cancelled, fmt.Errorf("failed to cancel order %d: %w", openOrders[i].ID, err)
+		}
+		cancelled = append(cancelled, order)
+	}
+
+	if len(cancelled) > 0 {
+		log.Printf("Cancelled %d open orders of simulation %d", len(cancelled), simulationID)
+	}
+	return cancelled, nil
+}
@@ -0,0 +1,164 @@
+package trading
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"tradesimulator/internal/models"
+)
+
+// LiquidityRole tells whether a fill added liquidity to the book or took it
+type LiquidityRole string
+
+const (
+	LiquidityMaker LiquidityRole = "maker" // Limit orders that rested in the book before filling
+	LiquidityTaker LiquidityRole = "taker" // Market orders, triggered protective orders and limits marketable on placement
+)
+
+// LiquidityRoleFor returns the role an order type fills with when it executes from the book
+// Market orders, including triggered stop-losses, take-profits and trailing stops, take liquidity;
+// limit orders make it
+func LiquidityRoleFor(orderType models.OrderType) LiquidityRole {
+	switch orderType {
+	case models.OrderTypeMarket, models.OrderTypeStopLoss, models.OrderTypeTakeProfit, models.OrderTypeTrailingStop:
+		return LiquidityTaker
+	}
+	return LiquidityMaker
+}
+
+// FeeTier holds the maker and taker fee rates for a symbol
+type FeeTier struct {
+	Maker float64 `json:"maker"` // Rate for limit orders resting in the book
+	Taker float64 `json:"taker"` // Rate for market orders
+}
+
+// DefaultFeeTier charges DefaultTradingFeeRate to makers and takers alike
+func DefaultFeeTier() FeeTier {
+	return FeeTier{Maker: DefaultTradingFeeRate, Taker: DefaultTradingFeeRate}
+}
+
+// NewFeeTier validates maker and taker rates expressed as fractions of the trade value
+func NewFeeTier(maker, taker float64) (FeeTier, error) {
+	if err := validateFeeRate(maker); err != nil {
+		return FeeTier{}, fmt.Errorf("invalid maker fee: %w", err)
+	}
+	if err := validateFeeRate(taker); err != nil {
+		return FeeTier{}, fmt.Errorf("invalid taker fee: %w", err)
+	}
+	return FeeTier{Maker: maker, Taker: taker}, nil
+}
+
+// Rate returns the tier's rate for a liquidity role
+func (ft FeeTier) Rate(role LiquidityRole) float64 {
+	if role == LiquidityTaker {
+		return ft.Taker
+	}
+	return ft.Maker
+}
+
+// FeeSchedule maps symbols to fee tiers, symbols not listed pay the engine's default tier
+type FeeSchedule map[string]FeeTier
+
+// ParseFeeSchedule parses a comma-separated list of SYMBOL=maker:taker entries, e.g. "BTCUSDT=0.0002:0.0004"
+func ParseFeeSchedule(value string) (FeeSchedule, error) {
+	schedule := make(FeeSchedule)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		symbol, rates, found := strings.Cut(entry, "=")
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		makerValue, takerValue, hasTaker := strings.Cut(rates, ":")
+		if !found || !hasTaker || symbol == "" {
+			return nil, fmt.Errorf("invalid fee schedule entry %q, expected SYMBOL=maker:taker", entry)
+		}
+
+		maker, err := parseFeeRate(makerValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maker fee for %s: %w", symbol, err)
+		}
+		taker, err := parseFeeRate(takerValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid taker fee for %s: %w", symbol, err)
+		}
+
+		schedule[symbol] = FeeTier{Maker: maker, Taker: taker}
+	}
+	return schedule, nil
+}
+
+// parseFeeRate parses a fee rate expressed as a fraction of the trade value
+func parseFeeRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, err
+	}
+	if err := validateFeeRate(rate); err != nil {
+		return 0, err
+	}
+	return rate, nil
+}
+
+// validateFeeRate checks a fee rate is a fraction of the trade value
+func validateFeeRate(rate float64) error {
+	if rate < 0 || rate >= 1 {
+		return fmt.Errorf("rate %v must be at least 0 and below 1", rate)
+	}
+	return nil
+}
+
+// Tier returns the fee tier of a symbol, or fallback when the symbol isn't listed
+func (fs FeeSchedule) Tier(symbol string, fallback FeeTier) FeeTier {
+	if tier, exists := fs[symbol]; exists {
+		return tier
+	}
+	return fallback
+}
+
+// FeeRounding controls how computed fees are rounded to the fee precision
+type FeeRounding string
+
+const (
+	FeeRoundingNone    FeeRounding = "none"    // Charge the exact product of quantity, price and rate
+	FeeRoundingUp      FeeRounding = "up"      // Round up to the fee precision, as exchanges usually charge
+	FeeRoundingNearest FeeRounding = "nearest" // Round half away from zero to the fee precision
+)
+
+// DefaultFeeDecimals is the precision fees are rounded to, matching the 8 decimals Binance charges commission in
+const DefaultFeeDecimals = 8
+
+// ParseFeeRounding validates a fee rounding setting
+func ParseFeeRounding(value string) (FeeRounding, error) {
+	switch rounding := FeeRounding(value); rounding {
+	case FeeRoundingNone, FeeRoundingUp, FeeRoundingNearest:
+		return rounding, nil
+	default:
+		return "", fmt.Errorf("unknown fee rounding %q (expected none, up or nearest)", value)
+	}
+}
+
+// Round rounds a fee to the given number of decimals
+// Rounding up ignores floating point noise, so a fee of exactly 0.0001 isn't charged as 0.00010001
+func (fr FeeRounding) Round(fee float64, decimals int) float64 {
+	if math.IsNaN(fee) || math.IsInf(fee, 0) {
+		return fee
+	}
+
+	factor := math.Pow(10, float64(decimals))
+	scaled := fee * factor
+	switch fr {
+	case FeeRoundingUp:
+		if nearest := math.Round(scaled); math.Abs(scaled-nearest) < 1e-6 {
+			scaled = nearest
+		}
+		return math.Ceil(scaled) / factor
+	case FeeRoundingNearest:
+		return math.Round(scaled) / factor
+	default:
+		return fee
+	}
+}
@@ -0,0 +1,209 @@
+package trading
+
+import (
+	"math"
+	"testing"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/testutil"
+)
+
+func TestFeeRoundingRound(t *testing.T) {
+	tests := []struct {
+		name     string
+		rounding FeeRounding
+		fee      float64
+		decimals int
+		want     float64
+	}{
+		{name: "none keeps the exact fee", rounding: FeeRoundingNone, fee: 0.123451, decimals: 4, want: 0.123451},
+		{name: "up rounds any remainder up", rounding: FeeRoundingUp, fee: 0.123401, decimals: 4, want: 0.1235},
+		{name: "up keeps an exact fee", rounding: FeeRoundingUp, fee: 0.0001, decimals: 4, want: 0.0001},
+		{name: "up ignores floating point noise", rounding: FeeRoundingUp, fee: 0.1 * 3 / 1000, decimals: 4, want: 0.0003},
+		{name: "nearest rounds down below half", rounding: FeeRoundingNearest, fee: 0.12344, decimals: 4, want: 0.1234},
+		{name: "nearest rounds half away from zero", rounding: FeeRoundingNearest, fee: 0.125, decimals: 2, want: 0.13},
+		{name: "eight decimals", rounding: FeeRoundingUp, fee: 0.000000001, decimals: DefaultFeeDecimals, want: 0.00000001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rounding.Round(tt.fee, tt.decimals); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("Round(%g, %d) = %g, want %g", tt.fee, tt.decimals, got, tt.want)
+			}
+		})
+	}
+
+	if got := FeeRoundingUp.Round(math.NaN(), 2); !math.IsNaN(got) {
+		t.Errorf("Round(NaN) = %g, want NaN", got)
+	}
+}
+
+func TestParseFeeRounding(t *testing.T) {
+	for _, value := range []string{"none", "up", "nearest"} {
+		if rounding, err := ParseFeeRounding(value); err != nil || string(rounding) != value {
+			t.Errorf("ParseFeeRounding(%q) = %q, %v", value, rounding, err)
+		}
+	}
+	if _, err := ParseFeeRounding("down"); err == nil {
+		t.Error("ParseFeeRounding(\"down\") succeeded")
+	}
+}
+
+// The fee a buy is validated against is the fee it is charged, so exactly enough cash is enough in every mode
+func TestFeeRoundingAppliedToFills(t *testing.T) {
+	feeRate := 0.001
+	tests := []struct {
+		rounding FeeRounding
+		wantFee  float64 // 0.3 at 101.5 is 30.45, whose exact fee is 0.03045
+	}{
+		{rounding: FeeRoundingNone, wantFee: 0.03045},
+		{rounding: FeeRoundingUp, wantFee: 0.04},
+		{rounding: FeeRoundingNearest, wantFee: 0.03},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.rounding), func(t *testing.T) {
+			cost := 0.3*101.5 + tt.wantFee
+
+			te := newTestOrderEngine(t, cost-0.005, ExecutionOptions{FeeRate: &feeRate})
+			te.SetFeeRounding(tt.rounding, 2)
+			if fee := te.CalculateFee("BTCUSDT", models.OrderTypeMarket, 0.3, 101.5); math.Abs(fee-tt.wantFee) > 1e-12 {
+				t.Errorf("CalculateFee() = %g, want %g", fee, tt.wantFee)
+			}
+			if err := te.ValidateOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 0.3, 101.5); err == nil {
+				t.Error("buy accepted with less cash than its cost and rounded fee")
+			}
+
+			te = newTestOrderEngine(t, cost, ExecutionOptions{FeeRate: &feeRate})
+			te.SetFeeRounding(tt.rounding, 2)
+			_, trade, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 0.3, 101.5, "", testStartTime)
+			if err != nil {
+				t.Fatalf("buy rejected with exactly its cost and rounded fee: %v", err)
+			}
+			if math.Abs(trade.Fee-tt.wantFee) > 1e-12 {
+				t.Errorf("charged fee = %g, want %g", trade.Fee, tt.wantFee)
+			}
+			if cash := te.position(t, "USDT").Quantity; math.Abs(cash) > 1e-9 {
+				t.Errorf("cash left = %g, want 0", cash)
+			}
+		})
+	}
+}
+
+// Buying everything affordable keeps back enough cash for a fee rounded up
+func TestFeeRoundingAffordableQuantity(t *testing.T) {
+	feeRate := 0.001
+	te := newTestOrderEngine(t, 1_000, ExecutionOptions{FeeRate: &feeRate, ClampToAffordable: true})
+	te.SetFeeRounding(FeeRoundingUp, 2)
+
+	quantity, err := te.affordableQuantity(1, testSimulationID, "BTCUSDT", 333.33)
+	if err != nil {
+		t.Fatalf("affordableQuantity: %v", err)
+	}
+	if quantity <= 0 {
+		t.Fatalf("affordable quantity = %g, want some", quantity)
+	}
+	if err := te.ValidateOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, quantity, 333.33); err != nil {
+		t.Errorf("affordable quantity %g rejected: %v", quantity, err)
+	}
+}
+
+// Market orders pay the taker rate, limit orders filled after resting in the book pay the maker rate
+func TestMakerAndTakerFees(t *testing.T) {
+	te := newTestOrderEngine(t, 10_000, ExecutionOptions{})
+	te.SetDefaultFeeTier(FeeTier{Maker: 0.001, Taker: 0.002})
+
+	_, taker, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 1, 100, "", testStartTime-minute)
+	if err != nil {
+		t.Fatalf("ExecuteMarketOrder: %v", err)
+	}
+	if want := 100 * 0.002; math.Abs(taker.Fee-want) > 1e-12 {
+		t.Errorf("market fee = %g, want the taker fee %g", taker.Fee, want)
+	}
+
+	order, err := te.PlaceLimitOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 2, 95, "", testStartTime-minute/2)
+	if err != nil {
+		t.Fatalf("PlaceLimitOrder: %v", err)
+	}
+	te.processCandles(t, "BTCUSDT", []models.OHLCV{testutil.Candle(testStartTime, "1m", 100, 101, 94, 94.5)})
+
+	var maker *models.Trade
+	for _, trade := range te.trades(t) {
+		if trade.OrderID == order.ID {
+			maker = &trade
+		}
+	}
+	if maker == nil {
+		t.Fatal("resting limit order did not fill")
+	}
+	if want := maker.Quantity * maker.Price * 0.001; math.Abs(maker.Fee-want) > 1e-12 {
+		t.Errorf("limit fee = %g, want the maker fee %g", maker.Fee, want)
+	}
+	if got := te.CalculateFee("BTCUSDT", models.OrderTypeStopLoss, 1, 100); math.Abs(got-0.2) > 1e-12 {
+		t.Errorf("stop-loss fee = %g, want the taker fee 0.2", got)
+	}
+}
+
+func TestParseFeeSchedule(t *testing.T) {
+	schedule, err := ParseFeeSchedule(" btcusdt=0.0002:0.0004, ETHUSDT=0:0.001 ,")
+	if err != nil {
+		t.Fatalf("ParseFeeSchedule: %v", err)
+	}
+	want := FeeSchedule{
+		"BTCUSDT": {Maker: 0.0002, Taker: 0.0004},
+		"ETHUSDT": {Maker: 0, Taker: 0.001},
+	}
+	if len(schedule) != len(want) {
+		t.Fatalf("parsed %d tiers, want %d", len(schedule), len(want))
+	}
+	for symbol, tier := range want {
+		if schedule[symbol] != tier {
+			t.Errorf("%s tier = %+v, want %+v", symbol, schedule[symbol], tier)
+		}
+	}
+
+	for _, value := range []string{"BTCUSDT=0.001", "=0.001:0.002", "BTCUSDT=x:0.001", "BTCUSDT=0.001:1", "BTCUSDT=-0.001:0.001"} {
+		if _, err := ParseFeeSchedule(value); err == nil {
+			t.Errorf("ParseFeeSchedule(%q) succeeded", value)
+		}
+	}
+}
+
+// Symbols with a tier in the fee schedule pay it, other symbols pay the default tier, and a simulation fee rate
+// overrides both
+func TestFeeScheduleTierOverridesDefault(t *testing.T) {
+	te := newTestOrderEngine(t, 10_000, ExecutionOptions{})
+	te.SetDefaultFeeTier(FeeTier{Maker: 0.001, Taker: 0.002})
+	te.SetFeeSchedule(FeeSchedule{"BTCUSDT": {Maker: 0.0001, Taker: 0.0003}})
+
+	tests := []struct {
+		symbol    string
+		orderType models.OrderType
+		want      float64
+	}{
+		{symbol: "BTCUSDT", orderType: models.OrderTypeMarket, want: 0.03},
+		{symbol: "BTCUSDT", orderType: models.OrderTypeLimit, want: 0.01},
+		{symbol: "ETHUSDT", orderType: models.OrderTypeMarket, want: 0.2},
+		{symbol: "ETHUSDT", orderType: models.OrderTypeLimit, want: 0.1},
+	}
+	for _, tt := range tests {
+		if got := te.CalculateFee(tt.symbol, tt.orderType, 1, 100); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%s %s fee = %g, want %g", tt.symbol, tt.orderType, got, tt.want)
+		}
+	}
+
+	_, trade, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 1, 100, "", testStartTime)
+	if err != nil {
+		t.Fatalf("ExecuteMarketOrder: %v", err)
+	}
+	if math.Abs(trade.Fee-0.03) > 1e-12 {
+		t.Errorf("charged fee = %g, want the scheduled taker fee 0.03", trade.Fee)
+	}
+
+	feeRate := 0.005
+	overridden := newTestOrderEngine(t, 10_000, ExecutionOptions{FeeRate: &feeRate})
+	overridden.SetFeeSchedule(FeeSchedule{"BTCUSDT": {Maker: 0.0001, Taker: 0.0003}})
+	if got := overridden.CalculateFee("BTCUSDT", models.OrderTypeMarket, 1, 100); math.Abs(got-0.5) > 1e-12 {
+		t.Errorf("fee with a simulation fee rate = %g, want 0.5", got)
+	}
+}
@@ -0,0 +1,24 @@
+package trading
+
+// setLatestPrice records the close of the latest candle played for symbol
+func (oe *OrderExecutionEngine) setLatestPrice(symbol string, price float64) {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+	if oe.latestPrices == nil {
+		oe.latestPrices = make(map[string]float64)
+	}
+	oe.latestPrices[symbol] = price
+}
+
+// LatestPrices returns the close of the latest candle played for each symbol, the prices a simulation values
+// its holdings at
+// Fills send it with the trade, as they are reported while the simulation engine holds its lock
+func (oe *OrderExecutionEngine) LatestPrices() map[string]float64 {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+	prices := make(map[string]float64, len(oe.latestPrices))
+	for symbol, price := range oe.latestPrices {
+		prices[symbol] = price
+	}
+	return prices
+}
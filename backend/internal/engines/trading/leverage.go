@@ -0,0 +1,202 @@
+package trading
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/types"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxLeverage is the highest leverage a futures mode simulation can use
+	MaxLeverage = 125
+	// DefaultMaintenanceMarginRate is the share of a leveraged position's notional value its equity must keep
+	DefaultMaintenanceMarginRate = 0.005
+
+	// LiquidationTag tags the market orders that force-close liquidated positions
+	LiquidationTag = "liquidation"
+)
+
+// liquidationLevel is the price at which a leveraged long of the current simulation is force-closed
+type liquidationLevel struct {
+	userID       uint
+	simulationID uint
+	price        float64
+}
+
+// validateLeverage checks the futures mode settings and fills in the default maintenance margin rate
+func (eo *ExecutionOptions) validateLeverage() error {
+	if eo.Leverage == 0 {
+		eo.Leverage = 1
+	}
+	if eo.Leverage < 1 || eo.Leverage > MaxLeverage {
+		return fmt.Errorf("invalid leverage: %g, must be between 1 and %d", eo.Leverage, MaxLeverage)
+	}
+	if eo.Leverage == 1 {
+		eo.MaintenanceMarginRate = 0
+		return nil
+	}
+	if eo.MaintenanceMarginRate == 0 {
+		eo.MaintenanceMarginRate = DefaultMaintenanceMarginRate
+	}
+	// At or above the initial margin rate a new position would be liquidated as soon as it opened
+	if eo.MaintenanceMarginRate < 0 || eo.MaintenanceMarginRate >= 1/eo.Leverage {
+		return fmt.Errorf("invalid maintenance margin rate: %g, must be between 0 and %g at %gx leverage",
+			eo.MaintenanceMarginRate, 1/eo.Leverage, eo.Leverage)
+	}
+	return nil
+}
+
+// Leveraged reports whether the options run a futures mode simulation
+func (eo ExecutionOptions) Leveraged() bool {
+	return eo.Leverage > 1
+}
+
+// marginRate returns the share of a buy's notional value paid in cash
+func (eo ExecutionOptions) marginRate() float64 {
+	if !eo.Leveraged() {
+		return 1
+	}
+	return 1 / eo.Leverage
+}
+
+// applyMarginWithTx updates a position's margin after a fill of quantityChange and recomputes its liquidation price
+// marginChange is the cash the fill locked into the position, negative when reducing it
+func (oe *OrderExecutionEngine) applyMarginWithTx(tx *gorm.DB, order *models.Order, marginChange float64, options ExecutionOptions) error {
+	position, err := oe.positionDAO.GetPositionWithTx(tx, order.UserID, *order.SimulationID, order.Symbol, order.BaseCurrency)
+	if err != nil {
+		return fmt.Errorf("failed to get position for margin: %w", err)
+	}
+
+	position.Leverage = options.Leverage
+	position.Margin = math.Max(position.Margin+marginChange, 0)
+	position.LiquidationPrice = nil
+	if position.Quantity <= fillQuantityTolerance {
+		position.Margin = 0
+	} else if price := models.LongLiquidationPrice(position.Quantity, position.Borrowed(), options.MaintenanceMarginRate); price > 0 {
+		position.LiquidationPrice = &price
+	}
+	if err := oe.positionDAO.UpdateWithTx(tx, position); err != nil {
+		return fmt.Errorf("failed to update position margin: %w", err)
+	}
+
+	oe.setLiquidationLevel(position)
+	return nil
+}
+
+// setLiquidationLevel tracks the liquidation price of a position, or stops tracking it once it has none
+func (oe *OrderExecutionEngine) setLiquidationLevel(position *models.Position) {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+
+	if position.LiquidationPrice == nil || position.SimulationID == nil {
+		delete(oe.liquidationLevels, position.Symbol)
+		return
+	}
+	if oe.liquidationLevels == nil {
+		oe.liquidationLevels = make(map[string]liquidationLevel)
+	}
+	oe.liquidationLevels[position.Symbol] = liquidationLevel{
+		userID:       position.UserID,
+		simulationID: *position.SimulationID,
+		price:        *position.LiquidationPrice,
+	}
+}
+
+// loadLiquidationLevels replaces the tracked liquidation prices with those of a simulation's leveraged positions
+func (oe *OrderExecutionEngine) loadLiquidationLevels(simulationID uint) error {
+	oe.mu.Lock()
+	oe.liquidationLevels = make(map[string]liquidationLevel)
+	oe.mu.Unlock()
+	if simulationID == 0 {
+		return nil
+	}
+
+	var positions []models.Position
+	err := oe.db.Where("simulation_id = ? AND liquidation_price IS NOT NULL AND quantity > 0", simulationID).Find(&positions).Error
+	if err != nil {
+		return fmt.Errorf("failed to load leveraged positions: %w", err)
+	}
+	for i := range positions {
+		oe.setLiquidationLevel(&positions[i])
+	}
+	return nil
+}
+
+// processLiquidations force-closes a leveraged position on symbol when the price reached its liquidation price
+// between open and low; a gap through the level closes the position at the open
+func (oe *OrderExecutionEngine) processLiquidations(symbol string, open, low float64, simulationTime int64) []*models.Trade {
+	oe.mu.Lock()
+	level, tracked := oe.liquidationLevels[symbol]
+	oe.mu.Unlock()
+	if !tracked || low <= 0 || low > level.price {
+		return nil
+	}
+
+	order, trade, err := oe.liquidatePosition(level, symbol, math.Min(open, level.price), simulationTime)
+	if err != nil {
+		log.Printf("Failed to liquidate %s position of simulation %d: %v", symbol, level.simulationID, err)
+		return nil
+	}
+	if trade == nil {
+		return nil
+	}
+
+	oe.tradeStats.record(trade)
+	oe.sendOrderUpdate(types.OrderExecuted, order, trade)
+	return []*models.Trade{trade}
+}
+
+// liquidatePosition sells a leveraged position in full with a market order tagged as a liquidation
+// The remaining equity is charged as the liquidation fee, so the margin is lost and nothing else; below the
+// price where the equity runs out the sale fills at that price, as the loss is capped at the margin
+func (oe *OrderExecutionEngine) liquidatePosition(level liquidationLevel, symbol string, price float64, simulationTime int64) (*models.Order, *models.Trade, error) {
+	var order *models.Order
+	var trade *models.Trade
+	err := oe.db.Transaction(func(tx *gorm.DB) error {
+		position, err := oe.positionDAO.GetPositionWithTx(tx, level.userID, level.simulationID, symbol, "USDT")
+		if err != nil {
+			return fmt.Errorf("failed to get position: %w", err)
+		}
+		if position.Quantity <= fillQuantityTolerance || position.LiquidationPrice == nil {
+			// Closed or reduced to nothing by an earlier fill
+			oe.setLiquidationLevel(position)
+			return nil
+		}
+
+		borrowed := position.Borrowed()
+		price = math.Max(price, borrowed/position.Quantity)
+		liquidationFee := math.Max(position.Quantity*price-borrowed, 0)
+
+		order = &models.Order{
+			UserID:       level.userID,
+			SimulationID: &level.simulationID,
+			Symbol:       symbol,
+			BaseCurrency: "USDT",
+			Side:         models.OrderSideSell,
+			Type:         models.OrderTypeMarket,
+			Quantity:     position.Quantity,
+			Status:       models.OrderStatusPending,
+			PlacedAt:     simulationTime,
+			Tag:          LiquidationTag,
+		}
+		order.OrderParams.ExpectedPrice = position.LiquidationPrice
+		if err := oe.orderDAO.CreateWithTx(tx, order); err != nil {
+			return fmt.Errorf("failed to create liquidation order: %w", err)
+		}
+
+		trade, err = oe.executeFillWithFee(tx, order, order.Quantity, price, liquidationFee, 0, simulationTime)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if trade != nil {
+		log.Printf("Liquidated %s position of simulation %d: %.8f at %.8f", symbol, level.simulationID, trade.Quantity, trade.Price)
+	}
+	return order, trade, nil
+}
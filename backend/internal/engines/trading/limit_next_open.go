@@ -0,0 +1,112 @@
+package trading
+
+import (
+	"log"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/types"
+)
+
+// LimitFillPrice selects when a limit order whose price is reached within a candle fills
+type LimitFillPrice string
+
+const (
+	LimitFillSameCandle LimitFillPrice = "same_candle" // Fill on the candle that reached the limit
+	LimitFillNextOpen   LimitFillPrice = "next_open"   // Fill at the next candle's open, if the open still reaches the limit
+)
+
+// deferLimitOrderToOpen holds a limit order that reached its price until the next candle opens
+func (oe *OrderExecutionEngine) deferLimitOrderToOpen(order *models.Order) {
+	oe.mu.Lock()
+	oe.nextOpenLimitOrders = append(oe.nextOpenLimitOrders, order)
+	oe.mu.Unlock()
+
+	log.Printf("Limit order %d reached its limit, waiting for the next candle open", order.ID)
+}
+
+// fillLimitOrdersAtOpen fills the limit orders deferred for a symbol at a candle's open
+// Orders the open no longer reaches, or that expired before it, go back to resting like any other limit order
+func (oe *OrderExecutionEngine) fillLimitOrdersAtOpen(symbol string, candle models.OHLCV) []*models.Trade {
+	oe.mu.Lock()
+	var ready, remaining []*models.Order
+	for _, order := range oe.nextOpenLimitOrders {
+		if order.Symbol == symbol {
+			ready = append(ready, order)
+		} else {
+			remaining = append(remaining, order)
+		}
+	}
+	oe.nextOpenLimitOrders = remaining
+	oe.mu.Unlock()
+
+	var trades []*models.Trade
+	for _, order := range ready {
+		if order.IsExpiredAt(candle.StartTime) {
+			oe.restLimitOrder(order) // Cancelled by the expiry check that follows
+			continue
+		}
+		if !limitReachedAt(order, candle.Open) {
+			oe.restLimitOrder(order)
+			continue
+		}
+
+		// Balances may have changed since the limit was reached, re-check at the fill price
+		if err := oe.ValidateOrder(order.UserID, *order.SimulationID, order.Symbol, order.Side, order.RemainingQuantity(), candle.Open); err != nil {
+			oe.failOrder(order, err)
+			continue
+		}
+
+		tx := oe.db.Begin()
+		if tx.Error != nil {
+			log.Printf("Failed to start transaction for limit order %d: %v", order.ID, tx.Error)
+			oe.restLimitOrder(order)
+			continue
+		}
+
+		trade, err := oe.executeFill(tx, order, order.RemainingQuantity(), candle.Open, LiquidityMaker, candle.StartTime)
+		if err != nil {
+			tx.Rollback()
+			oe.failOrder(order, err)
+			continue
+		}
+
+		if err := tx.Commit().Error; err != nil {
+			log.Printf("Failed to commit transaction for limit order %d: %v", order.ID, err)
+			continue
+		}
+		oe.tradeStats.record(trade)
+
+		log.Printf("Limit order %d filled at next open %.8f (limit was %.8f)", order.ID, candle.Open, *order.GetLimitPrice())
+		oe.sendOrderUpdate(types.OrderExecuted, order, trade)
+		oe.settleLinkedOrders(order)
+		trades = append(trades, trade)
+	}
+
+	return trades
+}
+
+// limitReachedAt reports whether price is at or better than a limit order's limit price
+func limitReachedAt(order *models.Order, price float64) bool {
+	limitPrice := order.GetLimitPrice()
+	if limitPrice == nil || price <= 0 {
+		return false
+	}
+	if order.Side == models.OrderSideBuy {
+		return price <= *limitPrice
+	}
+	return price >= *limitPrice
+}
+
+// removeNextOpenLimitOrder stops tracking a limit order waiting for the next open, returning nil if it isn't waiting
+func (oe *OrderExecutionEngine) removeNextOpenLimitOrder(orderID uint) *models.Order {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+
+	for i, order := range oe.nextOpenLimitOrders {
+		if order.ID == orderID {
+			oe.nextOpenLimitOrders = append(oe.nextOpenLimitOrders[:i], oe.nextOpenLimitOrders[i+1:]...)
+			return order
+		}
+	}
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"container/heap"
 	"fmt"
 	"log"
+	"math"
+	"sort"
 	"sync"
 
 	"tradesimulator/internal/models"
@@ -19,7 +21,10 @@ func (h BuyOrderHeap) Less(i, j int) bool {
 	if priceI == nil || priceJ == nil {
 		return false
 	}
-	return *priceI > *priceJ // Max heap - highest price first
+	if *priceI != *priceJ {
+		return *priceI > *priceJ // Max heap - highest price first
+	}
+	return placedBefore(h[i], h[j]) // Time priority among equal prices
 }
 func (h BuyOrderHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
 
@@ -45,7 +50,10 @@ func (h SellOrderHeap) Less(i, j int) bool {
 	if priceI == nil || priceJ == nil {
 		return false
 	}
-	return *priceI < *priceJ // Min heap - lowest price first
+	if *priceI != *priceJ {
+		return *priceI < *priceJ // Min heap - lowest price first
+	}
+	return placedBefore(h[i], h[j]) // Time priority among equal prices
 }
 func (h SellOrderHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
 
@@ -61,6 +69,15 @@ func (h *SellOrderHeap) Pop() interface{} {
 	return item
 }
 
+// placedBefore reports whether order a has time priority over order b
+// Orders are ranked by placement time, with the database ID breaking ties between orders placed at the same time
+func placedBefore(a, b *models.Order) bool {
+	if a.PlacedAt != b.PlacedAt {
+		return a.PlacedAt < b.PlacedAt
+	}
+	return a.ID < b.ID
+}
+
 // SymbolOrderBook holds buy and sell orders for a specific symbol
 type SymbolOrderBook struct {
 	Symbol     string
@@ -185,8 +202,21 @@ func (ob *OrderBook) RemoveOrder(orderID uint) (*models.Order, error) {
 
 // GetOrdersToExecute returns orders that should execute at the current price
 func (ob *OrderBook) GetOrdersToExecute(symbol string, currentPrice float64) []*models.Order {
-	if currentPrice <= 0 {
-		log.Printf("Invalid price for order execution: %.8f", currentPrice)
+	return ob.GetOrdersToExecutePlacedBy(symbol, currentPrice, math.MaxInt64)
+}
+
+// GetOrdersToExecutePlacedBy returns orders that should execute at the current price, considering
+// only orders placed at or before placedBy. Later orders stay in the book untouched
+func (ob *OrderBook) GetOrdersToExecutePlacedBy(symbol string, currentPrice float64, placedBy int64) []*models.Order {
+	return ob.GetOrdersToExecuteInRange(symbol, currentPrice, currentPrice, placedBy)
+}
+
+// GetOrdersToExecuteInRange returns orders whose limit price was reached while the price traded between
+// low and high: buys at or above low and sells at or below high. Only orders placed at or before
+// placedBy are considered
+func (ob *OrderBook) GetOrdersToExecuteInRange(symbol string, low, high float64, placedBy int64) []*models.Order {
+	if low <= 0 || high < low {
+		log.Printf("Invalid price range for order execution: %.8f-%.8f", low, high)
 		return nil
 	}
 	
@@ -199,61 +229,112 @@ func (ob *OrderBook) GetOrdersToExecute(symbol string, currentPrice float64) []*
 	}
 	
 	var ordersToExecute []*models.Order
+	var skippedBuys, skippedSells []*models.Order
 	
-	// Check buy orders (execute when current price <= limit price)
+	// Check buy orders (execute when the low <= limit price)
 	// Use heap to get best prices first (highest price buy orders)
+	// Every decision is made on the popped order itself; orders that don't fill are pushed back
 	for book.BuyOrders.Len() > 0 {
-		order := (*book.BuyOrders)[0] // Peek at top of heap
+		order := heap.Pop(book.BuyOrders).(*models.Order)
 		limitPrice := order.GetLimitPrice()
 		if limitPrice == nil {
-			// Remove invalid order
-			heap.Pop(book.BuyOrders)
+			// Drop invalid order
 			delete(book.OrderIndex, order.ID)
 			continue
 		}
-		
-		// Buy orders execute when current price <= limit price
-		if currentPrice <= *limitPrice {
-			ordersToExecute = append(ordersToExecute, order)
-			// Remove from buy orders heap and index
-			heap.Pop(book.BuyOrders)
-			delete(book.OrderIndex, order.ID)
-		} else {
+		if low > *limitPrice {
+			heap.Push(book.BuyOrders, order)
 			break // No more buy orders will execute (heap is sorted)
 		}
+		if order.PlacedAt > placedBy {
+			// Placed too recently to fill on this price, set aside and keep scanning
+			skippedBuys = append(skippedBuys, order)
+			continue
+		}
+
+		// Buy orders execute when the low <= limit price
+		delete(book.OrderIndex, order.ID)
+		ordersToExecute = append(ordersToExecute, order)
 	}
 	
-	// Check sell orders (execute when current price >= limit price)  
+	// Check sell orders (execute when the high >= limit price)
 	// Use heap to get best prices first (lowest price sell orders)
 	for book.SellOrders.Len() > 0 {
-		order := (*book.SellOrders)[0] // Peek at top of heap
+		order := heap.Pop(book.SellOrders).(*models.Order)
 		limitPrice := order.GetLimitPrice()
 		if limitPrice == nil {
-			// Remove invalid order
-			heap.Pop(book.SellOrders)
 			delete(book.OrderIndex, order.ID)
 			continue
 		}
-		
-		// Sell orders execute when current price >= limit price
-		if currentPrice >= *limitPrice {
-			ordersToExecute = append(ordersToExecute, order)
-			// Remove from sell orders heap and index
-			heap.Pop(book.SellOrders)
-			delete(book.OrderIndex, order.ID)
-		} else {
+		if high < *limitPrice {
+			heap.Push(book.SellOrders, order)
 			break // No more sell orders will execute (heap is sorted)
 		}
+		if order.PlacedAt > placedBy {
+			skippedSells = append(skippedSells, order)
+			continue
+		}
+
+		// Sell orders execute when the high >= limit price
+		delete(book.OrderIndex, order.ID)
+		ordersToExecute = append(ordersToExecute, order)
+	}
+	
+	// Return orders that were not yet eligible to the book
+	for _, order := range skippedBuys {
+		heap.Push(book.BuyOrders, order)
+	}
+	for _, order := range skippedSells {
+		heap.Push(book.SellOrders, order)
 	}
 	
 	if len(ordersToExecute) > 0 {
-		log.Printf("Found %d orders to execute for %s at price %.8f", 
-			len(ordersToExecute), symbol, currentPrice)
+		log.Printf("Found %d orders to execute for %s at prices %.8f-%.8f", 
+			len(ordersToExecute), symbol, low, high)
 	}
 	
 	return ordersToExecute
 }
 
+// RemoveExpiredOrders removes and returns every order whose expire time has been reached at simulationTime
+func (ob *OrderBook) RemoveExpiredOrders(simulationTime int64) []*models.Order {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	
+	var expired []*models.Order
+	for _, book := range ob.symbolBooks {
+		keptBuys := (*book.BuyOrders)[:0]
+		for _, order := range *book.BuyOrders {
+			if order.IsExpiredAt(simulationTime) {
+				delete(book.OrderIndex, order.ID)
+				expired = append(expired, order)
+				continue
+			}
+			keptBuys = append(keptBuys, order)
+		}
+		*book.BuyOrders = keptBuys
+		heap.Init(book.BuyOrders)
+
+		keptSells := (*book.SellOrders)[:0]
+		for _, order := range *book.SellOrders {
+			if order.IsExpiredAt(simulationTime) {
+				delete(book.OrderIndex, order.ID)
+				expired = append(expired, order)
+				continue
+			}
+			keptSells = append(keptSells, order)
+		}
+		*book.SellOrders = keptSells
+		heap.Init(book.SellOrders)
+	}
+	
+	// Report in placement order so cancellations are emitted deterministically
+	sort.SliceStable(expired, func(i, j int) bool {
+		return placedBefore(expired[i], expired[j])
+	})
+	return expired
+}
+
 // GetOrdersByUser returns all pending orders for a specific user and simulation
 func (ob *OrderBook) GetOrdersByUser(userID uint, simulationID *uint) []*models.Order {
 	ob.mu.RLock()
@@ -301,6 +382,13 @@ func (ob *OrderBook) GetOrderCountBySymbol(symbol string) int {
 	return len(book.OrderIndex)
 }
 
+// Clear removes every order from the order book
+func (ob *OrderBook) Clear() {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.symbolBooks = make(map[string]*SymbolOrderBook)
+}
+
 // LoadOrdersFromDatabase loads pending limit orders from database into the order book
 func (ob *OrderBook) LoadOrdersFromDatabase(orders []*models.Order) error {
 	if len(orders) == 0 {
@@ -322,9 +410,9 @@ func (ob *OrderBook) LoadOrdersFromDatabase(orders []*models.Order) error {
 			continue
 		}
 		
-		if order.Type != models.OrderTypeLimit || order.Status != models.OrderStatusPending {
+		if order.Type != models.OrderTypeLimit || !order.IsOpen() {
 			skippedCount++
-			continue // Skip non-limit or non-pending orders
+			continue // Skip non-limit or closed orders
 		}
 		
 		if err := ob.addOrderUnsafe(order); err != nil {
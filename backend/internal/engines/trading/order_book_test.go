@@ -0,0 +1,303 @@
+package trading
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"tradesimulator/internal/models"
+)
+
+func newBookOrder(id uint, side models.OrderSide, price float64, placedAt int64) *models.Order {
+	order := &models.Order{
+		ID:       id,
+		Symbol:   "BTCUSDT",
+		Side:     side,
+		Type:     models.OrderTypeLimit,
+		Quantity: 1,
+		Status:   models.OrderStatusPending,
+		PlacedAt: placedAt,
+	}
+	order.SetLimitPrice(price)
+	return order
+}
+
+func orderIDs(orders []*models.Order) []uint {
+	ids := []uint{}
+	for _, order := range orders {
+		ids = append(ids, order.ID)
+	}
+	return ids
+}
+
+func TestOrderBookGetOrdersToExecute(t *testing.T) {
+	tests := []struct {
+		name      string
+		orders    []*models.Order
+		price     float64
+		placedBy  int64
+		wantIDs   []uint
+		remaining int
+	}{
+		{
+			name: "buys fill best price first across levels",
+			orders: []*models.Order{
+				newBookOrder(1, models.OrderSideBuy, 98, 0),
+				newBookOrder(2, models.OrderSideBuy, 100, 0),
+				newBookOrder(3, models.OrderSideBuy, 99, 0),
+				newBookOrder(4, models.OrderSideBuy, 97, 0),
+			},
+			price:     98,
+			placedBy:  math.MaxInt64,
+			wantIDs:   []uint{2, 3, 1},
+			remaining: 1,
+		},
+		{
+			name: "sells fill lowest price first across levels",
+			orders: []*models.Order{
+				newBookOrder(1, models.OrderSideSell, 102, 0),
+				newBookOrder(2, models.OrderSideSell, 100, 0),
+				newBookOrder(3, models.OrderSideSell, 101, 0),
+				newBookOrder(4, models.OrderSideSell, 103, 0),
+			},
+			price:     102,
+			placedBy:  math.MaxInt64,
+			wantIDs:   []uint{2, 3, 1},
+			remaining: 1,
+		},
+		{
+			name: "limit price equal to the market fills",
+			orders: []*models.Order{
+				newBookOrder(1, models.OrderSideBuy, 100, 0),
+				newBookOrder(2, models.OrderSideSell, 100, 0),
+			},
+			price:     100,
+			placedBy:  math.MaxInt64,
+			wantIDs:   []uint{1, 2},
+			remaining: 0,
+		},
+		{
+			name: "mixed sides only fill the side the price reaches",
+			orders: []*models.Order{
+				newBookOrder(1, models.OrderSideBuy, 95, 0),
+				newBookOrder(2, models.OrderSideSell, 105, 0),
+				newBookOrder(3, models.OrderSideSell, 99, 0),
+				newBookOrder(4, models.OrderSideBuy, 90, 0),
+			},
+			price:     100,
+			placedBy:  math.MaxInt64,
+			wantIDs:   []uint{3},
+			remaining: 3,
+		},
+		{
+			name: "nothing fills between the best bid and ask",
+			orders: []*models.Order{
+				newBookOrder(1, models.OrderSideBuy, 99, 0),
+				newBookOrder(2, models.OrderSideSell, 101, 0),
+			},
+			price:     100,
+			placedBy:  math.MaxInt64,
+			wantIDs:   []uint{},
+			remaining: 2,
+		},
+		{
+			name: "orders placed after placedBy are set aside",
+			orders: []*models.Order{
+				newBookOrder(1, models.OrderSideBuy, 101, 2000),
+				newBookOrder(2, models.OrderSideBuy, 100, 1000),
+				newBookOrder(3, models.OrderSideSell, 98, 2000),
+				newBookOrder(4, models.OrderSideSell, 99, 1000),
+			},
+			price:     99.5,
+			placedBy:  1000,
+			wantIDs:   []uint{2, 4},
+			remaining: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := NewOrderBook()
+			for _, order := range tt.orders {
+				if err := book.AddOrder(order); err != nil {
+					t.Fatalf("AddOrder(%d): %v", order.ID, err)
+				}
+			}
+
+			got := book.GetOrdersToExecutePlacedBy("BTCUSDT", tt.price, tt.placedBy)
+			if ids := orderIDs(got); !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("executed orders = %v, want %v", ids, tt.wantIDs)
+			}
+			if count := book.GetOrderCount(); count != tt.remaining {
+				t.Errorf("orders left in book = %d, want %d", count, tt.remaining)
+			}
+		})
+	}
+}
+
+func TestOrderBookGetOrdersToExecuteInRange(t *testing.T) {
+	book := NewOrderBook()
+	for _, order := range []*models.Order{
+		newBookOrder(1, models.OrderSideBuy, 95, 0),
+		newBookOrder(2, models.OrderSideBuy, 90, 0),
+		newBookOrder(3, models.OrderSideSell, 105, 0),
+		newBookOrder(4, models.OrderSideSell, 110, 0),
+	} {
+		if err := book.AddOrder(order); err != nil {
+			t.Fatalf("AddOrder(%d): %v", order.ID, err)
+		}
+	}
+
+	// A candle trading between 94 and 106 reaches the 95 buy and the 105 sell, though it closes at 100
+	if ids := orderIDs(book.GetOrdersToExecuteInRange("BTCUSDT", 94, 106, math.MaxInt64)); !reflect.DeepEqual(ids, []uint{1, 3}) {
+		t.Errorf("executed orders = %v, want [1 3]", ids)
+	}
+	if count := book.GetOrderCount(); count != 2 {
+		t.Errorf("orders left in book = %d, want 2", count)
+	}
+
+	if got := book.GetOrdersToExecuteInRange("BTCUSDT", 100, 99, math.MaxInt64); got != nil {
+		t.Errorf("inverted range executed %v, want nothing", orderIDs(got))
+	}
+}
+
+func TestOrderBookSetAsideOrdersFillLater(t *testing.T) {
+	book := NewOrderBook()
+	for _, order := range []*models.Order{
+		newBookOrder(1, models.OrderSideBuy, 101, 2000),
+		newBookOrder(2, models.OrderSideBuy, 100, 1000),
+	} {
+		if err := book.AddOrder(order); err != nil {
+			t.Fatalf("AddOrder(%d): %v", order.ID, err)
+		}
+	}
+
+	if ids := orderIDs(book.GetOrdersToExecutePlacedBy("BTCUSDT", 99, 1000)); !reflect.DeepEqual(ids, []uint{2}) {
+		t.Fatalf("first pass executed %v, want [2]", ids)
+	}
+	// The order set aside must be back in the heap, still ranked by price
+	if ids := orderIDs(book.GetOrdersToExecutePlacedBy("BTCUSDT", 99, 2000)); !reflect.DeepEqual(ids, []uint{1}) {
+		t.Fatalf("second pass executed %v, want [1]", ids)
+	}
+	if count := book.GetOrderCount(); count != 0 {
+		t.Errorf("orders left in book = %d, want 0", count)
+	}
+}
+
+func TestOrderBookExecutedOrdersLeaveIndex(t *testing.T) {
+	book := NewOrderBook()
+	for _, order := range []*models.Order{
+		newBookOrder(1, models.OrderSideBuy, 100, 0),
+		newBookOrder(2, models.OrderSideSell, 110, 0),
+	} {
+		if err := book.AddOrder(order); err != nil {
+			t.Fatalf("AddOrder(%d): %v", order.ID, err)
+		}
+	}
+
+	book.GetOrdersToExecute("BTCUSDT", 100)
+
+	if _, err := book.RemoveOrder(1); err == nil {
+		t.Error("executed order 1 can still be removed from the book")
+	}
+	if _, err := book.RemoveOrder(2); err != nil {
+		t.Errorf("resting order 2 missing from the book: %v", err)
+	}
+}
+
+func TestOrderBookRejectsInvalidPrice(t *testing.T) {
+	book := NewOrderBook()
+	if err := book.AddOrder(newBookOrder(1, models.OrderSideBuy, 100, 0)); err != nil {
+		t.Fatalf("AddOrder: %v", err)
+	}
+
+	if got := book.GetOrdersToExecute("BTCUSDT", 0); got != nil {
+		t.Errorf("executed %v at a zero price, want none", orderIDs(got))
+	}
+	if count := book.GetOrderCount(); count != 1 {
+		t.Errorf("orders left in book = %d, want 1", count)
+	}
+}
+
+func TestOrderBookSamePriceFillsOldestFirst(t *testing.T) {
+	tests := []struct {
+		name    string
+		orders  []*models.Order
+		wantIDs []uint
+	}{
+		{
+			name: "buys by placement time",
+			orders: []*models.Order{
+				newBookOrder(1, models.OrderSideBuy, 100, 3000),
+				newBookOrder(2, models.OrderSideBuy, 100, 1000),
+				newBookOrder(3, models.OrderSideBuy, 100, 4000),
+				newBookOrder(4, models.OrderSideBuy, 100, 2000),
+			},
+			wantIDs: []uint{2, 4, 1, 3},
+		},
+		{
+			name: "sells by placement time",
+			orders: []*models.Order{
+				newBookOrder(1, models.OrderSideSell, 100, 3000),
+				newBookOrder(2, models.OrderSideSell, 100, 1000),
+				newBookOrder(3, models.OrderSideSell, 100, 2000),
+			},
+			wantIDs: []uint{2, 3, 1},
+		},
+		{
+			name: "ID breaks ties between orders placed at the same time",
+			orders: []*models.Order{
+				newBookOrder(7, models.OrderSideBuy, 100, 1000),
+				newBookOrder(5, models.OrderSideBuy, 100, 1000),
+				newBookOrder(6, models.OrderSideBuy, 100, 1000),
+				newBookOrder(4, models.OrderSideBuy, 100, 2000),
+			},
+			wantIDs: []uint{5, 6, 7, 4},
+		},
+		{
+			name: "price still ranks ahead of time",
+			orders: []*models.Order{
+				newBookOrder(1, models.OrderSideSell, 100, 1000),
+				newBookOrder(2, models.OrderSideSell, 99, 3000),
+				newBookOrder(3, models.OrderSideSell, 100, 2000),
+				newBookOrder(4, models.OrderSideSell, 99, 2000),
+			},
+			wantIDs: []uint{4, 2, 1, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := NewOrderBook()
+			for _, order := range tt.orders {
+				if err := book.AddOrder(order); err != nil {
+					t.Fatalf("AddOrder(%d): %v", order.ID, err)
+				}
+			}
+
+			got := book.GetOrdersToExecute("BTCUSDT", 100)
+			if ids := orderIDs(got); !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("fill order = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestOrderBookExpiredOrdersReportedOldestFirst(t *testing.T) {
+	book := NewOrderBook()
+	expireAt := int64(5000)
+	for _, order := range []*models.Order{
+		newBookOrder(3, models.OrderSideSell, 110, 1000),
+		newBookOrder(2, models.OrderSideBuy, 90, 1000),
+		newBookOrder(1, models.OrderSideBuy, 95, 2000),
+	} {
+		order.OrderParams.ExpireTime = &expireAt
+		if err := book.AddOrder(order); err != nil {
+			t.Fatalf("AddOrder(%d): %v", order.ID, err)
+		}
+	}
+
+	if ids := orderIDs(book.RemoveExpiredOrders(expireAt)); !reflect.DeepEqual(ids, []uint{2, 3, 1}) {
+		t.Errorf("expired orders = %v, want [2 3 1]", ids)
+	}
+}
@@ -1,8 +1,11 @@
 package trading
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"sync"
 
 	"tradesimulator/internal/dao/trading"
 	"tradesimulator/internal/models"
@@ -17,10 +20,147 @@ type ClientMessageSender interface {
 	SendError(message string, errorDetails string)
 }
 
+// ErrOrderNotTracked is returned when cancelling an order the engine isn't watching
+var ErrOrderNotTracked = errors.New("order is not tracked by the order engine")
+
+// ErrTooManyPendingOrders is returned when placing a limit order on a symbol already at the pending limit order cap
+var ErrTooManyPendingOrders = errors.New("too many pending limit orders")
+
 const (
-	DefaultTradingFeeRate = 0.001 // 0.1% flat rate
+	DefaultTradingFeeRate = 0.001 // 0.1% rate for makers and takers unless configured
+
+	DefaultMaxPendingLimitOrders = 1000 // Pending limit orders allowed per symbol unless configured
+
+	fillQuantityTolerance = 1e-8 // Remaining quantity below this counts as fully filled
 )
 
+// MarketFillPrice selects the reference price used to fill market orders
+type MarketFillPrice string
+
+const (
+	MarketFillLastClose MarketFillPrice = "last_close" // Fill immediately at the last completed candle's close
+	MarketFillNextOpen  MarketFillPrice = "next_open"  // Defer the fill to the open of the next candle
+)
+
+// ExecutionOptions holds per-simulation order execution settings
+type ExecutionOptions struct {
+	MarketFillPrice MarketFillPrice `json:"marketFillPrice,omitempty"`
+	// StrictLimitFills only lets limit orders fill on candles that start after the order was placed,
+	// so orders can't fill on price action that had already happened inside the current candle
+	StrictLimitFills bool `json:"strictLimitFills,omitempty"`
+	// ClampToAffordable shrinks market buys that exceed the available cash to the largest affordable
+	// quantity instead of rejecting them; the requested quantity is kept in the order params
+	ClampToAffordable bool `json:"clampToAffordable,omitempty"`
+	// FillAtLimitPrice fills triggered limit orders at their limit price, or at the candle open when
+	// the price gapped through the limit, instead of at the price that triggered them
+	FillAtLimitPrice bool `json:"fillAtLimitPrice,omitempty"`
+	// VolumeParticipation caps how much of a candle's volume limit orders can fill, as a fraction
+	// between 0 and 1; orders beyond the cap fill partially and rest for later candles (0 disables)
+	VolumeParticipation float64 `json:"volumeParticipation,omitempty"`
+	// VolumeScale multiplies candle volumes before fills consume them, simulating a thinner market;
+	// between 0 and 1, where 1 keeps the real volume
+	VolumeScale float64 `json:"volumeScale,omitempty"`
+	// FeeRate charges one rate to makers and takers on every symbol in place of the server's fee
+	// schedule, to simulate a different exchange; nil keeps the server rates
+	FeeRate *float64 `json:"feeRate,omitempty"`
+	// LimitFillPrice set to next_open fills limit orders reached within a candle at the next candle's
+	// open, and only if the open still reaches the limit, instead of on the candle that reached it
+	LimitFillPrice LimitFillPrice `json:"limitFillPrice,omitempty"`
+	// Leverage above 1 runs the simulation in futures mode: buys pay 1/Leverage of their notional value
+	// as margin and long positions are liquidated once their equity falls to MaintenanceMarginRate of
+	// their notional value. 0 or 1 trades spot with the full cost paid in cash
+	Leverage              float64 `json:"leverage,omitempty"`
+	MaintenanceMarginRate float64 `json:"maintenanceMarginRate,omitempty"`
+	// AllowShort lets sells exceed the held quantity, opening a short position valued as a liability;
+	// the cash not backing other shorts must cover the shorted value and fee. Spot only
+	AllowShort bool `json:"allowShort,omitempty"`
+	// SlippageMode moves market fills, including triggered protective orders, against the order from
+	// their reference price by SlippageBps basis points, fixed or scaled by the share of candle volume
+	SlippageMode SlippageMode `json:"slippageMode,omitempty"`
+	SlippageBps  float64      `json:"slippageBps,omitempty"`
+	// MinPartialFill is the smallest volume capped partial fill of a limit order; smaller shares of a
+	// candle's volume carry over until they reach it, so only the final remainder fills below it.
+	// A fraction of the order quantity by default (DefaultMinPartialFill when 0), or a base asset
+	// quantity with MinPartialFillMode set to quantity
+	MinPartialFill     float64            `json:"minPartialFill,omitempty"`
+	MinPartialFillMode MinPartialFillMode `json:"minPartialFillMode,omitempty"`
+}
+
+// DefaultExecutionOptions returns the execution settings used when a simulation doesn't override them
+func DefaultExecutionOptions() ExecutionOptions {
+	return ExecutionOptions{
+		MarketFillPrice:    MarketFillLastClose,
+		VolumeScale:        1,
+		LimitFillPrice:     LimitFillSameCandle,
+		MinPartialFill:     DefaultMinPartialFill,
+		MinPartialFillMode: MinPartialFillFraction,
+	}
+}
+
+// Validate checks the execution options and fills in defaults for empty values
+func (eo *ExecutionOptions) Validate() error {
+	switch eo.MarketFillPrice {
+	case "":
+		eo.MarketFillPrice = MarketFillLastClose
+	case MarketFillLastClose, MarketFillNextOpen:
+	default:
+		return fmt.Errorf("invalid market fill price: %s, must be '%s' or '%s'", eo.MarketFillPrice, MarketFillLastClose, MarketFillNextOpen)
+	}
+	switch eo.LimitFillPrice {
+	case "":
+		eo.LimitFillPrice = LimitFillSameCandle
+	case LimitFillSameCandle, LimitFillNextOpen:
+	default:
+		return fmt.Errorf("invalid limit fill price: %s, must be '%s' or '%s'", eo.LimitFillPrice, LimitFillSameCandle, LimitFillNextOpen)
+	}
+	if eo.VolumeParticipation < 0 || eo.VolumeParticipation > 1 {
+		return fmt.Errorf("invalid volume participation: %g, must be between 0 and 1", eo.VolumeParticipation)
+	}
+	if eo.FeeRate != nil {
+		if err := validateFeeRate(*eo.FeeRate); err != nil {
+			return fmt.Errorf("invalid fee rate: %w", err)
+		}
+	}
+	switch {
+	case eo.VolumeScale == 0:
+		eo.VolumeScale = 1
+	case eo.VolumeScale < 0 || eo.VolumeScale > 1:
+		return fmt.Errorf("invalid volume scale: %g, must be between 0 and 1", eo.VolumeScale)
+	}
+	if err := eo.validateSlippage(); err != nil {
+		return err
+	}
+	if err := eo.validateMinPartialFill(); err != nil {
+		return err
+	}
+	if err := eo.validateLeverage(); err != nil {
+		return err
+	}
+	if eo.AllowShort && eo.Leveraged() {
+		return fmt.Errorf("short selling is not supported with leverage above 1")
+	}
+	return nil
+}
+
+// scaledVolume applies the simulation's volume scale to a candle volume
+func scaledVolume(volume float64, options ExecutionOptions) float64 {
+	if options.VolumeScale <= 0 {
+		return volume
+	}
+	return volume * options.VolumeScale
+}
+
+// ClosePositionResult reports the outcome of closing a single position
+type ClosePositionResult struct {
+	Symbol   string           `json:"symbol"`
+	Side     models.OrderSide `json:"side"`     // Sell to close longs, buy to cover shorts
+	Quantity float64          `json:"quantity"` // Quantity traded to flatten the position
+	Success  bool             `json:"success"`
+	Order    *models.Order    `json:"order,omitempty"`
+	Trade    *models.Trade    `json:"trade,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
 // OrderExecutionEngine handles core order execution logic
 type OrderExecutionEngine struct {
 	orderDAO    trading.OrderDAOInterface
@@ -29,18 +169,60 @@ type OrderExecutionEngine struct {
 	client      ClientMessageSender
 	db          *gorm.DB
 	orderBook   *OrderBook
+
+	mu                   sync.Mutex
+	options              ExecutionOptions
+	deferredMarketOrders []*models.Order  // Market orders waiting for the next candle open
+	nextOpenLimitOrders  []*models.Order  // Limit orders that reached their limit, waiting for the next candle open
+	protectiveOrders     []*models.Order  // Pending stop-loss, take-profit and trailing stop orders
+	carriedFillVolume    map[uint]float64 // Candle volume limit orders were due but too small to fill, by order ID
+
+	tradeStats  *tradeStatsTracker // Running trade flow statistics for the current simulation
+	feeSchedule FeeSchedule        // Per symbol maker/taker rates
+	defaultFees FeeTier            // Rates for symbols missing from the fee schedule
+	feeRounding FeeRounding        // How computed fees are rounded to feeDecimals
+	feeDecimals int
+
+	maxPendingLimitOrders int // Pending limit orders allowed per symbol (0 is unlimited)
+
+	liquidationLevels map[string]liquidationLevel // Liquidation prices of leveraged positions by symbol
+
+	slippage         SlippageModel      // Price impact of market fills, nil when they fill at the reference price
+	referenceVolumes map[string]float64 // Scaled volume of the latest candle by symbol, for volume slippage
+
+	latestPrices map[string]float64 // Close of the latest candle by symbol, sent with fills to value the portfolio
 }
 
 // OrderExecutionEngineInterface defines the contract for order execution
 type OrderExecutionEngineInterface interface {
-	ExecuteMarketOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, currentPrice float64, simulationTime int64) (*models.Order, *models.Trade, error)
-	PlaceLimitOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, limitPrice float64, simulationTime int64) (*models.Order, error)
+	ExecuteMarketOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, currentPrice float64, tag string, simulationTime int64) (*models.Order, *models.Trade, error)
+	QueueMarketOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, currentPrice float64, tag string, simulationTime int64) (*models.Order, error)
+	PlaceLimitOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, limitPrice float64, tag string, simulationTime int64) (*models.Order, error)
+	PlaceLimitOrderWithOptions(userID, simulationID uint, symbol string, side models.OrderSide, quantity, limitPrice float64, options LimitOrderOptions, tag string, simulationTime int64) (*models.Order, error)
+	PlaceStopLossOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, stopPrice float64, tag string, simulationTime int64) (*models.Order, error)
+	PlaceTakeProfitOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, targetPrice float64, tag string, simulationTime int64) (*models.Order, error)
+	PlaceTrailingStopOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, trailingDelta, trailingPercent, currentPrice float64, tag string, simulationTime int64) (*models.Order, error)
+	PlaceBracketOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity float64, options BracketOrderOptions, tag string, simulationTime int64) (*models.Order, *models.Trade, error)
 	ProcessPriceUpdate(symbol string, currentPrice float64, simulationTime int64) ([]*models.Trade, error)
+	ProcessCandle(symbol string, candle models.OHLCV) ([]*models.Trade, error)
+	SetExecutionOptions(options ExecutionOptions)
+	GetExecutionOptions() ExecutionOptions
 	CancelOrder(orderID uint) (*models.Order, error)
+	CancelOpenOrders(userID, simulationID uint) ([]*models.Order, error)
 	LoadPendingOrders(simulationID uint) error
+	ResetOrderBook(simulationID uint) error
 	ValidateOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, currentPrice float64) error
 	ValidateLimitOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, limitPrice, currentPrice float64) error
-	CalculateFee(quantity, price float64) float64
+	CalculateFee(symbol string, orderType models.OrderType, quantity, price float64) float64
+	SetFeeSchedule(schedule FeeSchedule)
+	SetDefaultFeeTier(tier FeeTier)
+	SetFeeRounding(rounding FeeRounding, decimals int)
+	SetMaxPendingLimitOrders(limit int)
+	SetSlippageModel(model SlippageModel)
+	CloseAllPositions(userID, simulationID uint, prices map[string]float64, simulationTime int64) ([]ClosePositionResult, error)
+	GetTradeStats() TradeStats
+	GetUnrealizedPnL(prices map[string]float64) float64
+	ResetTradeStats(simulationID uint) error
 }
 
 // NewOrderExecutionEngine creates a new order execution engine
@@ -52,11 +234,104 @@ func NewOrderExecutionEngine(orderDAO trading.OrderDAOInterface, tradeDAO tradin
 		client:      client,
 		db:          db,
 		orderBook:   NewOrderBook(),
+		options:     DefaultExecutionOptions(),
+		tradeStats:  newTradeStatsTracker(),
+		defaultFees: DefaultFeeTier(),
+		feeRounding: FeeRoundingNone,
+		feeDecimals: DefaultFeeDecimals,
+
+		maxPendingLimitOrders: DefaultMaxPendingLimitOrders,
 	}
 }
 
+// SetExecutionOptions applies per-simulation execution settings
+func (oe *OrderExecutionEngine) SetExecutionOptions(options ExecutionOptions) {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+	oe.options = options
+	oe.slippage = options.slippageModel()
+}
+
+// SetFeeSchedule sets the per symbol fee tiers, symbols not listed pay the default rate
+func (oe *OrderExecutionEngine) SetFeeSchedule(schedule FeeSchedule) {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+	oe.feeSchedule = schedule
+}
+
+// SetDefaultFeeTier sets the maker/taker rates paid on symbols missing from the fee schedule
+func (oe *OrderExecutionEngine) SetDefaultFeeTier(tier FeeTier) {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+	oe.defaultFees = tier
+}
+
+// SetFeeRounding sets how fees are rounded to decimals, both when checking funds and when charging fills
+func (oe *OrderExecutionEngine) SetFeeRounding(rounding FeeRounding, decimals int) {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+	oe.feeRounding = rounding
+	oe.feeDecimals = decimals
+}
+
+// SetMaxPendingLimitOrders sets how many pending limit orders a symbol may hold, 0 removes the cap
+func (oe *OrderExecutionEngine) SetMaxPendingLimitOrders(limit int) {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+	oe.maxPendingLimitOrders = limit
+}
+
+// checkPendingLimitOrderCap rejects a new limit order when its symbol already holds the most pending limit orders allowed
+// Orders waiting for the next open count, as they return to the book unless they fill
+func (oe *OrderExecutionEngine) checkPendingLimitOrderCap(symbol string) error {
+	oe.mu.Lock()
+	limit := oe.maxPendingLimitOrders
+	pending := 0
+	for _, order := range oe.nextOpenLimitOrders {
+		if order.Symbol == symbol {
+			pending++
+		}
+	}
+	oe.mu.Unlock()
+
+	if limit <= 0 {
+		return nil
+	}
+	pending += oe.orderBook.GetOrderCountBySymbol(symbol)
+	if pending >= limit {
+		return fmt.Errorf("%w: %s already has %d of %d allowed, cancel some before placing more", ErrTooManyPendingOrders, symbol, pending, limit)
+	}
+	return nil
+}
+
+// GetExecutionOptions returns the current execution settings
+func (oe *OrderExecutionEngine) GetExecutionOptions() ExecutionOptions {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+	return oe.options
+}
+
 // ExecuteMarketOrder executes a market order immediately
-func (oe *OrderExecutionEngine) ExecuteMarketOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, currentPrice float64, simulationTime int64) (*models.Order, *models.Trade, error) {
+func (oe *OrderExecutionEngine) ExecuteMarketOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, currentPrice float64, tag string, simulationTime int64) (*models.Order, *models.Trade, error) {
+	return oe.executeMarketOrder(userID, simulationID, symbol, side, quantity, currentPrice, models.OrderParameters{}, tag, simulationTime)
+}
+
+// executeMarketOrder executes a market order carrying params immediately
+func (oe *OrderExecutionEngine) executeMarketOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, currentPrice float64, params models.OrderParameters, tag string, simulationTime int64) (*models.Order, *models.Trade, error) {
+	// Shrink oversized buys to the affordable quantity when configured
+	requestedQuantity := quantity
+	if side == models.OrderSideBuy && currentPrice > 0 && oe.GetExecutionOptions().ClampToAffordable {
+		affordable, err := oe.affordableQuantity(userID, simulationID, symbol, currentPrice)
+		if err != nil {
+			return nil, nil, fmt.Errorf("order validation failed: %w", err)
+		}
+		// With nothing affordable keep the request so validation reports insufficient funds
+		if affordable > 0 && quantity > affordable {
+			log.Printf("Clamping market buy of %.8f %s to affordable %.8f", quantity, symbol, affordable)
+			quantity = affordable
+		}
+	}
+
 	// Validate inputs
 	if err := oe.ValidateOrder(userID, simulationID, symbol, side, quantity, currentPrice); err != nil {
 		return nil, nil, fmt.Errorf("order validation failed: %w", err)
@@ -77,6 +352,20 @@ func (oe *OrderExecutionEngine) ExecuteMarketOrder(userID, simulationID uint, sy
 		Quantity:     quantity,
 		Status:       models.OrderStatusPending,
 		PlacedAt:     simulationTime,
+		Tag:          tag,
+		OrderParams:  params,
+	}
+	order.OrderParams.ExpectedPrice = &currentPrice
+	if quantity != requestedQuantity {
+		order.OrderParams.RequestedQuantity = &requestedQuantity
+	}
+
+	// Defer the fill to the next candle open when configured
+	if oe.GetExecutionOptions().MarketFillPrice == MarketFillNextOpen {
+		if err := oe.deferMarketOrder(order); err != nil {
+			return nil, nil, err
+		}
+		return order, nil, nil
 	}
 
 	// Start transaction
@@ -113,22 +402,191 @@ func (oe *OrderExecutionEngine) ExecuteMarketOrder(userID, simulationID uint, sy
 	if err := tx.Commit().Error; err != nil {
 		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	oe.tradeStats.record(trade)
 
 	log.Printf("Order %d executed successfully, trade %d created", order.ID, trade.ID)
 
 	// Send order executed notification to client
 	oe.sendOrderUpdate(types.OrderExecuted, order, trade)
+	oe.settleLinkedOrders(order)
 
 	return order, trade, nil
 }
 
+// QueueMarketOrder places a market order that fills at the open of the next processed candle
+// Used for orders placed while the simulation is paused, so they fill at the price trading resumes at
+func (oe *OrderExecutionEngine) QueueMarketOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, currentPrice float64, tag string, simulationTime int64) (*models.Order, error) {
+	return oe.queueMarketOrder(userID, simulationID, symbol, side, quantity, currentPrice, models.OrderParameters{}, tag, simulationTime)
+}
+
+// queueMarketOrder places a market order carrying params that fills at the open of the next processed candle
+func (oe *OrderExecutionEngine) queueMarketOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, currentPrice float64, params models.OrderParameters, tag string, simulationTime int64) (*models.Order, error) {
+	// Checked against the paused price now and re-checked at the fill price
+	if err := oe.ValidateOrder(userID, simulationID, symbol, side, quantity, currentPrice); err != nil {
+		return nil, fmt.Errorf("order validation failed: %w", err)
+	}
+
+	order := &models.Order{
+		UserID:       userID,
+		SimulationID: &simulationID,
+		Symbol:       symbol,
+		BaseCurrency: "USDT", // Default to USDT for now
+		Side:         side,
+		Type:         models.OrderTypeMarket,
+		Quantity:     quantity,
+		Status:       models.OrderStatusPending,
+		PlacedAt:     simulationTime,
+		Tag:          tag,
+		OrderParams:  params,
+	}
+	order.OrderParams.ExpectedPrice = &currentPrice
+	if err := oe.deferMarketOrder(order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// deferMarketOrder saves a pending market order to be filled at the next candle open
+func (oe *OrderExecutionEngine) deferMarketOrder(order *models.Order) error {
+	if err := oe.orderDAO.Create(order); err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+
+	oe.mu.Lock()
+	oe.deferredMarketOrders = append(oe.deferredMarketOrders, order)
+	oe.mu.Unlock()
+
+	log.Printf("Deferred market order %d: %s %s %.8f until next candle open",
+		order.ID, string(order.Side), order.Symbol, order.Quantity)
+
+	oe.sendOrderUpdate(types.OrderPlaced, order, nil)
+	return nil
+}
+
+// ProcessCandle processes a newly completed base candle
+// Deferred market and limit orders fill at the candle's open, stop-losses and take-profits trigger
+// within its high/low range, leveraged longs are liquidated, then limit orders are checked at its close
+func (oe *OrderExecutionEngine) ProcessCandle(symbol string, candle models.OHLCV) ([]*models.Trade, error) {
+	oe.setReferenceVolume(symbol, scaledVolume(candle.Volume, oe.GetExecutionOptions()))
+	oe.setLatestPrice(symbol, candle.Close)
+
+	// Runs before expiry, so limit orders waiting for the open still fill if they expire within the candle
+	trades := oe.fillLimitOrdersAtOpen(symbol, candle)
+
+	// Limit orders are checked once the candle completed, so anything expiring within it is gone by then
+	oe.expireLimitOrders(candle.EndTime)
+
+	trades = append(trades, oe.fillDeferredMarketOrders(symbol, candle.Open, candle.StartTime)...)
+
+	placedBy := int64(math.MaxInt64)
+	if oe.GetExecutionOptions().StrictLimitFills {
+		placedBy = candle.StartTime
+	}
+
+	trades = append(trades, oe.processProtectiveOrders(symbol, candle.Open, candle.High, candle.Low, candle.EndTime, placedBy)...)
+
+	// Stops above the liquidation price had their chance to close the position first
+	trades = append(trades, oe.processLiquidations(symbol, candle.Open, candle.Low, candle.EndTime)...)
+
+	limitTrades, err := oe.processLimitOrders(symbol, candle.Close, candle.EndTime, placedBy, &candle)
+	trades = append(trades, limitTrades...)
+	return trades, err
+}
+
+// fillDeferredMarketOrders executes queued market orders for a symbol at the given open price
+func (oe *OrderExecutionEngine) fillDeferredMarketOrders(symbol string, openPrice float64, simulationTime int64) []*models.Trade {
+	oe.mu.Lock()
+	var ready, remaining []*models.Order
+	for _, order := range oe.deferredMarketOrders {
+		if order.Symbol == symbol {
+			ready = append(ready, order)
+		} else {
+			remaining = append(remaining, order)
+		}
+	}
+	oe.deferredMarketOrders = remaining
+	oe.mu.Unlock()
+
+	var trades []*models.Trade
+	for _, order := range ready {
+		// Balances may have changed since placement, re-check at the fill price
+		if err := oe.ValidateOrder(order.UserID, *order.SimulationID, order.Symbol, order.Side, order.Quantity, openPrice); err != nil {
+			oe.failOrder(order, err)
+			continue
+		}
+
+		tx := oe.db.Begin()
+		if tx.Error != nil {
+			log.Printf("Failed to start transaction for deferred market order %d: %v", order.ID, tx.Error)
+			continue
+		}
+
+		trade, err := oe.executeOrder(tx, order, openPrice, simulationTime)
+		if err != nil {
+			tx.Rollback()
+			oe.failOrder(order, err)
+			continue
+		}
+
+		if err := tx.Commit().Error; err != nil {
+			log.Printf("Failed to commit transaction for deferred market order %d: %v", order.ID, err)
+			continue
+		}
+		oe.tradeStats.record(trade)
+
+		log.Printf("Deferred market order %d filled at next open %.8f", order.ID, openPrice)
+		oe.sendOrderUpdate(types.OrderExecuted, order, trade)
+		oe.settleLinkedOrders(order)
+		trades = append(trades, trade)
+	}
+
+	return trades
+}
+
+// removeDeferredMarketOrder stops tracking a deferred market order, returning nil if it isn't queued
+func (oe *OrderExecutionEngine) removeDeferredMarketOrder(orderID uint) *models.Order {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+
+	for i, order := range oe.deferredMarketOrders {
+		if order.ID == orderID {
+			oe.deferredMarketOrders = append(oe.deferredMarketOrders[:i], oe.deferredMarketOrders[i+1:]...)
+			return order
+		}
+	}
+	return nil
+}
+
+// failOrder marks an order as failed and notifies the client
+func (oe *OrderExecutionEngine) failOrder(order *models.Order, reason error) {
+	order.Status = models.OrderStatusFailed
+	if err := oe.orderDAO.Update(order); err != nil {
+		log.Printf("Failed to mark order %d as failed: %v", order.ID, err)
+	}
+
+	log.Printf("Order %d failed: %v", order.ID, reason)
+	oe.sendOrderUpdate(types.OrderFailed, order, nil)
+}
+
 // PlaceLimitOrder places a limit order that will be executed when price conditions are met
-func (oe *OrderExecutionEngine) PlaceLimitOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, limitPrice float64, simulationTime int64) (*models.Order, error) {
+func (oe *OrderExecutionEngine) PlaceLimitOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, limitPrice float64, tag string, simulationTime int64) (*models.Order, error) {
+	return oe.placeRestingLimitOrder(userID, simulationID, symbol, side, quantity, models.OrderParameters{LimitPrice: &limitPrice}, tag, simulationTime)
+}
+
+// placeRestingLimitOrder stores a limit order and adds it to the order book, params must hold the limit price
+func (oe *OrderExecutionEngine) placeRestingLimitOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity float64, params models.OrderParameters, tag string, simulationTime int64) (*models.Order, error) {
+	limitPrice := *params.LimitPrice
+
 	// Validate inputs
 	if err := oe.ValidateLimitOrder(userID, simulationID, symbol, side, quantity, limitPrice, 0); err != nil {
 		return nil, fmt.Errorf("limit order validation failed: %w", err)
 	}
 
+	// Resting orders are capped per symbol to bound the order book; orders returning to it aren't checked
+	if err := oe.checkPendingLimitOrderCap(symbol); err != nil {
+		return nil, err
+	}
+
 	// Create limit order record
 	order := &models.Order{
 		UserID:       userID,
@@ -140,9 +598,8 @@ func (oe *OrderExecutionEngine) PlaceLimitOrder(userID, simulationID uint, symbo
 		Quantity:     quantity,
 		Status:       models.OrderStatusPending,
 		PlacedAt:     simulationTime,
-		OrderParams: models.OrderParameters{
-			LimitPrice: &limitPrice,
-		},
+		Tag:          tag,
+		OrderParams:  params,
 	}
 
 	// Save order to database
@@ -165,8 +622,25 @@ func (oe *OrderExecutionEngine) PlaceLimitOrder(userID, simulationID uint, symbo
 	return order, nil
 }
 
-// ProcessPriceUpdate processes price updates and executes limit orders that meet conditions
+// ProcessPriceUpdate processes price updates and executes stop-loss, take-profit and limit orders that meet conditions
 func (oe *OrderExecutionEngine) ProcessPriceUpdate(symbol string, currentPrice float64, simulationTime int64) ([]*models.Trade, error) {
+	oe.expireLimitOrders(simulationTime)
+
+	var trades []*models.Trade
+	if currentPrice > 0 {
+		trades = oe.processProtectiveOrders(symbol, currentPrice, currentPrice, currentPrice, simulationTime, math.MaxInt64)
+		trades = append(trades, oe.processLiquidations(symbol, currentPrice, currentPrice, simulationTime)...)
+	}
+
+	limitTrades, err := oe.processLimitOrders(symbol, currentPrice, simulationTime, math.MaxInt64, nil)
+	trades = append(trades, limitTrades...)
+	return trades, err
+}
+
+// processLimitOrders executes limit orders placed at or before placedBy that meet conditions at currentPrice
+// candle is the completed candle currentPrice closed, or nil for a bare price update; with a candle, orders
+// whose limit its low or high reached fill too, at their limit when the close is beyond it
+func (oe *OrderExecutionEngine) processLimitOrders(symbol string, currentPrice float64, simulationTime int64, placedBy int64, candle *models.OHLCV) ([]*models.Trade, error) {
 	if symbol == "" {
 		return nil, fmt.Errorf("symbol cannot be empty")
 	}
@@ -180,18 +654,61 @@ func (oe *OrderExecutionEngine) ProcessPriceUpdate(symbol string, currentPrice f
 		return nil, nil
 	}
 	
-	// Get orders that should execute at current price from order book
-	ordersToExecute := oe.orderBook.GetOrdersToExecute(symbol, currentPrice)
+	// Get orders whose limit the price reached from order book, anywhere within the candle when there is one
+	low, high := currentPrice, currentPrice
+	if candle != nil && candle.Low > 0 && candle.Low <= currentPrice && candle.High >= currentPrice {
+		low, high = candle.Low, candle.High
+	}
+	ordersToExecute := oe.orderBook.GetOrdersToExecuteInRange(symbol, low, high, placedBy)
 	
 	if len(ordersToExecute) == 0 {
 		return nil, nil // No orders to execute
 	}
 	
+	options := oe.GetExecutionOptions()
+	if options.LimitFillPrice == LimitFillNextOpen {
+		for _, order := range ordersToExecute {
+			oe.deferLimitOrderToOpen(order)
+		}
+		return nil, nil
+	}
+
 	log.Printf("Processing %d limit orders for %s at price %.8f", len(ordersToExecute), symbol, currentPrice)
 
 	var executedTrades []*models.Trade
 
+	// With volume participation the orders share a slice of the candle's volume, best priced first
+	// A scaled-down volume caps fills at the whole scaled volume when no participation is set
+	availableVolume := -1.0
+	if candle != nil {
+		participation := options.VolumeParticipation
+		if participation == 0 && options.VolumeScale > 0 && options.VolumeScale < 1 {
+			participation = 1
+		}
+		if participation > 0 {
+			availableVolume = scaledVolume(candle.Volume, options) * participation
+		}
+	}
+
 	for _, order := range ordersToExecute {
+		// An order the close no longer reaches traded at its limit within the candle, not at the close
+		fillPrice := currentPrice
+		if options.FillAtLimitPrice || !limitReachedAt(order, currentPrice) {
+			fillPrice = limitFillPrice(order, currentPrice, candle)
+		}
+
+		fillQuantity := order.RemainingQuantity()
+		candleVolume := 0.0
+		if availableVolume >= 0 {
+			fillQuantity, candleVolume = oe.partialFillQuantity(order, math.Floor(availableVolume*1e8)/1e8, options)
+			if fillQuantity <= 0 {
+				// No volume left on this candle, or too little for the minimum partial fill, wait for later ones
+				availableVolume -= candleVolume
+				oe.restLimitOrder(order)
+				continue
+			}
+		}
+
 		// Start transaction for this order execution
 		tx := oe.db.Begin()
 		if tx.Error != nil {
@@ -199,8 +716,8 @@ func (oe *OrderExecutionEngine) ProcessPriceUpdate(symbol string, currentPrice f
 			continue
 		}
 
-		// Execute the limit order at current market price
-		trade, err := oe.executeOrder(tx, order, currentPrice, simulationTime)
+		// Execute the limit order at current market price, or its limit when configured
+		trade, err := oe.executeFill(tx, order, fillQuantity, fillPrice, LiquidityMaker, simulationTime)
 		if err != nil {
 			tx.Rollback()
 			log.Printf("Failed to execute limit order %d: %v", order.ID, err)
@@ -212,15 +729,26 @@ func (oe *OrderExecutionEngine) ProcessPriceUpdate(symbol string, currentPrice f
 			log.Printf("Failed to commit transaction for limit order %d: %v", order.ID, err)
 			continue
 		}
+		oe.tradeStats.record(trade)
 
 		limitPrice := order.GetLimitPrice()
 		if limitPrice != nil {
 			log.Printf("Limit order %d executed at price %.8f (limit was %.8f)", 
-				order.ID, currentPrice, *limitPrice)
+				order.ID, fillPrice, *limitPrice)
+		}
+
+		if availableVolume >= 0 {
+			availableVolume -= candleVolume
+		}
+		// The unfilled remainder rests for later candles
+		if order.Status == models.OrderStatusPartiallyFilled {
+			log.Printf("Limit order %d partially filled: %.8f of %.8f", order.ID, order.FilledQuantity, order.Quantity)
+			oe.restLimitOrder(order)
 		}
 
 		// Send order executed notification to client
 		oe.sendOrderUpdate(types.OrderExecuted, order, trade)
+		oe.settleLinkedOrders(order)
 		
 		executedTrades = append(executedTrades, trade)
 	}
@@ -228,17 +756,143 @@ func (oe *OrderExecutionEngine) ProcessPriceUpdate(symbol string, currentPrice f
 	return executedTrades, nil
 }
 
-// CancelOrder cancels a pending limit order
+// restLimitOrder returns a limit order taken from the order book for execution back to the book
+func (oe *OrderExecutionEngine) restLimitOrder(order *models.Order) {
+	if err := oe.orderBook.AddOrder(order); err != nil {
+		log.Printf("Failed to return limit order %d to order book: %v", order.ID, err)
+	}
+}
+
+// limitFillPrice returns the limit price a triggered order fills at, or the candle open when the
+// price gapped through the limit before the candle started
+// The open is only used for orders already resting when the candle opened
+func limitFillPrice(order *models.Order, currentPrice float64, candle *models.OHLCV) float64 {
+	limitPrice := order.GetLimitPrice()
+	if limitPrice == nil {
+		return currentPrice
+	}
+
+	fillPrice := *limitPrice
+	if candle != nil && order.PlacedAt <= candle.StartTime && candle.Open > 0 {
+		if order.Side == models.OrderSideBuy && candle.Open < fillPrice {
+			fillPrice = candle.Open
+		} else if order.Side == models.OrderSideSell && candle.Open > fillPrice {
+			fillPrice = candle.Open
+		}
+	}
+	return fillPrice
+}
+
+// CloseAllPositions flattens every non-USDT position in the simulation with market orders
+// Each position is closed independently at its symbol's price from prices; failures are reported per position
+func (oe *OrderExecutionEngine) CloseAllPositions(userID, simulationID uint, prices map[string]float64, simulationTime int64) ([]ClosePositionResult, error) {
+	positions, err := oe.positionDAO.GetUserPositions(userID, simulationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	var results []ClosePositionResult
+	for _, position := range positions {
+		if position.Symbol == "USDT" || position.Quantity == 0 {
+			continue
+		}
+
+		result := ClosePositionResult{
+			Symbol:   position.Symbol,
+			Side:     models.OrderSideSell,
+			Quantity: position.Quantity,
+		}
+		if position.Quantity < 0 {
+			// Short position, buy back to cover
+			result.Side = models.OrderSideBuy
+			result.Quantity = -position.Quantity
+		}
+
+		price, ok := prices[position.Symbol]
+		if !ok || price <= 0 {
+			result.Error = fmt.Sprintf("no current price available for %s", position.Symbol)
+			results = append(results, result)
+			continue
+		}
+
+		order, trade, err := oe.ExecuteMarketOrder(userID, simulationID, position.Symbol, result.Side, result.Quantity, price, "", simulationTime)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Success = true
+			result.Order = order
+			result.Trade = trade
+		}
+		results = append(results, result)
+	}
+
+	log.Printf("Closed positions for simulation %d: %d attempted", simulationID, len(results))
+	return results, nil
+}
+
+// CancelOrder cancels an open limit, protective or deferred market order
+// Returns ErrOrderNotTracked when the engine isn't watching the order
 func (oe *OrderExecutionEngine) CancelOrder(orderID uint) (*models.Order, error) {
+	// Deferred market orders wait outside the order book for the next candle open
+	if order := oe.removeDeferredMarketOrder(orderID); order != nil {
+		order.Status = models.OrderStatusCancelled
+		if err := oe.orderDAO.Update(order); err != nil {
+			order.Status = models.OrderStatusPending
+			oe.mu.Lock()
+			oe.deferredMarketOrders = append(oe.deferredMarketOrders, order)
+			oe.mu.Unlock()
+			return nil, fmt.Errorf("failed to update order status in database: %w", err)
+		}
+
+		log.Printf("Cancelled deferred market order %d", orderID)
+		oe.sendOrderUpdate(types.OrderCancelled, order, nil)
+		return order, nil
+	}
+
+	// Limit orders waiting for the next open are out of the order book until it fills them
+	if order := oe.removeNextOpenLimitOrder(orderID); order != nil {
+		previousStatus := order.Status
+		order.Status = models.OrderStatusCancelled
+		if err := oe.orderDAO.Update(order); err != nil {
+			order.Status = previousStatus
+			oe.mu.Lock()
+			oe.nextOpenLimitOrders = append(oe.nextOpenLimitOrders, order)
+			oe.mu.Unlock()
+			return nil, fmt.Errorf("failed to update order status in database: %w", err)
+		}
+
+		log.Printf("Cancelled limit order %d", orderID)
+		oe.sendOrderUpdate(types.OrderCancelled, order, nil)
+		return order, nil
+	}
+
+	// Stop-loss and take-profit orders are tracked outside the order book
+	if order := oe.removeProtectiveOrder(orderID); order != nil {
+		order.Status = models.OrderStatusCancelled
+		if err := oe.orderDAO.Update(order); err != nil {
+			order.Status = models.OrderStatusPending
+			oe.mu.Lock()
+			oe.protectiveOrders = append(oe.protectiveOrders, order)
+			oe.mu.Unlock()
+			return nil, fmt.Errorf("failed to update order status in database: %w", err)
+		}
+
+		log.Printf("Cancelled %s order %d", order.Type, orderID)
+		oe.sendOrderUpdate(types.OrderCancelled, order, nil)
+		return order, nil
+	}
+
 	// Remove from order book first
 	order, err := oe.orderBook.RemoveOrder(orderID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to remove order from order book: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrOrderNotTracked, err)
 	}
 
-	// Update order status in database
+	// Update order status in database, partially filled orders keep their fills
+	previousStatus := order.Status
 	order.Status = models.OrderStatusCancelled
 	if err := oe.orderDAO.Update(order); err != nil {
+		order.Status = previousStatus
 		// Try to re-add to order book if database update fails
 		if addErr := oe.orderBook.AddOrder(order); addErr != nil {
 			log.Printf("Failed to re-add order %d to order book after database error: %v", orderID, addErr)
@@ -263,18 +917,51 @@ func (oe *OrderExecutionEngine) LoadPendingOrders(simulationID uint) error {
 	if oe.db == nil {
 		return fmt.Errorf("database connection not available")
 	}
-	
-	// Get all pending limit orders for the simulation
-	var pendingOrders []models.Order
-	query := oe.db.Where("type = ? AND status = ?", models.OrderTypeLimit, models.OrderStatusPending)
-	if simulationID > 0 {
-		query = query.Where("simulation_id = ?", simulationID)
+
+	if err := oe.loadLiquidationLevels(simulationID); err != nil {
+		return err
 	}
 	
-	if err := query.Find(&pendingOrders).Error; err != nil {
+	openOrders, err := oe.orderDAO.GetSimulationOpenOrders(simulationID)
+	if err != nil {
 		return fmt.Errorf("failed to load pending orders from database: %w", err)
 	}
 
+	var pendingMarketOrders, pendingProtectiveOrders, pendingOrders []models.Order
+	for _, order := range openOrders {
+		switch {
+		case order.Type == models.OrderTypeLimit:
+			pendingOrders = append(pendingOrders, order)
+		case order.Status != models.OrderStatusPending:
+			// Only limit orders fill in parts and stay open
+		case order.Type == models.OrderTypeMarket:
+			pendingMarketOrders = append(pendingMarketOrders, order)
+		case order.Type == models.OrderTypeStopLoss || order.Type == models.OrderTypeTakeProfit || order.Type == models.OrderTypeTrailingStop:
+			pendingProtectiveOrders = append(pendingProtectiveOrders, order)
+		}
+	}
+
+	// Market orders left pending (deferred to the next open) fill when the simulation continues
+	if len(pendingMarketOrders) > 0 {
+		oe.mu.Lock()
+		for i := range pendingMarketOrders {
+			oe.deferredMarketOrders = append(oe.deferredMarketOrders, &pendingMarketOrders[i])
+		}
+		oe.mu.Unlock()
+		log.Printf("Queued %d pending market orders for simulation %d", len(pendingMarketOrders), simulationID)
+	}
+
+	// Stop-loss and take-profit orders resume watching their triggers
+	if len(pendingProtectiveOrders) > 0 {
+		oe.mu.Lock()
+		for i := range pendingProtectiveOrders {
+			oe.protectiveOrders = append(oe.protectiveOrders, &pendingProtectiveOrders[i])
+		}
+		oe.mu.Unlock()
+		log.Printf("Loaded %d pending stop-loss, take-profit and trailing stop orders for simulation %d", len(pendingProtectiveOrders), simulationID)
+	}
+
+	// Pending limit orders go back into the book
 	if len(pendingOrders) == 0 {
 		log.Printf("No pending limit orders found for simulation %d", simulationID)
 		return nil
@@ -295,19 +982,73 @@ func (oe *OrderExecutionEngine) LoadPendingOrders(simulationID uint) error {
 	return nil
 }
 
-// executeOrder executes an order at the given price within a transaction
+// ResetOrderBook discards the in-memory order book, deferred market and protective orders and reloads
+// the simulation's pending orders from the database, without modifying any stored orders
+func (oe *OrderExecutionEngine) ResetOrderBook(simulationID uint) error {
+	if oe.orderBook == nil {
+		return fmt.Errorf("order book not initialized")
+	}
+
+	oe.orderBook.Clear()
+	oe.mu.Lock()
+	oe.deferredMarketOrders = nil
+	oe.nextOpenLimitOrders = nil
+	oe.protectiveOrders = nil
+	oe.carriedFillVolume = nil
+	oe.mu.Unlock()
+
+	if err := oe.LoadPendingOrders(simulationID); err != nil {
+		return fmt.Errorf("failed to reload order book: %w", err)
+	}
+
+	log.Printf("Order book reset for simulation %d, %d limit orders loaded", simulationID, oe.orderBook.GetOrderCount())
+	return nil
+}
+
+// executeOrder executes the unfilled quantity of an order at the given price within a transaction
+// Orders executed in one go fill on placement or on a trigger, taking liquidity
 func (oe *OrderExecutionEngine) executeOrder(tx *gorm.DB, order *models.Order, price float64, simulationTime int64) (*models.Trade, error) {
-	// Calculate fee
-	fee := oe.CalculateFee(order.Quantity, price)
-	totalCost := order.Quantity * price
+	quantity := order.RemainingQuantity()
+	fillPrice, slippage := oe.slippedPrice(order.Symbol, order.Type, order.Side, quantity, price)
+	fee := oe.CalculateFeeForRole(order.Symbol, LiquidityTaker, quantity, fillPrice)
+	return oe.executeFillWithFee(tx, order, quantity, fillPrice, fee, slippage, simulationTime)
+}
+
+// executeFill fills quantity of an order at the given price within a transaction, creating one trade
+// The order is executed once fully filled and partially filled until then
+func (oe *OrderExecutionEngine) executeFill(tx *gorm.DB, order *models.Order, quantity, price float64, role LiquidityRole, simulationTime int64) (*models.Trade, error) {
+	fee := oe.CalculateFeeForRole(order.Symbol, role, quantity, price)
+	return oe.executeFillWithFee(tx, order, quantity, price, fee, 0, simulationTime)
+}
+
+// executeFillWithFee fills quantity of an order at the given price charging fee, within a transaction
+// slippage is the per unit price move already applied to price, recorded on the trade
+// In futures mode buys pay only their margin in cash, and sells repay the borrowed share of what they close
+func (oe *OrderExecutionEngine) executeFillWithFee(tx *gorm.DB, order *models.Order, quantity, price, fee, slippage float64, simulationTime int64) (*models.Trade, error) {
+	totalCost := quantity * price
+	options := oe.GetExecutionOptions()
+
+	var marginChange, repaid float64
+	if options.Leveraged() {
+		if order.Side == models.OrderSideBuy {
+			marginChange = totalCost * options.marginRate()
+		} else if position, err := oe.positionDAO.GetPositionWithTx(tx, order.UserID, *order.SimulationID, order.Symbol, order.BaseCurrency); err == nil && position.Quantity > 0 {
+			closedShare := math.Min(quantity/position.Quantity, 1)
+			marginChange = -position.Margin * closedShare
+			repaid = position.Borrowed() * closedShare
+		}
+	}
 
 	// For buy orders, add fee to total cost
 	// For sell orders, subtract fee from proceeds
 	var netCashImpact float64
 	if order.Side == models.OrderSideBuy {
 		netCashImpact = -(totalCost + fee) // Negative because we're spending cash
+		if options.Leveraged() {
+			netCashImpact = -(marginChange + fee) // Only the margin is paid, the rest is borrowed
+		}
 	} else {
-		netCashImpact = totalCost - fee // Positive because we're receiving cash
+		netCashImpact = totalCost - fee - repaid // Positive because we're receiving cash
 	}
 
 	// Update USDT position (cash)
@@ -318,19 +1059,36 @@ func (oe *OrderExecutionEngine) executeOrder(tx *gorm.DB, order *models.Order, p
 	// Update position for the traded symbol
 	var positionQuantityChange float64
 	if order.Side == models.OrderSideBuy {
-		positionQuantityChange = order.Quantity
+		positionQuantityChange = quantity
 	} else {
-		positionQuantityChange = -order.Quantity
+		positionQuantityChange = -quantity
 	}
 
 	if err := oe.positionDAO.UpdateOrCreatePosition(tx, order.UserID, order.SimulationID, order.Symbol, order.BaseCurrency, positionQuantityChange, price, fee); err != nil {
 		return nil, fmt.Errorf("failed to update position: %w", err)
 	}
+	if options.Leveraged() {
+		if err := oe.applyMarginWithTx(tx, order, marginChange, options); err != nil {
+			return nil, err
+		}
+	}
+	if err := oe.positionDAO.RecordSnapshotWithTx(tx, order.UserID, order.SimulationID, order.Symbol, order.BaseCurrency, order.ID, simulationTime); err != nil {
+		return nil, err
+	}
 
-	// Update order status
+	// Update order status, the executed price averages every fill
+	averagePrice := price
+	if order.FilledQuantity > 0 && order.ExecutedPrice != nil {
+		previousPrice := *order.ExecutedPrice
+		averagePrice = (order.FilledQuantity*previousPrice + quantity*price) / (order.FilledQuantity + quantity)
+	}
+	order.FilledQuantity += quantity
 	order.Status = models.OrderStatusExecuted
+	if order.RemainingQuantity() > fillQuantityTolerance {
+		order.Status = models.OrderStatusPartiallyFilled
+	}
 	order.ExecutedAt = &simulationTime
-	order.ExecutedPrice = &price
+	order.ExecutedPrice = &averagePrice
 
 	if err := oe.orderDAO.UpdateWithTx(tx, order); err != nil {
 		return nil, fmt.Errorf("failed to update order: %w", err)
@@ -344,9 +1102,10 @@ func (oe *OrderExecutionEngine) executeOrder(tx *gorm.DB, order *models.Order, p
 		Symbol:       order.Symbol,
 		BaseCurrency: order.BaseCurrency,
 		Side:         order.Side,
-		Quantity:     order.Quantity,
+		Quantity:     quantity,
 		Price:        price,
 		Fee:          fee,
+		Slippage:     slippage,
 		ExecutedAt:   simulationTime,
 	}
 
@@ -355,11 +1114,49 @@ func (oe *OrderExecutionEngine) executeOrder(tx *gorm.DB, order *models.Order, p
 	}
 
 	log.Printf("Executed order %d: %s %s %.8f at %.8f, fee: %.8f, net cash impact: %.8f",
-		order.ID, string(order.Side), order.Symbol, order.Quantity, price, fee, netCashImpact)
+		order.ID, string(order.Side), order.Symbol, quantity, price, fee, netCashImpact)
 
 	return trade, nil
 }
 
+// affordableQuantity returns the largest quantity a market buy at price can pay for, fees included,
+// rounded down to 8 decimals
+func (oe *OrderExecutionEngine) affordableQuantity(userID, simulationID uint, symbol string, price float64) (float64, error) {
+	usdtPosition, err := oe.positionDAO.GetPosition(userID, simulationID, "USDT", "USDT")
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return 0, fmt.Errorf("failed to check USDT balance: %w", err)
+	}
+
+	availableCash := 0.0
+	if usdtPosition != nil {
+		availableCash = usdtPosition.Quantity
+	}
+	if availableCash <= 0 {
+		return 0, nil
+	}
+
+	oe.mu.Lock()
+	rate := oe.feeRateUnsafe(symbol, LiquidityRoleFor(models.OrderTypeMarket))
+	marginRate := oe.options.marginRate()
+	if oe.feeRounding != FeeRoundingNone {
+		// Keep back one fee increment, rounding can charge up to that much above the exact fee
+		availableCash -= math.Pow(10, -float64(oe.feeDecimals))
+	}
+	oe.mu.Unlock()
+	if availableCash <= 0 {
+		return 0, nil
+	}
+
+	unitCost := price * (marginRate + rate)
+	quantity := availableCash / unitCost
+
+	// Slippage grows with size at most, so pricing at the unslipped quantity's slippage stays affordable
+	if fillPrice, _ := oe.slippedPrice(symbol, models.OrderTypeMarket, models.OrderSideBuy, quantity, price); fillPrice > price {
+		quantity = availableCash / (fillPrice * (marginRate + rate))
+	}
+	return math.Floor(quantity*1e8) / 1e8, nil
+}
+
 // ValidateOrder validates order parameters
 func (oe *OrderExecutionEngine) ValidateOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, currentPrice float64) error {
 	if userID == 0 {
@@ -378,10 +1175,11 @@ func (oe *OrderExecutionEngine) ValidateOrder(userID, simulationID uint, symbol
 		return fmt.Errorf("quantity must be positive: %f", quantity)
 	}
 
-	// For buy orders, check if user has sufficient USDT balance
+	// For buy orders, check if user has sufficient USDT balance at the slipped fill price
 	if side == models.OrderSideBuy {
-		totalCost := quantity * currentPrice
-		fee := oe.CalculateFee(quantity, currentPrice)
+		fillPrice, _ := oe.slippedPrice(symbol, models.OrderTypeMarket, side, quantity, currentPrice)
+		totalCost := quantity * fillPrice * oe.GetExecutionOptions().marginRate()
+		fee := oe.CalculateFee(symbol, models.OrderTypeMarket, quantity, fillPrice)
 		requiredCash := totalCost + fee
 
 		// Get USDT position to check available balance
@@ -413,7 +1211,13 @@ func (oe *OrderExecutionEngine) ValidateOrder(userID, simulationID uint, symbol
 		}
 
 		if availableQuantity < quantity {
-			return fmt.Errorf("insufficient position: required %.8f, available %.8f", quantity, availableQuantity)
+			if !oe.GetExecutionOptions().AllowShort {
+				return fmt.Errorf("insufficient position: required %.8f, available %.8f", quantity, availableQuantity)
+			}
+			shortQuantity := quantity - math.Max(availableQuantity, 0)
+			if err := oe.validateShortCollateral(userID, simulationID, symbol, models.OrderTypeMarket, shortQuantity, currentPrice); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -444,8 +1248,8 @@ func (oe *OrderExecutionEngine) ValidateLimitOrder(userID, simulationID uint, sy
 
 	// For buy orders, check if user has sufficient USDT balance for the limit price
 	if side == models.OrderSideBuy {
-		totalCost := quantity * limitPrice
-		fee := oe.CalculateFee(quantity, limitPrice)
+		totalCost := quantity * limitPrice * oe.GetExecutionOptions().marginRate()
+		fee := oe.CalculateFee(symbol, models.OrderTypeLimit, quantity, limitPrice)
 		requiredCash := totalCost + fee
 
 		// Get USDT position to check available balance
@@ -477,16 +1281,39 @@ func (oe *OrderExecutionEngine) ValidateLimitOrder(userID, simulationID uint, sy
 		}
 
 		if availableQuantity < quantity {
-			return fmt.Errorf("insufficient position: required %.8f, available %.8f", quantity, availableQuantity)
+			if !oe.GetExecutionOptions().AllowShort {
+				return fmt.Errorf("insufficient position: required %.8f, available %.8f", quantity, availableQuantity)
+			}
+			shortQuantity := quantity - math.Max(availableQuantity, 0)
+			if err := oe.validateShortCollateral(userID, simulationID, symbol, models.OrderTypeLimit, shortQuantity, limitPrice); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
-// CalculateFee calculates trading fee
-func (oe *OrderExecutionEngine) CalculateFee(quantity, price float64) float64 {
-	return quantity * price * DefaultTradingFeeRate
+// CalculateFee calculates the trading fee for an order type filling from the book
+func (oe *OrderExecutionEngine) CalculateFee(symbol string, orderType models.OrderType, quantity, price float64) float64 {
+	return oe.CalculateFeeForRole(symbol, LiquidityRoleFor(orderType), quantity, price)
+}
+
+// CalculateFeeForRole calculates the trading fee using the simulation's fee rate when set,
+// otherwise the symbol's maker or taker rate
+// The fee is rounded with the engine's fee rounding
+func (oe *OrderExecutionEngine) CalculateFeeForRole(symbol string, role LiquidityRole, quantity, price float64) float64 {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+	return oe.feeRounding.Round(quantity*price*oe.feeRateUnsafe(symbol, role), oe.feeDecimals)
+}
+
+// feeRateUnsafe returns the fee rate a symbol's fills pay in a liquidity role (caller must hold lock)
+func (oe *OrderExecutionEngine) feeRateUnsafe(symbol string, role LiquidityRole) float64 {
+	if oe.options.FeeRate != nil {
+		return *oe.options.FeeRate
+	}
+	return oe.feeSchedule.Tier(symbol, oe.defaultFees).Rate(role)
 }
 
 // sendOrderUpdate sends order updates to the client via WebSocket
@@ -501,6 +1328,7 @@ func (oe *OrderExecutionEngine) sendOrderUpdate(eventType types.MessageType, ord
 
 	if trade != nil {
 		data["trade"] = trade
+		data["prices"] = oe.LatestPrices()
 	}
 
 	oe.client.SendMessage(eventType, data)
@@ -0,0 +1,358 @@
+package trading
+
+import (
+	"io"
+	"log"
+	"math"
+	"os"
+	"testing"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/testutil"
+)
+
+const (
+	testSimulationID = uint(1)
+	testStartTime    = int64(1_704_067_200_000) // 2024-01-01T00:00:00Z
+	minute           = int64(60_000)
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testOrderEngine is an order engine backed by in-memory DAOs, funded with USDT in testSimulationID
+type testOrderEngine struct {
+	*OrderExecutionEngine
+	orderDAO    *testutil.OrderDAO
+	tradeDAO    *testutil.TradeDAO
+	positionDAO *testutil.PositionDAO
+	client      *testutil.Client
+}
+
+func newTestOrderEngine(t *testing.T, funding float64, options ExecutionOptions) *testOrderEngine {
+	t.Helper()
+
+	te := &testOrderEngine{
+		orderDAO:    testutil.NewOrderDAO(),
+		tradeDAO:    testutil.NewTradeDAO(),
+		positionDAO: testutil.NewPositionDAO(),
+		client:      testutil.NewClient(),
+	}
+	engine := NewOrderExecutionEngine(te.orderDAO, te.tradeDAO, te.positionDAO, te.client, testutil.NewDB())
+	te.OrderExecutionEngine = engine.(*OrderExecutionEngine)
+	te.tradeDAO.SetOrderDAO(te.orderDAO)
+
+	if err := options.Validate(); err != nil {
+		t.Fatalf("invalid execution options: %v", err)
+	}
+	te.SetExecutionOptions(options)
+
+	simulationID := testSimulationID
+	if funding > 0 {
+		if err := te.positionDAO.CreateInitialUSDTPosition(1, &simulationID, funding); err != nil {
+			t.Fatalf("CreateInitialUSDTPosition: %v", err)
+		}
+	}
+	return te
+}
+
+// position returns the quantity held of symbol, 0 without a position
+func (te *testOrderEngine) position(t *testing.T, symbol string) models.Position {
+	t.Helper()
+	position, err := te.positionDAO.GetPosition(1, testSimulationID, symbol, "USDT")
+	if err != nil {
+		return models.Position{Symbol: symbol}
+	}
+	return *position
+}
+
+// trades returns the simulation's trades in execution order
+func (te *testOrderEngine) trades(t *testing.T) []models.Trade {
+	t.Helper()
+	trades, err := te.tradeDAO.GetUserTrades(1, testSimulationID, 0)
+	if err != nil {
+		t.Fatalf("GetUserTrades: %v", err)
+	}
+	for i, j := 0, len(trades)-1; i < j; i, j = i+1, j-1 {
+		trades[i], trades[j] = trades[j], trades[i]
+	}
+	return trades
+}
+
+// processCandles feeds candles to the engine one after another
+func (te *testOrderEngine) processCandles(t *testing.T, symbol string, candles []models.OHLCV) {
+	t.Helper()
+	for _, candle := range candles {
+		if _, err := te.ProcessCandle(symbol, candle); err != nil {
+			t.Fatalf("ProcessCandle at %d: %v", candle.StartTime, err)
+		}
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-6*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+func TestLimitOrdersFillWithinCandleRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   ExecutionOptions
+		side      models.OrderSide
+		limit     float64
+		candles   []models.OHLCV
+		wantPrice float64 // 0 when the order must not fill
+	}{
+		{
+			name:      "buy reached by the low fills at its limit",
+			side:      models.OrderSideBuy,
+			limit:     100,
+			candles:   []models.OHLCV{testutil.Candle(testStartTime, "1m", 110, 112, 99, 108)},
+			wantPrice: 100,
+		},
+		{
+			name:      "sell reached by the high fills at its limit",
+			side:      models.OrderSideSell,
+			limit:     110,
+			candles:   []models.OHLCV{testutil.Candle(testStartTime, "1m", 100, 111, 98, 102)},
+			wantPrice: 110,
+		},
+		{
+			name:      "buy the close reaches fills at the close",
+			side:      models.OrderSideBuy,
+			limit:     100,
+			candles:   []models.OHLCV{testutil.Candle(testStartTime, "1m", 105, 105, 95, 97)},
+			wantPrice: 97,
+		},
+		{
+			name:      "buy gapped through fills at the open",
+			side:      models.OrderSideBuy,
+			limit:     100,
+			candles:   []models.OHLCV{testutil.Candle(testStartTime, "1m", 96, 104, 95, 103)},
+			wantPrice: 96,
+		},
+		{
+			name:    "buy the range doesn't reach stays resting",
+			side:    models.OrderSideBuy,
+			limit:   100,
+			candles: []models.OHLCV{testutil.Candle(testStartTime, "1m", 105, 110, 101, 104)},
+		},
+		{
+			name:    "strict fills skip the candle the order was placed in",
+			options: ExecutionOptions{StrictLimitFills: true},
+			side:    models.OrderSideBuy,
+			limit:   100,
+			candles: []models.OHLCV{testutil.Candle(testStartTime-minute, "1m", 110, 112, 99, 108)},
+		},
+		{
+			name:    "strict fills use the range of later candles",
+			options: ExecutionOptions{StrictLimitFills: true},
+			side:    models.OrderSideBuy,
+			limit:   100,
+			candles: []models.OHLCV{
+				testutil.Candle(testStartTime-minute, "1m", 110, 112, 99, 108),
+				testutil.Candle(testStartTime, "1m", 108, 109, 99.5, 107),
+			},
+			wantPrice: 100,
+		},
+		{
+			name:    "next open fills a range trigger at an open still at the limit",
+			options: ExecutionOptions{LimitFillPrice: LimitFillNextOpen},
+			side:    models.OrderSideBuy,
+			limit:   100,
+			candles: []models.OHLCV{
+				testutil.Candle(testStartTime, "1m", 110, 112, 99, 101),
+				testutil.Candle(testStartTime+minute, "1m", 99.5, 103, 99, 102),
+			},
+			wantPrice: 99.5,
+		},
+		{
+			name:    "next open rests a range trigger when the open moved away",
+			options: ExecutionOptions{LimitFillPrice: LimitFillNextOpen},
+			side:    models.OrderSideBuy,
+			limit:   100,
+			candles: []models.OHLCV{
+				testutil.Candle(testStartTime, "1m", 110, 112, 99, 108),
+				testutil.Candle(testStartTime+minute, "1m", 108, 109, 101, 108),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := newTestOrderEngine(t, 100_000, tt.options)
+			if tt.side == models.OrderSideSell {
+				if _, _, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 1, 100, "", testStartTime-2*minute); err != nil {
+					t.Fatalf("ExecuteMarketOrder: %v", err)
+				}
+			}
+			// Placed 30 seconds into the candle before testStartTime
+			order, err := te.PlaceLimitOrder(1, testSimulationID, "BTCUSDT", tt.side, 1, tt.limit, "", testStartTime-minute/2)
+			if err != nil {
+				t.Fatalf("PlaceLimitOrder: %v", err)
+			}
+			te.processCandles(t, "BTCUSDT", tt.candles)
+
+			var fills []models.Trade
+			for _, trade := range te.trades(t) {
+				if trade.OrderID == order.ID {
+					fills = append(fills, trade)
+				}
+			}
+			if tt.wantPrice == 0 {
+				if len(fills) != 0 {
+					t.Fatalf("filled at %g, want the order to keep resting", fills[0].Price)
+				}
+				return
+			}
+			if len(fills) != 1 {
+				t.Fatalf("got %d fills, want 1", len(fills))
+			}
+			if fills[0].Price != tt.wantPrice {
+				t.Errorf("filled at %g, want %g", fills[0].Price, tt.wantPrice)
+			}
+		})
+	}
+}
+
+// ResetOrderBook drops whatever the engine holds in memory and restores exactly the simulation's open orders
+func TestResetOrderBookRestoresStoredPendingOrders(t *testing.T) {
+	te := newTestOrderEngine(t, 10_000, DefaultExecutionOptions())
+	simulationID, otherSimulationID := testSimulationID, testSimulationID+1
+	limitPrice, stopPrice := 90.0, 80.0
+
+	stored := []*models.Order{
+		{SimulationID: &simulationID, Type: models.OrderTypeLimit, Status: models.OrderStatusPending},
+		{SimulationID: &simulationID, Type: models.OrderTypeLimit, Status: models.OrderStatusPartiallyFilled, FilledQuantity: 0.5},
+		{SimulationID: &simulationID, Type: models.OrderTypeLimit, Status: models.OrderStatusExecuted},
+		{SimulationID: &simulationID, Type: models.OrderTypeLimit, Status: models.OrderStatusCancelled},
+		{SimulationID: &otherSimulationID, Type: models.OrderTypeLimit, Status: models.OrderStatusPending},
+		{SimulationID: &simulationID, Type: models.OrderTypeMarket, Status: models.OrderStatusPending},
+		{SimulationID: &simulationID, Type: models.OrderTypeMarket, Status: models.OrderStatusExecuted},
+		{SimulationID: &simulationID, Type: models.OrderTypeStopLoss, Status: models.OrderStatusPending, OrderParams: models.OrderParameters{StopPrice: &stopPrice}},
+		{SimulationID: &simulationID, Type: models.OrderTypeStopLoss, Status: models.OrderStatusCancelled, OrderParams: models.OrderParameters{StopPrice: &stopPrice}},
+	}
+	for _, order := range stored {
+		order.UserID = 1
+		order.Symbol = "BTCUSDT"
+		order.BaseCurrency = "USDT"
+		order.Side = models.OrderSideBuy
+		order.Quantity = 1
+		if order.Type == models.OrderTypeLimit {
+			order.OrderParams.LimitPrice = &limitPrice
+		}
+		if err := te.orderDAO.Create(order); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	// Stale in-memory orders the store no longer has open
+	if err := te.orderBook.AddOrder(&models.Order{ID: 1000, UserID: 1, SimulationID: &simulationID, Symbol: "BTCUSDT", Side: models.OrderSideSell, Type: models.OrderTypeLimit, Quantity: 1, OrderParams: models.OrderParameters{LimitPrice: &limitPrice}}); err != nil {
+		t.Fatalf("AddOrder: %v", err)
+	}
+	if err := te.orderBook.AddOrder(stored[2]); err != nil {
+		t.Fatalf("AddOrder: %v", err)
+	}
+	te.deferredMarketOrders = append(te.deferredMarketOrders, stored[6])
+	te.protectiveOrders = append(te.protectiveOrders, stored[8])
+
+	if err := te.ResetOrderBook(testSimulationID); err != nil {
+		t.Fatalf("ResetOrderBook: %v", err)
+	}
+
+	ids := func(orders []*models.Order) map[uint]bool {
+		set := map[uint]bool{}
+		for _, order := range orders {
+			set[order.ID] = true
+		}
+		return set
+	}
+	checkIDs := func(what string, got map[uint]bool, want ...*models.Order) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Errorf("%s hold %d orders, want %d", what, len(got), len(want))
+		}
+		for _, order := range want {
+			if !got[order.ID] {
+				t.Errorf("%s are missing order %d (%s %s)", what, order.ID, order.Type, order.Status)
+			}
+		}
+	}
+	checkIDs("book orders", ids(te.orderBook.GetOrdersByUser(1, &simulationID)), stored[0], stored[1])
+	checkIDs("deferred market orders", ids(te.deferredMarketOrders), stored[5])
+	checkIDs("protective orders", ids(te.protectiveOrders), stored[7])
+	if count := te.orderBook.GetOrderCount(); count != 2 {
+		t.Errorf("book holds %d orders, want 2", count)
+	}
+}
+
+// Tags set on placement select a strategy's orders and, through them, group the trades they filled with
+func TestOrdersAndTradesGroupedByTag(t *testing.T) {
+	te := newTestOrderEngine(t, 10_000, DefaultExecutionOptions())
+
+	breakout, _, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 1, 100, "breakout", testStartTime)
+	if err != nil {
+		t.Fatalf("ExecuteMarketOrder: %v", err)
+	}
+	dcaMarket, _, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 2, 100, "dca", testStartTime)
+	if err != nil {
+		t.Fatalf("ExecuteMarketOrder: %v", err)
+	}
+	dcaLimit, err := te.PlaceLimitOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 3, 95, "dca", testStartTime)
+	if err != nil {
+		t.Fatalf("PlaceLimitOrder: %v", err)
+	}
+	untagged, _, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", models.OrderSideSell, 1, 100, "", testStartTime)
+	if err != nil {
+		t.Fatalf("ExecuteMarketOrder: %v", err)
+	}
+	te.processCandles(t, "BTCUSDT", []models.OHLCV{testutil.Candle(testStartTime+minute, "1m", 100, 101, 94, 98)})
+	if trades := te.trades(t); len(trades) != 4 {
+		t.Fatalf("made %d trades, want 4", len(trades))
+	}
+
+	tests := []struct {
+		tag        string
+		wantOrders []*models.Order
+	}{
+		{tag: "breakout", wantOrders: []*models.Order{breakout}},
+		{tag: "dca", wantOrders: []*models.Order{dcaMarket, dcaLimit}},
+		{tag: "", wantOrders: []*models.Order{untagged}},
+		{tag: "unused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			want := map[uint]bool{}
+			for _, order := range tt.wantOrders {
+				want[order.ID] = true
+			}
+
+			orders, err := te.orderDAO.GetUserOrdersByTag(1, testSimulationID, tt.tag, 0)
+			if err != nil {
+				t.Fatalf("GetUserOrdersByTag: %v", err)
+			}
+			if len(orders) != len(want) {
+				t.Errorf("tag %q lists %d orders, want %d", tt.tag, len(orders), len(want))
+			}
+			for _, order := range orders {
+				if !want[order.ID] || order.Tag != tt.tag {
+					t.Errorf("tag %q lists order %d tagged %q", tt.tag, order.ID, order.Tag)
+				}
+			}
+
+			trades, err := te.tradeDAO.GetUserTradesByTag(1, testSimulationID, tt.tag, 0)
+			if err != nil {
+				t.Fatalf("GetUserTradesByTag: %v", err)
+			}
+			if len(trades) != len(want) {
+				t.Errorf("tag %q groups %d trades, want one for each of its %d orders", tt.tag, len(trades), len(want))
+			}
+			for _, trade := range trades {
+				if !want[trade.OrderID] {
+					t.Errorf("tag %q groups trade %d of order %d", tt.tag, trade.ID, trade.OrderID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package trading
+
+import (
+	"fmt"
+	"math"
+
+	"tradesimulator/internal/models"
+)
+
+// MinPartialFillMode selects how MinPartialFill is measured
+type MinPartialFillMode string
+
+const (
+	MinPartialFillFraction MinPartialFillMode = "fraction" // A share of the order quantity between 0 and 1
+	MinPartialFillQuantity MinPartialFillMode = "quantity" // A base asset quantity
+)
+
+// DefaultMinPartialFill is the fraction of the order a partial fill takes at least unless configured, so a large
+// order in a thin market fills in at most about a hundred trades
+const DefaultMinPartialFill = 0.01
+
+// validateMinPartialFill checks the partial fill minimum and fills in the default fraction
+func (eo *ExecutionOptions) validateMinPartialFill() error {
+	switch eo.MinPartialFillMode {
+	case "":
+		eo.MinPartialFillMode = MinPartialFillFraction
+	case MinPartialFillFraction, MinPartialFillQuantity:
+	default:
+		return fmt.Errorf("invalid min partial fill mode: %s, must be '%s' or '%s'", eo.MinPartialFillMode, MinPartialFillFraction, MinPartialFillQuantity)
+	}
+	if eo.MinPartialFill < 0 {
+		return fmt.Errorf("invalid min partial fill: %g, must not be negative", eo.MinPartialFill)
+	}
+	if eo.MinPartialFillMode == MinPartialFillFraction {
+		if eo.MinPartialFill == 0 {
+			eo.MinPartialFill = DefaultMinPartialFill
+		}
+		if eo.MinPartialFill > 1 {
+			return fmt.Errorf("invalid min partial fill: %g, a fraction must be between 0 and 1", eo.MinPartialFill)
+		}
+	}
+	return nil
+}
+
+// minPartialFill returns the smallest quantity order may partially fill
+func (eo ExecutionOptions) minPartialFill(order *models.Order) float64 {
+	if eo.MinPartialFillMode == MinPartialFillQuantity {
+		return eo.MinPartialFill
+	}
+	return eo.MinPartialFill * order.Quantity
+}
+
+// partialFillQuantity sizes a volume capped limit fill given the share of the candle volume left for the order, and
+// returns the part of that share the order takes
+// Shares too small for the minimum are carried over to later candles instead of filling as dust; the quantity is 0
+// while the carried volume is still short of the minimum, and never below it except for the final remainder
+func (oe *OrderExecutionEngine) partialFillQuantity(order *models.Order, candleShare float64, options ExecutionOptions) (float64, float64) {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+
+	carried := oe.carriedFillVolume[order.ID]
+	remaining := order.RemainingQuantity()
+	fillQuantity := math.Min(remaining, candleShare+carried)
+	if fillQuantity < remaining-fillQuantityTolerance && fillQuantity < options.minPartialFill(order) {
+		if oe.carriedFillVolume == nil {
+			oe.carriedFillVolume = make(map[uint]float64)
+		}
+		oe.carriedFillVolume[order.ID] = fillQuantity
+		return 0, candleShare
+	}
+
+	delete(oe.carriedFillVolume, order.ID)
+	return fillQuantity, math.Max(0, math.Min(candleShare, fillQuantity-carried))
+}
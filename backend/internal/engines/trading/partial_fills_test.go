@@ -0,0 +1,129 @@
+package trading
+
+import (
+	"testing"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/testutil"
+)
+
+// volumeCandles builds 1m candles from testStartTime closing at price with the given volumes
+func volumeCandles(price float64, volumes ...float64) []models.OHLCV {
+	candles := make([]models.OHLCV, 0, len(volumes))
+	for i, volume := range volumes {
+		candle := testutil.Candle(testStartTime+int64(i)*minute, "1m", price, price, price, price)
+		candle.Volume = volume
+		candles = append(candles, candle)
+	}
+	return candles
+}
+
+func TestMinPartialFill(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   ExecutionOptions
+		quantity  float64
+		volumes   []float64
+		minFill   float64
+		wantFills []float64
+	}{
+		{
+			name:      "fraction carries small shares until they reach it",
+			options:   ExecutionOptions{VolumeParticipation: 0.1, MinPartialFill: 0.1},
+			quantity:  10,
+			volumes:   []float64{4, 4, 4, 30, 100, 25},
+			minFill:   1,
+			wantFills: []float64{1.2, 3, 5.8},
+		},
+		{
+			name:      "quantity mode",
+			options:   ExecutionOptions{VolumeParticipation: 0.5, MinPartialFill: 2, MinPartialFillMode: MinPartialFillQuantity},
+			quantity:  5,
+			volumes:   []float64{1, 1, 1, 1, 4, 1, 1},
+			minFill:   2,
+			wantFills: []float64{2, 2, 1},
+		},
+		{
+			name:      "final remainder fills below the minimum",
+			options:   ExecutionOptions{VolumeParticipation: 1, MinPartialFill: 0.4},
+			quantity:  10,
+			volumes:   []float64{4, 4, 1},
+			minFill:   4,
+			wantFills: []float64{4, 4},
+		},
+		{
+			name:      "default minimum is one percent of the order",
+			options:   ExecutionOptions{VolumeParticipation: 0.01},
+			quantity:  100,
+			volumes:   []float64{50, 50, 50},
+			minFill:   1,
+			wantFills: []float64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := newTestOrderEngine(t, 1_000_000, tt.options)
+			if _, err := te.PlaceLimitOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, tt.quantity, 100, "", testStartTime-minute); err != nil {
+				t.Fatalf("PlaceLimitOrder: %v", err)
+			}
+
+			// A trailing candle with plenty of volume fills whatever is left
+			candles := volumeCandles(100, append(tt.volumes, 1e6)...)
+			te.processCandles(t, "BTCUSDT", candles[:len(tt.volumes)])
+
+			var fills []float64
+			for _, trade := range te.trades(t) {
+				fills = append(fills, trade.Quantity)
+			}
+			if len(fills) != len(tt.wantFills) {
+				t.Fatalf("fills = %v, want %v", fills, tt.wantFills)
+			}
+			for i, fill := range fills {
+				if !approxEqual(fill, tt.wantFills[i]) {
+					t.Errorf("fill %d = %g, want %g", i, fill, tt.wantFills[i])
+				}
+			}
+
+			te.processCandles(t, "BTCUSDT", candles[len(tt.volumes):])
+			trades := te.trades(t)
+			filled := 0.0
+			for i, trade := range trades {
+				filled += trade.Quantity
+				if i < len(trades)-1 && trade.Quantity < tt.minFill-1e-9 {
+					t.Errorf("fill %d of %g is below the %g minimum and not the final remainder", i, trade.Quantity, tt.minFill)
+				}
+			}
+			if !approxEqual(filled, tt.quantity) {
+				t.Errorf("filled %g in total, want %g", filled, tt.quantity)
+			}
+		})
+	}
+}
+
+func TestMinPartialFillValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ExecutionOptions
+		wantErr bool
+		want    float64
+	}{
+		{name: "defaults to one percent", options: ExecutionOptions{}, want: DefaultMinPartialFill},
+		{name: "fraction above 1", options: ExecutionOptions{MinPartialFill: 1.5}, wantErr: true},
+		{name: "quantity above 1", options: ExecutionOptions{MinPartialFill: 1.5, MinPartialFillMode: MinPartialFillQuantity}, want: 1.5},
+		{name: "negative", options: ExecutionOptions{MinPartialFill: -1, MinPartialFillMode: MinPartialFillQuantity}, wantErr: true},
+		{name: "unknown mode", options: ExecutionOptions{MinPartialFillMode: "percent"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, want error %t", err, tt.wantErr)
+			}
+			if err == nil && tt.options.MinPartialFill != tt.want {
+				t.Errorf("min partial fill = %g, want %g", tt.options.MinPartialFill, tt.want)
+			}
+		})
+	}
+}
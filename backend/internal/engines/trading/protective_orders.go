@@ -0,0 +1,233 @@
+package trading
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/types"
+)
+
+// PlaceStopLossOrder places an order that executes at market once the price moves against the position to stopPrice
+// Sell stop-losses protect longs and trigger when the price falls to stopPrice; buy stop-losses protect shorts
+func (oe *OrderExecutionEngine) PlaceStopLossOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, stopPrice float64, tag string, simulationTime int64) (*models.Order, error) {
+	return oe.placeProtectiveOrder(models.OrderTypeStopLoss, userID, simulationID, symbol, side, quantity, models.OrderParameters{StopLossPrice: &stopPrice}, tag, simulationTime)
+}
+
+// PlaceTakeProfitOrder places an order that executes at market once the price moves in favour of the position to targetPrice
+// Sell take-profits close longs and trigger when the price rises to targetPrice; buy take-profits cover shorts
+func (oe *OrderExecutionEngine) PlaceTakeProfitOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, targetPrice float64, tag string, simulationTime int64) (*models.Order, error) {
+	return oe.placeProtectiveOrder(models.OrderTypeTakeProfit, userID, simulationID, symbol, side, quantity, models.OrderParameters{TakeProfitPrice: &targetPrice}, tag, simulationTime)
+}
+
+// PlaceTrailingStopOrder places a stop-loss whose stop trails the best price seen since placement by
+// either trailingDelta (absolute) or trailingPercent; exactly one of the two must be set
+// Sell trailing stops protect longs and ratchet up as the price rises; buy trailing stops protect shorts and ratchet down
+func (oe *OrderExecutionEngine) PlaceTrailingStopOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, trailingDelta, trailingPercent, currentPrice float64, tag string, simulationTime int64) (*models.Order, error) {
+	if currentPrice <= 0 {
+		return nil, fmt.Errorf("current price must be positive to place a trailing stop")
+	}
+
+	params := models.OrderParameters{HighWaterMark: &currentPrice}
+	switch {
+	case trailingDelta > 0 && trailingPercent > 0:
+		return nil, fmt.Errorf("trailing stop takes either a trailing delta or a trailing percent, not both")
+	case trailingDelta > 0:
+		if side == models.OrderSideSell && trailingDelta >= currentPrice {
+			return nil, fmt.Errorf("trailing delta %.8f must be below the current price %.8f", trailingDelta, currentPrice)
+		}
+		params.TrailingDelta = &trailingDelta
+	case trailingPercent > 0:
+		if trailingPercent >= 100 {
+			return nil, fmt.Errorf("trailing percent must be below 100, got %.4f", trailingPercent)
+		}
+		params.TrailingPercent = &trailingPercent
+	default:
+		return nil, fmt.Errorf("trailing stop requires a positive trailing delta or trailing percent")
+	}
+
+	return oe.placeProtectiveOrder(models.OrderTypeTrailingStop, userID, simulationID, symbol, side, quantity, params, tag, simulationTime)
+}
+
+// placeProtectiveOrder stores a pending stop-loss, take-profit or trailing stop order and starts watching its trigger
+func (oe *OrderExecutionEngine) placeProtectiveOrder(orderType models.OrderType, userID, simulationID uint, symbol string, side models.OrderSide, quantity float64, params models.OrderParameters, tag string, simulationTime int64) (*models.Order, error) {
+	order := &models.Order{
+		UserID:       userID,
+		SimulationID: &simulationID,
+		Symbol:       symbol,
+		BaseCurrency: "USDT", // Default to USDT for now
+		Side:         side,
+		Type:         orderType,
+		Quantity:     quantity,
+		Status:       models.OrderStatusPending,
+		PlacedAt:     simulationTime,
+		Tag:          tag,
+		OrderParams:  params,
+	}
+	triggerPrice := *order.GetTriggerPrice()
+
+	// The trigger price bounds the cash or position needed just like a limit price
+	if err := oe.ValidateLimitOrder(userID, simulationID, symbol, side, quantity, triggerPrice, 0); err != nil {
+		return nil, fmt.Errorf("%s order validation failed: %w", orderType, err)
+	}
+
+	if err := oe.orderDAO.Create(order); err != nil {
+		return nil, fmt.Errorf("failed to create %s order: %w", orderType, err)
+	}
+
+	oe.mu.Lock()
+	oe.protectiveOrders = append(oe.protectiveOrders, order)
+	oe.mu.Unlock()
+
+	log.Printf("Created %s order %d: %s %s %.8f triggering at %.8f",
+		orderType, order.ID, string(side), symbol, quantity, triggerPrice)
+
+	oe.sendOrderUpdate(types.OrderPlaced, order, nil)
+
+	return order, nil
+}
+
+// protectiveFillPrice reports whether a protective order triggers within a candle's range and the price it fills at
+// Orders fill at their trigger price, or at the open when the candle gapped through it
+func protectiveFillPrice(order *models.Order, open, high, low float64) (float64, bool) {
+	triggerPrice := *order.GetTriggerPrice()
+	if order.TriggersOnDrop() {
+		if low > triggerPrice {
+			return 0, false
+		}
+		if open < triggerPrice {
+			return open, true
+		}
+		return triggerPrice, true
+	}
+
+	if high < triggerPrice {
+		return 0, false
+	}
+	if open > triggerPrice {
+		return open, true
+	}
+	return triggerPrice, true
+}
+
+// ratchetTrailingStop moves a trailing stop's high-water mark to the best price of a movement,
+// never loosening the stop, and reports whether the mark moved
+func ratchetTrailingStop(order *models.Order, high, low float64) bool {
+	mark := order.OrderParams.HighWaterMark
+	if order.Side == models.OrderSideSell {
+		if high <= *mark {
+			return false
+		}
+		order.OrderParams.HighWaterMark = &high
+		return true
+	}
+
+	if low >= *mark {
+		return false
+	}
+	order.OrderParams.HighWaterMark = &low
+	return true
+}
+
+// processProtectiveOrders executes stop-loss, take-profit and trailing stop orders placed at or before placedBy
+// whose trigger lies within the open/high/low range of a symbol's price movement
+// Trailing stops are checked against the stop in force before the movement, then ratchet to its best price
+func (oe *OrderExecutionEngine) processProtectiveOrders(symbol string, open, high, low float64, simulationTime int64, placedBy int64) []*models.Trade {
+	type triggeredOrder struct {
+		order     *models.Order
+		fillPrice float64
+	}
+
+	oe.mu.Lock()
+	var triggered []triggeredOrder
+	var ratcheted []*models.Order
+	remaining := oe.protectiveOrders[:0]
+	for _, order := range oe.protectiveOrders {
+		if order.Symbol == symbol && order.PlacedAt <= placedBy {
+			if fillPrice, ok := protectiveFillPrice(order, open, high, low); ok {
+				triggered = append(triggered, triggeredOrder{order: order, fillPrice: fillPrice})
+				continue
+			}
+			if order.Type == models.OrderTypeTrailingStop && ratchetTrailingStop(order, high, low) {
+				ratcheted = append(ratcheted, order)
+			}
+		}
+		remaining = append(remaining, order)
+	}
+	oe.protectiveOrders = remaining
+	oe.mu.Unlock()
+
+	// Persist moved marks so a reloaded order book resumes from the ratcheted stop
+	for _, order := range ratcheted {
+		if err := oe.orderDAO.Update(order); err != nil {
+			log.Printf("Failed to persist trailing stop %d high-water mark: %v", order.ID, err)
+		}
+	}
+
+	// Execute in placement order so competing orders on the same position resolve deterministically
+	sort.SliceStable(triggered, func(i, j int) bool {
+		return placedBefore(triggered[i].order, triggered[j].order)
+	})
+
+	var trades []*models.Trade
+	closedBrackets := make(map[uint]bool) // Parent IDs of brackets whose exit filled in this movement
+	for _, entry := range triggered {
+		order := entry.order
+
+		// Both exits of a bracket can trigger in one movement, the one placed first closes it
+		if parentID := order.OrderParams.ParentOrderID; parentID != nil && closedBrackets[*parentID] {
+			oe.cancelUntrackedOrder(order)
+			continue
+		}
+
+		// The position may already have been closed by another order
+		if err := oe.ValidateOrder(order.UserID, *order.SimulationID, order.Symbol, order.Side, order.Quantity, entry.fillPrice); err != nil {
+			oe.failOrder(order, err)
+			continue
+		}
+
+		tx := oe.db.Begin()
+		if tx.Error != nil {
+			log.Printf("Failed to start transaction for %s order %d: %v", order.Type, order.ID, tx.Error)
+			continue
+		}
+
+		trade, err := oe.executeOrder(tx, order, entry.fillPrice, simulationTime)
+		if err != nil {
+			tx.Rollback()
+			oe.failOrder(order, err)
+			continue
+		}
+
+		if err := tx.Commit().Error; err != nil {
+			log.Printf("Failed to commit transaction for %s order %d: %v", order.Type, order.ID, err)
+			continue
+		}
+		oe.tradeStats.record(trade)
+
+		log.Printf("%s order %d triggered at %.8f (trigger was %.8f)", order.Type, order.ID, entry.fillPrice, *order.GetTriggerPrice())
+		oe.sendOrderUpdate(types.OrderExecuted, order, trade)
+		oe.settleLinkedOrders(order)
+		if parentID := order.OrderParams.ParentOrderID; parentID != nil {
+			closedBrackets[*parentID] = true
+		}
+		trades = append(trades, trade)
+	}
+
+	return trades
+}
+
+// removeProtectiveOrder stops watching a pending protective order, returning nil if it isn't tracked
+func (oe *OrderExecutionEngine) removeProtectiveOrder(orderID uint) *models.Order {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+
+	for i, order := range oe.protectiveOrders {
+		if order.ID == orderID {
+			oe.protectiveOrders = append(oe.protectiveOrders[:i], oe.protectiveOrders[i+1:]...)
+			return order
+		}
+	}
+	return nil
+}
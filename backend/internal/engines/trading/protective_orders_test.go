@@ -0,0 +1,189 @@
+package trading
+
+import (
+	"testing"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/testutil"
+	"tradesimulator/internal/types"
+)
+
+func TestProtectiveOrdersOnPriceSequence(t *testing.T) {
+	tests := []struct {
+		name            string
+		takeProfitFirst bool
+		candles         []models.OHLCV
+		wantType        models.OrderType // Exit that fills, empty when neither does
+		wantPrice       float64
+		otherFails      bool // The same candle reaches the exit that didn't fill, after the position is closed
+	}{
+		{
+			name:    "prices between the exits fill neither",
+			candles: testutil.Candles(testStartTime, "1m", 100, 95, 105, 101),
+		},
+		{
+			name:      "rising prices fill the take-profit at its target",
+			candles:   testutil.Candles(testStartTime, "1m", 100, 105, 112),
+			wantType:  models.OrderTypeTakeProfit,
+			wantPrice: 110,
+		},
+		{
+			name:      "falling prices fill the stop-loss at its stop",
+			candles:   testutil.Candles(testStartTime, "1m", 100, 95, 88),
+			wantType:  models.OrderTypeStopLoss,
+			wantPrice: 90,
+		},
+		{
+			name:      "a gap through the stop fills at the open",
+			candles:   []models.OHLCV{testutil.Candle(testStartTime, "1m", 85, 86, 84, 85)},
+			wantType:  models.OrderTypeStopLoss,
+			wantPrice: 85,
+		},
+		{
+			name:       "a candle crossing both fills the stop-loss placed first",
+			candles:    []models.OHLCV{testutil.Candle(testStartTime, "1m", 100, 112, 88, 105)},
+			wantType:   models.OrderTypeStopLoss,
+			wantPrice:  90,
+			otherFails: true,
+		},
+		{
+			name:            "a candle crossing both fills the take-profit placed first",
+			takeProfitFirst: true,
+			candles:         []models.OHLCV{testutil.Candle(testStartTime, "1m", 100, 112, 88, 105)},
+			wantType:        models.OrderTypeTakeProfit,
+			wantPrice:       110,
+			otherFails:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := newTestOrderEngine(t, 10_000, ExecutionOptions{})
+			if _, _, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 1, 100, "", testStartTime-2*minute); err != nil {
+				t.Fatalf("ExecuteMarketOrder: %v", err)
+			}
+
+			placeStopLoss := func() *models.Order {
+				order, err := te.PlaceStopLossOrder(1, testSimulationID, "BTCUSDT", models.OrderSideSell, 1, 90, "", testStartTime-minute)
+				if err != nil {
+					t.Fatalf("PlaceStopLossOrder: %v", err)
+				}
+				return order
+			}
+			placeTakeProfit := func() *models.Order {
+				order, err := te.PlaceTakeProfitOrder(1, testSimulationID, "BTCUSDT", models.OrderSideSell, 1, 110, "", testStartTime-minute)
+				if err != nil {
+					t.Fatalf("PlaceTakeProfitOrder: %v", err)
+				}
+				return order
+			}
+			var stopLoss, takeProfit *models.Order
+			if tt.takeProfitFirst {
+				takeProfit = placeTakeProfit()
+				stopLoss = placeStopLoss()
+			} else {
+				stopLoss = placeStopLoss()
+				takeProfit = placeTakeProfit()
+			}
+
+			te.processCandles(t, "BTCUSDT", tt.candles)
+
+			trades := te.trades(t)[1:] // Past the entry
+			if tt.wantType == "" {
+				if len(trades) != 0 {
+					t.Fatalf("got %d exit trades, want none", len(trades))
+				}
+				if position := te.position(t, "BTCUSDT"); position.Quantity != 1 {
+					t.Errorf("position = %g, want the 1 BTC still held", position.Quantity)
+				}
+				return
+			}
+
+			filled, other := stopLoss, takeProfit
+			if tt.wantType == models.OrderTypeTakeProfit {
+				filled, other = takeProfit, stopLoss
+			}
+			if len(trades) != 1 {
+				t.Fatalf("got %d exit trades, want 1", len(trades))
+			}
+			if trades[0].OrderID != filled.ID || trades[0].Price != tt.wantPrice {
+				t.Errorf("exit = order %d at %g, want the %s (order %d) at %g", trades[0].OrderID, trades[0].Price, tt.wantType, filled.ID, tt.wantPrice)
+			}
+			if position := te.position(t, "BTCUSDT"); position.Quantity != 0 {
+				t.Errorf("position = %g after the exit, want 0", position.Quantity)
+			}
+			if executed := te.client.Messages(types.OrderExecuted); len(executed) != 2 {
+				t.Errorf("sent %d order_executed messages, want the entry's and the exit's", len(executed))
+			}
+
+			// The other exit has nothing left to close once the same candle reaches it
+			wantStatus := models.OrderStatusPending
+			if tt.otherFails {
+				wantStatus = models.OrderStatusFailed
+			}
+			if order, err := te.orderDAO.GetByID(other.ID); err != nil {
+				t.Errorf("GetByID(%d): %v", other.ID, err)
+			} else if order.Status != wantStatus {
+				t.Errorf("other exit status = %s, want %s", order.Status, wantStatus)
+			}
+		})
+	}
+}
+
+func TestProtectiveOrdersOnPriceUpdates(t *testing.T) {
+	te := newTestOrderEngine(t, 10_000, ExecutionOptions{})
+	if _, _, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 1, 100, "", testStartTime); err != nil {
+		t.Fatalf("ExecuteMarketOrder: %v", err)
+	}
+	takeProfit, err := te.PlaceTakeProfitOrder(1, testSimulationID, "BTCUSDT", models.OrderSideSell, 1, 110, "", testStartTime)
+	if err != nil {
+		t.Fatalf("PlaceTakeProfitOrder: %v", err)
+	}
+	if _, err := te.PlaceStopLossOrder(1, testSimulationID, "BTCUSDT", models.OrderSideSell, 1, 90, "", testStartTime); err != nil {
+		t.Fatalf("PlaceStopLossOrder: %v", err)
+	}
+
+	for i, price := range []float64{104, 97, 108, 111} {
+		trades, err := te.ProcessPriceUpdate("BTCUSDT", price, testStartTime+int64(i+1)*minute)
+		if err != nil {
+			t.Fatalf("ProcessPriceUpdate(%g): %v", price, err)
+		}
+		if price < 110 && len(trades) != 0 {
+			t.Fatalf("%d trades at %g, before either exit was reached", len(trades), price)
+		}
+		if price == 111 {
+			// A bare price update has no range, the take-profit fills at the price that crossed it
+			if len(trades) != 1 || trades[0].OrderID != takeProfit.ID || trades[0].Price != 111 {
+				t.Fatalf("trades at 111 = %+v, want the take-profit at 111", trades)
+			}
+		}
+	}
+}
+
+func TestBracketExitsCrossedInOneCandle(t *testing.T) {
+	te := newTestOrderEngine(t, 10_000, ExecutionOptions{})
+	entry, _, err := te.PlaceBracketOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 1,
+		BracketOrderOptions{TakeProfitPrice: 110, StopLossPrice: 90, CurrentPrice: 100}, "", testStartTime-minute)
+	if err != nil {
+		t.Fatalf("PlaceBracketOrder: %v", err)
+	}
+
+	te.processCandles(t, "BTCUSDT", []models.OHLCV{testutil.Candle(testStartTime, "1m", 100, 112, 88, 105)})
+
+	var exits []models.Trade
+	for _, trade := range te.trades(t) {
+		if trade.OrderID != entry.ID {
+			exits = append(exits, trade)
+		}
+	}
+	if len(exits) != 1 || exits[0].Price != 90 {
+		t.Fatalf("exits = %+v, want the stop-loss at 90", exits)
+	}
+	exit, err := te.orderDAO.GetByID(exits[0].OrderID)
+	if err != nil || exit.Type != models.OrderTypeStopLoss {
+		t.Fatalf("filled exit = %+v (%v), want the stop-loss", exit, err)
+	}
+	if cancelled := te.client.Messages(types.OrderCancelled); len(cancelled) != 1 {
+		t.Errorf("sent %d order_cancelled messages, want the take-profit's", len(cancelled))
+	}
+}
@@ -0,0 +1,35 @@
+package trading
+
+import (
+	"fmt"
+
+	"tradesimulator/internal/models"
+)
+
+// validateShortCollateral checks a sell can open or add shortQuantity to a short position at price
+// The proceeds of open shorts stay in cash to buy them back, so only the cash beyond them counts as
+// collateral, and it must cover the new short's value and the order fee
+func (oe *OrderExecutionEngine) validateShortCollateral(userID, simulationID uint, symbol string, orderType models.OrderType, shortQuantity, price float64) error {
+	positions, err := oe.positionDAO.GetUserPositions(userID, simulationID)
+	if err != nil {
+		return fmt.Errorf("failed to check collateral: %w", err)
+	}
+
+	var cash, shortProceeds float64
+	for _, position := range positions {
+		if position.Symbol == "USDT" {
+			cash = position.Quantity
+		} else if position.Quantity < 0 {
+			// A short's total cost is its negated proceeds net of the opening fee
+			shortProceeds += -position.TotalCost
+		}
+	}
+
+	availableCollateral := cash - shortProceeds
+	requiredCollateral := shortQuantity*price + oe.CalculateFee(symbol, orderType, shortQuantity, price)
+	if availableCollateral < requiredCollateral {
+		return fmt.Errorf("insufficient collateral to short %.8f %s: required %.8f, available %.8f",
+			shortQuantity, symbol, requiredCollateral, availableCollateral)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package trading
+
+import (
+	"strings"
+	"testing"
+
+	"tradesimulator/internal/models"
+)
+
+func newShortingEngine(t *testing.T, funding float64) *testOrderEngine {
+	t.Helper()
+	noFee := 0.0
+	return newTestOrderEngine(t, funding, ExecutionOptions{AllowShort: true, FeeRate: &noFee})
+}
+
+func TestShortSelling(t *testing.T) {
+	type fill struct {
+		side     models.OrderSide
+		quantity float64
+		price    float64
+	}
+	tests := []struct {
+		name         string
+		fills        []fill
+		wantQuantity float64
+		wantAverage  float64
+		wantCash     float64
+		wantRealized float64
+	}{
+		{
+			name:         "opening a short",
+			fills:        []fill{{models.OrderSideSell, 10, 100}},
+			wantQuantity: -10,
+			wantAverage:  100,
+			wantCash:     11_000,
+		},
+		{
+			name:         "adding to a short averages its price",
+			fills:        []fill{{models.OrderSideSell, 10, 100}, {models.OrderSideSell, 10, 130}},
+			wantQuantity: -20,
+			wantAverage:  115,
+			wantCash:     12_300,
+		},
+		{
+			name:         "covering part of a short realizes against its average",
+			fills:        []fill{{models.OrderSideSell, 10, 100}, {models.OrderSideSell, 10, 130}, {models.OrderSideBuy, 5, 95}},
+			wantQuantity: -15,
+			wantAverage:  115,
+			wantCash:     11_825,
+			wantRealized: 100,
+		},
+		{
+			name:         "covering a whole short at a loss",
+			fills:        []fill{{models.OrderSideSell, 10, 100}, {models.OrderSideBuy, 10, 120}},
+			wantQuantity: 0,
+			wantCash:     9_800,
+			wantRealized: -200,
+		},
+		{
+			name:         "selling through a long opens a short",
+			fills:        []fill{{models.OrderSideBuy, 5, 100}, {models.OrderSideSell, 8, 110}},
+			wantQuantity: -3,
+			wantAverage:  110,
+			wantCash:     10_380,
+			wantRealized: 50,
+		},
+		{
+			name:         "buying through a short opens a long",
+			fills:        []fill{{models.OrderSideSell, 5, 100}, {models.OrderSideBuy, 8, 90}},
+			wantQuantity: 3,
+			wantAverage:  90,
+			wantCash:     9_780,
+			wantRealized: 50,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := newShortingEngine(t, 10_000)
+			for i, fill := range tt.fills {
+				if _, _, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", fill.side, fill.quantity, fill.price, "", testStartTime+int64(i)*minute); err != nil {
+					t.Fatalf("fill %d: %v", i, err)
+				}
+			}
+
+			position := te.position(t, "BTCUSDT")
+			if !approxEqual(position.Quantity, tt.wantQuantity) {
+				t.Errorf("quantity = %g, want %g", position.Quantity, tt.wantQuantity)
+			}
+			if tt.wantQuantity != 0 && !approxEqual(position.AveragePrice, tt.wantAverage) {
+				t.Errorf("average price = %g, want %g", position.AveragePrice, tt.wantAverage)
+			}
+			if !approxEqual(position.RealizedPnL, tt.wantRealized) {
+				t.Errorf("realized P&L = %g, want %g", position.RealizedPnL, tt.wantRealized)
+			}
+			if cash := te.position(t, "USDT").Quantity; !approxEqual(cash, tt.wantCash) {
+				t.Errorf("cash = %g, want %g", cash, tt.wantCash)
+			}
+		})
+	}
+}
+
+func TestShortSellingValidation(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		te := newTestOrderEngine(t, 10_000, ExecutionOptions{})
+		err := te.ValidateOrder(1, testSimulationID, "BTCUSDT", models.OrderSideSell, 1, 100)
+		if err == nil || !strings.Contains(err.Error(), "insufficient position") {
+			t.Errorf("ValidateOrder() error = %v, want insufficient position", err)
+		}
+	})
+
+	t.Run("collateral excludes the proceeds of open shorts", func(t *testing.T) {
+		te := newShortingEngine(t, 10_000)
+		if _, _, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", models.OrderSideSell, 50, 100, "", testStartTime); err != nil {
+			t.Fatalf("ExecuteMarketOrder: %v", err)
+		}
+
+		// 15000 cash less the 5000 owed on the open short leaves 10000 of collateral
+		if err := te.ValidateOrder(1, testSimulationID, "BTCUSDT", models.OrderSideSell, 100, 100); err != nil {
+			t.Errorf("short covered by the collateral rejected: %v", err)
+		}
+		err := te.ValidateOrder(1, testSimulationID, "BTCUSDT", models.OrderSideSell, 101, 100)
+		if err == nil || !strings.Contains(err.Error(), "insufficient collateral") {
+			t.Errorf("ValidateOrder() error = %v, want insufficient collateral", err)
+		}
+		err = te.ValidateLimitOrder(1, testSimulationID, "BTCUSDT", models.OrderSideSell, 101, 100, 0)
+		if err == nil || !strings.Contains(err.Error(), "insufficient collateral") {
+			t.Errorf("ValidateLimitOrder() error = %v, want insufficient collateral", err)
+		}
+	})
+
+	t.Run("only the quantity beyond a long needs collateral", func(t *testing.T) {
+		te := newShortingEngine(t, 1_000)
+		if _, _, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 10, 100, "", testStartTime); err != nil {
+			t.Fatalf("ExecuteMarketOrder: %v", err)
+		}
+		// No cash left, so only the held 10 can be sold
+		if err := te.ValidateOrder(1, testSimulationID, "BTCUSDT", models.OrderSideSell, 10, 100); err != nil {
+			t.Errorf("selling the long rejected: %v", err)
+		}
+		if err := te.ValidateOrder(1, testSimulationID, "BTCUSDT", models.OrderSideSell, 11, 100); err == nil {
+			t.Error("short without collateral accepted")
+		}
+	})
+}
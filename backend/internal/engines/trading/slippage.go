@@ -0,0 +1,121 @@
+package trading
+
+import (
+	"fmt"
+	"math"
+
+	"tradesimulator/internal/models"
+)
+
+// SlippageMode selects how market fills slip from their reference price
+type SlippageMode string
+
+const (
+	SlippageNone   SlippageMode = "none"   // Fill exactly at the reference price
+	SlippageFixed  SlippageMode = "fixed"  // Slip by SlippageBps on every fill
+	SlippageVolume SlippageMode = "volume" // Slip by SlippageBps scaled by the share of the candle volume taken
+)
+
+// MaxSlippageBps caps the configured slippage at 10% of the reference price
+const MaxSlippageBps = 1000
+
+// SlippageModel prices how far a market fill moves against the order from its reference price
+// Alternative models can be swapped in with SetSlippageModel
+type SlippageModel interface {
+	// Rate returns the adverse price move as a fraction of the reference price for quantity filling
+	// against a market that traded volume in its last candle; volume is 0 when unknown
+	Rate(quantity, volume float64) float64
+}
+
+// FixedSlippage slips every fill by the same number of basis points
+type FixedSlippage struct {
+	Bps float64
+}
+
+// Rate returns the fixed slippage rate regardless of size
+func (fs FixedSlippage) Rate(quantity, volume float64) float64 {
+	return fs.Bps / 10000
+}
+
+// VolumeSlippage slips fills in proportion to the share of the candle volume they take, by Bps for
+// orders taking the whole volume or more
+// Fills against an unknown volume are assumed to take all of it
+type VolumeSlippage struct {
+	Bps float64
+}
+
+// Rate returns the slippage rate scaled by the order's share of volume
+func (vs VolumeSlippage) Rate(quantity, volume float64) float64 {
+	share := 1.0
+	if volume > 0 {
+		share = math.Min(quantity/volume, 1)
+	}
+	return vs.Bps / 10000 * share
+}
+
+// validateSlippage checks the slippage settings and fills in the default mode
+func (eo *ExecutionOptions) validateSlippage() error {
+	switch eo.SlippageMode {
+	case "":
+		eo.SlippageMode = SlippageNone
+	case SlippageNone, SlippageFixed, SlippageVolume:
+	default:
+		return fmt.Errorf("invalid slippage mode: %s, must be '%s', '%s' or '%s'", eo.SlippageMode, SlippageNone, SlippageFixed, SlippageVolume)
+	}
+	if eo.SlippageBps < 0 || eo.SlippageBps > MaxSlippageBps {
+		return fmt.Errorf("invalid slippage: %g bps, must be between 0 and %d", eo.SlippageBps, MaxSlippageBps)
+	}
+	return nil
+}
+
+// slippageModel builds the model selected by the options, nil when fills don't slip
+func (eo ExecutionOptions) slippageModel() SlippageModel {
+	switch eo.SlippageMode {
+	case SlippageFixed:
+		return FixedSlippage{Bps: eo.SlippageBps}
+	case SlippageVolume:
+		return VolumeSlippage{Bps: eo.SlippageBps}
+	}
+	return nil
+}
+
+// SetSlippageModel replaces the slippage model built from the execution options, nil disables slippage
+// Setting execution options afterwards rebuilds the model from them
+func (oe *OrderExecutionEngine) SetSlippageModel(model SlippageModel) {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+	oe.slippage = model
+}
+
+// setReferenceVolume records the candle volume market fills on symbol are measured against
+func (oe *OrderExecutionEngine) setReferenceVolume(symbol string, volume float64) {
+	oe.mu.Lock()
+	defer oe.mu.Unlock()
+	if oe.referenceVolumes == nil {
+		oe.referenceVolumes = make(map[string]float64)
+	}
+	oe.referenceVolumes[symbol] = volume
+}
+
+// slippedPrice returns the price a market order of quantity fills at, moved against its side from price,
+// and the per unit slippage applied
+// Limit orders never slip, so they can't fill beyond their limit
+func (oe *OrderExecutionEngine) slippedPrice(symbol string, orderType models.OrderType, side models.OrderSide, quantity, price float64) (float64, float64) {
+	if orderType == models.OrderTypeLimit {
+		return price, 0
+	}
+
+	oe.mu.Lock()
+	model := oe.slippage
+	volume := oe.referenceVolumes[symbol]
+	oe.mu.Unlock()
+	if model == nil {
+		return price, 0
+	}
+
+	slippage := price * model.Rate(quantity, volume)
+	if side == models.OrderSideSell {
+		return price - slippage, slippage
+	}
+	return price + slippage, slippage
+}
@@ -0,0 +1,171 @@
+package trading
+
+import (
+	"testing"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/testutil"
+)
+
+func TestSlippageModels(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    SlippageModel
+		quantity float64
+		volume   float64
+		want     float64
+	}{
+		{name: "fixed", model: FixedSlippage{Bps: 10}, quantity: 1, volume: 100, want: 0.001},
+		{name: "fixed ignores size", model: FixedSlippage{Bps: 10}, quantity: 500, volume: 100, want: 0.001},
+		{name: "fixed zero", model: FixedSlippage{}, quantity: 1, volume: 100, want: 0},
+		{name: "volume share", model: VolumeSlippage{Bps: 100}, quantity: 25, volume: 100, want: 0.0025},
+		{name: "volume capped at the whole candle", model: VolumeSlippage{Bps: 100}, quantity: 300, volume: 100, want: 0.01},
+		{name: "volume unknown takes it all", model: VolumeSlippage{Bps: 100}, quantity: 1, volume: 0, want: 0.01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.model.Rate(tt.quantity, tt.volume); !approxEqual(got, tt.want) {
+				t.Errorf("Rate(%g, %g) = %g, want %g", tt.quantity, tt.volume, got, tt.want)
+			}
+		})
+	}
+}
+
+// Market orders slip against their side and pay the fee on the slipped price
+func TestMarketOrderSlippageWithFees(t *testing.T) {
+	feeRate := 0.001
+	tests := []struct {
+		name         string
+		side         models.OrderSide
+		mode         SlippageMode
+		bps          float64
+		volume       float64 // Reference candle volume
+		wantPrice    float64
+		wantSlippage float64
+	}{
+		{name: "buy without slippage", side: models.OrderSideBuy, mode: SlippageNone, volume: 100, wantPrice: 100},
+		{name: "sell without slippage", side: models.OrderSideSell, mode: SlippageNone, volume: 100, wantPrice: 100},
+		{name: "fixed buy fills higher", side: models.OrderSideBuy, mode: SlippageFixed, bps: 50, volume: 100, wantPrice: 100.5, wantSlippage: 0.5},
+		{name: "fixed sell fills lower", side: models.OrderSideSell, mode: SlippageFixed, bps: 50, volume: 100, wantPrice: 99.5, wantSlippage: 0.5},
+		{name: "volume buy scales by share", side: models.OrderSideBuy, mode: SlippageVolume, bps: 100, volume: 8, wantPrice: 100.25, wantSlippage: 0.25},
+		{name: "volume sell capped at the bps", side: models.OrderSideSell, mode: SlippageVolume, bps: 100, volume: 1, wantPrice: 99, wantSlippage: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := newTestOrderEngine(t, 10_000, ExecutionOptions{FeeRate: &feeRate})
+			if tt.side == models.OrderSideSell {
+				if _, _, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 2, 100, "", testStartTime); err != nil {
+					t.Fatalf("ExecuteMarketOrder: %v", err)
+				}
+			}
+			options := ExecutionOptions{FeeRate: &feeRate, SlippageMode: tt.mode, SlippageBps: tt.bps}
+			if err := options.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			te.SetExecutionOptions(options)
+			te.setReferenceVolume("BTCUSDT", tt.volume)
+
+			cashBefore := te.position(t, "USDT").Quantity
+			_, trade, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", tt.side, 2, 100, "", testStartTime+minute)
+			if err != nil {
+				t.Fatalf("ExecuteMarketOrder: %v", err)
+			}
+
+			if !approxEqual(trade.Price, tt.wantPrice) || !approxEqual(trade.Slippage, tt.wantSlippage) {
+				t.Errorf("filled at %g with %g slippage, want %g with %g", trade.Price, trade.Slippage, tt.wantPrice, tt.wantSlippage)
+			}
+			wantFee := 2 * tt.wantPrice * feeRate
+			if !approxEqual(trade.Fee, wantFee) {
+				t.Errorf("fee = %g, want %g on the slipped price", trade.Fee, wantFee)
+			}
+			wantCashChange := 2*tt.wantPrice - wantFee
+			if tt.side == models.OrderSideBuy {
+				wantCashChange = -(2*tt.wantPrice + wantFee)
+			}
+			if change := te.position(t, "USDT").Quantity - cashBefore; !approxEqual(change, wantCashChange) {
+				t.Errorf("cash changed by %g, want %g", change, wantCashChange)
+			}
+		})
+	}
+}
+
+func TestLimitOrdersDontSlip(t *testing.T) {
+	feeRate := 0.001
+	te := newTestOrderEngine(t, 10_000, ExecutionOptions{FeeRate: &feeRate, SlippageMode: SlippageFixed, SlippageBps: 100})
+	if _, err := te.PlaceLimitOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 1, 100, "", testStartTime-minute); err != nil {
+		t.Fatalf("PlaceLimitOrder: %v", err)
+	}
+	te.processCandles(t, "BTCUSDT", testutil.Candles(testStartTime, "1m", 99))
+
+	trades := te.trades(t)
+	if len(trades) != 1 {
+		t.Fatalf("got %d trades, want 1", len(trades))
+	}
+	if trades[0].Price != 99 || trades[0].Slippage != 0 {
+		t.Errorf("limit fill at %g with %g slippage, want 99 without slippage", trades[0].Price, trades[0].Slippage)
+	}
+}
+
+func TestExecuteFillWithFee(t *testing.T) {
+	tests := []struct {
+		name           string
+		side           models.OrderSide
+		price          float64
+		fee            float64
+		slippage       float64
+		wantCashChange float64
+		wantPosition   float64
+	}{
+		{name: "buy pays the fee on top", side: models.OrderSideBuy, price: 100, fee: 0.5, wantCashChange: -200.5, wantPosition: 3},
+		{name: "buy without fee", side: models.OrderSideBuy, price: 100, wantCashChange: -200, wantPosition: 3},
+		{name: "sell deducts the fee", side: models.OrderSideSell, price: 100, fee: 0.5, wantCashChange: 199.5, wantPosition: -1},
+		{name: "slipped buy", side: models.OrderSideBuy, price: 101, fee: 0.202, slippage: 1, wantCashChange: -202.202, wantPosition: 3},
+		{name: "slipped sell", side: models.OrderSideSell, price: 99, fee: 0.198, slippage: 1, wantCashChange: 197.802, wantPosition: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := newTestOrderEngine(t, 10_000, ExecutionOptions{})
+			// One BTC held before the fill
+			if _, _, err := te.ExecuteMarketOrder(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 1, 100, "", testStartTime); err != nil {
+				t.Fatalf("ExecuteMarketOrder: %v", err)
+			}
+			simulationID := testSimulationID
+			order := &models.Order{
+				UserID:       1,
+				SimulationID: &simulationID,
+				Symbol:       "BTCUSDT",
+				BaseCurrency: "USDT",
+				Side:         tt.side,
+				Type:         models.OrderTypeMarket,
+				Quantity:     2,
+				Status:       models.OrderStatusPending,
+			}
+			if err := te.orderDAO.Create(order); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			cashBefore := te.position(t, "USDT").Quantity
+			trade, err := te.executeFillWithFee(testutil.NewDB(), order, 2, tt.price, tt.fee, tt.slippage, testStartTime+minute)
+			if err != nil {
+				t.Fatalf("executeFillWithFee: %v", err)
+			}
+
+			if trade.Price != tt.price || trade.Fee != tt.fee || trade.Slippage != tt.slippage || trade.Quantity != 2 {
+				t.Errorf("trade = %g at %g, fee %g, slippage %g; want 2 at %g, fee %g, slippage %g",
+					trade.Quantity, trade.Price, trade.Fee, trade.Slippage, tt.price, tt.fee, tt.slippage)
+			}
+			if change := te.position(t, "USDT").Quantity - cashBefore; !approxEqual(change, tt.wantCashChange) {
+				t.Errorf("cash changed by %g, want %g", change, tt.wantCashChange)
+			}
+			if position := te.position(t, "BTCUSDT").Quantity; !approxEqual(position, tt.wantPosition) {
+				t.Errorf("position = %g, want %g", position, tt.wantPosition)
+			}
+			if order.Status != models.OrderStatusExecuted || *order.ExecutedPrice != tt.price {
+				t.Errorf("order %s at %v, want executed at %g", order.Status, order.ExecutedPrice, tt.price)
+			}
+		})
+	}
+}
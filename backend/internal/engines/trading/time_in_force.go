@@ -0,0 +1,145 @@
+package trading
+
+import (
+	"fmt"
+	"log"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/types"
+)
+
+// LimitOrderOptions holds the optional time in force settings of a limit order
+type LimitOrderOptions struct {
+	TimeInForce  string // GTC (default), IOC or FOK
+	ExpireTime   *int64 // Simulation time in milliseconds after which a GTC order is cancelled
+	CurrentPrice float64
+}
+
+// PlaceLimitOrderWithOptions places a limit order honoring its time in force
+// GTC orders rest in the order book until filled, cancelled or expired; IOC and FOK orders fill
+// at placement when the current price reaches the limit. Orders fill in full or not at all, so an
+// IOC order that can't fill is cancelled and an FOK order that can't fill is rejected
+func (oe *OrderExecutionEngine) PlaceLimitOrderWithOptions(userID, simulationID uint, symbol string, side models.OrderSide, quantity, limitPrice float64, options LimitOrderOptions, tag string, simulationTime int64) (*models.Order, error) {
+	timeInForce := options.TimeInForce
+	if timeInForce == "" {
+		timeInForce = models.TimeInForceGTC
+	}
+
+	switch timeInForce {
+	case models.TimeInForceGTC:
+		params := models.OrderParameters{LimitPrice: &limitPrice}
+		if options.ExpireTime != nil {
+			if *options.ExpireTime <= simulationTime {
+				return nil, fmt.Errorf("expire time %d must be after the current simulation time %d", *options.ExpireTime, simulationTime)
+			}
+			params.TimeInForce = &timeInForce
+			params.ExpireTime = options.ExpireTime
+		}
+		return oe.placeRestingLimitOrder(userID, simulationID, symbol, side, quantity, params, tag, simulationTime)
+	case models.TimeInForceIOC, models.TimeInForceFOK:
+		if options.ExpireTime != nil {
+			return nil, fmt.Errorf("expire time only applies to GTC orders")
+		}
+		return oe.placeImmediateLimitOrder(userID, simulationID, symbol, side, quantity, limitPrice, timeInForce, options.CurrentPrice, tag, simulationTime)
+	default:
+		return nil, fmt.Errorf("invalid time in force: %s, must be '%s', '%s' or '%s'", timeInForce, models.TimeInForceGTC, models.TimeInForceIOC, models.TimeInForceFOK)
+	}
+}
+
+// placeImmediateLimitOrder fills an IOC or FOK limit order at the current price if it reaches the limit
+func (oe *OrderExecutionEngine) placeImmediateLimitOrder(userID, simulationID uint, symbol string, side models.OrderSide, quantity, limitPrice float64, timeInForce string, currentPrice float64, tag string, simulationTime int64) (*models.Order, error) {
+	if currentPrice <= 0 {
+		return nil, fmt.Errorf("invalid current price: %f", currentPrice)
+	}
+	if err := oe.ValidateLimitOrder(userID, simulationID, symbol, side, quantity, limitPrice, 0); err != nil {
+		return nil, fmt.Errorf("limit order validation failed: %w", err)
+	}
+
+	marketable := currentPrice <= limitPrice
+	if side == models.OrderSideSell {
+		marketable = currentPrice >= limitPrice
+	}
+	if !marketable && timeInForce == models.TimeInForceFOK {
+		return nil, fmt.Errorf("FOK limit order can't fill at the current price %.8f (limit %.8f)", currentPrice, limitPrice)
+	}
+
+	order := &models.Order{
+		UserID:       userID,
+		SimulationID: &simulationID,
+		Symbol:       symbol,
+		BaseCurrency: "USDT", // Default to USDT for now
+		Side:         side,
+		Type:         models.OrderTypeLimit,
+		Quantity:     quantity,
+		Status:       models.OrderStatusPending,
+		PlacedAt:     simulationTime,
+		Tag:          tag,
+		OrderParams: models.OrderParameters{
+			LimitPrice:  &limitPrice,
+			TimeInForce: &timeInForce,
+		},
+	}
+
+	// An IOC order that can't fill has nothing left to rest and is cancelled straight away
+	if !marketable {
+		order.Status = models.OrderStatusCancelled
+		if err := oe.orderDAO.Create(order); err != nil {
+			return nil, fmt.Errorf("failed to create limit order: %w", err)
+		}
+		log.Printf("Cancelled IOC limit order %d: %s %s at %.8f can't fill at %.8f",
+			order.ID, string(side), symbol, limitPrice, currentPrice)
+		oe.sendOrderUpdate(types.OrderCancelled, order, nil)
+		return order, nil
+	}
+
+	// The order takes the resting price, just like a market order
+	if err := oe.ValidateOrder(userID, simulationID, symbol, side, quantity, currentPrice); err != nil {
+		return nil, fmt.Errorf("limit order validation failed: %w", err)
+	}
+
+	tx := oe.db.Begin()
+	if tx.Error != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", tx.Error)
+	}
+
+	if err := oe.orderDAO.CreateWithTx(tx, order); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create limit order: %w", err)
+	}
+
+	trade, err := oe.executeOrder(tx, order, currentPrice, simulationTime)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to execute order: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	oe.tradeStats.record(trade)
+
+	log.Printf("%s limit order %d filled at placement at %.8f (limit was %.8f)", timeInForce, order.ID, currentPrice, limitPrice)
+
+	oe.sendOrderUpdate(types.OrderPlaced, order, nil)
+	oe.sendOrderUpdate(types.OrderExecuted, order, trade)
+
+	return order, nil
+}
+
+// expireLimitOrders cancels resting limit orders whose expire time has been reached at simulationTime
+// Expiry uses simulation time so it stays correct at any playback speed
+func (oe *OrderExecutionEngine) expireLimitOrders(simulationTime int64) {
+	if oe.orderBook == nil {
+		return
+	}
+
+	for _, order := range oe.orderBook.RemoveExpiredOrders(simulationTime) {
+		order.Status = models.OrderStatusCancelled
+		if err := oe.orderDAO.Update(order); err != nil {
+			log.Printf("Failed to mark expired order %d as cancelled: %v", order.ID, err)
+		}
+
+		log.Printf("Limit order %d expired at simulation time %d", order.ID, simulationTime)
+		oe.sendOrderUpdate(types.OrderCancelled, order, nil)
+	}
+}
@@ -0,0 +1,131 @@
+package trading
+
+import (
+	"testing"
+
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/testutil"
+	"tradesimulator/internal/types"
+)
+
+// orderStatus reads an order's stored status
+func (te *testOrderEngine) orderStatus(t *testing.T, orderID uint) models.OrderStatus {
+	t.Helper()
+	order, err := te.orderDAO.GetByID(orderID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	return order.Status
+}
+
+// Expiry follows simulation time: the order rests until its expire time and is cancelled once updates reach it
+func TestLimitOrderExpiresAtSimulationTime(t *testing.T) {
+	te := newTestOrderEngine(t, 10_000, ExecutionOptions{})
+	expireTime := testStartTime + 5*minute
+
+	order, err := te.PlaceLimitOrderWithOptions(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 1, 90,
+		LimitOrderOptions{ExpireTime: &expireTime, CurrentPrice: 100}, "", testStartTime)
+	if err != nil {
+		t.Fatalf("PlaceLimitOrderWithOptions: %v", err)
+	}
+
+	if _, err := te.ProcessPriceUpdate("BTCUSDT", 100, expireTime-1); err != nil {
+		t.Fatalf("ProcessPriceUpdate: %v", err)
+	}
+	if status := te.orderStatus(t, order.ID); status != models.OrderStatusPending {
+		t.Fatalf("status before the expire time = %s, want pending", status)
+	}
+
+	// The limit is reached at the expire time itself, but the order has already expired
+	trades, err := te.ProcessPriceUpdate("BTCUSDT", 80, expireTime)
+	if err != nil {
+		t.Fatalf("ProcessPriceUpdate: %v", err)
+	}
+	if len(trades) != 0 {
+		t.Errorf("got %d trades, want the expired order not to fill", len(trades))
+	}
+	if status := te.orderStatus(t, order.ID); status != models.OrderStatusCancelled {
+		t.Errorf("status at the expire time = %s, want cancelled", status)
+	}
+	if cancelled := te.client.Messages(types.OrderCancelled); len(cancelled) != 1 {
+		t.Errorf("sent %d order_cancelled messages, want 1", len(cancelled))
+	}
+	if count := te.orderBook.GetOrderCount(); count != 0 {
+		t.Errorf("order book holds %d orders, want the expired order removed", count)
+	}
+
+	// Later updates neither fill nor cancel it again
+	te.ProcessPriceUpdate("BTCUSDT", 80, expireTime+minute)
+	if len(te.trades(t)) != 0 || len(te.client.Messages(types.OrderCancelled)) != 1 {
+		t.Error("expired order acted on after it was cancelled")
+	}
+}
+
+// Orders expiring within a candle are cancelled before its range is checked, even when the range reached them
+func TestLimitOrderExpiresWithinCandle(t *testing.T) {
+	te := newTestOrderEngine(t, 10_000, ExecutionOptions{})
+	expireTime := testStartTime + minute/2
+
+	order, err := te.PlaceLimitOrderWithOptions(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 1, 90,
+		LimitOrderOptions{ExpireTime: &expireTime, CurrentPrice: 100}, "", testStartTime-minute/2)
+	if err != nil {
+		t.Fatalf("PlaceLimitOrderWithOptions: %v", err)
+	}
+	te.processCandles(t, "BTCUSDT", []models.OHLCV{testutil.Candle(testStartTime, "1m", 100, 100, 85, 95)})
+	if len(te.trades(t)) != 0 {
+		t.Error("order filled within the candle it expired in")
+	}
+
+	if status := te.orderStatus(t, order.ID); status != models.OrderStatusCancelled {
+		t.Errorf("status after a candle ending past the expire time = %s, want cancelled", status)
+	}
+}
+
+func TestTimeInForce(t *testing.T) {
+	expireTime, pastExpireTime := testStartTime+minute, testStartTime
+	tests := []struct {
+		name       string
+		options    LimitOrderOptions
+		limit      float64
+		wantErr    bool
+		wantStatus models.OrderStatus
+		wantTrades int
+	}{
+		{name: "IOC fills when marketable", options: LimitOrderOptions{TimeInForce: models.TimeInForceIOC, CurrentPrice: 100}, limit: 101, wantStatus: models.OrderStatusExecuted, wantTrades: 1},
+		{name: "IOC is cancelled when it can't fill", options: LimitOrderOptions{TimeInForce: models.TimeInForceIOC, CurrentPrice: 100}, limit: 99, wantStatus: models.OrderStatusCancelled},
+		{name: "FOK fills when marketable", options: LimitOrderOptions{TimeInForce: models.TimeInForceFOK, CurrentPrice: 100}, limit: 100, wantStatus: models.OrderStatusExecuted, wantTrades: 1},
+		{name: "FOK is rejected when it can't fill", options: LimitOrderOptions{TimeInForce: models.TimeInForceFOK, CurrentPrice: 100}, limit: 99, wantErr: true},
+		{name: "GTC rests by default", options: LimitOrderOptions{CurrentPrice: 100}, limit: 99, wantStatus: models.OrderStatusPending},
+		{name: "expire time only applies to GTC", options: LimitOrderOptions{TimeInForce: models.TimeInForceIOC, ExpireTime: &expireTime, CurrentPrice: 100}, limit: 101, wantErr: true},
+		{name: "expire time must be in the future", options: LimitOrderOptions{ExpireTime: &pastExpireTime, CurrentPrice: 100}, limit: 99, wantErr: true},
+		{name: "unknown time in force", options: LimitOrderOptions{TimeInForce: "GTD", CurrentPrice: 100}, limit: 99, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := newTestOrderEngine(t, 10_000, ExecutionOptions{})
+			order, err := te.PlaceLimitOrderWithOptions(1, testSimulationID, "BTCUSDT", models.OrderSideBuy, 1, tt.limit, tt.options, "", testStartTime)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("order accepted, want an error")
+				}
+				if orders, _ := te.orderDAO.GetUserOrders(1, testSimulationID, 0); len(orders) != 0 {
+					t.Errorf("stored %d orders, want none", len(orders))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PlaceLimitOrderWithOptions: %v", err)
+			}
+			if status := te.orderStatus(t, order.ID); status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", status, tt.wantStatus)
+			}
+			if trades := te.trades(t); len(trades) != tt.wantTrades {
+				t.Errorf("got %d trades, want %d", len(trades), tt.wantTrades)
+			}
+			if tt.wantStatus != models.OrderStatusPending && te.orderBook.GetOrderCount() != 0 {
+				t.Error("order left resting in the book")
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package trading
+
+import (
+	"fmt"
+	"sync"
+
+	"tradesimulator/internal/models"
+)
+
+// TradeStats is a snapshot of the trade flow recorded by the execution engine
+type TradeStats struct {
+	TradeCount    int     `json:"tradeCount"`
+	ClosingTrades int     `json:"closingTrades"` // Trades that reduced or closed a position
+	WinningTrades int     `json:"winningTrades"` // Closing trades with positive realized P&L after fees
+	RealizedPnL   float64 `json:"realizedPnl"`   // Realized P&L net of fees, fees enter the cost basis of each lot
+	TotalFees     float64 `json:"totalFees"`
+}
+
+// WinRate returns the percentage of closing trades that were profitable
+func (ts TradeStats) WinRate() float64 {
+	if ts.ClosingTrades == 0 {
+		return 0
+	}
+	return float64(ts.WinningTrades) / float64(ts.ClosingTrades) * 100
+}
+
+// tradeStatsTracker accumulates trade statistics incrementally as trades are executed
+type tradeStatsTracker struct {
+	mu     sync.Mutex
+	stats  TradeStats
+	ledger *models.CostBasisLedger
+}
+
+func newTradeStatsTracker() *tradeStatsTracker {
+	return &tradeStatsTracker{
+		ledger: models.NewCostBasisLedger(models.GetCostBasisMethod()),
+	}
+}
+
+// record folds a committed trade into the running statistics
+func (t *tradeStatsTracker) record(trade *models.Trade) {
+	if trade == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stats.TradeCount++
+	t.stats.TotalFees += trade.Fee
+
+	realized, closing := t.ledger.Apply(trade)
+	if !closing {
+		return
+	}
+	t.stats.RealizedPnL += realized
+	t.stats.ClosingTrades++
+	if realized > 0 {
+		t.stats.WinningTrades++
+	}
+}
+
+// snapshot returns the current statistics
+func (t *tradeStatsTracker) snapshot() TradeStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// unrealizedPnL values the tracked open positions at the given prices
+// Symbols without a price are skipped
+func (t *tradeStatsTracker) unrealizedPnL(prices map[string]float64) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.ledger.UnrealizedPnL(prices)
+}
+
+// reset clears all statistics and tracked positions
+func (t *tradeStatsTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats = TradeStats{}
+	t.ledger = models.NewCostBasisLedger(models.GetCostBasisMethod())
+}
+
+// GetTradeStats returns the trade statistics recorded since the last reset
+func (oe *OrderExecutionEngine) GetTradeStats() TradeStats {
+	return oe.tradeStats.snapshot()
+}
+
+// GetUnrealizedPnL values positions opened since the last stats reset at the given prices
+func (oe *OrderExecutionEngine) GetUnrealizedPnL(prices map[string]float64) float64 {
+	return oe.tradeStats.unrealizedPnL(prices)
+}
+
+// ResetTradeStats clears the recorded trade statistics and replays the simulation's existing trades
+// so resumed simulations keep their positions' entry prices and earlier results
+func (oe *OrderExecutionEngine) ResetTradeStats(simulationID uint) error {
+	oe.tradeStats.reset()
+
+	if simulationID == 0 || oe.tradeDAO == nil {
+		return nil
+	}
+
+	trades, err := oe.tradeDAO.GetUserTrades(1, simulationID, 0)
+	if err != nil {
+		return fmt.Errorf("failed to load trades for stats: %w", err)
+	}
+
+	// Trades are returned newest first
+	for i := len(trades) - 1; i >= 0; i-- {
+		oe.tradeStats.record(&trades[i])
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	wsHandlers "tradesimulator/internal/handlers/websocket"
+	"tradesimulator/internal/services/market"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OrderBookResetter rebuilds the in-memory order book of a running simulation
+type OrderBookResetter interface {
+	ResetOrderBook(simulationID uint) error
+}
+
+// ActiveSimulationLister lists the simulation engines held in memory
+type ActiveSimulationLister interface {
+	ListActiveSimulations() []wsHandlers.ActiveSimulation
+}
+
+// AdminHandler exposes diagnostic endpoints for operators
+type AdminHandler struct {
+	marketDataService market.MarketDataServiceInterface
+	orderBookResetter OrderBookResetter
+	simulationLister  ActiveSimulationLister
+}
+
+func NewAdminHandler(marketDataService market.MarketDataServiceInterface, orderBookResetter OrderBookResetter, simulationLister ActiveSimulationLister) *AdminHandler {
+	return &AdminHandler{
+		marketDataService: marketDataService,
+		orderBookResetter: orderBookResetter,
+		simulationLister:  simulationLister,
+	}
+}
+
+// GetBinanceRequests handles GET /api/v1/admin/binance/requests
+// @Summary Get Binance Request Log
+// @Description Get counters and recent entries of requests made to Binance. Requires BINANCE_REQUEST_LOG=true
+// @Tags admin
+// @Produce json
+// @Success 200 {object} binance.RequestLogSnapshot "Request log"
+// @Failure 404 {object} map[string]interface{} "Request log disabled"
+// @Router /admin/binance/requests [get]
+func (ah *AdminHandler) GetBinanceRequests(c *gin.Context) {
+	snapshot, enabled := ah.marketDataService.GetUpstreamRequestLog()
+	if !enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "binance request log is disabled, set BINANCE_REQUEST_LOG=true to enable"})
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// GetKlineCacheStats handles GET /api/v1/admin/binance/cache
+// @Summary Get Kline Cache Statistics
+// @Description Get hit and miss counters and the size of the in-memory kline cache that serves repeated historical data requests without calling Binance
+// @Tags admin
+// @Produce json
+// @Success 200 {object} binance.KlineCacheStats "Kline cache statistics"
+// @Failure 401 {object} map[string]interface{} "Missing or invalid admin token"
+// @Router /admin/binance/cache [get]
+func (ah *AdminHandler) GetKlineCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, ah.marketDataService.GetKlineCacheStats())
+}
+
+// ResetOrderBook handles POST /api/v1/admin/simulations/:id/order-book/reset
+// @Summary Reset Simulation Order Book
+// @Description Discard the in-memory order book of a running simulation and reload its pending orders from the database. Stored orders are not modified
+// @Tags admin
+// @Produce json
+// @Param id path int true "Simulation ID"
+// @Success 200 {object} map[string]interface{} "Order book reset"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Simulation not running on a connected client"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/simulations/{id}/order-book/reset [post]
+func (ah *AdminHandler) ResetOrderBook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid simulation ID"})
+		return
+	}
+
+	if err := ah.orderBookResetter.ResetOrderBook(uint(id)); err != nil {
+		if errors.Is(err, wsHandlers.ErrSimulationNotConnected) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"simulationId": id,
+		"message":      "order book reloaded from database",
+	})
+}
+
+// GetActiveSimulations handles GET /api/v1/admin/active-simulations
+// @Summary List Active In-Memory Simulations
+// @Description List every simulation engine held in memory with its client, state, speed, simulation time and candle buffer, including engines left running after their client disconnected
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Active simulations"
+// @Failure 401 {object} map[string]interface{} "Missing or invalid admin token"
+// @Router /admin/active-simulations [get]
+func (ah *AdminHandler) GetActiveSimulations(c *gin.Context) {
+	simulations := ah.simulationLister.ListActiveSimulations()
+	c.JSON(http.StatusOK, gin.H{
+		"simulations": simulations,
+		"count":       len(simulations),
+	})
+}
+
+// requireAdminToken rejects requests without the configured bearer token
+// An empty token leaves the admin routes open, as in local development
+func requireAdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid admin token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RegisterAdminRoutes registers admin routes, guarded by adminToken when set
+func RegisterAdminRoutes(router *gin.RouterGroup, handler *AdminHandler, adminToken string) {
+	admin := router.Group("/admin", requireAdminToken(adminToken))
+	{
+		admin.GET("/binance/requests", handler.GetBinanceRequests)
+		admin.GET("/binance/cache", handler.GetKlineCacheStats)
+		admin.GET("/active-simulations", handler.GetActiveSimulations)
+		admin.POST("/simulations/:id/order-book/reset", handler.ResetOrderBook)
+	}
+}
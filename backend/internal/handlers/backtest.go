@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	simulationEngine "tradesimulator/internal/engines/simulation"
+	wsHandlers "tradesimulator/internal/handlers/websocket"
+	"tradesimulator/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BacktestRunner plays simulations to completion without a client
+type BacktestRunner interface {
+	SimulationStartValidator
+	RunBacktest(ctx context.Context, startData wsHandlers.SimulationStartData, strategy simulationEngine.BacktestStrategy) (*simulationEngine.BacktestResult, error)
+}
+
+// BacktestRequest is a simulation start configuration with a required end time and the strategy to play it with
+type BacktestRequest struct {
+	wsHandlers.SimulationStartData
+	Strategy      simulationEngine.BacktestStrategyConfig `json:"strategy"`
+	CompareSizing bool                                    `json:"compareSizing,omitempty"` // Also play the strategy with the other sizing
+}
+
+// BacktestResponse is a completed backtest with its performance metrics
+type BacktestResponse struct {
+	*simulationEngine.BacktestResult
+	Metrics          *services.PerformanceMetrics `json:"metrics"`
+	SizingComparison *SizingComparison            `json:"sizingComparison,omitempty"`
+}
+
+// SizingComparison is the final value of the same backtest played with compounding and with fixed sizing
+type SizingComparison struct {
+	Compounding            float64 `json:"compounding"`
+	Fixed                  float64 `json:"fixed"`
+	Difference             float64 `json:"difference"`             // Compounding minus fixed
+	ComparisonSimulationID uint    `json:"comparisonSimulationId"` // Simulation played with the other sizing
+}
+
+// BacktestHandler runs backtests over the REST API
+type BacktestHandler struct {
+	runner         BacktestRunner
+	metricsService *services.MetricsService
+}
+
+// NewBacktestHandler creates a new backtest handler
+func NewBacktestHandler(runner BacktestRunner, metricsService *services.MetricsService) *BacktestHandler {
+	return &BacktestHandler{
+		runner:         runner,
+		metricsService: metricsService,
+	}
+}
+
+// RunBacktest handles POST /api/v1/backtests
+// @Summary Run Backtest
+// @Description Play a simulation from startTime to endTime as fast as candles can be processed, trading with a built-in strategy (buy_and_hold, or sma_crossover with fastPeriod and slowPeriod) sized by compounding all cash or a fixed stake of the first buy, and return the completed simulation with its trade stats and performance metrics. Takes the simulation_control_start message data plus a strategy; endTime is required, loop is not supported and market buys are clamped to the affordable quantity. With compareSizing, the backtest is played again with the other sizing and the difference in final value is reported. The request blocks until the backtest completes
+// @Tags backtests
+// @Accept json
+// @Produce json
+// @Param request body BacktestRequest true "Backtest configuration"
+// @Success 200 {object} BacktestResponse "Completed backtest"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /backtests [post]
+func (bh *BacktestHandler) RunBacktest(c *gin.Context) {
+	// For now, use default user ID 1
+	userID := uint(1)
+
+	var request BacktestRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	strategy, err := simulationEngine.NewBacktestStrategy(request.Strategy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.EndTime == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endTime is required"})
+		return
+	}
+	if request.Loop {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "backtests cannot loop"})
+		return
+	}
+
+	// Built-in strategies buy with all available cash, which only fits once fees are taken into account
+	request.ClampToAffordable = true
+
+	if issues := bh.runner.ValidateSimulationStart(request.SimulationStartData); len(issues) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": strings.Join(issues, "; "), "issues": issues})
+		return
+	}
+
+	// The backtest stops when the client disconnects, rather than playing to the end for nobody
+	result, err := bh.runner.RunBacktest(c.Request.Context(), request.SimulationStartData, strategy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	metrics, err := bh.metricsService.GetPerformanceMetrics(userID, result.Simulation.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := BacktestResponse{
+		BacktestResult: result,
+		Metrics:        metrics,
+	}
+	if request.CompareSizing {
+		comparison, err := bh.compareSizing(c.Request.Context(), request, result)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		response.SizingComparison = comparison
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// compareSizing plays the backtest again with the sizing the request didn't use and compares the final values
+func (bh *BacktestHandler) compareSizing(ctx context.Context, request BacktestRequest, result *simulationEngine.BacktestResult) (*SizingComparison, error) {
+	other := request.Strategy
+	if other.Sizing == simulationEngine.SizingFixed {
+		other.Sizing = simulationEngine.SizingCompounding
+	} else {
+		other.Sizing = simulationEngine.SizingFixed
+	}
+	strategy, err := simulationEngine.NewBacktestStrategy(other)
+	if err != nil {
+		return nil, err
+	}
+	otherResult, err := bh.runner.RunBacktest(ctx, request.SimulationStartData, strategy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s sizing backtest: %w", other.Sizing, err)
+	}
+
+	comparison := &SizingComparison{ComparisonSimulationID: otherResult.Simulation.ID}
+	if other.Sizing == simulationEngine.SizingFixed {
+		comparison.Compounding, comparison.Fixed = finalValue(result), finalValue(otherResult)
+	} else {
+		comparison.Compounding, comparison.Fixed = finalValue(otherResult), finalValue(result)
+	}
+	comparison.Difference = comparison.Compounding - comparison.Fixed
+	return comparison, nil
+}
+
+// finalValue returns the portfolio value a backtest completed with
+func finalValue(result *simulationEngine.BacktestResult) float64 {
+	if result.Simulation.TotalValue == nil {
+		return result.Simulation.InitialFunding
+	}
+	return *result.Simulation.TotalValue
+}
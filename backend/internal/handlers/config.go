@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"tradesimulator/internal/engines/trading"
+
+	"github.com/gin-gonic/gin"
+)
+
+// configCacheMaxAge is how long clients may reuse the server configuration without revalidating, in seconds
+const configCacheMaxAge = 300
+
+// ServerLimits are the caps simulations, orders and market data requests are validated against
+type ServerLimits struct {
+	MaxSimulationSpeed    int `json:"maxSimulationSpeed"`    // Market seconds per real second
+	MaxHistoricalCandles  int `json:"maxHistoricalCandles"`  // Candles returned by one historical data request
+	MaxReplayCandles      int `json:"maxReplayCandles"`      // Candles returned by one simulation replay request
+	MaxExtraIntervals     int `json:"maxExtraIntervals"`     // Extra intervals streamed alongside a simulation's base interval
+	MaxExtraSymbols       int `json:"maxExtraSymbols"`       // Extra symbols played alongside a simulation's main symbol
+	MaxWarmupBars         int `json:"maxWarmupBars"`         // Base candles before the start time a simulation can preload
+	MaxPendingLimitOrders int `json:"maxPendingLimitOrders"` // Pending limit orders per symbol, 0 is unlimited
+	MaxLeverage           int `json:"maxLeverage"`
+	MaxOrderTagLength     int `json:"maxOrderTagLength"`
+	MaxAnnotationLength   int `json:"maxAnnotationLength"`
+	WebSocketReadLimit    int `json:"webSocketReadLimit"` // Largest WebSocket message in bytes a client may send
+}
+
+// ServerFees are the fee rates charged to simulations that don't set their own feeRate
+type ServerFees struct {
+	Default  trading.FeeTier     `json:"default"`
+	Schedule trading.FeeSchedule `json:"schedule"` // Per symbol tiers overriding the default
+	Rounding trading.FeeRounding `json:"rounding"`
+	Decimals int                 `json:"decimals"`
+}
+
+// ServerConfig is the non-secret server configuration clients build their UIs from
+// It must never carry the database URL, API tokens or other credentials, and holds nothing that changes while
+// the server runs; symbols can be imported at runtime and are served by GET /market/symbols instead
+type ServerConfig struct {
+	Environment                  string       `json:"environment"`
+	MarketDataProvider           string       `json:"marketDataProvider"`
+	Intervals                    []string     `json:"intervals"` // Shortest first
+	Limits                       ServerLimits `json:"limits"`
+	Fees                         ServerFees   `json:"fees"`
+	CostBasisMethod              string       `json:"costBasisMethod"`
+	ValueDecimals                int          `json:"valueDecimals"`
+	SimulationDisconnectBehavior string       `json:"simulationDisconnectBehavior"`
+	PausedOrderBehavior          string       `json:"pausedOrderBehavior"`
+	StopOrderBehavior            string       `json:"stopOrderBehavior"`
+	ClampMarketDataToSimTime     bool         `json:"clampMarketDataToSimTime"`
+}
+
+// ConfigHandler serves the server configuration, encoded once since it doesn't change while the server runs
+type ConfigHandler struct {
+	body []byte
+	etag string
+}
+
+func NewConfigHandler(config ServerConfig) (*ConfigHandler, error) {
+	body, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode server config: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	return &ConfigHandler{
+		body: body,
+		etag: `"` + hex.EncodeToString(sum[:8]) + `"`,
+	}, nil
+}
+
+// GetConfig returns the server's limits and non-secret configuration
+// @Summary Get Server Configuration
+// @Description Get the supported intervals, simulation and order limits and default fees, so clients don't hardcode them. Responses carry an ETag and may be cached for 5 minutes
+// @Tags config
+// @Produce json
+// @Param If-None-Match header string false "ETag of a cached response"
+// @Success 200 {object} ServerConfig "Server configuration"
+// @Success 304 "Cached response is current"
+// @Router /config [get]
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", configCacheMaxAge))
+	c.Header("ETag", h.etag)
+	if c.GetHeader("If-None-Match") == h.etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.body)
+}
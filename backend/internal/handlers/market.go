@@ -1,22 +1,41 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"tradesimulator/internal/integrations/binance"
+	"tradesimulator/internal/integrations/marketdata"
 	"tradesimulator/internal/models"
+	"tradesimulator/internal/services"
 	"tradesimulator/internal/services/market"
 )
 
+const (
+	defaultRandomStartMinDuration   = 24 * time.Hour
+	defaultRandomStartExcludeRecent = 30 * 24 * time.Hour
+
+	// maxImportBodyBytes bounds the size of an uploaded candle CSV
+	maxImportBodyBytes = 64 << 20
+
+	// MaxHistoricalLimit is the most candles a single historical data request returns
+	MaxHistoricalLimit = 1000
+)
+
 type MarketHandler struct {
 	marketDataService market.MarketDataServiceInterface
+	cutoffRegistry     *services.MarketCutoffRegistry
 }
 
-func NewMarketHandler(marketDataService market.MarketDataServiceInterface) *MarketHandler {
+func NewMarketHandler(marketDataService market.MarketDataServiceInterface, cutoffRegistry *services.MarketCutoffRegistry) *MarketHandler {
 	return &MarketHandler{
 		marketDataService: marketDataService,
+		cutoffRegistry:     cutoffRegistry,
 	}
 }
 
@@ -25,7 +44,7 @@ func NewMarketHandler(marketDataService market.MarketDataServiceInterface) *Mark
 // @Description Fetch historical OHLCV data for supported trading pairs (BTCUSDT, ETHUSDT)
 // @Tags market
 // @Produce json
-// @Param symbol query string true "Trading symbol" Enums(BTCUSDT,ETHUSDT)
+// @Param symbol query string true "Trading symbol, one of GET /market/symbols" example(BTCUSDT)
 // @Param interval query string false "Kline interval" default(1h) Enums(1m,3m,5m,15m,30m,1h,2h,4h,6h,8h,12h,1d,3d,1w,1M)
 // @Param limit query int false "Number of klines to return (1-1000)" default(1000) minimum(1) maximum(1000)
 // @Param startTime query int false "Start time in milliseconds"
@@ -37,7 +56,7 @@ func NewMarketHandler(marketDataService market.MarketDataServiceInterface) *Mark
 // @Router /market/historical [get]
 func (h *MarketHandler) GetHistoricalData(c *gin.Context) {
 	// Get query parameters
-	symbol := c.Query("symbol")
+	symbol := models.NormalizeSymbol(c.Query("symbol"))
 	if symbol == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "symbol parameter is required",
@@ -57,9 +76,9 @@ func (h *MarketHandler) GetHistoricalData(c *gin.Context) {
 	}
 
 	// Parse optional limit parameter
-	limit := 1000 // Default limit
+	limit := MaxHistoricalLimit // Default limit
 	if limitStr := c.Query("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 1000 {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= MaxHistoricalLimit {
 			limit = parsedLimit
 		}
 	}
@@ -86,8 +105,24 @@ func (h *MarketHandler) GetHistoricalData(c *gin.Context) {
 		}
 	}
 
-	// Fetch historical data
-	data, err := h.marketDataService.GetHistoricalData(symbol, interval, limit, startTime, endTime, enableIncomplete)
+	// An active replay must not see data past its current time
+	cutoff, restricted := h.marketCutoff()
+	if restricted {
+		if startTime != nil && *startTime > cutoff {
+			c.JSON(http.StatusOK, models.HistoricalDataResponse{
+				Symbol:      symbol,
+				Data:        []models.OHLCV{},
+				Cutoff: &cutoff,
+			})
+			return
+		}
+		if endTime == nil || *endTime > cutoff {
+			endTime = &cutoff
+		}
+	}
+
+	// Fetch historical data (falls back to cached candles if Binance is unreachable)
+	data, source, err := h.marketDataService.GetHistoricalDataWithSource(symbol, interval, limit, startTime, endTime, enableIncomplete)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -97,8 +132,14 @@ func (h *MarketHandler) GetHistoricalData(c *gin.Context) {
 
 	// Return response
 	response := models.HistoricalDataResponse{
-		Symbol: symbol,
-		Data:   data,
+		Symbol:    symbol,
+		Data:      data,
+		FromCache: source == binance.DataSourceCache || source == binance.DataSourceStaleCache,
+		Stale:     source == binance.DataSourceStaleCache,
+	}
+	if restricted {
+		response.Data = filterCandlesBefore(data, cutoff)
+		response.Cutoff = &cutoff
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -123,14 +164,14 @@ func (h *MarketHandler) GetSupportedSymbols(c *gin.Context) {
 // @Description Get the earliest available data timestamp for a specific trading symbol
 // @Tags market
 // @Produce json
-// @Param symbol path string true "Trading symbol" Enums(BTCUSDT,ETHUSDT)
+// @Param symbol path string true "Trading symbol, one of GET /market/symbols" example(BTCUSDT)
 // @Success 200 {object} models.EarliestTimeResponse "Earliest available time"
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /market/earliest-time/{symbol} [get]
 func (h *MarketHandler) GetEarliestTime(c *gin.Context) {
 	// Get symbol from URL parameter
-	symbol := c.Param("symbol")
+	symbol := models.NormalizeSymbol(c.Param("symbol"))
 	if symbol == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "symbol parameter is required",
@@ -158,4 +199,346 @@ func (h *MarketHandler) GetEarliestTime(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, response)
-}
\ No newline at end of file
+}
+
+// GetRandomStart handles GET /api/market/random-start requests
+// @Summary Get Random Start Time
+// @Description Pick a random simulation start time within the symbol's history that leaves at least minDuration of data after it and avoids the most recent period
+// @Tags market
+// @Produce json
+// @Param symbol query string true "Trading symbol, one of GET /market/symbols" example(BTCUSDT)
+// @Param minDuration query int false "Minimum replay duration in milliseconds (default: 1 day)"
+// @Param excludeRecent query int false "Most recent period to avoid in milliseconds (default: 30 days)"
+// @Success 200 {object} models.RandomStartResponse "Random start time"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /market/random-start [get]
+func (h *MarketHandler) GetRandomStart(c *gin.Context) {
+	symbol := models.NormalizeSymbol(c.Query("symbol"))
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "symbol parameter is required",
+		})
+		return
+	}
+
+	minDuration, err := strconv.ParseInt(c.DefaultQuery("minDuration", strconv.FormatInt(defaultRandomStartMinDuration.Milliseconds(), 10)), 10, 64)
+	if err != nil || minDuration < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "minDuration must be a non-negative duration in milliseconds",
+		})
+		return
+	}
+
+	excludeRecent, err := strconv.ParseInt(c.DefaultQuery("excludeRecent", strconv.FormatInt(defaultRandomStartExcludeRecent.Milliseconds(), 10)), 10, 64)
+	if err != nil || excludeRecent < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "excludeRecent must be a non-negative duration in milliseconds",
+		})
+		return
+	}
+
+	startTime, err := h.marketDataService.GetRandomStartTime(symbol, minDuration, excludeRecent)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, market.ErrNoValidStartTime) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RandomStartResponse{
+		Symbol:        symbol,
+		StartTime:     startTime,
+		StartTimeISO:  time.UnixMilli(startTime).UTC().Format(time.RFC3339),
+		MinDuration:   minDuration,
+		ExcludeRecent: excludeRecent,
+	})
+}
+
+// GetCandleAt handles GET /api/market/candle requests
+// @Summary Get Candle at Time
+// @Description Get the single candle of an interval that contains the given timestamp
+// @Tags market
+// @Produce json
+// @Param symbol query string true "Trading symbol, one of GET /market/symbols" example(BTCUSDT)
+// @Param interval query string false "Kline interval" default(1m)
+// @Param time query int true "Timestamp in milliseconds"
+// @Success 200 {object} models.OHLCV "Candle containing the timestamp"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 403 {object} map[string]interface{} "Time is past the current time of an active simulation"
+// @Failure 404 {object} map[string]interface{} "No data covers the timestamp"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /market/candle [get]
+func (h *MarketHandler) GetCandleAt(c *gin.Context) {
+	symbol := models.NormalizeSymbol(c.Query("symbol"))
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "symbol parameter is required",
+		})
+		return
+	}
+
+	interval := c.DefaultQuery("interval", "1m")
+	if !h.marketDataService.ValidateInterval(interval) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid interval. Valid intervals: 1m, 3m, 5m, 15m, 30m, 1h, 2h, 4h, 6h, 8h, 12h, 1d, 3d, 1w, 1M",
+		})
+		return
+	}
+
+	timestamp, err := strconv.ParseInt(c.Query("time"), 10, 64)
+	if err != nil || timestamp <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "time parameter is required and must be a timestamp in milliseconds",
+		})
+		return
+	}
+
+	cutoff, restricted := h.marketCutoff()
+	if restricted && timestamp > cutoff {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "requested time is past the current simulation time",
+		})
+		return
+	}
+
+	candle, err := h.marketDataService.GetCandleAt(symbol, interval, timestamp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if candle == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "no candle data covers the requested time",
+		})
+		return
+	}
+
+	// A candle still open at the cutoff would reveal its future close
+	if restricted && candle.EndTime > cutoff {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "candle closes after the current simulation time",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, candle)
+}
+
+// GetLatestPrices handles GET /api/market/prices requests
+// @Summary Get Latest Prices
+// @Description Get the current price of several symbols in one request
+// @Tags market
+// @Produce json
+// @Param symbols query string false "Comma-separated trading symbols (default: all supported symbols)"
+// @Success 200 {object} map[string]interface{} "Prices keyed by symbol"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 403 {object} map[string]interface{} "A simulation restricting market data is active"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /market/prices [get]
+func (h *MarketHandler) GetLatestPrices(c *gin.Context) {
+	supportedSymbols := h.marketDataService.GetSupportedSymbols()
+	supported := make(map[string]bool, len(supportedSymbols))
+	for _, symbol := range supportedSymbols {
+		supported[symbol] = true
+	}
+
+	var symbols []string
+	for _, symbol := range strings.Split(c.Query("symbols"), ",") {
+		if symbol = models.NormalizeSymbol(symbol); symbol != "" {
+			symbols = append(symbols, symbol)
+		}
+	}
+	if len(symbols) == 0 {
+		symbols = supportedSymbols
+	}
+
+	for _, symbol := range symbols {
+		if !supported[symbol] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("unsupported symbol: %s", symbol),
+			})
+			return
+		}
+	}
+
+	// Live prices are always ahead of a replay
+	if _, restricted := h.marketCutoff(); restricted {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "live prices are unavailable while a simulation is active",
+		})
+		return
+	}
+
+	prices, err := h.marketDataService.GetLatestPrices(symbols)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"prices": prices,
+	})
+}
+
+// GetWatchlist handles GET /api/market/watchlist requests
+// @Summary Get Symbol Watchlist
+// @Description Get the supported symbols with their latest price, 24h change and whether data is available for simulation. Cached briefly
+// @Tags market
+// @Produce json
+// @Success 200 {object} market.Watchlist "Watchlist entries"
+// @Failure 403 {object} map[string]interface{} "A simulation restricting market data is active"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /market/watchlist [get]
+func (h *MarketHandler) GetWatchlist(c *gin.Context) {
+	// Live prices are always ahead of a replay
+	if _, restricted := h.marketCutoff(); restricted {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "live prices are unavailable while a simulation is active",
+		})
+		return
+	}
+
+	watchlist, err := h.marketDataService.GetWatchlist()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, watchlist)
+}
+
+// PrefetchData handles POST /api/market/prefetch requests
+// @Summary Prefetch Market Data
+// @Description Load candles for a planned simulation into the cache in the background. Use the simulation's base interval to avoid lazy loading during replay
+// @Tags market
+// @Accept json
+// @Produce json
+// @Param request body market.PrefetchRequest true "Range to prefetch"
+// @Success 202 {object} market.PrefetchJob "Prefetch job started"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Router /market/prefetch [post]
+func (h *MarketHandler) PrefetchData(c *gin.Context) {
+	var req market.PrefetchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	job, err := h.marketDataService.StartPrefetch(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetPrefetchStatus handles GET /api/market/prefetch/:id requests
+// @Summary Get Prefetch Job Status
+// @Description Get progress of a background prefetch job. Finished jobs are kept for an hour, and only the 100 most recent of them
+// @Tags market
+// @Produce json
+// @Param id path string true "Prefetch job ID"
+// @Success 200 {object} market.PrefetchJob "Prefetch job status"
+// @Failure 404 {object} map[string]interface{} "Job not found"
+// @Router /market/prefetch/{id} [get]
+func (h *MarketHandler) GetPrefetchStatus(c *gin.Context) {
+	job, exists := h.marketDataService.GetPrefetchJob(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "prefetch job not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// marketCutoff returns the market time limit imposed by active simulations, if any
+func (h *MarketHandler) marketCutoff() (int64, bool) {
+	if h.cutoffRegistry == nil {
+		return 0, false
+	}
+	return h.cutoffRegistry.Cutoff()
+}
+
+// filterCandlesBefore drops complete candles that close after cutoff
+func filterCandlesBefore(candles []models.OHLCV, cutoff int64) []models.OHLCV {
+	filtered := make([]models.OHLCV, 0, len(candles))
+	for _, candle := range candles {
+		if candle.IsComplete && candle.EndTime > cutoff {
+			continue
+		}
+		filtered = append(filtered, candle)
+	}
+	return filtered
+}
+
+// ImportCandles handles POST /api/market/import requests
+// @Summary Import Candles
+// @Description Store a custom candle series for a symbol Binance doesn't serve, replacing an earlier import of the same symbol and interval. Simulations replay imported symbols like Binance data, aggregating larger intervals from the imported one. The body is CSV with startTime (Unix ms),open,high,low,close,volume rows and an optional header. Start times must be aligned to the interval and strictly increasing; gaps are allowed. Errors name the offending row, counting the header
+// @Tags market
+// @Accept text/csv
+// @Produce json
+// @Param symbol query string true "USDT pair to import as, not one Binance serves" example(SYNTHUSDT)
+// @Param interval query string true "Interval of the rows, e.g. 1m or 1h; 1w and 1M are not supported" example(1m)
+// @Param body body string true "Candle rows as CSV"
+// @Success 201 {object} marketDAO.ImportedSeries "Imported series"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 413 {object} map[string]interface{} "Body too large"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Failure 503 {object} map[string]interface{} "Imports not available"
+// @Router /market/import [post]
+func (h *MarketHandler) ImportCandles(c *gin.Context) {
+	symbol := c.Query("symbol")
+	interval := c.Query("interval")
+	if symbol == "" || interval == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "symbol and interval are required",
+		})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodyBytes)
+	series, err := h.marketDataService.ImportCandles(symbol, interval, body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("import body exceeds %d bytes", maxImportBodyBytes),
+			})
+		case errors.Is(err, marketdata.ErrInvalidImport):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		case errors.Is(err, market.ErrImportUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, series)
+}
@@ -1,25 +1,36 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
+	"tradesimulator/internal/engines/trading"
+	wsHandlers "tradesimulator/internal/handlers/websocket"
+	"tradesimulator/internal/models"
 	"tradesimulator/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
+// SimulationOrderCanceller cancels an order through the order engine of the client running its simulation
+type SimulationOrderCanceller interface {
+	CancelSimulationOrder(simulationID, orderID uint) (*models.Order, error)
+}
+
 // OrderHandler handles order-related HTTP and WebSocket requests
 type OrderHandler struct {
 	orderService      *services.OrderService
 	portfolioService  *services.PortfolioService
+	orderCanceller    SimulationOrderCanceller
 }
 
 
 // NewOrderHandler creates a new order handler
-func NewOrderHandler(orderService *services.OrderService, portfolioService *services.PortfolioService) *OrderHandler {
+func NewOrderHandler(orderService *services.OrderService, portfolioService *services.PortfolioService, orderCanceller SimulationOrderCanceller) *OrderHandler {
 	return &OrderHandler{
 		orderService:     orderService,
 		portfolioService: portfolioService,
+		orderCanceller:   orderCanceller,
 	}
 }
 
@@ -31,6 +42,7 @@ func NewOrderHandler(orderService *services.OrderService, portfolioService *serv
 // @Produce json
 // @Param simulation_id query string true "Simulation ID"
 // @Param limit query int false "Number of orders to return (default: 50)" default(50) minimum(1) maximum(1000)
+// @Param tag query string false "Only return orders placed with this tag"
 // @Success 200 {object} map[string]interface{} "List of orders"
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
@@ -59,7 +71,13 @@ func (oh *OrderHandler) GetOrders(c *gin.Context) {
 		limit = 50
 	}
 
-	orders, err := oh.orderService.GetUserOrders(userID, uint(simulationID), limit)
+	// Optionally narrow the orders to one strategy tag
+	var orders []models.Order
+	if tag := c.Query("tag"); tag != "" {
+		orders, err = oh.orderService.GetUserOrdersByTag(userID, uint(simulationID), tag, limit)
+	} else {
+		orders, err = oh.orderService.GetUserOrders(userID, uint(simulationID), limit)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -78,6 +96,7 @@ func (oh *OrderHandler) GetOrders(c *gin.Context) {
 // @Produce json
 // @Param simulation_id query string true "Simulation ID"
 // @Param limit query int false "Number of trades to return (default: 50)" default(50) minimum(1) maximum(1000)
+// @Param tag query string false "Only return trades of orders placed with this tag"
 // @Success 200 {object} map[string]interface{} "List of trades"
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
@@ -106,8 +125,51 @@ func (oh *OrderHandler) GetTrades(c *gin.Context) {
 		limit = 50
 	}
 
-	trades, err := oh.orderService.GetUserTrades(userID, uint(simulationID), limit)
+	// Optionally narrow the trades to those of one strategy tag's orders
+	var trades []models.Trade
+	if tag := c.Query("tag"); tag != "" {
+		trades, err = oh.orderService.GetUserTradesByTag(userID, uint(simulationID), tag, limit)
+	} else {
+		trades, err = oh.orderService.GetUserTrades(userID, uint(simulationID), limit)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trades": trades,
+		"count":  len(trades),
+	})
+}
+
+// GetOrderTrades handles HTTP requests to get the trades resulting from an order
+// @Summary Get Order Trades
+// @Description Get the trades executed for a specific order
+// @Tags orders
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} map[string]interface{} "List of trades"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Order not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /orders/{id}/trades [get]
+func (oh *OrderHandler) GetOrderTrades(c *gin.Context) {
+	// For now, use default user ID 1
+	userID := uint(1)
+
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+		return
+	}
+
+	trades, err := oh.orderService.GetOrderTrades(userID, uint(orderID))
 	if err != nil {
+		if errors.Is(err, services.ErrOrderNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -118,6 +180,68 @@ func (oh *OrderHandler) GetTrades(c *gin.Context) {
 	})
 }
 
+// CancelOrder handles HTTP requests to cancel an open order
+// @Summary Cancel Order
+// @Description Cancel a pending or partially filled order. Orders of a running simulation are also removed from its live order book
+// @Tags orders
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} models.Order "Cancelled order"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Order not found"
+// @Failure 409 {object} map[string]interface{} "Order already executed, cancelled or failed"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /orders/{id} [delete]
+func (oh *OrderHandler) CancelOrder(c *gin.Context) {
+	// For now, use default user ID 1
+	userID := uint(1)
+
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+		return
+	}
+
+	order, err := oh.orderService.GetOpenOrder(userID, uint(orderID))
+	if err != nil {
+		writeCancelOrderError(c, err)
+		return
+	}
+
+	// A running simulation's engine holds the order in memory and must drop it too
+	if order.SimulationID != nil {
+		cancelled, err := oh.orderCanceller.CancelSimulationOrder(*order.SimulationID, order.ID)
+		if err == nil {
+			c.JSON(http.StatusOK, cancelled)
+			return
+		}
+		if !errors.Is(err, wsHandlers.ErrSimulationNotConnected) && !errors.Is(err, trading.ErrOrderNotTracked) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	cancelled, err := oh.orderService.CancelStoredOrder(userID, order.ID)
+	if err != nil {
+		writeCancelOrderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cancelled)
+}
+
+// writeCancelOrderError maps order lookup errors to HTTP responses
+func writeCancelOrderError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOrderNotOpen):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 // GetPositions handles HTTP requests to get user positions
 // @Summary Get User Positions
 // @Description Get list of current positions for a specific simulation
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"tradesimulator/internal/dao/simulation"
+	wsHandlers "tradesimulator/internal/handlers/websocket"
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PortfolioHandler serves the valued portfolio of a simulation
+type PortfolioHandler struct {
+	portfolioService *services.PortfolioService
+	orderService     *services.OrderService
+	simulationDAO    simulation.SimulationDAOInterface
+	statusProvider   SimulationStatusProvider
+}
+
+// NewPortfolioHandler creates a new portfolio handler
+func NewPortfolioHandler(portfolioService *services.PortfolioService, orderService *services.OrderService, simulationDAO simulation.SimulationDAOInterface, statusProvider SimulationStatusProvider) *PortfolioHandler {
+	return &PortfolioHandler{
+		portfolioService: portfolioService,
+		orderService:     orderService,
+		simulationDAO:    simulationDAO,
+		statusProvider:   statusProvider,
+	}
+}
+
+// GetPortfolio handles GET /api/v1/portfolio
+// @Summary Get Portfolio Summary
+// @Description Get the positions of a simulation valued with their P&L and exposure. A running simulation is valued at its engine's current price, others at the simulation symbol's last trade price, and completed simulations report their final total value
+// @Tags portfolio
+// @Produce json
+// @Param simulation_id query string true "Simulation ID"
+// @Success 200 {object} services.PortfolioSummary "Portfolio summary"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Simulation not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /portfolio [get]
+func (ph *PortfolioHandler) GetPortfolio(c *gin.Context) {
+	// For now, use default user ID 1
+	userID := uint(1)
+
+	simulationIDStr := c.Query("simulation_id")
+	if simulationIDStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "simulation_id parameter is required"})
+		return
+	}
+
+	id, err := strconv.ParseUint(simulationIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid simulation_id parameter"})
+		return
+	}
+	simulationID := uint(id)
+
+	simulationRecord, err := ph.simulationDAO.GetSimulationByID(simulationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "simulation not found"})
+		return
+	}
+
+	// Value at the running engine's price, or the last price the simulation traded at without one
+	var currentPrice float64
+	status, err := ph.statusProvider.GetSimulationStatus(simulationID)
+	switch {
+	case err == nil:
+		currentPrice = status.CurrentPrice
+	case errors.Is(err, wsHandlers.ErrSimulationNotConnected):
+		currentPrice, err = ph.orderService.GetLastTradePrice(userID, simulationID, simulationRecord.Symbol)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	portfolio, err := ph.portfolioService.GetUserPortfolio(userID, simulationID, simulationRecord.Symbol, currentPrice)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A completed simulation reports the value it was finalized with, taken at its closing prices rather than the
+	// last trade price
+	if simulationRecord.Status == models.SimulationStatusCompleted && simulationRecord.TotalValue != nil {
+		portfolio.TotalValue = *simulationRecord.TotalValue
+		portfolio.Portfolio.TotalValue = *simulationRecord.TotalValue
+	}
+
+	c.JSON(http.StatusOK, portfolio)
+}
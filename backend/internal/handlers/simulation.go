@@ -1,21 +1,65 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"tradesimulator/internal/dao/simulation"
+	simulationEngine "tradesimulator/internal/engines/simulation"
+	wsHandlers "tradesimulator/internal/handlers/websocket"
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
+// DefaultDashboardTrades is the number of recent trades included in a dashboard
+const DefaultDashboardTrades = 20
+
+// SimulationStatusProvider reports the live status of a simulation running on a connected client
+type SimulationStatusProvider interface {
+	GetSimulationStatus(simulationID uint) (simulationEngine.SimulationStatus, error)
+}
+
+// SimulationStartValidator checks a simulation start configuration without starting it
+type SimulationStartValidator interface {
+	ValidateSimulationStart(startData wsHandlers.SimulationStartData) []string
+}
+
+// SimulationDashboard combines everything the main UI shows for a simulation in one response
+type SimulationDashboard struct {
+	SimulationID uint                               `json:"simulationId"`
+	Live         bool                               `json:"live"` // Status and prices come from the running engine
+	Simulation   *models.Simulation                 `json:"simulation"`
+	Status       *simulationEngine.SimulationStatus `json:"status,omitempty"`
+	Portfolio    *services.PortfolioSummary         `json:"portfolio"`
+	OpenOrders   []models.Order                     `json:"openOrders"`
+	RecentTrades []models.Trade                     `json:"recentTrades"`
+	Metrics      map[string]interface{}             `json:"metrics"`
+}
+
 type SimulationHandler struct {
-	simulationDAO simulation.SimulationDAOInterface
+	simulationDAO     simulation.SimulationDAOInterface
+	replayService     *services.ReplayService
+	metricsService    *services.MetricsService
+	annotationService *services.AnnotationService
+	orderService      *services.OrderService
+	portfolioService  *services.PortfolioService
+	statusProvider    SimulationStatusProvider
+	startValidator    SimulationStartValidator
 }
 
-func NewSimulationHandler(simulationDAO simulation.SimulationDAOInterface) *SimulationHandler {
+func NewSimulationHandler(simulationDAO simulation.SimulationDAOInterface, replayService *services.ReplayService, metricsService *services.MetricsService, annotationService *services.AnnotationService, orderService *services.OrderService, portfolioService *services.PortfolioService, statusProvider SimulationStatusProvider, startValidator SimulationStartValidator) *SimulationHandler {
 	return &SimulationHandler{
-		simulationDAO: simulationDAO,
+		simulationDAO:     simulationDAO,
+		replayService:     replayService,
+		metricsService:    metricsService,
+		annotationService: annotationService,
+		orderService:      orderService,
+		portfolioService:  portfolioService,
+		statusProvider:    statusProvider,
+		startValidator:    startValidator,
 	}
 }
 
@@ -27,6 +71,7 @@ func NewSimulationHandler(simulationDAO simulation.SimulationDAOInterface) *Simu
 // @Produce json
 // @Param limit query int false "Number of simulations to return (default: 50)" default(50) minimum(1) maximum(1000)
 // @Param offset query int false "Number of simulations to skip (default: 0)" default(0) minimum(0)
+// @Param sort query string false "Order of the list (default: newest)" Enums(newest,oldest,pnl_desc,pnl_asc,return_desc,return_asc)
 // @Success 200 {object} map[string]interface{} "List of simulations"
 // @Failure 400 {object} map[string]interface{} "Bad request"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
@@ -51,7 +96,14 @@ func (sh *SimulationHandler) GetSimulations(c *gin.Context) {
 		return
 	}
 
-	simulations, err := sh.simulationDAO.GetUserSimulations(userID, limit, offset)
+	// Sorting by P&L or return turns the list into a leaderboard of runs
+	sort, err := simulation.ParseSimulationSort(c.Query("sort"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	simulations, err := sh.simulationDAO.GetUserSimulations(userID, sort, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -92,7 +144,7 @@ func (sh *SimulationHandler) GetSimulation(c *gin.Context) {
 
 // GetSimulationStats handles GET /api/v1/simulations/:id/stats
 // @Summary Get Simulation Statistics
-// @Description Get performance statistics for a specific simulation
+// @Description Get performance statistics for a specific simulation, including price improvement: how much better fills were than their limit or expected market price (negative for slippage)
 // @Tags simulations
 // @Produce json
 // @Param id path int true "Simulation ID"
@@ -117,6 +169,516 @@ func (sh *SimulationHandler) GetSimulationStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetSimulationMetrics handles GET /api/v1/simulations/:id/metrics
+// @Summary Get Simulation Performance Metrics
+// @Description Get risk and trade metrics of a simulation: max drawdown and Sharpe ratio over an equity curve rebuilt from its trades and candle closes, and win rate and average win/loss of closing trades. Simulations without trades report zero metrics
+// @Tags simulations
+// @Produce json
+// @Param id path int true "Simulation ID"
+// @Success 200 {object} services.PerformanceMetrics "Performance metrics"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Simulation not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /simulations/{id}/metrics [get]
+func (sh *SimulationHandler) GetSimulationMetrics(c *gin.Context) {
+	// Default to user 1 for now
+	userID := uint(1)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid simulation ID"})
+		return
+	}
+
+	metrics, err := sh.metricsService.GetPerformanceMetrics(userID, uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrMetricsSimulationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
+
+// GetReplayData handles GET /api/v1/simulations/:id/replay-data
+// @Summary Get Simulation Replay Data
+// @Description Get candles for a simulation's time range with trade markers aligned to them, for animating a run. The interval is coarsened if the range would exceed maxCandles
+// @Tags simulations
+// @Produce json
+// @Param id path int true "Simulation ID"
+// @Param interval query string false "Preferred candle interval (default: finest that fits)"
+// @Param maxCandles query int false "Maximum number of candles (default: 500)" default(500) minimum(1) maximum(1000)
+// @Success 200 {object} services.ReplayData "Replay candles and trade markers"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Simulation not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /simulations/{id}/replay-data [get]
+func (sh *SimulationHandler) GetReplayData(c *gin.Context) {
+	// Default to user 1 for now
+	userID := uint(1)
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid simulation ID"})
+		return
+	}
+
+	maxCandles, err := strconv.Atoi(c.DefaultQuery("maxCandles", strconv.Itoa(services.DefaultReplayMaxCandles)))
+	if err != nil || maxCandles <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid maxCandles parameter"})
+		return
+	}
+
+	replayData, err := sh.replayService.GetReplayData(userID, uint(id), c.Query("interval"), maxCandles)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrReplaySimulationNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrReplayInvalidInterval), errors.Is(err, services.ErrReplayNoTimeRange):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, replayData)
+}
+
+// GetEquityCurve handles GET /api/v1/simulations/:id/equity-curve
+// @Summary Get Simulation Equity Curve
+// @Description Get the portfolio total value sampled at simulation time intervals during the run, oldest first, with its split into cash and position value where recorded
+// @Tags simulations
+// @Produce json
+// @Param id path int true "Simulation ID"
+// @Success 200 {object} models.EquityCurveResponse "Equity curve"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Simulation not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /simulations/{id}/equity-curve [get]
+func (sh *SimulationHandler) GetEquityCurve(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid simulation ID"})
+		return
+	}
+
+	if _, err := sh.simulationDAO.GetSimulationByID(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "simulation not found"})
+		return
+	}
+
+	samples, err := sh.simulationDAO.GetEquitySamples(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := models.EquityCurveResponse{
+		SimulationID: uint(id),
+		Points:       make([]models.EquityCurvePoint, 0, len(samples)),
+	}
+	for _, sample := range samples {
+		point := models.EquityCurvePoint{
+			Time:       sample.SimTime,
+			TotalValue: models.RoundValue("USDT", sample.TotalValue),
+		}
+		if sample.Cash != nil && sample.PositionValue != nil {
+			cash := models.RoundValue("USDT", *sample.Cash)
+			positionValue := models.RoundValue("USDT", *sample.PositionValue)
+			point.Cash = &cash
+			point.PositionValue = &positionValue
+		}
+		response.Points = append(response.Points, point)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetPositionHistory handles GET /api/v1/simulations/:id/position-history
+// @Summary Get Simulation Position History
+// @Description Get snapshots of the simulation's positions recorded after every fill, oldest first
+// @Tags simulations
+// @Produce json
+// @Param id path int true "Simulation ID"
+// @Param symbol query string false "Only include this symbol, e.g. BTCUSDT"
+// @Success 200 {object} models.PositionHistoryResponse "Position history"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Simulation not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /simulations/{id}/position-history [get]
+func (sh *SimulationHandler) GetPositionHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid simulation ID"})
+		return
+	}
+
+	if _, err := sh.simulationDAO.GetSimulationByID(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "simulation not found"})
+		return
+	}
+
+	symbol := ""
+	if rawSymbol := c.Query("symbol"); rawSymbol != "" {
+		symbol = models.NormalizeSymbol(rawSymbol)
+	}
+
+	snapshots, err := sh.portfolioService.GetPositionHistory(uint(id), symbol)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PositionHistoryResponse{
+		SimulationID: uint(id),
+		Symbol:       symbol,
+		Snapshots:    snapshots,
+	})
+}
+
+// GetAnnotations handles GET /api/v1/simulations/:id/annotations
+// @Summary Get Simulation Annotations
+// @Description Get the notes attached to a simulation, ordered by simulation time
+// @Tags simulations
+// @Produce json
+// @Param id path int true "Simulation ID"
+// @Success 200 {object} map[string]interface{} "List of annotations"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Simulation not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /simulations/{id}/annotations [get]
+func (sh *SimulationHandler) GetAnnotations(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid simulation ID"})
+		return
+	}
+
+	annotations, err := sh.annotationService.GetAnnotations(uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrAnnotationSimulationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"annotations": annotations,
+		"count":       len(annotations),
+	})
+}
+
+// CreateAnnotation handles POST /api/v1/simulations/:id/annotations
+// @Summary Create Simulation Annotation
+// @Description Attach a note to a simulation time or trade. When only tradeId is given, the trade's execution time is used
+// @Tags simulations
+// @Accept json
+// @Produce json
+// @Param id path int true "Simulation ID"
+// @Param annotation body services.CreateAnnotationRequest true "Annotation"
+// @Success 201 {object} models.Annotation "Created annotation"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Simulation not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /simulations/{id}/annotations [post]
+func (sh *SimulationHandler) CreateAnnotation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid simulation ID"})
+		return
+	}
+
+	var request services.CreateAnnotationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	annotation, err := sh.annotationService.CreateAnnotation(uint(id), request)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAnnotationSimulationNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidAnnotation):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, annotation)
+}
+
+// DeleteAnnotation handles DELETE /api/v1/simulations/:id/annotations/:annotationId
+// @Summary Delete Simulation Annotation
+// @Description Delete a note from a simulation
+// @Tags simulations
+// @Produce json
+// @Param id path int true "Simulation ID"
+// @Param annotationId path int true "Annotation ID"
+// @Success 200 {object} map[string]interface{} "Success message"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Annotation not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /simulations/{id}/annotations/{annotationId} [delete]
+func (sh *SimulationHandler) DeleteAnnotation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid simulation ID"})
+		return
+	}
+	annotationID, err := strconv.ParseUint(c.Param("annotationId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid annotation ID"})
+		return
+	}
+
+	if err := sh.annotationService.DeleteAnnotation(uint(id), uint(annotationID)); err != nil {
+		if errors.Is(err, services.ErrAnnotationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "annotation deleted successfully"})
+}
+
+// GetDashboard handles GET /api/v1/simulations/:id/dashboard
+// @Summary Get Simulation Dashboard
+// @Description Get status, portfolio summary, open orders, recent trades and metrics of a simulation in one response. While the simulation runs on a connected client the status and current price come from the engine; otherwise the portfolio is valued at the last trade price
+// @Tags simulations
+// @Produce json
+// @Param id path int true "Simulation ID"
+// @Param trades query int false "Number of recent trades to include (default: 20)" default(20) minimum(1) maximum(1000)
+// @Success 200 {object} SimulationDashboard "Simulation dashboard"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Simulation not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /simulations/{id}/dashboard [get]
+func (sh *SimulationHandler) GetDashboard(c *gin.Context) {
+	// Default to user 1 for now
+	userID := uint(1)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid simulation ID"})
+		return
+	}
+	simulationID := uint(id)
+
+	tradeLimit, err := strconv.Atoi(c.DefaultQuery("trades", strconv.Itoa(DefaultDashboardTrades)))
+	if err != nil || tradeLimit <= 0 || tradeLimit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid trades parameter"})
+		return
+	}
+
+	simulationRecord, err := sh.simulationDAO.GetSimulationByID(simulationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "simulation not found"})
+		return
+	}
+
+	dashboard := SimulationDashboard{
+		SimulationID: simulationID,
+		Simulation:   simulationRecord,
+	}
+
+	// Take the engine snapshot first so the stored data read below is at least as recent
+	var currentPrice float64
+	status, err := sh.statusProvider.GetSimulationStatus(simulationID)
+	switch {
+	case err == nil:
+		dashboard.Live = true
+		dashboard.Status = &status
+		currentPrice = status.CurrentPrice
+	case !errors.Is(err, wsHandlers.ErrSimulationNotConnected):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	dashboard.OpenOrders, err = sh.orderService.GetOpenOrders(userID, simulationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	dashboard.RecentTrades, err = sh.orderService.GetUserTrades(userID, simulationID, tradeLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Without a running engine value the position at the most recent trade price
+	if !dashboard.Live {
+		for _, trade := range dashboard.RecentTrades {
+			if trade.Symbol == simulationRecord.Symbol {
+				currentPrice = trade.Price
+				break
+			}
+		}
+	}
+
+	dashboard.Portfolio, err = sh.portfolioService.GetUserPortfolio(userID, simulationID, simulationRecord.Symbol, currentPrice)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	dashboard.Metrics, err = sh.simulationDAO.GetSimulationStats(simulationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dashboard)
+}
+
+// GetDataStatus handles GET /api/v1/simulations/:id/data-status
+// @Summary Get Simulation Data Loading Status
+// @Description Get whether the running engine is loading historical data, has reached the end of available data, and how full its candle buffer is
+// @Tags simulations
+// @Produce json
+// @Param id path int true "Simulation ID"
+// @Success 200 {object} map[string]interface{} "Data loading status"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Simulation not running"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /simulations/{id}/data-status [get]
+func (sh *SimulationHandler) GetDataStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid simulation ID"})
+		return
+	}
+	simulationID := uint(id)
+
+	status, err := sh.statusProvider.GetSimulationStatus(simulationID)
+	if err != nil {
+		if errors.Is(err, wsHandlers.ErrSimulationNotConnected) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"simulationId": simulationID,
+		"state":        status.State,
+		"data":         status.Data,
+	})
+}
+
+// GetNearestOrders handles GET /api/v1/simulations/:id/nearest-orders
+// @Summary Get Orders Nearest to Filling
+// @Description Get open orders per side sorted by how far the price must move before each fills. The current price comes from the running engine, the price query parameter, or the last trade price, in that order
+// @Tags simulations
+// @Produce json
+// @Param id path int true "Simulation ID"
+// @Param price query number false "Price to measure distances from when the simulation is not running"
+// @Success 200 {object} services.NearestOrders "Open orders by distance"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 404 {object} map[string]interface{} "Simulation not found"
+// @Failure 409 {object} map[string]interface{} "Current price unavailable"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /simulations/{id}/nearest-orders [get]
+func (sh *SimulationHandler) GetNearestOrders(c *gin.Context) {
+	// Default to user 1 for now
+	userID := uint(1)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid simulation ID"})
+		return
+	}
+	simulationID := uint(id)
+
+	var requestedPrice float64
+	if priceStr := c.Query("price"); priceStr != "" {
+		requestedPrice, err = strconv.ParseFloat(priceStr, 64)
+		if err != nil || requestedPrice <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid price parameter"})
+			return
+		}
+	}
+
+	simulationRecord, err := sh.simulationDAO.GetSimulationByID(simulationID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "simulation not found"})
+		return
+	}
+
+	currentPrice := requestedPrice
+	status, err := sh.statusProvider.GetSimulationStatus(simulationID)
+	switch {
+	case err == nil && status.CurrentPrice > 0:
+		currentPrice = status.CurrentPrice
+	case err != nil && !errors.Is(err, wsHandlers.ErrSimulationNotConnected):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if currentPrice <= 0 {
+		trades, err := sh.orderService.GetUserTrades(userID, simulationID, 1)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(trades) > 0 && trades[0].Symbol == simulationRecord.Symbol {
+			currentPrice = trades[0].Price
+		}
+	}
+	if currentPrice <= 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "current price unavailable, pass the price parameter"})
+		return
+	}
+
+	nearestOrders, err := sh.orderService.GetNearestOrders(userID, simulationID, currentPrice)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, nearestOrders)
+}
+
+// ValidateSimulation handles POST /api/v1/simulations/validate
+// @Summary Validate Simulation Configuration
+// @Description Run the checks a simulation start performs (symbol, funding, speed, timeframe, options and data availability) without starting it. Takes the same body as the simulation_control_start message data
+// @Tags simulations
+// @Accept json
+// @Produce json
+// @Param request body websocket.SimulationStartData true "Simulation start configuration"
+// @Success 200 {object} map[string]interface{} "Validation result with the list of issues"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Router /simulations/validate [post]
+func (sh *SimulationHandler) ValidateSimulation(c *gin.Context) {
+	var startData wsHandlers.SimulationStartData
+	if err := c.ShouldBindJSON(&startData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	issues := sh.startValidator.ValidateSimulationStart(startData)
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":  len(issues) == 0,
+		"issues": issues,
+	})
+}
+
 // DeleteSimulation handles DELETE /api/v1/simulations/:id
 // @Summary Delete Simulation
 // @Description Delete a specific simulation and all its related data
@@ -150,8 +712,19 @@ func RegisterSimulationRoutes(router *gin.RouterGroup, handler *SimulationHandle
 	simulations := router.Group("/simulations")
 	{
 		simulations.GET("", handler.GetSimulations)
+		simulations.POST("/validate", handler.ValidateSimulation)
 		simulations.GET("/:id", handler.GetSimulation)
 		simulations.GET("/:id/stats", handler.GetSimulationStats)
+		simulations.GET("/:id/metrics", handler.GetSimulationMetrics)
+		simulations.GET("/:id/replay-data", handler.GetReplayData)
+		simulations.GET("/:id/equity-curve", handler.GetEquityCurve)
+		simulations.GET("/:id/position-history", handler.GetPositionHistory)
+		simulations.GET("/:id/dashboard", handler.GetDashboard)
+		simulations.GET("/:id/nearest-orders", handler.GetNearestOrders)
+		simulations.GET("/:id/data-status", handler.GetDataStatus)
+		simulations.GET("/:id/annotations", handler.GetAnnotations)
+		simulations.POST("/:id/annotations", handler.CreateAnnotation)
+		simulations.DELETE("/:id/annotations/:annotationId", handler.DeleteAnnotation)
 		simulations.DELETE("/:id", handler.DeleteSimulation)
 	}
 }
@@ -2,11 +2,16 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"sync"
+	"time"
 
 	simulationEngine "tradesimulator/internal/engines/simulation"
 	"tradesimulator/internal/engines/trading"
+	"tradesimulator/internal/models"
 	"tradesimulator/internal/types"
 
 	"github.com/gorilla/websocket"
@@ -22,6 +27,46 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// DefaultReadLimit is the largest message in bytes a client may send unless the server overrides it
+const DefaultReadLimit = 8192
+
+// DisconnectBehavior controls what happens to a client's simulation when the client disconnects
+type DisconnectBehavior string
+
+const (
+	DisconnectStop        DisconnectBehavior = "stop"         // Stop the simulation; it can be resumed later from its stopped state
+	DisconnectPause       DisconnectBehavior = "pause"        // Pause the simulation and release the engine
+	DisconnectKeepRunning DisconnectBehavior = "keep_running" // Leave the simulation running without a viewer
+)
+
+// ParseDisconnectBehavior validates a disconnect behavior setting
+func ParseDisconnectBehavior(value string) (DisconnectBehavior, error) {
+	switch behavior := DisconnectBehavior(value); behavior {
+	case DisconnectStop, DisconnectPause, DisconnectKeepRunning:
+		return behavior, nil
+	default:
+		return "", fmt.Errorf("unknown disconnect behavior %q (expected stop, pause or keep_running)", value)
+	}
+}
+
+// PausedOrderBehavior controls what happens to orders placed while the client's simulation is paused
+type PausedOrderBehavior string
+
+const (
+	PausedOrderReject PausedOrderBehavior = "reject" // Reject every order until the simulation resumes
+	PausedOrderQueue  PausedOrderBehavior = "queue"  // Queue market orders to fill at the first candle open after resuming
+)
+
+// ParsePausedOrderBehavior validates a paused order behavior setting
+func ParsePausedOrderBehavior(value string) (PausedOrderBehavior, error) {
+	switch behavior := PausedOrderBehavior(value); behavior {
+	case PausedOrderReject, PausedOrderQueue:
+		return behavior, nil
+	default:
+		return "", fmt.Errorf("unknown paused order behavior %q (expected reject or queue)", value)
+	}
+}
+
 // Client represents a WebSocket client with its own engines
 type Client struct {
 	Conn              *websocket.Conn
@@ -31,6 +76,30 @@ type Client struct {
 	SimulationHandler SimulationEventHandler
 	OrderHandler      OrderEventHandler
 
+	// What to do with the simulation when this client disconnects
+	DisconnectBehavior DisconnectBehavior
+
+	// What to do with orders placed while the simulation is paused
+	PausedOrderBehavior PausedOrderBehavior
+
+	// How order events that don't fit in Send are retried
+	OrderEventRetry OrderEventRetry
+
+	// Pings detecting a connection that went away without closing
+	Heartbeat Heartbeat
+
+	// Largest message in bytes the client may send; larger ones close the connection
+	ReadLimit int64
+
+	// Guards Send against writes after the client has disconnected
+	sendMu   sync.RWMutex
+	detached bool
+
+	// Order events waiting for room in Send, and those given up on until the next resync
+	retryMu            sync.Mutex
+	pendingOrderEvents []pendingOrderEvent
+	missedOrderEvents  [][]byte
+
 	// Session-specific engines
 	SimulationEngine *simulationEngine.SimulationEngine
 	OrderEngine      trading.OrderExecutionEngineInterface
@@ -44,19 +113,25 @@ type SimulationEventHandler interface {
 // OrderEventHandler interface for handling order events
 type OrderEventHandler interface {
 	HandleMessage(client *Client, message types.WebSocketMessage) error
+	HandleTradeExecuted(client *Client, trade *models.Trade, prices map[string]float64)
 }
 
 // NewClient creates a new WebSocket client with its own engine instances
 func NewClient(conn *websocket.Conn, hub *Hub, simHandler SimulationEventHandler, orderHandler OrderEventHandler, simEngine *simulationEngine.SimulationEngine, orderEngine trading.OrderExecutionEngineInterface) *Client {
 	return &Client{
-		Conn:              conn,
-		Send:              make(chan []byte, 256),
-		Hub:               hub,
-		ID:                generateClientID(),
-		SimulationHandler: simHandler,
-		OrderHandler:      orderHandler,
-		SimulationEngine:  simEngine,
-		OrderEngine:       orderEngine,
+		Conn:                conn,
+		Send:                make(chan []byte, 256),
+		Hub:                 hub,
+		ID:                  generateClientID(),
+		SimulationHandler:   simHandler,
+		OrderHandler:        orderHandler,
+		SimulationEngine:    simEngine,
+		OrderEngine:         orderEngine,
+		DisconnectBehavior:  DisconnectStop,
+		PausedOrderBehavior: PausedOrderReject,
+		OrderEventRetry:     DefaultOrderEventRetry(),
+		Heartbeat:           DefaultHeartbeat(),
+		ReadLimit:           DefaultReadLimit,
 	}
 }
 
@@ -69,19 +144,26 @@ func (c *Client) readPump() {
 	}()
 
 	// Set read deadline and pong handler for keep-alive
-	c.Conn.SetReadLimit(512)
+	c.Conn.SetReadLimit(c.ReadLimit)
+	c.extendReadDeadline()
 	c.Conn.SetPongHandler(func(string) error {
-		return nil
+		return c.extendReadDeadline()
 	})
 
 	for {
 		_, message, err := c.Conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if isHeartbeatTimeout(err) {
+				log.Printf("Client %s missed its heartbeat for %v, disconnecting", c.ID, c.Heartbeat.PongTimeout)
+			} else if errors.Is(err, websocket.ErrReadLimit) {
+				log.Printf("Client %s sent a message over the %d byte read limit, disconnecting", c.ID, c.ReadLimit)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error for client %s: %v", c.ID, err)
 			}
 			break
 		}
+		// Any message shows the client is alive, like a pong
+		c.extendReadDeadline()
 
 		// Handle incoming messages
 		log.Printf("Received message from client %s: %s", c.ID, string(message))
@@ -90,22 +172,44 @@ func (c *Client) readPump() {
 }
 
 // writePump handles writing messages to the WebSocket connection
+// Pings go out every heartbeat interval; a failed write closes the connection, which ends readPump
 func (c *Client) writePump() {
 	defer c.Conn.Close()
 
-	for message := range c.Send {
-		if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
-			log.Printf("WebSocket write error for client %s: %v", c.ID, err)
-			return
+	var pings <-chan time.Time
+	if c.Heartbeat.PingInterval > 0 {
+		ticker := time.NewTicker(c.Heartbeat.PingInterval)
+		defer ticker.Stop()
+		pings = ticker.C
+	}
+
+	for {
+		select {
+		case message, ok := <-c.Send:
+			if !ok {
+				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				log.Printf("WebSocket write error for client %s: %v", c.ID, err)
+				return
+			}
+		case <-pings:
+			if err := c.sendPing(); err != nil {
+				log.Printf("WebSocket ping error for client %s: %v", c.ID, err)
+				return
+			}
 		}
 	}
-	c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
 }
 
 // Start starts the client's read and write pumps
 func (c *Client) Start() {
 	go c.writePump()
 	go c.readPump()
+	if c.OrderEventRetry.BacklogSize > 0 {
+		go c.retryPump()
+	}
 }
 
 // handleMessage routes messages to appropriate handlers based on message type
@@ -120,7 +224,8 @@ func (c *Client) handleMessage(messageBytes []byte) {
 	// Route message based on type
 	switch message.Type {
 	case types.SimulationStart, types.SimulationStop, types.SimulationPause, types.SimulationResume,
-		types.SimulationSetSpeed, types.SimulationSetTimeframe, types.SimulationGetStatus:
+		types.SimulationSetSpeed, types.SimulationSetTimeframe, types.SimulationGetStatus, types.SimulationResync,
+		types.SimulationSeek, types.SimulationStepBack, types.SimulationGetPrice, types.GetMetadata:
 		if c.SimulationHandler != nil {
 			if err := c.SimulationHandler.HandleMessage(c, message); err != nil {
 				log.Printf("Simulation handler error for client %s: %v", c.ID, err)
@@ -129,7 +234,7 @@ func (c *Client) handleMessage(messageBytes []byte) {
 			c.SendError("Simulation handler not available", "Internal error")
 		}
 
-	case types.OrderPlace, types.OrderCancel:
+	case types.OrderPlace, types.OrderCancel, types.OrderCloseAll:
 		if c.OrderHandler != nil {
 			if err := c.OrderHandler.HandleMessage(c, message); err != nil {
 				log.Printf("Order handler error for client %s: %v", c.ID, err)
@@ -168,6 +273,19 @@ func (c *Client) SendMessage(message types.WebSocketMessage) {
 		return
 	}
 
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+
+	// Engines left running after a disconnect must not write to the closed channel
+	if c.detached {
+		return
+	}
+
+	if isOrderEvent(message.Type) && c.OrderEventRetry.BacklogSize > 0 {
+		c.sendOrderEvent(data)
+		return
+	}
+
 	select {
 	case c.Send <- data:
 	default:
@@ -175,16 +293,56 @@ func (c *Client) SendMessage(message types.WebSocketMessage) {
 	}
 }
 
+// detach stops further messages from being queued for a disconnected client
+func (c *Client) detach() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.detached = true
+}
+
+// isDetached reports whether the client has disconnected
+func (c *Client) isDetached() bool {
+	c.sendMu.RLock()
+	defer c.sendMu.RUnlock()
+	return c.detached
+}
+
 // cleanup handles cleanup of session-specific engines when client disconnects
 func (c *Client) cleanup() {
-	log.Printf("Cleaning up engines for client %s", c.ID)
+	log.Printf("Cleaning up engines for client %s (disconnect behavior: %s)", c.ID, c.DisconnectBehavior)
+
+	// The hub closes Send once the client is unregistered
+	c.detach()
 
-	// Stop and cleanup simulation engine
 	if c.SimulationEngine != nil {
-		if err := c.SimulationEngine.Stop(); err != nil {
-			log.Printf("Error stopping simulation engine for client %s: %v", c.ID, err)
+		switch c.DisconnectBehavior {
+		case DisconnectKeepRunning:
+			// Leave the engine running headless; it stops itself when data runs out
+			if c.SimulationEngine.GetStatus().State == string(simulationEngine.StatePlaying) {
+				log.Printf("Simulation for client %s keeps running without a client", c.ID)
+				c.SimulationEngine = nil
+				c.OrderEngine = nil
+				return
+			}
+
+		case DisconnectPause:
+			// Pause so the run is recorded as paused and can be resumed from a new connection
+			if c.SimulationEngine.GetStatus().State == string(simulationEngine.StatePlaying) {
+				if err := c.SimulationEngine.Pause(); err != nil {
+					log.Printf("Error pausing simulation engine for client %s: %v", c.ID, err)
+				} else {
+					log.Printf("Simulation paused for disconnected client %s", c.ID)
+				}
+			}
+
+		default:
+			if err := c.SimulationEngine.Stop(); err != nil {
+				log.Printf("Error stopping simulation engine for client %s: %v", c.ID, err)
+			}
 		}
+
 		c.SimulationEngine.Cleanup()
+		c.Hub.ReleaseEngine(c.SimulationEngine)
 		c.SimulationEngine = nil
 		log.Printf("Simulation engine cleaned up for client %s", c.ID)
 	}
@@ -207,6 +365,16 @@ func (cma *ClientMessageAdapter) SendMessage(messageType types.MessageType, data
 		Data: data,
 	}
 	cma.client.SendMessage(message)
+
+	// Follow each fill with the portfolio it left behind
+	if messageType == types.OrderExecuted && cma.client.OrderHandler != nil {
+		if update, ok := data.(map[string]interface{}); ok {
+			if trade, ok := update["trade"].(*models.Trade); ok && trade != nil {
+				prices, _ := update["prices"].(map[string]float64)
+				cma.client.OrderHandler.HandleTradeExecuted(cma.client, trade, prices)
+			}
+		}
+	}
 }
 
 // SendErrorResponse sends a structured error response to the client
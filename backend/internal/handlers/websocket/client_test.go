@@ -0,0 +1,98 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"tradesimulator/internal/types"
+)
+
+// dialTestClient serves a single client with the given read limit and connects to it
+func dialTestClient(t *testing.T, readLimit int64) *websocket.Conn {
+	t.Helper()
+
+	hub := NewHub()
+	go hub.Run()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		client := NewClient(conn, hub, nil, nil, nil, nil)
+		client.ReadLimit = readLimit
+		hub.RegisterClient(client)
+		client.Start()
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if message := readTestMessage(t, conn); message.Type != types.ConnectionStatus {
+		t.Fatalf("first message = %s, want %s", message.Type, types.ConnectionStatus)
+	}
+	return conn
+}
+
+// readTestMessage reads the next message, failing after a second
+func readTestMessage(t *testing.T, conn *websocket.Conn) types.WebSocketMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var message types.WebSocketMessage
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return message
+}
+
+// paddedMessage returns a message of an unknown type padded to size bytes, which the server answers with an error
+func paddedMessage(t *testing.T, size int) []byte {
+	t.Helper()
+	message, err := json.Marshal(types.WebSocketMessage{Type: "padding", Data: ""})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	padding := strings.Repeat("x", size-len(message))
+	message, _ = json.Marshal(types.WebSocketMessage{Type: "padding", Data: padding})
+	if len(message) != size {
+		t.Fatalf("padded message is %d bytes, want %d", len(message), size)
+	}
+	return message
+}
+
+// A message just over the old 512 byte limit is read and handled, and the connection stays open
+func TestMessageOverOldReadLimitIsProcessed(t *testing.T) {
+	conn := dialTestClient(t, DefaultReadLimit)
+
+	for i := 0; i < 2; i++ {
+		if err := conn.WriteMessage(websocket.TextMessage, paddedMessage(t, 513)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if message := readTestMessage(t, conn); message.Type != types.Error {
+			t.Fatalf("reply %d = %s, want the unknown message type error", i, message.Type)
+		}
+	}
+}
+
+// Messages over the configured limit close the connection
+func TestMessageOverReadLimitDisconnects(t *testing.T) {
+	conn := dialTestClient(t, 1024)
+
+	if err := conn.WriteMessage(websocket.TextMessage, paddedMessage(t, 1025)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseMessageTooBig) && !websocket.IsUnexpectedCloseError(err) {
+		t.Errorf("read after an oversized message = %v, want the connection closed", err)
+	}
+}
@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	simulationEngine "tradesimulator/internal/engines/simulation"
+)
+
+// ActiveSimulation describes a simulation engine held in memory
+type ActiveSimulation struct {
+	ClientID       string    `json:"clientId"`
+	Connected      bool      `json:"connected"` // False for engines left running after their client disconnected
+	SimulationID   uint      `json:"simulationId"`
+	Symbol         string    `json:"symbol"`
+	Interval       string    `json:"interval"`
+	State          string    `json:"state"`
+	Speed          int       `json:"speed"`
+	SimulationTime int64     `json:"simulationTime"`
+	CurrentPrice   float64   `json:"currentPrice"`
+	CreatedAt      time.Time `json:"createdAt"`
+
+	simulationEngine.EngineDiagnostics
+}
+
+// trackedEngine is a simulation engine and the client it was created for
+type trackedEngine struct {
+	client    *Client
+	createdAt time.Time
+}
+
+// engineRegistry tracks every simulation engine in memory, independently of client connections
+type engineRegistry struct {
+	mu      sync.Mutex
+	engines map[*simulationEngine.SimulationEngine]*trackedEngine
+}
+
+func newEngineRegistry() *engineRegistry {
+	return &engineRegistry{
+		engines: make(map[*simulationEngine.SimulationEngine]*trackedEngine),
+	}
+}
+
+// TrackEngine starts tracking a simulation engine created for a client
+func (h *Hub) TrackEngine(client *Client, engine *simulationEngine.SimulationEngine) {
+	h.engines.mu.Lock()
+	defer h.engines.mu.Unlock()
+	h.engines.engines[engine] = &trackedEngine{client: client, createdAt: time.Now()}
+}
+
+// ReleaseEngine stops tracking a simulation engine that has been cleaned up
+func (h *Hub) ReleaseEngine(engine *simulationEngine.SimulationEngine) {
+	h.engines.mu.Lock()
+	defer h.engines.mu.Unlock()
+	delete(h.engines.engines, engine)
+}
+
+// ActiveSimulations lists the tracked engines, oldest first
+// Headless engines that have stopped on their own are released instead of listed
+func (h *Hub) ActiveSimulations() []ActiveSimulation {
+	h.engines.mu.Lock()
+	defer h.engines.mu.Unlock()
+
+	simulations := make([]ActiveSimulation, 0, len(h.engines.engines))
+	for engine, tracked := range h.engines.engines {
+		status := engine.GetStatus()
+		connected := !tracked.client.isDetached()
+		if !connected && status.State == string(simulationEngine.StateStopped) {
+			delete(h.engines.engines, engine)
+			continue
+		}
+
+		simulations = append(simulations, ActiveSimulation{
+			ClientID:          tracked.client.ID,
+			Connected:         connected,
+			SimulationID:      status.SimulationID,
+			Symbol:            status.Symbol,
+			Interval:          status.Interval,
+			State:             status.State,
+			Speed:             status.Speed,
+			SimulationTime:    status.SimulationTime,
+			CurrentPrice:      status.CurrentPrice,
+			CreatedAt:         tracked.createdAt,
+			EngineDiagnostics: engine.GetDiagnostics(),
+		})
+	}
+
+	sort.Slice(simulations, func(i, j int) bool {
+		return simulations[i].CreatedAt.Before(simulations[j].CreatedAt)
+	})
+	return simulations
+}
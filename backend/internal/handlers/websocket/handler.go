@@ -1,6 +1,9 @@
 package websocket
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 
@@ -10,18 +13,78 @@ import (
 	"tradesimulator/internal/database"
 	simulationEngine "tradesimulator/internal/engines/simulation"
 	"tradesimulator/internal/engines/trading"
-	"tradesimulator/internal/integrations/binance"
+	"tradesimulator/internal/integrations/marketdata"
+	"tradesimulator/internal/models"
 	"tradesimulator/internal/services"
 )
 
+// ErrSimulationNotConnected is returned when no connected client is running the simulation
+var ErrSimulationNotConnected = errors.New("simulation is not running on a connected client")
+
 // WebSocketHandler handles WebSocket connections and manages event routing
 type WebSocketHandler struct {
 	hub               *Hub
 	simulationHandler SimulationEventHandler
 	orderHandler      OrderEventHandler
+
+	// What to do with a client's simulation when it disconnects
+	disconnectBehavior DisconnectBehavior
+
+	// What to do with orders placed while a simulation is paused
+	pausedOrderBehavior PausedOrderBehavior
+
+	// What to do with a simulation's open orders when it stops (empty uses the engine default)
+	stopOrderBehavior simulationEngine.StopOrderBehavior
+
+	// How clients retry order events their send channel has no room for
+	orderEventRetry OrderEventRetry
+
+	// Pings each client sends to detect connections that went away without closing
+	heartbeat Heartbeat
+
+	// Largest message in bytes a client may send
+	readLimit int64
+
+	// Number of symbol prices each engine fetches in parallel during portfolio valuation
+	valuationConcurrency int
+
+	// Highest simulation speed engines accept (0 uses the engine default)
+	maxSpeed int
+
+	// Whether every simulation, not only blind ones, clamps market data to its current time
+	clampMarketData bool
+
+	// Per symbol fee tiers applied to every order engine, the tier for unlisted symbols and the fee rounding
+	feeSchedule trading.FeeSchedule
+	defaultFees trading.FeeTier
+	feeRounding trading.FeeRounding
+	feeDecimals int
+
+	// Pending limit orders each order engine allows per symbol (0 is unlimited)
+	maxPendingLimitOrders int
+
+	// Base candles between trading_stats messages (0 disables them)
+	tradingStatsInterval int
+
+	// Base candles each engine loads per data batch (0 sizes batches by the base interval)
+	dataBatchSize int
+
+	// Equity curve sampling applied to every simulation engine
+	equitySampleIntervalMs int64
+	maxEquitySamples       int
+	equitySamplingSet      bool
+
+	// Base candles emitted per tick in smooth playback (0 keeps time based ticks)
+	smoothCandlesPerTick int
+
+	// Stores the candles of finished simulations (nil disables archiving)
+	candleArchiver simulationEngine.CandleArchiver
+
+	// Shared registry enforcing the market data cutoff of active simulations
+	cutoffRegistry *services.MarketCutoffRegistry
 	
 	// Dependencies for creating session-specific engines
-	binanceService   *binance.BinanceService
+	marketData       marketdata.MarketDataProvider
 	portfolioService *services.PortfolioService
 	simulationDAO    simulationDAO.SimulationDAOInterface
 	orderDAO         tradingDAO.OrderDAOInterface
@@ -30,27 +93,186 @@ type WebSocketHandler struct {
 }
 
 // NewWebSocketHandler creates a new WebSocket handler with initialized event handlers
-func NewWebSocketHandler(binanceService *binance.BinanceService, portfolioService *services.PortfolioService, simulationDAO simulationDAO.SimulationDAOInterface, orderDAO tradingDAO.OrderDAOInterface, tradeDAO tradingDAO.TradeDAOInterface, positionDAO tradingDAO.PositionDAOInterface, orderService *services.OrderService) *WebSocketHandler {
+func NewWebSocketHandler(marketData marketdata.MarketDataProvider, portfolioService *services.PortfolioService, simulationDAO simulationDAO.SimulationDAOInterface, orderDAO tradingDAO.OrderDAOInterface, tradeDAO tradingDAO.TradeDAOInterface, positionDAO tradingDAO.PositionDAOInterface, orderService *services.OrderService) *WebSocketHandler {
 	hub := NewHub()
 	go hub.Run()
 	
 	// Initialize event handlers
-	simulationHandler := NewSimulationEventHandler()
+	simulationHandler := NewSimulationEventHandler(orderService, portfolioService)
 	orderHandler := NewOrderEventHandler(orderService, portfolioService)
 	
 	return &WebSocketHandler{
-		hub:               hub,
-		simulationHandler: simulationHandler,
-		orderHandler:      orderHandler,
-		binanceService:    binanceService,
-		portfolioService:  portfolioService,
-		simulationDAO:     simulationDAO,
-		orderDAO:          orderDAO,
-		tradeDAO:          tradeDAO,
-		positionDAO:       positionDAO,
+		hub:                 hub,
+		simulationHandler:   simulationHandler,
+		orderHandler:        orderHandler,
+		marketData:          marketData,
+		portfolioService:    portfolioService,
+		simulationDAO:       simulationDAO,
+		orderDAO:            orderDAO,
+		tradeDAO:            tradeDAO,
+		positionDAO:         positionDAO,
+		disconnectBehavior:  DisconnectPause,
+		pausedOrderBehavior: PausedOrderReject,
+		defaultFees:         trading.DefaultFeeTier(),
+		feeRounding:         trading.FeeRoundingNone,
+		feeDecimals:         trading.DefaultFeeDecimals,
+		orderEventRetry:     DefaultOrderEventRetry(),
+		heartbeat:           DefaultHeartbeat(),
+		readLimit:           DefaultReadLimit,
+
+		maxPendingLimitOrders: trading.DefaultMaxPendingLimitOrders,
+	}
+}
+
+// SetMarketCutoffRegistry sets the market cutoff registry shared with the market endpoints
+func (wh *WebSocketHandler) SetMarketCutoffRegistry(registry *services.MarketCutoffRegistry) {
+	wh.cutoffRegistry = registry
+}
+
+// SetFeeSchedule sets the fee tiers used by newly created order engines
+func (wh *WebSocketHandler) SetFeeSchedule(schedule trading.FeeSchedule) {
+	wh.feeSchedule = schedule
+}
+
+// SetOrderEventRetry sets how new clients retry order events their send channel has no room for
+func (wh *WebSocketHandler) SetOrderEventRetry(retry OrderEventRetry) {
+	wh.orderEventRetry = retry
+}
+
+// SetHeartbeat sets the pings new clients use to detect dead connections
+func (wh *WebSocketHandler) SetHeartbeat(heartbeat Heartbeat) {
+	wh.heartbeat = heartbeat
+}
+
+// SetReadLimit sets the largest message in bytes new clients may send
+func (wh *WebSocketHandler) SetReadLimit(limit int64) {
+	wh.readLimit = limit
+}
+
+// SetDefaultFeeTier sets the maker/taker rates newly created order engines charge on unlisted symbols
+func (wh *WebSocketHandler) SetDefaultFeeTier(tier trading.FeeTier) {
+	wh.defaultFees = tier
+}
+
+// SetFeeRounding sets how newly created order engines round fees to decimals
+func (wh *WebSocketHandler) SetFeeRounding(rounding trading.FeeRounding, decimals int) {
+	wh.feeRounding = rounding
+	wh.feeDecimals = decimals
+}
+
+// SetMaxPendingLimitOrders sets how many pending limit orders newly created order engines allow per symbol
+func (wh *WebSocketHandler) SetMaxPendingLimitOrders(limit int) {
+	wh.maxPendingLimitOrders = limit
+}
+
+// SetEquitySampling sets the equity sampling interval and sample cap for newly created engines
+func (wh *WebSocketHandler) SetEquitySampling(intervalMs int64, maxSamples int) {
+	wh.equitySampleIntervalMs = intervalMs
+	wh.maxEquitySamples = maxSamples
+	wh.equitySamplingSet = true
+}
+
+// SetSmoothPlayback sets how many base candles newly created engines emit per tick (0 disables smooth playback)
+func (wh *WebSocketHandler) SetSmoothPlayback(candlesPerTick int) {
+	wh.smoothCandlesPerTick = candlesPerTick
+}
+
+// SetCandleArchiver sets the archiver newly created engines use to store the candles of finished simulations
+func (wh *WebSocketHandler) SetCandleArchiver(archiver simulationEngine.CandleArchiver) {
+	wh.candleArchiver = archiver
+}
+
+// SetTradingStatsInterval sets how many base candles pass between trading_stats messages (0 disables them)
+func (wh *WebSocketHandler) SetTradingStatsInterval(candles int) {
+	wh.tradingStatsInterval = candles
+}
+
+// SetDataBatchSize sets how many base candles newly created engines load per data batch (0 sizes batches by interval)
+func (wh *WebSocketHandler) SetDataBatchSize(candles int) {
+	wh.dataBatchSize = candles
+}
+
+// SetClampMarketData sets whether newly created engines clamp market data to their simulation time
+func (wh *WebSocketHandler) SetClampMarketData(clamp bool) {
+	wh.clampMarketData = clamp
+}
+
+// SetMaxSpeed sets the simulation speed cap for newly created engines
+func (wh *WebSocketHandler) SetMaxSpeed(maxSpeed int) {
+	wh.maxSpeed = maxSpeed
+}
+
+// SetValuationConcurrency sets the portfolio valuation concurrency for newly created engines
+func (wh *WebSocketHandler) SetValuationConcurrency(concurrency int) {
+	wh.valuationConcurrency = concurrency
+}
+
+// SetDisconnectBehavior sets how simulations of disconnected clients are handled
+func (wh *WebSocketHandler) SetDisconnectBehavior(behavior DisconnectBehavior) {
+	wh.disconnectBehavior = behavior
+}
+
+// SetPausedOrderBehavior sets how orders placed while a simulation is paused are handled
+func (wh *WebSocketHandler) SetPausedOrderBehavior(behavior PausedOrderBehavior) {
+	wh.pausedOrderBehavior = behavior
+}
+
+// SetStopOrderBehavior sets what happens to a simulation's open orders when it stops
+func (wh *WebSocketHandler) SetStopOrderBehavior(behavior simulationEngine.StopOrderBehavior) {
+	wh.stopOrderBehavior = behavior
+}
+
+// ResetOrderBook rebuilds the in-memory order book of the client running the simulation from the database
+func (wh *WebSocketHandler) ResetOrderBook(simulationID uint) error {
+	client := wh.hub.FindClientBySimulation(simulationID)
+	if client == nil || client.OrderEngine == nil {
+		return ErrSimulationNotConnected
+	}
+
+	if err := client.OrderEngine.ResetOrderBook(simulationID); err != nil {
+		return fmt.Errorf("failed to reset order book for simulation %d: %w", simulationID, err)
+	}
+	return nil
+}
+
+// CancelSimulationOrder cancels an open order through the order engine of the client running the simulation
+func (wh *WebSocketHandler) CancelSimulationOrder(simulationID, orderID uint) (*models.Order, error) {
+	client := wh.hub.FindClientBySimulation(simulationID)
+	if client == nil || client.OrderEngine == nil {
+		return nil, ErrSimulationNotConnected
 	}
+	return client.OrderEngine.CancelOrder(orderID)
+}
+
+// ListActiveSimulations returns every simulation engine held in memory, including headless ones
+func (wh *WebSocketHandler) ListActiveSimulations() []ActiveSimulation {
+	return wh.hub.ActiveSimulations()
 }
 
+// GetSimulationStatus returns the live status of the simulation from the client running it
+func (wh *WebSocketHandler) GetSimulationStatus(simulationID uint) (simulationEngine.SimulationStatus, error) {
+	client := wh.hub.FindClientBySimulation(simulationID)
+	if client == nil || client.SimulationEngine == nil {
+		return simulationEngine.SimulationStatus{}, ErrSimulationNotConnected
+	}
+	return client.SimulationEngine.GetStatus(), nil
+}
+
+// ValidateSimulationStart checks a start configuration the same way starting a simulation would
+// Returns the list of issues, empty when the configuration is valid
+func (wh *WebSocketHandler) ValidateSimulationStart(startData SimulationStartData) []string {
+	return simulationEngine.ValidateStart(wh.marketData, wh.maxSpeed, startData.Symbol, startData.Interval, startData.StartTime, startData.Speed, startData.InitialFunding, startData.toOptions())
+}
+
+// RunBacktest plays a simulation from the start settings to their end time without a client, on fresh engines
+// configured like a client's, calling strategy with every base candle, until ctx is done
+func (wh *WebSocketHandler) RunBacktest(ctx context.Context, startData SimulationStartData, strategy simulationEngine.BacktestStrategy) (*simulationEngine.BacktestResult, error) {
+	orderEngine := wh.createOrderEngineForClient(nil)
+	engine := wh.createSimulationEngineForClient(nil, orderEngine)
+	defer engine.Cleanup()
+
+	return engine.RunBacktestWithOptions(ctx, startData.Symbol, startData.Interval, startData.StartTime, startData.EndTime, startData.Speed, startData.InitialFunding, startData.toOptions(), strategy)
+}
 
 // HandleWebSocket upgrades HTTP connection to WebSocket and manages client
 func (wh *WebSocketHandler) HandleWebSocket(c *gin.Context) {
@@ -63,6 +285,11 @@ func (wh *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 	
 	// Create client first
 	client := NewClient(conn, wh.hub, wh.simulationHandler, wh.orderHandler, nil, nil)
+	client.DisconnectBehavior = wh.disconnectBehavior
+	client.PausedOrderBehavior = wh.pausedOrderBehavior
+	client.OrderEventRetry = wh.orderEventRetry
+	client.Heartbeat = wh.heartbeat
+	client.ReadLimit = wh.readLimit
 	
 	// Create client message adapter
 	clientAdapter := NewClientMessageAdapter(client)
@@ -78,18 +305,54 @@ func (wh *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 	client.OrderEngine = orderEngineInstance
 	
 	// Register client and start processing
+	wh.hub.TrackEngine(client, simulationEngineInstance)
 	wh.hub.RegisterClient(client)
 	client.Start()
 }
 
 // createSimulationEngineForClient creates a new simulation engine instance for a client
-func (wh *WebSocketHandler) createSimulationEngineForClient(clientAdapter *ClientMessageAdapter, orderEngine trading.OrderExecutionEngineInterface) *simulationEngine.SimulationEngine {
-	return simulationEngine.NewSimulationEngine(clientAdapter, wh.binanceService, wh.portfolioService, wh.simulationDAO, wh.positionDAO, orderEngine)
+func (wh *WebSocketHandler) createSimulationEngineForClient(clientAdapter simulationEngine.ClientMessageSender, orderEngine trading.OrderExecutionEngineInterface) *simulationEngine.SimulationEngine {
+	engine := simulationEngine.NewSimulationEngine(clientAdapter, wh.marketData, wh.portfolioService, wh.simulationDAO, wh.positionDAO, orderEngine)
+	if wh.valuationConcurrency > 0 {
+		engine.SetValuationConcurrency(wh.valuationConcurrency)
+	}
+	if wh.maxSpeed > 0 {
+		engine.SetMaxSpeed(wh.maxSpeed)
+	}
+	if wh.cutoffRegistry != nil {
+		engine.SetMarketCutoffRegistry(wh.cutoffRegistry, wh.clampMarketData)
+	}
+	if wh.tradingStatsInterval > 0 {
+		engine.SetTradingStatsInterval(wh.tradingStatsInterval)
+	}
+	if wh.dataBatchSize > 0 {
+		engine.SetDataBatchSize(wh.dataBatchSize)
+	}
+	if wh.equitySamplingSet {
+		engine.SetEquitySampling(wh.equitySampleIntervalMs, wh.maxEquitySamples)
+	}
+	if wh.candleArchiver != nil {
+		engine.SetCandleArchiver(wh.candleArchiver)
+	}
+	if wh.smoothCandlesPerTick > 0 {
+		engine.SetSmoothPlayback(wh.smoothCandlesPerTick)
+	}
+	if wh.stopOrderBehavior != "" {
+		engine.SetStopOrderBehavior(wh.stopOrderBehavior)
+	}
+	return engine
 }
 
 // createOrderEngineForClient creates a new order execution engine instance for a client
-func (wh *WebSocketHandler) createOrderEngineForClient(clientAdapter *ClientMessageAdapter) trading.OrderExecutionEngineInterface {
-	return trading.NewOrderExecutionEngine(wh.orderDAO, wh.tradeDAO, wh.positionDAO, clientAdapter, database.DB)
+func (wh *WebSocketHandler) createOrderEngineForClient(clientAdapter trading.ClientMessageSender) trading.OrderExecutionEngineInterface {
+	engine := trading.NewOrderExecutionEngine(wh.orderDAO, wh.tradeDAO, wh.positionDAO, clientAdapter, database.DB)
+	if len(wh.feeSchedule) > 0 {
+		engine.SetFeeSchedule(wh.feeSchedule)
+	}
+	engine.SetDefaultFeeTier(wh.defaultFees)
+	engine.SetFeeRounding(wh.feeRounding, wh.feeDecimals)
+	engine.SetMaxPendingLimitOrders(wh.maxPendingLimitOrders)
+	return engine
 }
 
 // GetHub returns the WebSocket hub for broadcasting messages
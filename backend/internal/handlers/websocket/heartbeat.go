@@ -0,0 +1,55 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// heartbeatWriteWait is how long a ping may take to write before the connection counts as dead
+const heartbeatWriteWait = 10 * time.Second
+
+// Heartbeat configures the pings that detect half-open connections
+// A client that doesn't answer within PongTimeout is disconnected, releasing its engines like any other disconnect
+type Heartbeat struct {
+	PingInterval time.Duration // Time between pings (0 disables the heartbeat)
+	PongTimeout  time.Duration // Time without a pong or message before the client is dropped
+}
+
+// DefaultHeartbeat returns the heartbeat used when the server doesn't override it
+func DefaultHeartbeat() Heartbeat {
+	return Heartbeat{PingInterval: 30 * time.Second, PongTimeout: 60 * time.Second}
+}
+
+// Validate checks an enabled heartbeat waits longer for a pong than the time between pings
+func (hb Heartbeat) Validate() error {
+	if hb.PingInterval < 0 {
+		return fmt.Errorf("ping interval must not be negative")
+	}
+	if hb.PingInterval > 0 && hb.PongTimeout <= hb.PingInterval {
+		return fmt.Errorf("pong timeout %v must be longer than the ping interval %v", hb.PongTimeout, hb.PingInterval)
+	}
+	return nil
+}
+
+// extendReadDeadline gives the client another PongTimeout to send a pong or message, or clears the deadline without a heartbeat
+func (c *Client) extendReadDeadline() error {
+	if c.Heartbeat.PingInterval <= 0 {
+		return c.Conn.SetReadDeadline(time.Time{})
+	}
+	return c.Conn.SetReadDeadline(time.Now().Add(c.Heartbeat.PongTimeout))
+}
+
+// sendPing writes a ping control frame, giving up after heartbeatWriteWait
+func (c *Client) sendPing() error {
+	return c.Conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(heartbeatWriteWait))
+}
+
+// isHeartbeatTimeout reports whether a read failed because the client stopped answering pings
+func isHeartbeatTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
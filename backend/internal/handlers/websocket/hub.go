@@ -14,6 +14,7 @@ type Hub struct {
 	register   chan *Client
 	unregister chan *Client
 	mutex      sync.RWMutex
+	engines    *engineRegistry // Every simulation engine in memory, including headless ones
 }
 
 // NewHub creates a new Hub
@@ -23,6 +24,7 @@ func NewHub() *Hub {
 		broadcast:  make(chan []byte),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		engines:    newEngineRegistry(),
 	}
 }
 
@@ -108,6 +110,22 @@ func (h *Hub) GetClientCount() int {
 	return len(h.clients)
 }
 
+// FindClientBySimulation returns the connected client running the given simulation, or nil
+func (h *Hub) FindClientBySimulation(simulationID uint) *Client {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for client := range h.clients {
+		if client.SimulationEngine == nil {
+			continue
+		}
+		if client.SimulationEngine.GetStatus().SimulationID == simulationID {
+			return client
+		}
+	}
+	return nil
+}
+
 // RegisterClient registers a new client
 func (h *Hub) RegisterClient(client *Client) {
 	h.register <- client
@@ -0,0 +1,128 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"tradesimulator/internal/types"
+)
+
+// orderEventRetryInterval is how often queued order events are offered to the send channel again
+const orderEventRetryInterval = 50 * time.Millisecond
+
+// OrderEventRetry configures how order events that don't fit in a client's send channel are retried
+type OrderEventRetry struct {
+	BacklogSize int // Order events held for retry, and missed events kept for resync (0 drops them like other messages)
+	Attempts    int // Retries before an event is recorded as missed
+}
+
+// DefaultOrderEventRetry returns the retry settings used when the server doesn't override them
+func DefaultOrderEventRetry() OrderEventRetry {
+	return OrderEventRetry{BacklogSize: 100, Attempts: 20}
+}
+
+// pendingOrderEvent is an order event waiting for room in the send channel
+type pendingOrderEvent struct {
+	data     []byte
+	attempts int
+}
+
+// isOrderEvent reports whether a message reports an order outcome the client must not miss
+func isOrderEvent(messageType types.MessageType) bool {
+	switch messageType {
+	case types.OrderPlaced, types.OrderExecuted, types.OrderCancelled, types.OrderFailed, types.OrderCloseAllResult:
+		return true
+	}
+	return false
+}
+
+// sendOrderEvent queues an order event behind any earlier ones still waiting for retry (caller must hold sendMu)
+func (c *Client) sendOrderEvent(data []byte) {
+	c.retryMu.Lock()
+	defer c.retryMu.Unlock()
+
+	c.flushOrderEventsLocked(false)
+	if len(c.pendingOrderEvents) == 0 {
+		select {
+		case c.Send <- data:
+			return
+		default:
+		}
+	}
+
+	if len(c.pendingOrderEvents) >= c.OrderEventRetry.BacklogSize {
+		log.Printf("Client %s order event backlog full, recording oldest event for resync", c.ID)
+		c.recordMissedLocked(c.pendingOrderEvents[0].data)
+		c.pendingOrderEvents = c.pendingOrderEvents[1:]
+	}
+	c.pendingOrderEvents = append(c.pendingOrderEvents, pendingOrderEvent{data: data})
+}
+
+// flushOrderEventsLocked moves waiting order events into the send channel in order until it is full
+// Counting a retry attempt moves events that have used up their attempts to the missed list (caller must hold retryMu)
+func (c *Client) flushOrderEventsLocked(countAttempt bool) {
+	for channelHasRoom := true; channelHasRoom && len(c.pendingOrderEvents) > 0; {
+		select {
+		case c.Send <- c.pendingOrderEvents[0].data:
+			c.pendingOrderEvents = c.pendingOrderEvents[1:]
+		default:
+			channelHasRoom = false
+		}
+	}
+
+	if !countAttempt {
+		return
+	}
+	remaining := c.pendingOrderEvents[:0]
+	for _, event := range c.pendingOrderEvents {
+		event.attempts++
+		if event.attempts >= c.OrderEventRetry.Attempts {
+			c.recordMissedLocked(event.data)
+			continue
+		}
+		remaining = append(remaining, event)
+	}
+	c.pendingOrderEvents = remaining
+}
+
+// recordMissedLocked keeps an undeliverable order event for the next resync, dropping the oldest when full
+func (c *Client) recordMissedLocked(data []byte) {
+	if len(c.missedOrderEvents) >= c.OrderEventRetry.BacklogSize {
+		c.missedOrderEvents = c.missedOrderEvents[1:]
+	}
+	c.missedOrderEvents = append(c.missedOrderEvents, data)
+}
+
+// retryPump periodically retries order events the send channel had no room for
+func (c *Client) retryPump() {
+	ticker := time.NewTicker(orderEventRetryInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.sendMu.RLock()
+		if c.detached {
+			c.sendMu.RUnlock()
+			return
+		}
+		c.retryMu.Lock()
+		if len(c.pendingOrderEvents) > 0 {
+			c.flushOrderEventsLocked(true)
+		}
+		c.retryMu.Unlock()
+		c.sendMu.RUnlock()
+	}
+}
+
+// takeMissedOrderEvents returns the order events that could not be delivered and forgets them
+func (c *Client) takeMissedOrderEvents() []json.RawMessage {
+	c.retryMu.Lock()
+	defer c.retryMu.Unlock()
+
+	missed := make([]json.RawMessage, 0, len(c.missedOrderEvents))
+	for _, data := range c.missedOrderEvents {
+		missed = append(missed, data)
+	}
+	c.missedOrderEvents = nil
+	return missed
+}
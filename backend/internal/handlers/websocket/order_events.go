@@ -2,7 +2,12 @@ package websocket
 
 import (
 	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
 
+	simulationEngine "tradesimulator/internal/engines/simulation"
+	"tradesimulator/internal/engines/trading"
 	"tradesimulator/internal/models"
 	"tradesimulator/internal/services"
 	"tradesimulator/internal/types"
@@ -10,11 +15,18 @@ import (
 
 // Order control message structures
 type OrderPlaceData struct {
-	Symbol     string   `json:"symbol"`
-	Side       string   `json:"side"` // "buy" or "sell"
-	Type       string   `json:"type"` // "market" or "limit"
-	Quantity   float64  `json:"quantity"`
-	LimitPrice *float64 `json:"limit_price,omitempty"` // Required for limit orders
+	Symbol          string   `json:"symbol"`
+	Side            string   `json:"side"` // "buy" or "sell"
+	Type            string   `json:"type"` // "market", "limit", "stop_loss", "take_profit", "trailing_stop" or "bracket"
+	Quantity        float64  `json:"quantity"`
+	LimitPrice      *float64 `json:"limit_price,omitempty"`       // Required for limit orders, optional bracket entry price
+	StopLossPrice   *float64 `json:"stop_loss_price,omitempty"`   // Required for stop-loss and bracket orders
+	TakeProfitPrice *float64 `json:"take_profit_price,omitempty"` // Required for take-profit and bracket orders
+	TrailingDelta   *float64 `json:"trailing_delta,omitempty"`    // Absolute trailing distance for trailing stop orders
+	TrailingPercent *float64 `json:"trailing_percent,omitempty"`  // Percentage trailing distance for trailing stop orders
+	TimeInForce     string   `json:"time_in_force,omitempty"`     // Limit orders only: "GTC" (default), "IOC" or "FOK"
+	ExpireTime      *int64   `json:"expire_time,omitempty"`       // Limit orders only: simulation time in ms when a GTC order is cancelled
+	Tag             string   `json:"tag,omitempty"`               // Free-text strategy label, filterable when listing orders and trades
 }
 
 type OrderControlResponse struct {
@@ -24,6 +36,12 @@ type OrderControlResponse struct {
 	Error   string      `json:"error,omitempty"`
 }
 
+// TradeExecutedData is sent after each fill with the portfolio of the trade's simulation
+type TradeExecutedData struct {
+	Trade     *models.Trade              `json:"trade"`
+	Portfolio *services.PortfolioSummary `json:"portfolio"`
+}
+
 // OrderEventHandlerImpl handles order-related WebSocket events
 type OrderEventHandlerImpl struct {
 	orderService     *services.OrderService
@@ -46,6 +64,8 @@ func (h *OrderEventHandlerImpl) HandleMessage(client *Client, message types.WebS
 		h.handlePlaceOrder(client, message.Data)
 	case types.OrderCancel:
 		h.handleCancelOrder(client, message.Data)
+	case types.OrderCloseAll:
+		h.handleCloseAll(client)
 	default:
 		client.SendError("Unknown order message", "Unknown message type "+string(message.Type))
 	}
@@ -60,6 +80,7 @@ func (h *OrderEventHandlerImpl) handlePlaceOrder(client *Client, data interface{
 		client.SendError("Invalid order data", err.Error())
 		return nil
 	}
+	orderData.Symbol = models.NormalizeSymbol(orderData.Symbol)
 
 	// Convert side string to OrderSide enum
 	var side string
@@ -79,8 +100,8 @@ func (h *OrderEventHandlerImpl) handlePlaceOrder(client *Client, data interface{
 		orderType = "market" // Default to market order for backward compatibility
 	}
 
-	if orderType != "market" && orderType != "limit" {
-		client.SendError("Invalid order type", "Type must be 'market' or 'limit'")
+	if orderType != "market" && orderType != "limit" && orderType != "stop_loss" && orderType != "take_profit" && orderType != "trailing_stop" && orderType != "bracket" {
+		client.SendError("Invalid order type", "Type must be 'market', 'limit', 'stop_loss', 'take_profit', 'trailing_stop' or 'bracket'")
 		return nil
 	}
 
@@ -94,6 +115,44 @@ func (h *OrderEventHandlerImpl) handlePlaceOrder(client *Client, data interface{
 		return nil
 	}
 
+	if orderType == "stop_loss" && (orderData.StopLossPrice == nil || *orderData.StopLossPrice <= 0) {
+		client.SendError("Invalid stop-loss price", "A positive stop-loss price is required for stop-loss orders")
+		return nil
+	}
+
+	if orderType == "take_profit" && (orderData.TakeProfitPrice == nil || *orderData.TakeProfitPrice <= 0) {
+		client.SendError("Invalid take-profit price", "A positive take-profit price is required for take-profit orders")
+		return nil
+	}
+
+	if orderType == "bracket" && (orderData.StopLossPrice == nil || orderData.TakeProfitPrice == nil) {
+		client.SendError("Invalid bracket prices", "Bracket orders require a take-profit price and a stop-loss price")
+		return nil
+	}
+
+	if orderType == "bracket" && orderData.LimitPrice != nil && *orderData.LimitPrice <= 0 {
+		client.SendError("Invalid limit price", "Limit price must be positive")
+		return nil
+	}
+
+	var trailingDelta, trailingPercent float64
+	if orderData.TrailingDelta != nil {
+		trailingDelta = *orderData.TrailingDelta
+	}
+	if orderData.TrailingPercent != nil {
+		trailingPercent = *orderData.TrailingPercent
+	}
+	if orderType == "trailing_stop" && (trailingDelta > 0) == (trailingPercent > 0) {
+		client.SendError("Invalid trailing distance", "Trailing stop orders require exactly one positive trailing delta or trailing percent")
+		return nil
+	}
+
+	tag, err := models.NormalizeOrderTag(orderData.Tag)
+	if err != nil {
+		client.SendError("Invalid tag", err.Error())
+		return nil
+	}
+
 	// Check if simulation is running and get current data
 	status := client.SimulationEngine.GetStatus()
 	if !status.IsRunning {
@@ -101,7 +160,25 @@ func (h *OrderEventHandlerImpl) handlePlaceOrder(client *Client, data interface{
 		return nil
 	}
 
-	if status.CurrentPrice <= 0 {
+	if status.WarmingUp {
+		client.SendError("Simulation warming up", warmupMessage(status))
+		return nil
+	}
+
+	// The current price is frozen while paused, so orders are either rejected or market orders wait for the resume
+	paused := status.State == string(simulationEngine.StatePaused)
+	if paused && client.PausedOrderBehavior != PausedOrderQueue {
+		client.SendError("Simulation paused", "Cannot place orders while the simulation is paused, resume it first")
+		return nil
+	}
+
+	// Orders are priced at their own symbol's latest candle, so only symbols the simulation plays can trade
+	if !status.Plays(orderData.Symbol) {
+		client.SendError("Symbol not in simulation", fmt.Sprintf("%s is not played by this simulation", orderData.Symbol))
+		return nil
+	}
+	currentPrice := status.PriceFor(orderData.Symbol)
+	if currentPrice <= 0 {
 		client.SendError("Invalid current price", "Cannot determine current price")
 		return nil
 	}
@@ -109,14 +186,39 @@ func (h *OrderEventHandlerImpl) handlePlaceOrder(client *Client, data interface{
 	// Place the order using the client's order execution engine (using default user ID 1 for now)
 	var order *models.Order
 	var trade *models.Trade
-	var err error
 
-	if orderType == "market" {
-		order, trade, err = client.OrderEngine.ExecuteMarketOrder(1, status.SimulationID, orderData.Symbol, models.OrderSide(side), orderData.Quantity, status.CurrentPrice, status.SimulationTime)
+	if orderType == "market" && paused {
+		order, err = client.OrderEngine.QueueMarketOrder(1, status.SimulationID, orderData.Symbol, models.OrderSide(side), orderData.Quantity, currentPrice, tag, status.SimulationTime)
+	} else if orderType == "market" {
+		order, trade, err = client.OrderEngine.ExecuteMarketOrder(1, status.SimulationID, orderData.Symbol, models.OrderSide(side), orderData.Quantity, currentPrice, tag, status.SimulationTime)
 	} else if orderType == "limit" {
-		order, err = client.OrderEngine.PlaceLimitOrder(1, status.SimulationID, orderData.Symbol, models.OrderSide(side), orderData.Quantity, *orderData.LimitPrice, status.SimulationTime)
-		// Limit orders don't have immediate trades, they are placed as pending
+		limitOptions := trading.LimitOrderOptions{
+			TimeInForce:  strings.ToUpper(orderData.TimeInForce),
+			ExpireTime:   orderData.ExpireTime,
+			CurrentPrice: currentPrice,
+		}
+		order, err = client.OrderEngine.PlaceLimitOrderWithOptions(1, status.SimulationID, orderData.Symbol, models.OrderSide(side), orderData.Quantity, *orderData.LimitPrice, limitOptions, tag, status.SimulationTime)
+		// Limit orders don't return trades, IOC and FOK fills are reported through order_executed
 		trade = nil
+	} else if orderType == "stop_loss" {
+		order, err = client.OrderEngine.PlaceStopLossOrder(1, status.SimulationID, orderData.Symbol, models.OrderSide(side), orderData.Quantity, *orderData.StopLossPrice, tag, status.SimulationTime)
+	} else if orderType == "take_profit" {
+		order, err = client.OrderEngine.PlaceTakeProfitOrder(1, status.SimulationID, orderData.Symbol, models.OrderSide(side), orderData.Quantity, *orderData.TakeProfitPrice, tag, status.SimulationTime)
+	} else if orderType == "trailing_stop" {
+		// The stop starts trailing from the current price
+		order, err = client.OrderEngine.PlaceTrailingStopOrder(1, status.SimulationID, orderData.Symbol, models.OrderSide(side), orderData.Quantity, trailingDelta, trailingPercent, currentPrice, tag, status.SimulationTime)
+	} else if orderType == "bracket" {
+		// The entry is a limit order when a limit price is given and a market order otherwise
+		bracketOptions := trading.BracketOrderOptions{
+			TakeProfitPrice: *orderData.TakeProfitPrice,
+			StopLossPrice:   *orderData.StopLossPrice,
+			CurrentPrice:    currentPrice,
+			QueueEntry:      paused,
+		}
+		if orderData.LimitPrice != nil {
+			bracketOptions.EntryPrice = *orderData.LimitPrice
+		}
+		order, trade, err = client.OrderEngine.PlaceBracketOrder(1, status.SimulationID, orderData.Symbol, models.OrderSide(side), orderData.Quantity, bracketOptions, tag, status.SimulationTime)
 	}
 
 	if err != nil {
@@ -140,3 +242,89 @@ func (h *OrderEventHandlerImpl) handleCancelOrder(client *Client, data interface
 	client.SendError("Order cancellation not implemented", "Feature not yet implemented")
 	return nil
 }
+
+// handleCloseAll flattens all open positions in the current simulation at the current price
+func (h *OrderEventHandlerImpl) handleCloseAll(client *Client) error {
+	status := client.SimulationEngine.GetStatus()
+	if !status.IsRunning {
+		client.SendError("Simulation not running", "Cannot close positions when simulation is not running")
+		return nil
+	}
+
+	if status.WarmingUp {
+		client.SendError("Simulation warming up", warmupMessage(status))
+		return nil
+	}
+
+	if status.CurrentPrice <= 0 {
+		client.SendError("Invalid current price", "Cannot determine current price")
+		return nil
+	}
+
+	prices := status.CurrentPrices()
+	results, err := client.OrderEngine.CloseAllPositions(1, status.SimulationID, prices, status.SimulationTime)
+	if err != nil {
+		client.SendError("Failed to close positions", err.Error())
+		return nil
+	}
+
+	closed := 0
+	for _, result := range results {
+		if result.Success {
+			closed++
+		}
+	}
+
+	client.SendMessage(types.WebSocketMessage{
+		Type: types.OrderCloseAllResult,
+		Data: OrderControlResponse{
+			Success: closed == len(results),
+			Message: fmt.Sprintf("Closed %d of %d positions", closed, len(results)),
+			Data: map[string]interface{}{
+				"results": results,
+			},
+		},
+	})
+	return nil
+}
+
+// HandleTradeExecuted sends the portfolio of a trade's simulation as it stands after the trade
+// The traded symbol is valued at the fill price and other held symbols at the latest prices sent with the fill,
+// rather than read from the simulation status, because fills are reported while the simulation engine holds its lock
+func (h *OrderEventHandlerImpl) HandleTradeExecuted(client *Client, trade *models.Trade, prices map[string]float64) {
+	if trade.SimulationID == nil {
+		return // Portfolios are only kept per simulation
+	}
+
+	valuation := make(map[string]float64, len(prices)+1)
+	for symbol, price := range prices {
+		valuation[symbol] = price
+	}
+	valuation[trade.Symbol] = trade.Price
+
+	portfolio, err := h.portfolioService.GetUserPortfolioAtPrices(trade.UserID, *trade.SimulationID, valuation)
+	if err != nil {
+		log.Printf("Failed to load portfolio after trade %d for client %s: %v", trade.ID, client.ID, err)
+		return
+	}
+
+	client.SendMessage(types.WebSocketMessage{
+		Type: types.TradeExecuted,
+		Data: TradeExecutedData{
+			Trade:     trade,
+			Portfolio: portfolio,
+		},
+	})
+}
+
+// warmupMessage describes how much warmup remains before orders are accepted
+func warmupMessage(status simulationEngine.SimulationStatus) string {
+	message := "Orders are rejected until warmup completes"
+	if status.WarmupCandlesRemaining > 0 {
+		message += fmt.Sprintf(": %d candles remaining", status.WarmupCandlesRemaining)
+	}
+	if status.WarmupEndTime > status.SimulationTime {
+		message += fmt.Sprintf(" (warmup ends at %d)", status.WarmupEndTime)
+	}
+	return message
+}
@@ -0,0 +1,264 @@
+package websocket
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	simulationEngine "tradesimulator/internal/engines/simulation"
+	"tradesimulator/internal/engines/trading"
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/services"
+	"tradesimulator/internal/testutil"
+	"tradesimulator/internal/types"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testStartTime is aligned to daily candles
+const testStartTime = int64(1_704_067_200_000) // 2024-01-01T00:00:00Z
+
+// pausedClient is a client whose simulation is paused shortly after starting to replay daily candles
+type pausedClient struct {
+	*Client
+	simulation *simulationEngine.SimulationEngine
+	orderDAO   *testutil.OrderDAO
+	tradeDAO   *testutil.TradeDAO
+	candles    []models.OHLCV
+	status     simulationEngine.SimulationStatus
+}
+
+func newPausedClient(t *testing.T, behavior PausedOrderBehavior) *pausedClient {
+	t.Helper()
+
+	closes := make([]float64, 600)
+	for i := range closes {
+		closes[i] = 100 + float64(i)/10
+	}
+	pc := &pausedClient{
+		orderDAO: testutil.NewOrderDAO(),
+		tradeDAO: testutil.NewTradeDAO(),
+		candles:  testutil.Candles(testStartTime, "1d", closes...),
+	}
+	marketData := testutil.NewMarketData()
+	marketData.SetCandles("BTCUSDT", "1d", pc.candles)
+	positionDAO := testutil.NewPositionDAO()
+	engineClient := testutil.NewClient()
+	orders := trading.NewOrderExecutionEngine(pc.orderDAO, pc.tradeDAO, positionDAO, engineClient, testutil.NewDB())
+	pc.simulation = simulationEngine.NewSimulationEngine(engineClient, marketData, services.NewPortfolioService(positionDAO),
+		testutil.NewSimulationDAO(), positionDAO, orders)
+	t.Cleanup(pc.simulation.Cleanup)
+
+	pc.Client = NewClient(nil, nil, nil, NewOrderEventHandler(nil, nil), pc.simulation, orders)
+	pc.PausedOrderBehavior = behavior
+
+	pc.simulation.SetMaxSpeed(86400 * 2000)
+	if err := pc.simulation.Start("BTCUSDT", "1d", testStartTime, 86400*2000, 10000, simulationEngine.SimulationOptions{}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitFor(t, "the first candle", func() bool {
+		return pc.simulation.GetStatus().PriceFor("BTCUSDT") > 0
+	})
+	if err := pc.simulation.Pause(); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	pc.status = pc.simulation.GetStatus()
+	return pc
+}
+
+// waitFor polls condition until it holds or five seconds have passed
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// placeOrder sends an order_place message and returns the errors sent back
+func (pc *pausedClient) placeOrder(t *testing.T, order OrderPlaceData) []string {
+	t.Helper()
+
+	pc.OrderHandler.HandleMessage(pc.Client, types.WebSocketMessage{Type: types.OrderPlace, Data: order})
+
+	var errors []string
+	for {
+		select {
+		case data := <-pc.Send:
+			var message struct {
+				Type types.MessageType `json:"type"`
+				Data struct {
+					Message string `json:"message"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(data, &message); err != nil {
+				t.Fatalf("invalid message %s: %v", data, err)
+			}
+			if message.Type == types.Error {
+				errors = append(errors, message.Data.Message)
+			}
+		default:
+			return errors
+		}
+	}
+}
+
+func TestOrdersRejectedWhilePaused(t *testing.T) {
+	pc := newPausedClient(t, PausedOrderReject)
+
+	limitPrice := 50.0
+	for _, order := range []OrderPlaceData{
+		{Symbol: "BTCUSDT", Side: "buy", Type: "market", Quantity: 1},
+		{Symbol: "BTCUSDT", Side: "buy", Type: "limit", Quantity: 1, LimitPrice: &limitPrice},
+	} {
+		errors := pc.placeOrder(t, order)
+		if len(errors) != 1 || errors[0] != "Simulation paused" {
+			t.Errorf("%s order errors = %v, want Simulation paused", order.Type, errors)
+		}
+	}
+
+	if orders, _ := pc.orderDAO.GetUserOrders(1, pc.status.SimulationID, 0); len(orders) != 0 {
+		t.Errorf("stored %d orders, want none", len(orders))
+	}
+}
+
+// Queued market orders wait for the resume and fill at the open trading resumes at, limit orders rest in the book
+func TestOrdersQueuedWhilePaused(t *testing.T) {
+	pc := newPausedClient(t, PausedOrderQueue)
+
+	limitPrice := 50.0
+	for _, order := range []OrderPlaceData{
+		{Symbol: "BTCUSDT", Side: "buy", Type: "market", Quantity: 1},
+		{Symbol: "BTCUSDT", Side: "buy", Type: "limit", Quantity: 1, LimitPrice: &limitPrice},
+	} {
+		if errors := pc.placeOrder(t, order); len(errors) != 0 {
+			t.Fatalf("%s order errors = %v, want none", order.Type, errors)
+		}
+	}
+
+	orders, err := pc.orderDAO.GetPendingOrders(1, pc.status.SimulationID)
+	if err != nil {
+		t.Fatalf("GetPendingOrders: %v", err)
+	}
+	if len(orders) != 2 {
+		t.Fatalf("got %d pending orders, want the market and limit orders", len(orders))
+	}
+	if trades, _ := pc.tradeDAO.GetUserTrades(1, pc.status.SimulationID, 0); len(trades) != 0 {
+		t.Fatalf("got %d trades while paused, want none", len(trades))
+	}
+
+	if err := pc.simulation.Resume(); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	waitFor(t, "the simulation to complete", func() bool {
+		return pc.simulation.GetStatus().State == string(simulationEngine.StateStopped)
+	})
+
+	trades, err := pc.tradeDAO.GetUserTrades(1, pc.status.SimulationID, 0)
+	if err != nil {
+		t.Fatalf("GetUserTrades: %v", err)
+	}
+	if len(trades) != 1 {
+		t.Fatalf("got %d trades, want the queued market order's fill", len(trades))
+	}
+	for _, candle := range pc.candles {
+		if candle.StartTime >= pc.status.SimulationTime {
+			if trades[0].ExecutedAt != candle.StartTime || trades[0].Price != candle.Open {
+				t.Errorf("filled at %g at time %d, want the %g open of the first candle after resuming at %d",
+					trades[0].Price, trades[0].ExecutedAt, candle.Open, candle.StartTime)
+			}
+			break
+		}
+	}
+}
+
+// Orders for lowercase and whitespace-padded symbols trade the canonical symbol
+func TestPlaceOrderNormalizesSymbol(t *testing.T) {
+	pc := newPausedClient(t, PausedOrderQueue)
+
+	if errors := pc.placeOrder(t, OrderPlaceData{Symbol: "  btcusdt ", Side: "buy", Type: "market", Quantity: 1}); len(errors) != 0 {
+		t.Fatalf("order errors = %v, want none", errors)
+	}
+	orders, err := pc.orderDAO.GetUserOrders(1, pc.status.SimulationID, 0)
+	if err != nil {
+		t.Fatalf("GetUserOrders: %v", err)
+	}
+	if len(orders) != 1 || orders[0].Symbol != "BTCUSDT" {
+		t.Errorf("orders = %+v, want one BTCUSDT order", orders)
+	}
+}
+
+// The portfolio sent after a fill values the traded symbol at the fill price and every other held symbol at its
+// latest played price, not at its average price
+func TestTradeExecutedValuesOtherSymbolsAtPlayedPrices(t *testing.T) {
+	simulationID := uint(1)
+	positionDAO := testutil.NewPositionDAO()
+	if err := positionDAO.CreateInitialUSDTPosition(1, &simulationID, 10_000); err != nil {
+		t.Fatalf("CreateInitialUSDTPosition: %v", err)
+	}
+	client := NewClient(nil, nil, nil, NewOrderEventHandler(nil, services.NewPortfolioService(positionDAO)), nil, nil)
+	orders := trading.NewOrderExecutionEngine(testutil.NewOrderDAO(), testutil.NewTradeDAO(), positionDAO, NewClientMessageAdapter(client), testutil.NewDB())
+	noFee := 0.0
+	orders.SetExecutionOptions(trading.ExecutionOptions{FeeRate: &noFee})
+
+	process := func(symbol string, close float64) {
+		t.Helper()
+		if _, err := orders.ProcessCandle(symbol, testutil.Candle(testStartTime, "1d", close, close, close, close)); err != nil {
+			t.Fatalf("ProcessCandle: %v", err)
+		}
+	}
+	buy := func(symbol string, quantity, price float64) {
+		t.Helper()
+		if _, _, err := orders.ExecuteMarketOrder(1, simulationID, symbol, models.OrderSideBuy, quantity, price, "", testStartTime); err != nil {
+			t.Fatalf("ExecuteMarketOrder: %v", err)
+		}
+	}
+
+	// ETH bought at 2000 rises to 2500 before BTC is bought
+	process("ETHUSDT", 2000)
+	buy("ETHUSDT", 1, 2000)
+	process("ETHUSDT", 2500)
+	process("BTCUSDT", 100)
+	buy("BTCUSDT", 10, 100)
+
+	var last *TradeExecutedData
+	for len(client.Send) > 0 {
+		data := <-client.Send
+		var message struct {
+			Type types.MessageType `json:"type"`
+			Data TradeExecutedData `json:"data"`
+		}
+		if err := json.Unmarshal(data, &message); err != nil {
+			t.Fatalf("invalid message %s: %v", data, err)
+		}
+		if message.Type == types.TradeExecuted {
+			last = &message.Data
+		}
+	}
+	if last == nil || last.Trade.Symbol != "BTCUSDT" {
+		t.Fatalf("last trade_executed = %+v, want the BTCUSDT fill", last)
+	}
+
+	// 7000 cash, 10 BTC at 100 and 1 ETH at 2500
+	if last.Portfolio.TotalValue != 10_500 {
+		t.Errorf("total value = %v, want 10500", last.Portfolio.TotalValue)
+	}
+	if last.Portfolio.UnrealizedPnL != 500 {
+		t.Errorf("unrealized P&L = %v, want 500 on ETH", last.Portfolio.UnrealizedPnL)
+	}
+	for _, position := range last.Portfolio.Positions {
+		want := map[string]float64{"USDT": 1, "BTCUSDT": 100, "ETHUSDT": 2500}[position.Position.Symbol]
+		if position.CurrentPrice != want {
+			t.Errorf("%s valued at %v, want %v", position.Position.Symbol, position.CurrentPrice, want)
+		}
+	}
+}
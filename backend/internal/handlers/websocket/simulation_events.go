@@ -2,16 +2,87 @@ package websocket
 
 import (
 	"encoding/json"
+	"log"
+
+	simulationEngine "tradesimulator/internal/engines/simulation"
+	"tradesimulator/internal/engines/trading"
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/services"
 	"tradesimulator/internal/types"
 )
 
+// resyncCandleCount is the number of recent base candles resent on resync
+const resyncCandleCount = 200
+
 // Simulation control message structures
 type SimulationStartData struct {
-	Symbol         string  `json:"symbol"`
-	StartTime      int64   `json:"startTime"`
-	Interval       string  `json:"interval"`
-	Speed          int     `json:"speed"`
-	InitialFunding float64 `json:"initialFunding"`
+	Symbol              string   `json:"symbol"`
+	StartTime           int64    `json:"startTime"`
+	Interval            string   `json:"interval"`
+	Speed               int      `json:"speed"`
+	InitialFunding      float64  `json:"initialFunding"`
+	MarketFillPrice     string   `json:"marketFillPrice,omitempty"` // "last_close" (default) or "next_open"
+	StrictLimitFills    bool     `json:"strictLimitFills,omitempty"`
+	ClampToAffordable   bool     `json:"clampToAffordable,omitempty"`   // Shrink market buys to the affordable quantity instead of rejecting
+	FillAtLimitPrice    bool     `json:"fillAtLimitPrice,omitempty"`    // Fill limit orders at the limit price instead of the triggering price
+	VolumeParticipation float64  `json:"volumeParticipation,omitempty"` // Largest fraction of a candle's volume limit orders can fill (0 fills in full)
+	VolumeScale         float64  `json:"volumeScale,omitempty"`         // Factor applied to candle volumes to simulate thin markets (default 1)
+	FeeRate             *float64 `json:"feeRate,omitempty"`             // Fee rate for every fill, e.g. 0.00075; omitted uses the server's rates
+	LimitFillPrice      string   `json:"limitFillPrice,omitempty"`      // "same_candle" (default) or "next_open" to fill reached limits at the next open
+	WarmupCandles       int      `json:"warmupCandles,omitempty"`       // Base candles to stream before trading is allowed
+	WarmupDuration      int64    `json:"warmupDuration,omitempty"`      // Market time in milliseconds before trading is allowed
+	WarmupBars          int      `json:"warmupBars,omitempty"`          // Base candles before startTime to preload without trading
+	Blind               bool     `json:"blind,omitempty"`               // Block market data past the simulation time
+	Intervals           []string `json:"intervals,omitempty"`           // Extra intervals to stream completed candles for, e.g. ["5m", "1h"]
+	EndTime             int64    `json:"endTime,omitempty"`             // Simulation time in milliseconds at which the run completes
+	Symbols             []string `json:"symbols,omitempty"`             // Extra symbols to play and trade alongside symbol, e.g. ["ETHUSDT"]
+
+	Leverage              float64 `json:"leverage,omitempty"`              // Above 1 runs in futures mode, paying 1/leverage of each buy as margin
+	MaintenanceMarginRate float64 `json:"maintenanceMarginRate,omitempty"` // Equity share of notional value below which longs are liquidated
+	AllowShort            bool    `json:"allowShort,omitempty"`            // Sells may exceed holdings and open short positions
+	SlippageMode          string  `json:"slippageMode,omitempty"`          // none (default), fixed or volume
+	SlippageBps           float64 `json:"slippageBps,omitempty"`           // Market fill slippage in basis points
+	MinPartialFill        float64 `json:"minPartialFill,omitempty"`        // Smallest volume capped limit fill, 1% of the order by default
+	MinPartialFillMode    string  `json:"minPartialFillMode,omitempty"`    // fraction (default) of the order quantity or base asset quantity
+
+	Loop                 bool `json:"loop,omitempty"`                 // Replay from startTime whenever the end time or the end of data is reached
+	ResetPortfolioOnLoop bool `json:"resetPortfolioOnLoop,omitempty"` // Start each replay as a new simulation with the initial funding
+	AggregateCandles     bool `json:"aggregateCandles,omitempty"`     // Send display interval candles built on the server
+}
+
+// toOptions builds engine options from the optional start settings
+func (sd SimulationStartData) toOptions() simulationEngine.SimulationOptions {
+	return simulationEngine.SimulationOptions{
+		Execution: trading.ExecutionOptions{
+			MarketFillPrice:     trading.MarketFillPrice(sd.MarketFillPrice),
+			StrictLimitFills:    sd.StrictLimitFills,
+			ClampToAffordable:   sd.ClampToAffordable,
+			FillAtLimitPrice:    sd.FillAtLimitPrice,
+			VolumeParticipation: sd.VolumeParticipation,
+			VolumeScale:         sd.VolumeScale,
+			FeeRate:             sd.FeeRate,
+			LimitFillPrice:      trading.LimitFillPrice(sd.LimitFillPrice),
+
+			Leverage:              sd.Leverage,
+			MaintenanceMarginRate: sd.MaintenanceMarginRate,
+			AllowShort:            sd.AllowShort,
+			SlippageMode:          trading.SlippageMode(sd.SlippageMode),
+			SlippageBps:           sd.SlippageBps,
+			MinPartialFill:        sd.MinPartialFill,
+			MinPartialFillMode:    trading.MinPartialFillMode(sd.MinPartialFillMode),
+		},
+		WarmupCandles:    sd.WarmupCandles,
+		WarmupDurationMs: sd.WarmupDuration,
+		WarmupBars:       sd.WarmupBars,
+		Blind:            sd.Blind,
+		Intervals:        sd.Intervals,
+		EndTime:          sd.EndTime,
+		Symbols:          sd.Symbols,
+
+		Loop:                 sd.Loop,
+		ResetPortfolioOnLoop: sd.ResetPortfolioOnLoop,
+		AggregateCandles:     sd.AggregateCandles,
+	}
 }
 
 type SimulationSetSpeedData struct {
@@ -22,6 +93,21 @@ type SimulationSetTimeframeData struct {
 	Timeframe string `json:"timeframe"`
 }
 
+// SimulationSeekData is the simulation time to move a running simulation to
+type SimulationSeekData struct {
+	TargetTime int64 `json:"targetTime"` // Milliseconds
+}
+
+// SimulationStepBackData is the number of played base candles to step a running simulation back
+type SimulationStepBackData struct {
+	Steps int `json:"steps"`
+}
+
+// SimulationGetPriceData selects the symbol to price, the simulation's main symbol when omitted
+type SimulationGetPriceData struct {
+	Symbol string `json:"symbol,omitempty"`
+}
+
 type SimulationControlResponse struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message"`
@@ -29,14 +115,30 @@ type SimulationControlResponse struct {
 	Error   string      `json:"error,omitempty"`
 }
 
+// ResyncStateData carries the full client-visible state of a simulation
+type ResyncStateData struct {
+	Status        simulationEngine.SimulationStatus `json:"status"`
+	Portfolio     *services.PortfolioSummary        `json:"portfolio,omitempty"`
+	OpenOrders    []models.Order                    `json:"openOrders"`
+	RecentCandles []models.OHLCV                    `json:"recentCandles"` // Most recent base candles, oldest first
+
+	// Order event messages that could not be delivered, oldest first, each sent only once
+	MissedOrderEvents []json.RawMessage `json:"missedOrderEvents,omitempty"`
+}
+
 // SimulationEventHandlerImpl handles simulation-related WebSocket events
 type SimulationEventHandlerImpl struct {
 	// Remove global engine - now each client has its own
+	orderService     *services.OrderService
+	portfolioService *services.PortfolioService
 }
 
 // NewSimulationEventHandler creates a new simulation event handler
-func NewSimulationEventHandler() *SimulationEventHandlerImpl {
-	return &SimulationEventHandlerImpl{}
+func NewSimulationEventHandler(orderService *services.OrderService, portfolioService *services.PortfolioService) *SimulationEventHandlerImpl {
+	return &SimulationEventHandlerImpl{
+		orderService:     orderService,
+		portfolioService: portfolioService,
+	}
 }
 
 // HandleMessage handles simulation control messages
@@ -56,6 +158,16 @@ func (h *SimulationEventHandlerImpl) HandleMessage(client *Client, message types
 		return h.handleSetTimeframe(client, message.Data)
 	case types.SimulationGetStatus:
 		return h.handleGetStatus(client)
+	case types.SimulationResync:
+		return h.handleResync(client)
+	case types.SimulationSeek:
+		return h.handleSeek(client, message.Data)
+	case types.SimulationStepBack:
+		return h.handleStepBack(client, message.Data)
+	case types.SimulationGetPrice:
+		return h.handleGetPrice(client, message.Data)
+	case types.GetMetadata:
+		return h.handleGetMetadata(client)
 	default:
 		client.SendError("Unknown simulation message", "Unknown message type "+string(message.Type))
 		return nil
@@ -77,7 +189,7 @@ func (h *SimulationEventHandlerImpl) handleStart(client *Client, data interface{
 		return nil
 	}
 
-	if err := client.SimulationEngine.Start(startData.Symbol, startData.Interval, startData.StartTime, startData.Speed, startData.InitialFunding); err != nil {
+	if err := client.SimulationEngine.Start(startData.Symbol, startData.Interval, startData.StartTime, startData.Speed, startData.InitialFunding, startData.toOptions()); err != nil {
 		client.SendError("Failed to start simulation", err.Error())
 		return nil
 	}
@@ -170,9 +282,119 @@ func (h *SimulationEventHandlerImpl) handleSetTimeframe(client *Client, data int
 	return nil
 }
 
+// handleSeek moves the running simulation to a requested simulation time
+func (h *SimulationEventHandlerImpl) handleSeek(client *Client, data interface{}) error {
+	dataBytes, _ := json.Marshal(data)
+	var seekData SimulationSeekData
+	if err := json.Unmarshal(dataBytes, &seekData); err != nil {
+		client.SendError("Invalid seek data", err.Error())
+		return nil
+	}
+
+	if err := client.SimulationEngine.SeekTo(seekData.TargetTime); err != nil {
+		client.SendError("Failed to seek", err.Error())
+		return nil
+	}
+
+	return nil
+}
+
+// handleStepBack moves the running simulation back over candles it already played
+func (h *SimulationEventHandlerImpl) handleStepBack(client *Client, data interface{}) error {
+	dataBytes, _ := json.Marshal(data)
+	var stepBackData SimulationStepBackData
+	if err := json.Unmarshal(dataBytes, &stepBackData); err != nil {
+		client.SendError("Invalid step back data", err.Error())
+		return nil
+	}
+
+	if err := client.SimulationEngine.StepBack(stepBackData.Steps); err != nil {
+		client.SendError("Failed to step back", err.Error())
+		return nil
+	}
+
+	return nil
+}
+
+// handleGetPrice sends the latest price of a symbol at the current simulation time
+func (h *SimulationEventHandlerImpl) handleGetPrice(client *Client, data interface{}) error {
+	var priceData SimulationGetPriceData
+	if data != nil {
+		dataBytes, _ := json.Marshal(data)
+		if err := json.Unmarshal(dataBytes, &priceData); err != nil {
+			client.SendError("Invalid get price data", err.Error())
+			return nil
+		}
+	}
+
+	symbol := ""
+	if priceData.Symbol != "" {
+		symbol = models.NormalizeSymbol(priceData.Symbol)
+	}
+
+	current, err := client.SimulationEngine.GetCurrentPrice(symbol)
+	if err != nil {
+		client.SendError("Failed to get current price", err.Error())
+		return nil
+	}
+
+	client.SendMessage(types.WebSocketMessage{
+		Type: types.CurrentPrice,
+		Data: current,
+	})
+	return nil
+}
+
+// handleGetMetadata sends the supported symbols, intervals and speed constraints so socket-only clients can bootstrap
+func (h *SimulationEventHandlerImpl) handleGetMetadata(client *Client) error {
+	client.SendMessage(types.WebSocketMessage{
+		Type: types.Metadata,
+		Data: client.SimulationEngine.GetMetadata(),
+	})
+	return nil
+}
+
 // handleGetStatus handles simulation status requests
 func (h *SimulationEventHandlerImpl) handleGetStatus(client *Client) error {
 	// Explicitly send status update on request
 	client.SimulationEngine.SendStatusUpdate("")
 	return nil
 }
+
+// handleResync resends the simulation status, portfolio, open orders and recent candles
+// so a client that missed messages can recover without reconnecting
+func (h *SimulationEventHandlerImpl) handleResync(client *Client) error {
+	// For now, use default user ID 1
+	userID := uint(1)
+
+	status := client.SimulationEngine.GetStatus()
+	resync := ResyncStateData{
+		Status:        status,
+		OpenOrders:    []models.Order{},
+		RecentCandles: client.SimulationEngine.GetRecentCandles(resyncCandleCount),
+
+		MissedOrderEvents: client.takeMissedOrderEvents(),
+	}
+
+	if status.SimulationID != 0 {
+		portfolio, err := h.portfolioService.GetUserPortfolio(userID, status.SimulationID, status.Symbol, status.CurrentPrice)
+		if err != nil {
+			log.Printf("Failed to load portfolio for resync of client %s: %v", client.ID, err)
+		} else {
+			resync.Portfolio = portfolio
+		}
+
+		openOrders, err := h.orderService.GetOpenOrders(userID, status.SimulationID)
+		if err != nil {
+			log.Printf("Failed to load open orders for resync of client %s: %v", client.ID, err)
+		} else {
+			resync.OpenOrders = openOrders
+		}
+	}
+
+	client.SendMessage(types.WebSocketMessage{
+		Type: types.ResyncState,
+		Data: resync,
+	})
+	return nil
+}
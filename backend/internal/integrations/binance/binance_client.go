@@ -3,6 +3,9 @@ package binance
 import (
 	"context"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,12 +14,24 @@ import (
 	"github.com/adshao/go-binance/v2"
 )
 
+// DataSource describes where kline data was served from
+type DataSource string
+
+const (
+	DataSourceUpstream   DataSource = "upstream"    // Fetched from Binance
+	DataSourceCache      DataSource = "cache"       // Fully served from the kline cache
+	DataSourceStaleCache DataSource = "stale_cache" // Binance failed, partial cached data served instead
+)
+
 // BinanceService wraps the Binance API client
 type BinanceService struct {
 	client       *binance.Client
 	rateLimiter  chan struct{}
 	lastRequest  int64 // Last request time in milliseconds
 	requestMutex sync.Mutex
+	cache        *KlineCache // Completed klines fetched so far
+	requestLog   *RequestLog // Optional log of upstream requests, nil when disabled
+	symbols      []string    // Supported trading pairs
 }
 
 // NewBinanceService creates a new Binance service instance
@@ -33,20 +48,70 @@ func NewBinanceService() *BinanceService {
 		client:      client,
 		rateLimiter: rateLimiter,
 		lastRequest: time.Now().UnixMilli(),
+		cache:       NewKlineCache(),
+		symbols:     append([]string(nil), DefaultSupportedSymbols...),
 	}
 }
 
+// EnableRequestLog starts recording every Binance request, keeping the most recent entries
+// Call during startup before the service is shared
+func (b *BinanceService) EnableRequestLog(capacity int) {
+	b.requestLog = NewRequestLog(capacity)
+}
+
+// GetRequestLogSnapshot returns logged request statistics, or false if logging is disabled
+func (b *BinanceService) GetRequestLogSnapshot() (*RequestLogSnapshot, bool) {
+	if b.requestLog == nil {
+		return nil, false
+	}
+	snapshot := b.requestLog.Snapshot()
+	return &snapshot, true
+}
+
+// Cache returns the kline cache used by this service
+func (b *BinanceService) Cache() *KlineCache {
+	return b.cache
+}
+
 // GetKlines fetches historical kline data for a symbol
 func (b *BinanceService) GetKlines(symbol, interval string, limit int, startTime, endTime *int64) ([]models.Kline, error) {
-	// Apply rate limiting
-	if err := b.waitForRateLimit(); err != nil {
-		return nil, fmt.Errorf("rate limit error: %w", err)
-	}
+	klines, _, err := b.GetKlinesWithSource(symbol, interval, limit, startTime, endTime)
+	return klines, err
+}
 
+// GetKlinesWithSource fetches historical kline data and reports whether it came from Binance or the cache
+// If Binance is unreachable, any cached candles at the start of the range are returned as stale data
+func (b *BinanceService) GetKlinesWithSource(symbol, interval string, limit int, startTime, endTime *int64) ([]models.Kline, DataSource, error) {
 	// Validate supported symbols
 	if !b.isSupportedSymbol(symbol) {
-		return nil, fmt.Errorf("unsupported symbol: %s. Only BTCUSDT and ETHUSDT are supported", symbol)
+		return nil, "", b.unsupportedSymbolError(symbol)
+	}
+
+	// Serve from cache when the requested range has already been fetched
+	if cached, ok := b.cache.Lookup(symbol, interval, limit, startTime, endTime); ok {
+		return cached, DataSourceCache, nil
+	}
+
+	klines, err := b.fetchKlines(symbol, interval, limit, startTime, endTime)
+	if err != nil {
+		if stale := b.cache.LookupPartial(symbol, interval, limit, startTime, endTime); len(stale) > 0 {
+			log.Printf("Binance request failed, serving %d cached %s %s candles: %v", len(stale), symbol, interval, err)
+			return stale, DataSourceStaleCache, nil
+		}
+		return nil, "", err
+	}
+
+	return klines, DataSourceUpstream, nil
+}
+
+// fetchKlines requests klines from Binance and stores completed candles in the cache
+func (b *BinanceService) fetchKlines(symbol, interval string, limit int, startTime, endTime *int64) ([]models.Kline, error) {
+	// Apply rate limiting
+	waitStart := time.Now()
+	if err := b.waitForRateLimit(); err != nil {
+		return nil, fmt.Errorf("rate limit error: %w", err)
 	}
+	waitMs := time.Since(waitStart).Milliseconds()
 
 	// Create klines service
 	klineService := b.client.NewKlinesService().
@@ -66,7 +131,20 @@ func (b *BinanceService) GetKlines(symbol, interval string, limit int, startTime
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	requestStart := time.Now()
 	klines, err := klineService.Do(ctx)
+	b.recordRequest(RequestLogEntry{
+		Endpoint:    "klines",
+		Symbol:      symbol,
+		Interval:    interval,
+		Limit:       limit,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		RequestedAt: requestStart.UnixMilli(),
+		WaitMs:      waitMs,
+		DurationMs:  time.Since(requestStart).Milliseconds(),
+		Results:     len(klines),
+	}, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch klines: %w", err)
 	}
@@ -89,14 +167,22 @@ func (b *BinanceService) GetKlines(symbol, interval string, limit int, startTime
 		}
 	}
 
+	b.cache.Store(symbol, interval, result)
+
 	return result, nil
 }
 
 // GetHistoricalData fetches historical data with optional incomplete candle support
 func (b *BinanceService) GetHistoricalData(symbol, interval string, limit int, startTime, endTime *int64, enableIncomplete bool) ([]models.OHLCV, error) {
-	klines, err := b.GetKlines(symbol, interval, limit, startTime, endTime)
+	data, _, err := b.GetHistoricalDataWithSource(symbol, interval, limit, startTime, endTime, enableIncomplete)
+	return data, err
+}
+
+// GetHistoricalDataWithSource fetches historical data and reports where the candles were served from
+func (b *BinanceService) GetHistoricalDataWithSource(symbol, interval string, limit int, startTime, endTime *int64, enableIncomplete bool) ([]models.OHLCV, DataSource, error) {
+	klines, source, err := b.GetKlinesWithSource(symbol, interval, limit, startTime, endTime)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	// Convert to OHLCV format
@@ -110,6 +196,7 @@ func (b *BinanceService) GetHistoricalData(symbol, interval string, limit int, s
 		}
 		ohlcvData = append(ohlcvData, *ohlcv)
 	}
+	ohlcvData = validateCandleSpacing(symbol, interval, ohlcvData)
 
 	// Check if we need to create an incomplete last candle (only if enableIncomplete is true)
 	if enableIncomplete && endTime != nil && len(ohlcvData) > 0 {
@@ -136,7 +223,7 @@ func (b *BinanceService) GetHistoricalData(symbol, interval string, limit int, s
 		}
 	}
 
-	return ohlcvData, nil
+	return ohlcvData, source, nil
 }
 
 // buildIncompleteCandleFromMinuteData builds an incomplete candle using 1m data
@@ -224,52 +311,43 @@ func (b *BinanceService) buildIncompleteCandleFromMinuteData(symbol string, cand
 	return incompleteCandle, nil
 }
 
-// isSupportedSymbol checks if the symbol is in our supported list
-func (b *BinanceService) isSupportedSymbol(symbol string) bool {
-	supportedSymbols := map[string]bool{
-		"BTCUSDT": true,
-		"ETHUSDT": true,
-	}
-	return supportedSymbols[symbol]
+// supportedIntervals are the Binance kline intervals, shortest first
+var supportedIntervals = []string{"1m", "3m", "5m", "15m", "30m", "1h", "2h", "4h", "6h", "8h", "12h", "1d", "3d", "1w", "1M"}
+
+// KlineIntervals returns every Binance kline interval, shortest first
+func KlineIntervals() []string {
+	intervals := make([]string, len(supportedIntervals))
+	copy(intervals, supportedIntervals)
+	return intervals
 }
 
-// GetSupportedSymbols returns list of supported trading pairs
-func (b *BinanceService) GetSupportedSymbols() []string {
-	return []string{"BTCUSDT", "ETHUSDT"}
+// GetSupportedIntervals returns the supported kline intervals, shortest first
+func (b *BinanceService) GetSupportedIntervals() []string {
+	return KlineIntervals()
 }
 
 // ValidateInterval checks if the interval is valid
 func (b *BinanceService) ValidateInterval(interval string) bool {
-	validIntervals := map[string]bool{
-		"1m":  true,
-		"3m":  true,
-		"5m":  true,
-		"15m": true,
-		"30m": true,
-		"1h":  true,
-		"2h":  true,
-		"4h":  true,
-		"6h":  true,
-		"8h":  true,
-		"12h": true,
-		"1d":  true,
-		"3d":  true,
-		"1w":  true,
-		"1M":  true,
-	}
-	return validIntervals[interval]
+	for _, supported := range supportedIntervals {
+		if supported == interval {
+			return true
+		}
+	}
+	return false
 }
 
 // GetEarliestAvailableTime fetches the earliest available data point for a symbol
 func (b *BinanceService) GetEarliestAvailableTime(symbol string) (int64, error) {
 	// Apply rate limiting
+	waitStart := time.Now()
 	if err := b.waitForRateLimit(); err != nil {
 		return 0, fmt.Errorf("rate limit error: %w", err)
 	}
+	waitMs := time.Since(waitStart).Milliseconds()
 
 	// Validate supported symbols
 	if !b.isSupportedSymbol(symbol) {
-		return 0, fmt.Errorf("unsupported symbol: %s. Only BTCUSDT and ETHUSDT are supported", symbol)
+		return 0, b.unsupportedSymbolError(symbol)
 	}
 
 	// Get the earliest kline data with limit 1 and start time 0
@@ -284,7 +362,18 @@ func (b *BinanceService) GetEarliestAvailableTime(symbol string) (int64, error)
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	requestStart := time.Now()
 	klines, err := klineService.Do(ctx)
+	b.recordRequest(RequestLogEntry{
+		Endpoint:    "klines_earliest",
+		Symbol:      symbol,
+		Interval:    "1d",
+		Limit:       1,
+		RequestedAt: requestStart.UnixMilli(),
+		WaitMs:      waitMs,
+		DurationMs:  time.Since(requestStart).Milliseconds(),
+		Results:     len(klines),
+	}, err)
 	if err != nil {
 		return 0, fmt.Errorf("failed to fetch earliest kline: %w", err)
 	}
@@ -297,6 +386,160 @@ func (b *BinanceService) GetEarliestAvailableTime(symbol string) (int64, error)
 	return klines[0].OpenTime, nil
 }
 
+// GetLatestPrices fetches the current ticker price for several symbols in a single request
+func (b *BinanceService) GetLatestPrices(symbols []string) (map[string]float64, error) {
+	if len(symbols) == 0 {
+		return map[string]float64{}, nil
+	}
+
+	for _, symbol := range symbols {
+		if !b.isSupportedSymbol(symbol) {
+			return nil, b.unsupportedSymbolError(symbol)
+		}
+	}
+
+	// Apply rate limiting
+	waitStart := time.Now()
+	if err := b.waitForRateLimit(); err != nil {
+		return nil, fmt.Errorf("rate limit error: %w", err)
+	}
+	waitMs := time.Since(waitStart).Milliseconds()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	requestStart := time.Now()
+	symbolPrices, err := b.client.NewListPricesService().Symbols(symbols).Do(ctx)
+	b.recordRequest(RequestLogEntry{
+		Endpoint:    "ticker_price",
+		Symbol:      strings.Join(symbols, ","),
+		RequestedAt: requestStart.UnixMilli(),
+		WaitMs:      waitMs,
+		DurationMs:  time.Since(requestStart).Milliseconds(),
+		Results:     len(symbolPrices),
+	}, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ticker prices: %w", err)
+	}
+
+	prices := make(map[string]float64, len(symbolPrices))
+	for _, symbolPrice := range symbolPrices {
+		price, err := strconv.ParseFloat(symbolPrice.Price, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse price for %s: %w", symbolPrice.Symbol, err)
+		}
+		prices[symbolPrice.Symbol] = price
+	}
+
+	return prices, nil
+}
+
+// PriceChange24h holds a symbol's price change over the last 24 hours
+type PriceChange24h struct {
+	PriceChange        float64 `json:"priceChange"`
+	PriceChangePercent float64 `json:"priceChangePercent"`
+}
+
+// GetPriceChanges24h fetches the rolling 24 hour price change for several symbols in a single request
+func (b *BinanceService) GetPriceChanges24h(symbols []string) (map[string]PriceChange24h, error) {
+	if len(symbols) == 0 {
+		return map[string]PriceChange24h{}, nil
+	}
+
+	// Apply rate limiting
+	waitStart := time.Now()
+	if err := b.waitForRateLimit(); err != nil {
+		return nil, fmt.Errorf("rate limit error: %w", err)
+	}
+	waitMs := time.Since(waitStart).Milliseconds()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	requestStart := time.Now()
+	stats, err := b.client.NewListPriceChangeStatsService().Symbols(symbols).Do(ctx)
+	b.recordRequest(RequestLogEntry{
+		Endpoint:    "ticker_24hr",
+		Symbol:      strings.Join(symbols, ","),
+		RequestedAt: requestStart.UnixMilli(),
+		WaitMs:      waitMs,
+		DurationMs:  time.Since(requestStart).Milliseconds(),
+		Results:     len(stats),
+	}, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch 24h ticker stats: %w", err)
+	}
+
+	changes := make(map[string]PriceChange24h, len(stats))
+	for _, stat := range stats {
+		change, err := strconv.ParseFloat(stat.PriceChange, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse price change for %s: %w", stat.Symbol, err)
+		}
+		changePercent, err := strconv.ParseFloat(stat.PriceChangePercent, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse price change percent for %s: %w", stat.Symbol, err)
+		}
+		changes[stat.Symbol] = PriceChange24h{PriceChange: change, PriceChangePercent: changePercent}
+	}
+
+	return changes, nil
+}
+
+// GetSymbolStatuses fetches the exchange trading status (e.g. TRADING, BREAK) of several symbols
+// Symbols missing from the result are not listed on the exchange
+func (b *BinanceService) GetSymbolStatuses(symbols []string) (map[string]string, error) {
+	if len(symbols) == 0 {
+		return map[string]string{}, nil
+	}
+
+	// Apply rate limiting
+	waitStart := time.Now()
+	if err := b.waitForRateLimit(); err != nil {
+		return nil, fmt.Errorf("rate limit error: %w", err)
+	}
+	waitMs := time.Since(waitStart).Milliseconds()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	requestStart := time.Now()
+	info, err := b.client.NewExchangeInfoService().Symbols(symbols...).Do(ctx)
+	results := 0
+	if info != nil {
+		results = len(info.Symbols)
+	}
+	b.recordRequest(RequestLogEntry{
+		Endpoint:    "exchange_info",
+		Symbol:      strings.Join(symbols, ","),
+		RequestedAt: requestStart.UnixMilli(),
+		WaitMs:      waitMs,
+		DurationMs:  time.Since(requestStart).Milliseconds(),
+		Results:     results,
+	}, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch exchange info: %w", err)
+	}
+
+	statuses := make(map[string]string, len(info.Symbols))
+	for _, symbol := range info.Symbols {
+		statuses[symbol.Symbol] = symbol.Status
+	}
+
+	return statuses, nil
+}
+
+// recordRequest adds a request to the request log when logging is enabled
+func (b *BinanceService) recordRequest(entry RequestLogEntry, err error) {
+	if b.requestLog == nil {
+		return
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	b.requestLog.Record(entry)
+}
+
 // waitForRateLimit implements basic rate limiting
 func (b *BinanceService) waitForRateLimit() error {
 	b.requestMutex.Lock()
@@ -0,0 +1,139 @@
+package binance
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testStartTime = int64(1_704_067_200_000) // 2024-01-01T00:00:00Z
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// newFakeBinance serves count 1m klines from startTime on the first successes klines requests and fails every later
+// one, like an upstream that goes down after that many successful fetches
+func newFakeBinance(t *testing.T, startTime int64, count int, successes int32) (*BinanceService, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) > successes {
+			http.Error(w, `{"code":-1003,"msg":"upstream unavailable"}`, http.StatusServiceUnavailable)
+			return
+		}
+
+		klines := make([][]interface{}, 0, count)
+		for i := 0; i < count; i++ {
+			openTime := startTime + int64(i)*60_000
+			price := strconv.Itoa(100 + i)
+			klines = append(klines, []interface{}{openTime, price, price, price, price, "1000", openTime + 59_999, "100000", 10, "500", "50000", "0"})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(klines); err != nil {
+			t.Errorf("encode klines: %v", err)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	service := NewBinanceService()
+	service.client.BaseURL = server.URL
+	return service, &requests
+}
+
+func TestGetHistoricalDataServesCacheWhenUpstreamFails(t *testing.T) {
+	service, requests := newFakeBinance(t, testStartTime, 10, 1)
+	start := testStartTime
+
+	data, source, err := service.GetHistoricalDataWithSource("BTCUSDT", "1m", 10, &start, nil, false)
+	if err != nil {
+		t.Fatalf("first fetch: %v", err)
+	}
+	if source != DataSourceUpstream || len(data) != 10 {
+		t.Fatalf("first fetch = %d candles from %s, want 10 from %s", len(data), source, DataSourceUpstream)
+	}
+
+	// The fetched range is served from the cache without asking the failing upstream
+	data, source, err = service.GetHistoricalDataWithSource("BTCUSDT", "1m", 10, &start, nil, false)
+	if err != nil {
+		t.Fatalf("cached fetch: %v", err)
+	}
+	if source != DataSourceCache || len(data) != 10 {
+		t.Errorf("cached fetch = %d candles from %s, want 10 from %s", len(data), source, DataSourceCache)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("upstream requests = %d, want 1", got)
+	}
+
+	// A larger range misses the cache, the upstream fails and the cached part is served as stale
+	data, source, err = service.GetHistoricalDataWithSource("BTCUSDT", "1m", 20, &start, nil, false)
+	if err != nil {
+		t.Fatalf("stale fetch: %v", err)
+	}
+	if source != DataSourceStaleCache || len(data) != 10 {
+		t.Errorf("stale fetch = %d candles from %s, want 10 from %s", len(data), source, DataSourceStaleCache)
+	}
+	if len(data) > 0 && (data[0].StartTime != testStartTime || data[len(data)-1].Close != 109) {
+		t.Errorf("stale candles run from %d to close %g, want the cached range", data[0].StartTime, data[len(data)-1].Close)
+	}
+
+	// Nothing is cached for a later range, so the upstream error comes through
+	later := testStartTime + 60*60_000
+	if _, _, err := service.GetHistoricalDataWithSource("BTCUSDT", "1m", 10, &later, nil, false); err == nil {
+		t.Error("uncached fetch succeeded while the upstream is down")
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("upstream requests = %d, want 3", got)
+	}
+}
+
+// Repeated windows of closed candles are served from the cache, while a window reaching the current candle goes
+// to Binance every time, as that candle is never cached; the cache statistics count each lookup
+func TestKlineCacheHitsAndMisses(t *testing.T) {
+	// Keep the current minute from closing while the test runs
+	currentMinute := time.Now().Truncate(time.Minute)
+	if time.Until(currentMinute.Add(time.Minute)) < time.Second {
+		time.Sleep(time.Until(currentMinute.Add(time.Minute)))
+		currentMinute = currentMinute.Add(time.Minute)
+	}
+	// Four closed candles followed by the current, incomplete one
+	start := currentMinute.Add(-4 * time.Minute).UnixMilli()
+	service, requests := newFakeBinance(t, start, 5, math.MaxInt32)
+
+	fetch := func(limit int, wantSource DataSource, wantRequests int32) {
+		t.Helper()
+		klines, source, err := service.GetKlinesWithSource("BTCUSDT", "1m", limit, &start, nil)
+		if err != nil {
+			t.Fatalf("fetch of %d candles: %v", limit, err)
+		}
+		if source != wantSource || len(klines) < limit {
+			t.Errorf("fetch of %d candles = %d from %s, want %d from %s", limit, len(klines), source, limit, wantSource)
+		}
+		if got := requests.Load(); got != wantRequests {
+			t.Errorf("upstream requests after fetching %d candles = %d, want %d", limit, got, wantRequests)
+		}
+	}
+
+	fetch(4, DataSourceUpstream, 1)
+	fetch(4, DataSourceCache, 1) // The same window makes no further call
+	fetch(5, DataSourceUpstream, 2)
+	fetch(5, DataSourceUpstream, 3) // The incomplete candle is fetched again
+
+	stats := service.Cache().Stats()
+	if stats.Hits != 1 || stats.Misses != 3 || stats.HitRatio != 25 {
+		t.Errorf("stats = %d hits, %d misses, %v%% hit ratio, want 1, 3 and 25%%", stats.Hits, stats.Misses, stats.HitRatio)
+	}
+	if stats.Series != 1 || stats.Candles != 4 {
+		t.Errorf("cache holds %d series of %d candles, want 1 series of the 4 closed candles", stats.Series, stats.Candles)
+	}
+}
@@ -0,0 +1,60 @@
+package binance
+
+import (
+	"log"
+
+	"tradesimulator/internal/models"
+)
+
+// CandleSpacingReport counts the candles that didn't match the requested interval
+type CandleSpacingReport struct {
+	WrongDuration int // Candles whose own span differs from the interval, dropped
+	Misaligned    int // Candles starting less than an interval after the previous one or off its grid, dropped
+	Gaps          int // Missing stretches of one or more whole intervals, kept as upstream has no data for them
+}
+
+// HasAnomalies reports whether any candle was dropped or any gap found
+func (r CandleSpacingReport) HasAnomalies() bool {
+	return r.WrongDuration > 0 || r.Misaligned > 0 || r.Gaps > 0
+}
+
+// checkCandleSpacing drops candles whose span or spacing doesn't match the interval, which would break the engine's time math
+// A candle must last exactly one interval and start a whole number of intervals after the previous kept candle
+// Monthly candles vary in length and are returned unchecked
+func checkCandleSpacing(interval string, candles []models.OHLCV) ([]models.OHLCV, CandleSpacingReport) {
+	var report CandleSpacingReport
+	if interval == "1M" || len(candles) == 0 {
+		return candles, report
+	}
+	durationMs := models.GetIntervalDurationMs(interval)
+
+	valid := make([]models.OHLCV, 0, len(candles))
+	for _, candle := range candles {
+		if candle.EndTime-candle.StartTime+1 != durationMs {
+			report.WrongDuration++
+			continue
+		}
+		if len(valid) > 0 {
+			spacing := candle.StartTime - valid[len(valid)-1].StartTime
+			if spacing < durationMs || spacing%durationMs != 0 {
+				report.Misaligned++
+				continue
+			}
+			if spacing > durationMs {
+				report.Gaps++
+			}
+		}
+		valid = append(valid, candle)
+	}
+	return valid, report
+}
+
+// validateCandleSpacing applies checkCandleSpacing and logs any anomalies found
+func validateCandleSpacing(symbol, interval string, candles []models.OHLCV) []models.OHLCV {
+	valid, report := checkCandleSpacing(interval, candles)
+	if report.HasAnomalies() {
+		log.Printf("Candle spacing anomalies in %s %s data: dropped %d with wrong duration and %d misaligned, %d gaps kept",
+			symbol, interval, report.WrongDuration, report.Misaligned, report.Gaps)
+	}
+	return valid
+}
@@ -0,0 +1,131 @@
+package binance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tradesimulator/internal/models"
+)
+
+const minute = int64(60_000)
+
+// spacedCandle returns a candle starting at start that lasts duration milliseconds
+func spacedCandle(start, duration int64) models.OHLCV {
+	return models.OHLCV{StartTime: start, EndTime: start + duration - 1, Open: 100, High: 100, Low: 100, Close: 100, Volume: 1000}
+}
+
+func TestCheckCandleSpacing(t *testing.T) {
+	tests := []struct {
+		name       string
+		interval   string
+		candles    []models.OHLCV
+		wantStarts []int64
+		want       CandleSpacingReport
+	}{
+		{
+			name:     "evenly spaced",
+			interval: "1m",
+			candles: []models.OHLCV{
+				spacedCandle(testStartTime, minute),
+				spacedCandle(testStartTime+minute, minute),
+				spacedCandle(testStartTime+2*minute, minute),
+			},
+			wantStarts: []int64{testStartTime, testStartTime + minute, testStartTime + 2*minute},
+		},
+		{
+			name:     "candle of the wrong duration is dropped",
+			interval: "1m",
+			candles: []models.OHLCV{
+				spacedCandle(testStartTime, minute),
+				spacedCandle(testStartTime+minute, 5*minute),
+				spacedCandle(testStartTime+2*minute, minute),
+			},
+			wantStarts: []int64{testStartTime, testStartTime + 2*minute},
+			want:       CandleSpacingReport{WrongDuration: 1, Gaps: 1}, // The dropped candle leaves a gap
+		},
+		{
+			name:     "candles off the grid or overlapping are dropped",
+			interval: "1m",
+			candles: []models.OHLCV{
+				spacedCandle(testStartTime, minute),
+				spacedCandle(testStartTime+minute/2, minute),
+				spacedCandle(testStartTime, minute),
+				spacedCandle(testStartTime+minute, minute),
+			},
+			wantStarts: []int64{testStartTime, testStartTime + minute},
+			want:       CandleSpacingReport{Misaligned: 2},
+		},
+		{
+			name:     "gaps of whole intervals are kept",
+			interval: "1m",
+			candles: []models.OHLCV{
+				spacedCandle(testStartTime, minute),
+				spacedCandle(testStartTime+3*minute, minute),
+			},
+			wantStarts: []int64{testStartTime, testStartTime + 3*minute},
+			want:       CandleSpacingReport{Gaps: 1},
+		},
+		{
+			name:     "monthly candles are unchecked",
+			interval: "1M",
+			candles: []models.OHLCV{
+				spacedCandle(testStartTime, 31*1440*minute),
+				spacedCandle(testStartTime+31*1440*minute, 29*1440*minute),
+			},
+			wantStarts: []int64{testStartTime, testStartTime + 31*1440*minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, report := checkCandleSpacing(tt.interval, tt.candles)
+			if report != tt.want {
+				t.Errorf("report = %+v, want %+v", report, tt.want)
+			}
+			if report.HasAnomalies() != (tt.want != CandleSpacingReport{}) {
+				t.Errorf("HasAnomalies() = %v", report.HasAnomalies())
+			}
+			if len(valid) != len(tt.wantStarts) {
+				t.Fatalf("kept %d candles, want %d", len(valid), len(tt.wantStarts))
+			}
+			for i, candle := range valid {
+				if candle.StartTime != tt.wantStarts[i] {
+					t.Errorf("candle %d starts at %d, want %d", i, candle.StartTime, tt.wantStarts[i])
+				}
+			}
+		})
+	}
+}
+
+// Mis-spaced candles from upstream are dropped before historical data reaches a simulation
+func TestGetHistoricalDataDropsMisspacedCandles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var klines [][]interface{}
+		for _, openTime := range []int64{testStartTime, testStartTime + minute, testStartTime + minute + 30_000, testStartTime + 2*minute} {
+			klines = append(klines, []interface{}{openTime, "100", "100", "100", "100", "1000", openTime + minute - 1, "100000", 10, "500", "50000", "0"})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(klines); err != nil {
+			t.Errorf("encode klines: %v", err)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	service := NewBinanceService()
+	service.client.BaseURL = server.URL
+	start := testStartTime
+	data, err := service.GetHistoricalData("BTCUSDT", "1m", 4, &start, nil, false)
+	if err != nil {
+		t.Fatalf("GetHistoricalData: %v", err)
+	}
+	if len(data) != 3 {
+		t.Fatalf("got %d candles, want the 3 on the minute grid", len(data))
+	}
+	for i, candle := range data {
+		if want := testStartTime + int64(i)*minute; candle.StartTime != want {
+			t.Errorf("candle %d starts at %d, want %d", i, candle.StartTime, want)
+		}
+	}
+}
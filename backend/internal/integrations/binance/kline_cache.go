@@ -0,0 +1,279 @@
+package binance
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tradesimulator/internal/models"
+)
+
+const (
+	// DefaultMaxCachedKlinesPerSeries bounds memory used by a single symbol/interval series
+	DefaultMaxCachedKlinesPerSeries = 200000
+
+	// DefaultMaxCachedSeries bounds the number of symbol/interval series kept, evicting the least recently used
+	DefaultMaxCachedSeries = 32
+
+	// cacheStatsLogEvery is the number of lookups between hit ratio log lines
+	cacheStatsLogEvery = 500
+)
+
+// KlineCache keeps completed klines in memory, keyed by symbol and interval
+// Only closed candles are cached, so cached data never changes once stored
+type KlineCache struct {
+	mu                 sync.RWMutex
+	series             map[string][]models.Kline // Sorted by OpenTime, no duplicates
+	lastUsed           map[string]time.Time      // Last store or full lookup of each series
+	maxKlinesPerSeries int
+	maxSeries          int
+
+	hits   atomic.Int64 // Lookups served from the cache
+	misses atomic.Int64 // Lookups that had to go to Binance
+}
+
+// KlineCacheStats reports how well the kline cache avoids Binance requests
+type KlineCacheStats struct {
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	HitRatio float64 `json:"hitRatio"` // Percentage of lookups served from the cache
+	Series   int     `json:"series"`
+	Candles  int     `json:"candles"`
+}
+
+// NewKlineCache creates a new empty kline cache
+func NewKlineCache() *KlineCache {
+	return &KlineCache{
+		series:             make(map[string][]models.Kline),
+		lastUsed:           make(map[string]time.Time),
+		maxKlinesPerSeries: DefaultMaxCachedKlinesPerSeries,
+		maxSeries:          DefaultMaxCachedSeries,
+	}
+}
+
+// cacheKey builds the series key for a symbol and interval
+func cacheKey(symbol, interval string) string {
+	return symbol + "|" + interval
+}
+
+// Store merges klines into the cache, skipping candles that have not closed yet
+func (c *KlineCache) Store(symbol, interval string, klines []models.Kline) {
+	if len(klines) == 0 {
+		return
+	}
+
+	nowMs := time.Now().UnixMilli()
+	closed := make([]models.Kline, 0, len(klines))
+	for _, kline := range klines {
+		if kline.CloseTime < nowMs {
+			closed = append(closed, kline)
+		}
+	}
+	if len(closed) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey(symbol, interval)
+	merged := mergeKlines(c.series[key], closed)
+
+	// Drop the oldest candles once the series grows past its limit
+	if len(merged) > c.maxKlinesPerSeries {
+		merged = merged[len(merged)-c.maxKlinesPerSeries:]
+	}
+	c.series[key] = merged
+	c.lastUsed[key] = time.Now()
+	c.evictLeastRecentlyUsed()
+}
+
+// evictLeastRecentlyUsed drops whole series, least recently used first, until at most maxSeries remain (caller must hold lock)
+func (c *KlineCache) evictLeastRecentlyUsed() {
+	for c.maxSeries > 0 && len(c.series) > c.maxSeries {
+		var oldestKey string
+		var oldest time.Time
+		for key, used := range c.lastUsed {
+			if oldestKey == "" || used.Before(oldest) {
+				oldestKey, oldest = key, used
+			}
+		}
+		delete(c.series, oldestKey)
+		delete(c.lastUsed, oldestKey)
+		log.Printf("Evicted kline cache series %s", oldestKey)
+	}
+}
+
+// Lookup returns cached klines for the request if the cache fully covers it
+// The request is covered when the cache holds a gap-free run of candles starting at
+// startTime and reaching either limit candles or endTime
+// Every call counts as a hit or a miss in the cache statistics
+func (c *KlineCache) Lookup(symbol, interval string, limit int, startTime, endTime *int64) ([]models.Kline, bool) {
+	run, complete := c.lookupRun(symbol, interval, limit, startTime, endTime)
+	if !complete {
+		c.recordLookup(false)
+		return nil, false
+	}
+
+	key := cacheKey(symbol, interval)
+	c.mu.Lock()
+	if _, ok := c.series[key]; ok {
+		c.lastUsed[key] = time.Now()
+	}
+	c.mu.Unlock()
+
+	c.recordLookup(true)
+	return run, true
+}
+
+// recordLookup counts a cache hit or miss and periodically logs the hit ratio
+func (c *KlineCache) recordLookup(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+
+	hits, misses := c.hits.Load(), c.misses.Load()
+	if (hits+misses)%cacheStatsLogEvery == 0 {
+		stats := c.Stats()
+		log.Printf("Kline cache: %d hits, %d misses (%.1f%% hit ratio), %d series, %d candles",
+			stats.Hits, stats.Misses, stats.HitRatio, stats.Series, stats.Candles)
+	}
+}
+
+// Stats returns the cache hit and miss counters and its current size
+func (c *KlineCache) Stats() KlineCacheStats {
+	stats := KlineCacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = models.RoundPercent(float64(stats.Hits) / float64(lookups) * 100)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	stats.Series = len(c.series)
+	for _, series := range c.series {
+		stats.Candles += len(series)
+	}
+	return stats
+}
+
+// LookupPartial returns the gap-free run of cached klines starting at startTime, even if
+// it does not fully cover the request. Used as a fallback when the upstream is unavailable
+func (c *KlineCache) LookupPartial(symbol, interval string, limit int, startTime, endTime *int64) []models.Kline {
+	run, _ := c.lookupRun(symbol, interval, limit, startTime, endTime)
+	return run
+}
+
+// Covers reports whether the cache holds a gap-free run of candles for [startTime, endTime]
+func (c *KlineCache) Covers(symbol, interval string, startTime, endTime int64) bool {
+	_, complete := c.lookupRun(symbol, interval, 0, &startTime, &endTime)
+	return complete
+}
+
+// Size returns the number of cached candles for a symbol and interval
+func (c *KlineCache) Size(symbol, interval string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.series[cacheKey(symbol, interval)])
+}
+
+// lookupRun walks the cached series from startTime and returns the contiguous run found,
+// along with whether it satisfies the requested limit or end time
+func (c *KlineCache) lookupRun(symbol, interval string, limit int, startTime, endTime *int64) ([]models.Kline, bool) {
+	// Without a start time the request asks for the most recent data, which is never cached
+	if startTime == nil {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	series := c.series[cacheKey(symbol, interval)]
+	if len(series) == 0 {
+		return nil, false
+	}
+
+	start := *startTime
+	index := sort.Search(len(series), func(i int) bool {
+		return series[i].OpenTime >= start
+	})
+	if index >= len(series) {
+		return nil, false
+	}
+
+	// The first cached candle must be the first candle at or after startTime
+	first := series[index]
+	if first.OpenTime-start >= models.GetIntervalDurationMs(interval) {
+		return nil, false
+	}
+
+	var run []models.Kline
+	for i := index; i < len(series); i++ {
+		kline := series[i]
+		if endTime != nil && kline.OpenTime > *endTime {
+			return run, true
+		}
+		if len(run) > 0 && kline.OpenTime != run[len(run)-1].CloseTime+1 {
+			return run, false // Gap in cached data
+		}
+
+		run = append(run, kline)
+		if limit > 0 && len(run) >= limit {
+			return run, true
+		}
+	}
+
+	// Ran out of cached candles; only complete if the last one already reaches endTime
+	if endTime != nil && len(run) > 0 && run[len(run)-1].CloseTime >= *endTime {
+		return run, true
+	}
+	return run, false
+}
+
+// mergeKlines merges two OpenTime-sorted kline slices, preferring incoming candles on duplicates
+func mergeKlines(existing, incoming []models.Kline) []models.Kline {
+	sorted := make([]models.Kline, len(incoming))
+	copy(sorted, incoming)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].OpenTime < sorted[j].OpenTime
+	})
+
+	merged := make([]models.Kline, 0, len(existing)+len(sorted))
+	i, j := 0, 0
+	for i < len(existing) || j < len(sorted) {
+		switch {
+		case j >= len(sorted):
+			merged = append(merged, existing[i])
+			i++
+		case i >= len(existing):
+			merged = appendUnique(merged, sorted[j])
+			j++
+		case existing[i].OpenTime < sorted[j].OpenTime:
+			merged = append(merged, existing[i])
+			i++
+		case existing[i].OpenTime > sorted[j].OpenTime:
+			merged = appendUnique(merged, sorted[j])
+			j++
+		default:
+			merged = appendUnique(merged, sorted[j])
+			i++
+			j++
+		}
+	}
+	return merged
+}
+
+// appendUnique appends a kline unless the previous entry has the same OpenTime
+func appendUnique(klines []models.Kline, kline models.Kline) []models.Kline {
+	if len(klines) > 0 && klines[len(klines)-1].OpenTime == kline.OpenTime {
+		klines[len(klines)-1] = kline
+		return klines
+	}
+	return append(klines, kline)
+}
@@ -0,0 +1,107 @@
+package binance
+
+import (
+	"sync"
+)
+
+const (
+	// DefaultRequestLogSize is the number of recent requests kept when no size is configured
+	DefaultRequestLogSize = 500
+)
+
+// RequestLogEntry records a single request made to the Binance API
+type RequestLogEntry struct {
+	Endpoint    string `json:"endpoint"`
+	Symbol      string `json:"symbol"`
+	Interval    string `json:"interval,omitempty"`
+	Limit       int    `json:"limit,omitempty"`
+	StartTime   *int64 `json:"startTime,omitempty"`
+	EndTime     *int64 `json:"endTime,omitempty"`
+	RequestedAt int64  `json:"requestedAt"` // Wall clock time in milliseconds
+	WaitMs      int64  `json:"waitMs"`      // Time spent waiting on the local rate limiter
+	DurationMs  int64  `json:"durationMs"`  // Time spent on the HTTP request
+	Results     int    `json:"results"`
+	Error       string `json:"error,omitempty"`
+}
+
+// RequestLogSnapshot summarizes logged requests
+type RequestLogSnapshot struct {
+	TotalRequests int64             `json:"totalRequests"`
+	TotalErrors   int64             `json:"totalErrors"`
+	ByEndpoint    map[string]int64  `json:"byEndpoint"`
+	BySymbol      map[string]int64  `json:"bySymbol"`
+	Capacity      int               `json:"capacity"`
+	Recent        []RequestLogEntry `json:"recent"` // Oldest first
+}
+
+// RequestLog keeps counters and a ring buffer of recent Binance requests
+type RequestLog struct {
+	mu            sync.Mutex
+	entries       []RequestLogEntry
+	next          int
+	full          bool
+	totalRequests int64
+	totalErrors   int64
+	byEndpoint    map[string]int64
+	bySymbol      map[string]int64
+}
+
+// NewRequestLog creates a request log keeping the given number of recent entries
+func NewRequestLog(capacity int) *RequestLog {
+	if capacity <= 0 {
+		capacity = DefaultRequestLogSize
+	}
+	return &RequestLog{
+		entries:    make([]RequestLogEntry, capacity),
+		byEndpoint: make(map[string]int64),
+		bySymbol:   make(map[string]int64),
+	}
+}
+
+// Record adds a request to the log
+func (rl *RequestLog) Record(entry RequestLogEntry) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.totalRequests++
+	if entry.Error != "" {
+		rl.totalErrors++
+	}
+	rl.byEndpoint[entry.Endpoint]++
+	if entry.Symbol != "" {
+		rl.bySymbol[entry.Symbol]++
+	}
+
+	rl.entries[rl.next] = entry
+	rl.next = (rl.next + 1) % len(rl.entries)
+	if rl.next == 0 {
+		rl.full = true
+	}
+}
+
+// Snapshot returns a copy of the counters and recent entries
+func (rl *RequestLog) Snapshot() RequestLogSnapshot {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	snapshot := RequestLogSnapshot{
+		TotalRequests: rl.totalRequests,
+		TotalErrors:   rl.totalErrors,
+		ByEndpoint:    make(map[string]int64, len(rl.byEndpoint)),
+		BySymbol:      make(map[string]int64, len(rl.bySymbol)),
+		Capacity:      len(rl.entries),
+	}
+	for endpoint, count := range rl.byEndpoint {
+		snapshot.ByEndpoint[endpoint] = count
+	}
+	for symbol, count := range rl.bySymbol {
+		snapshot.BySymbol[symbol] = count
+	}
+
+	if rl.full {
+		snapshot.Recent = append(snapshot.Recent, rl.entries[rl.next:]...)
+	}
+	snapshot.Recent = append(snapshot.Recent, rl.entries[:rl.next]...)
+
+	return snapshot
+}
@@ -0,0 +1,134 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"tradesimulator/internal/models"
+
+	"github.com/adshao/go-binance/v2/common"
+)
+
+// DefaultSupportedSymbols are the trading pairs supported when none are configured
+var DefaultSupportedSymbols = []string{"BTCUSDT", "ETHUSDT"}
+
+// supportedQuoteAsset is the quote asset every supported pair must use, as portfolios are held in it
+const supportedQuoteAsset = "USDT"
+
+// ParseSupportedSymbols parses a comma-separated symbol list, normalizing each symbol and skipping blanks and repeats
+func ParseSupportedSymbols(value string) []string {
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		symbol := models.NormalizeSymbol(entry)
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// SetSupportedSymbols replaces the supported trading pairs, which must be USDT pairs
+// Call during startup before the service is shared
+func (b *BinanceService) SetSupportedSymbols(symbols []string) error {
+	if len(symbols) == 0 {
+		return fmt.Errorf("at least one symbol must be supported")
+	}
+	for _, symbol := range symbols {
+		if !strings.HasSuffix(symbol, supportedQuoteAsset) || symbol == supportedQuoteAsset {
+			return fmt.Errorf("unsupported symbol %s: only %s pairs can be traded", symbol, supportedQuoteAsset)
+		}
+	}
+
+	b.symbols = append([]string(nil), symbols...)
+	return nil
+}
+
+// VerifySupportedSymbols checks the supported pairs against Binance's exchange info and drops the ones
+// Binance doesn't list as a USDT pair, returning the dropped symbols
+// Pairs that can't be checked, e.g. because Binance is unreachable, are kept. Call during startup before the service is shared
+func (b *BinanceService) VerifySupportedSymbols() []string {
+	var verified, dropped []string
+	for _, symbol := range b.symbols {
+		listed, err := b.isListedSymbol(symbol)
+		if err != nil {
+			log.Printf("Could not verify symbol %s with Binance, keeping it: %v", symbol, err)
+			verified = append(verified, symbol)
+			continue
+		}
+		if !listed {
+			dropped = append(dropped, symbol)
+			continue
+		}
+		verified = append(verified, symbol)
+	}
+
+	b.symbols = verified
+	return dropped
+}
+
+// isListedSymbol looks a pair up in Binance's exchange info, reporting whether it exists with the supported quote asset
+func (b *BinanceService) isListedSymbol(symbol string) (bool, error) {
+	waitStart := time.Now()
+	if err := b.waitForRateLimit(); err != nil {
+		return false, fmt.Errorf("rate limit error: %w", err)
+	}
+	waitMs := time.Since(waitStart).Milliseconds()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	requestStart := time.Now()
+	info, err := b.client.NewExchangeInfoService().Symbol(symbol).Do(ctx)
+	results := 0
+	if info != nil {
+		results = len(info.Symbols)
+	}
+	b.recordRequest(RequestLogEntry{
+		Endpoint:    "exchange_info",
+		Symbol:      symbol,
+		RequestedAt: requestStart.UnixMilli(),
+		WaitMs:      waitMs,
+		DurationMs:  time.Since(requestStart).Milliseconds(),
+		Results:     results,
+	}, err)
+	if err != nil {
+		// Binance rejects symbols it doesn't know with an API error
+		if common.IsAPIError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to fetch exchange info: %w", err)
+	}
+
+	for _, listed := range info.Symbols {
+		if listed.Symbol == symbol {
+			return listed.QuoteAsset == supportedQuoteAsset, nil
+		}
+	}
+	return false, nil
+}
+
+// isSupportedSymbol checks if the symbol is in our supported list
+func (b *BinanceService) isSupportedSymbol(symbol string) bool {
+	for _, supported := range b.symbols {
+		if supported == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+// GetSupportedSymbols returns list of supported trading pairs
+func (b *BinanceService) GetSupportedSymbols() []string {
+	return append([]string(nil), b.symbols...)
+}
+
+// unsupportedSymbolError reports a symbol outside the supported list
+func (b *BinanceService) unsupportedSymbolError(symbol string) error {
+	return fmt.Errorf("unsupported symbol: %s. Supported symbols: %s", symbol, strings.Join(b.symbols, ", "))
+}
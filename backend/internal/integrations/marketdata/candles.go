@@ -0,0 +1,131 @@
+package marketdata
+
+import (
+	"sort"
+	"strconv"
+
+	"tradesimulator/internal/integrations/binance"
+	"tradesimulator/internal/models"
+)
+
+// defaultCandleLimit is the number of candles returned when no limit is given, matching Binance's default
+const defaultCandleLimit = 500
+
+// isFixedInterval reports whether interval is a Binance kline interval of fixed length
+// Weekly and monthly candles are calendar-aligned and can't be bucketed by duration
+func isFixedInterval(interval string) bool {
+	if interval == "1w" || interval == "1M" {
+		return false
+	}
+	for _, supported := range binance.KlineIntervals() {
+		if supported == interval {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregatableIntervals returns baseInterval and the larger fixed intervals that are whole multiples of it, shortest first
+func aggregatableIntervals(baseInterval string) []string {
+	baseMs := models.GetIntervalDurationMs(baseInterval)
+	var intervals []string
+	for _, interval := range binance.KlineIntervals() {
+		if !isFixedInterval(interval) {
+			continue
+		}
+		durationMs := models.GetIntervalDurationMs(interval)
+		if durationMs >= baseMs && durationMs%baseMs == 0 {
+			intervals = append(intervals, interval)
+		}
+	}
+	return intervals
+}
+
+// selectCandles picks candles from an ascending series with the same range semantics as Binance:
+// from startTime forwards when given, otherwise the latest candles up to endTime. Returns a copy
+func selectCandles(series []models.OHLCV, limit int, startTime, endTime *int64) []models.OHLCV {
+	if limit <= 0 {
+		limit = defaultCandleLimit
+	}
+
+	from := 0
+	if startTime != nil {
+		from = sort.Search(len(series), func(i int) bool { return series[i].StartTime >= *startTime })
+	}
+	to := len(series)
+	if endTime != nil {
+		to = sort.Search(len(series), func(i int) bool { return series[i].StartTime > *endTime })
+	}
+	if from >= to {
+		return []models.OHLCV{}
+	}
+	if to-from > limit {
+		if startTime != nil {
+			to = from + limit
+		} else {
+			from = to - limit
+		}
+	}
+
+	result := make([]models.OHLCV, to-from)
+	copy(result, series[from:to])
+	return result
+}
+
+// cutOffLastCandle replaces a last candle containing endTime with a partial candle built from the
+// source candles that started before endTime
+func cutOffLastCandle(candles, source []models.OHLCV, interval string, endTime *int64) {
+	if endTime == nil || len(candles) == 0 {
+		return
+	}
+	last := &candles[len(candles)-1]
+	if *endTime <= last.StartTime || *endTime >= last.EndTime {
+		return
+	}
+
+	from := sort.Search(len(source), func(i int) bool { return source[i].StartTime >= last.StartTime })
+	to := sort.Search(len(source), func(i int) bool { return source[i].StartTime >= *endTime })
+	*last = models.CreateIncompleteCandle(last.StartTime, *endTime, interval, source[from:to])
+}
+
+// aggregateCandles groups base candles into interval candles; a candle missing any of its base candles is marked incomplete
+func aggregateCandles(base []models.OHLCV, baseInterval, interval string) []models.OHLCV {
+	durationMs := models.GetIntervalDurationMs(interval)
+	expected := int(durationMs / models.GetIntervalDurationMs(baseInterval))
+
+	var series []models.OHLCV
+	for from := 0; from < len(base); {
+		startTime := models.CalculateCandleStartTime(base[from].StartTime, interval)
+		to := from
+		for to < len(base) && base[to].StartTime < startTime+durationMs {
+			to++
+		}
+		candle := models.CreateIncompleteCandle(startTime, startTime+durationMs-1, interval, base[from:to])
+		candle.IsComplete = to-from == expected
+		series = append(series, candle)
+		from = to
+	}
+	return series
+}
+
+// toKlines converts candles to Binance's kline format
+func toKlines(candles []models.OHLCV) []models.Kline {
+	klines := make([]models.Kline, 0, len(candles))
+	for _, candle := range candles {
+		klines = append(klines, models.Kline{
+			OpenTime:  candle.StartTime,
+			Open:      formatPrice(candle.Open),
+			High:      formatPrice(candle.High),
+			Low:       formatPrice(candle.Low),
+			Close:     formatPrice(candle.Close),
+			Volume:    formatPrice(candle.Volume),
+			CloseTime: candle.EndTime,
+		})
+	}
+	return klines
+}
+
+// formatPrice formats a value the way Kline string fields carry it
+func formatPrice(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
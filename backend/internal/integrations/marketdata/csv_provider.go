@@ -0,0 +1,234 @@
+package marketdata
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"tradesimulator/internal/models"
+)
+
+// csvColumns are the expected header columns of a market data CSV file; open_time is in Unix milliseconds
+var csvColumns = []string{"symbol", "open_time", "open", "high", "low", "close", "volume"}
+
+// CSVMarketDataProvider serves candles loaded from a CSV file instead of an exchange
+// The file holds candles at a single base interval; larger intervals that are whole multiples of it are aggregated on demand
+type CSVMarketDataProvider struct {
+	baseInterval string
+	candles      map[string][]models.OHLCV // Base interval candles per symbol, ascending by start time
+	symbols      []string
+
+	mu         sync.Mutex
+	aggregated map[string][]models.OHLCV // Aggregated candles keyed by symbol and interval
+}
+
+var _ MarketDataProvider = (*CSVMarketDataProvider)(nil)
+
+// NewCSVMarketDataProvider loads OHLCV candles at baseInterval from the CSV file at path
+// The file must start with the header symbol,open_time,open,high,low,close,volume
+func NewCSVMarketDataProvider(path, baseInterval string) (*CSVMarketDataProvider, error) {
+	if !isFixedInterval(baseInterval) {
+		return nil, fmt.Errorf("unsupported base interval %q", baseInterval)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open market data file: %w", err)
+	}
+	defer file.Close()
+
+	candles, err := readCSVCandles(file, baseInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read market data file %s: %w", path, err)
+	}
+	if len(candles) == 0 {
+		return nil, fmt.Errorf("market data file %s has no candles", path)
+	}
+
+	provider := &CSVMarketDataProvider{
+		baseInterval: baseInterval,
+		candles:      candles,
+		aggregated:   make(map[string][]models.OHLCV),
+	}
+	for symbol := range candles {
+		provider.symbols = append(provider.symbols, symbol)
+	}
+	sort.Strings(provider.symbols)
+	return provider, nil
+}
+
+// readCSVCandles parses the CSV rows into base interval candles per symbol, sorted and without duplicate start times
+func readCSVCandles(r io.Reader, baseInterval string) (map[string][]models.OHLCV, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvColumns)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	for i, column := range csvColumns {
+		if strings.ToLower(strings.TrimSpace(header[i])) != column {
+			return nil, fmt.Errorf("unexpected header %q, expected %s", strings.Join(header, ","), strings.Join(csvColumns, ","))
+		}
+	}
+
+	durationMs := models.GetIntervalDurationMs(baseInterval)
+	candles := make(map[string][]models.OHLCV)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		symbol := models.NormalizeSymbol(record[0])
+		if symbol == "" {
+			return nil, fmt.Errorf("line %d: missing symbol", line)
+		}
+		openTime, err := strconv.ParseInt(strings.TrimSpace(record[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid open_time: %w", line, err)
+		}
+		if openTime%durationMs != 0 {
+			return nil, fmt.Errorf("line %d: open_time %d is not aligned to the %s interval", line, openTime, baseInterval)
+		}
+		var values [5]float64
+		for i := range values {
+			values[i], err = strconv.ParseFloat(strings.TrimSpace(record[i+2]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s: %w", line, csvColumns[i+2], err)
+			}
+		}
+
+		candles[symbol] = append(candles[symbol], models.OHLCV{
+			StartTime:  openTime,
+			EndTime:    openTime + durationMs - 1,
+			Open:       values[0],
+			High:       values[1],
+			Low:        values[2],
+			Close:      values[3],
+			Volume:     values[4],
+			IsComplete: true,
+		})
+	}
+
+	for symbol, series := range candles {
+		sort.SliceStable(series, func(i, j int) bool { return series[i].StartTime < series[j].StartTime })
+		// Keep the last row for a repeated start time
+		deduped := series[:0]
+		for _, candle := range series {
+			if len(deduped) > 0 && deduped[len(deduped)-1].StartTime == candle.StartTime {
+				deduped[len(deduped)-1] = candle
+				continue
+			}
+			deduped = append(deduped, candle)
+		}
+		candles[symbol] = deduped
+	}
+	return candles, nil
+}
+
+// GetHistoricalData returns candles for a symbol and interval, with the same range semantics as Binance:
+// from startTime forwards when given, otherwise the latest candles up to endTime
+// With enableIncomplete, a last candle containing endTime is cut off at endTime
+func (p *CSVMarketDataProvider) GetHistoricalData(symbol, interval string, limit int, startTime, endTime *int64, enableIncomplete bool) ([]models.OHLCV, error) {
+	series, err := p.series(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	result := selectCandles(series, limit, startTime, endTime)
+	if enableIncomplete {
+		cutOffLastCandle(result, p.candles[symbol], interval, endTime)
+	}
+	return result, nil
+}
+
+// GetKlines returns candles in Binance's kline format
+func (p *CSVMarketDataProvider) GetKlines(symbol, interval string, limit int, startTime, endTime *int64) ([]models.Kline, error) {
+	candles, err := p.GetHistoricalData(symbol, interval, limit, startTime, endTime, false)
+	if err != nil {
+		return nil, err
+	}
+	return toKlines(candles), nil
+}
+
+// GetEarliestAvailableTime returns the start time of the symbol's first candle in the file
+func (p *CSVMarketDataProvider) GetEarliestAvailableTime(symbol string) (int64, error) {
+	base, ok := p.candles[symbol]
+	if !ok {
+		return 0, p.unsupportedSymbolError(symbol)
+	}
+	return base[0].StartTime, nil
+}
+
+// GetLatestPrices returns the close of each symbol's last candle in the file
+func (p *CSVMarketDataProvider) GetLatestPrices(symbols []string) (map[string]float64, error) {
+	prices := make(map[string]float64, len(symbols))
+	for _, symbol := range symbols {
+		base, ok := p.candles[symbol]
+		if !ok {
+			return nil, p.unsupportedSymbolError(symbol)
+		}
+		prices[symbol] = base[len(base)-1].Close
+	}
+	return prices, nil
+}
+
+// GetSupportedSymbols returns the symbols found in the file
+func (p *CSVMarketDataProvider) GetSupportedSymbols() []string {
+	return append([]string(nil), p.symbols...)
+}
+
+// GetSupportedIntervals returns the base interval and the larger intervals aggregated from it, shortest first
+func (p *CSVMarketDataProvider) GetSupportedIntervals() []string {
+	return aggregatableIntervals(p.baseInterval)
+}
+
+// ValidateInterval checks if the interval can be served from the file
+func (p *CSVMarketDataProvider) ValidateInterval(interval string) bool {
+	for _, supported := range p.GetSupportedIntervals() {
+		if supported == interval {
+			return true
+		}
+	}
+	return false
+}
+
+// series returns the symbol's candles at an interval, aggregating and caching them on first use
+func (p *CSVMarketDataProvider) series(symbol, interval string) ([]models.OHLCV, error) {
+	base, ok := p.candles[symbol]
+	if !ok {
+		return nil, p.unsupportedSymbolError(symbol)
+	}
+	if !p.ValidateInterval(interval) {
+		return nil, fmt.Errorf("unsupported interval %s for CSV market data with base interval %s", interval, p.baseInterval)
+	}
+	if interval == p.baseInterval {
+		return base, nil
+	}
+
+	key := symbol + "|" + interval
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if series, ok := p.aggregated[key]; ok {
+		return series, nil
+	}
+	series := aggregateCandles(base, p.baseInterval, interval)
+	p.aggregated[key] = series
+	return series, nil
+}
+
+// unsupportedSymbolError reports a symbol that isn't in the file
+func (p *CSVMarketDataProvider) unsupportedSymbolError(symbol string) error {
+	return fmt.Errorf("unsupported symbol: %s. Supported symbols: %s", symbol, strings.Join(p.symbols, ", "))
+}
@@ -0,0 +1,113 @@
+package marketdata
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"tradesimulator/internal/models"
+)
+
+// MaxImportRows is the most candles a single import can hold
+const MaxImportRows = 500000
+
+// ErrInvalidImport is wrapped by every error caused by the imported data rather than the server
+var ErrInvalidImport = errors.New("invalid import")
+
+// importColumns are the columns of an import CSV; startTime is in Unix milliseconds
+var importColumns = []string{"startTime", "open", "high", "low", "close", "volume"}
+
+// ParseImportCSV parses candle rows at interval, requiring aligned, strictly increasing start times and sane prices
+// A startTime,open,high,low,close,volume header row is optional. Errors name the row, counting from 1 at the top of the file
+func ParseImportCSV(r io.Reader, interval string) ([]models.OHLCV, error) {
+	if !isFixedInterval(interval) {
+		return nil, fmt.Errorf("%w: unsupported interval %q", ErrInvalidImport, interval)
+	}
+	durationMs := models.GetIntervalDurationMs(interval)
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(importColumns)
+	reader.TrimLeadingSpace = true
+
+	var candles []models.OHLCV
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidImport, parseErr.StartLine, parseErr.Err)
+			}
+			return nil, fmt.Errorf("failed to read import: %w", err)
+		}
+		row, _ := reader.FieldPos(0)
+
+		if len(candles) == 0 && strings.EqualFold(strings.TrimSpace(record[0]), importColumns[0]) {
+			continue // Header row
+		}
+		if len(candles) == MaxImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidImport, MaxImportRows)
+		}
+
+		candle, err := parseImportRow(record, durationMs)
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidImport, row, err)
+		}
+		if len(candles) > 0 && candle.StartTime <= candles[len(candles)-1].StartTime {
+			return nil, fmt.Errorf("%w: row %d: startTime %d is not after the previous row's %d",
+				ErrInvalidImport, row, candle.StartTime, candles[len(candles)-1].StartTime)
+		}
+		candles = append(candles, candle)
+	}
+
+	if len(candles) == 0 {
+		return nil, fmt.Errorf("%w: no candle rows", ErrInvalidImport)
+	}
+	return candles, nil
+}
+
+// parseImportRow parses one candle row and checks it describes a valid candle
+func parseImportRow(record []string, durationMs int64) (models.OHLCV, error) {
+	startTime, err := strconv.ParseInt(strings.TrimSpace(record[0]), 10, 64)
+	if err != nil {
+		return models.OHLCV{}, fmt.Errorf("invalid startTime %q", record[0])
+	}
+	if startTime < 0 || startTime%durationMs != 0 {
+		return models.OHLCV{}, fmt.Errorf("startTime %d is not aligned to the interval", startTime)
+	}
+
+	var values [5]float64
+	for i := range values {
+		values[i], err = strconv.ParseFloat(strings.TrimSpace(record[i+1]), 64)
+		if err != nil || math.IsNaN(values[i]) || math.IsInf(values[i], 0) {
+			return models.OHLCV{}, fmt.Errorf("invalid %s %q", importColumns[i+1], record[i+1])
+		}
+	}
+	open, high, low, closePrice, volume := values[0], values[1], values[2], values[3], values[4]
+
+	switch {
+	case open <= 0 || high <= 0 || low <= 0 || closePrice <= 0:
+		return models.OHLCV{}, fmt.Errorf("prices must be positive")
+	case volume < 0:
+		return models.OHLCV{}, fmt.Errorf("volume must not be negative")
+	case high < math.Max(open, closePrice) || low > math.Min(open, closePrice):
+		return models.OHLCV{}, fmt.Errorf("open and close must lie between low and high")
+	}
+
+	return models.OHLCV{
+		StartTime:  startTime,
+		EndTime:    startTime + durationMs - 1,
+		Open:       open,
+		High:       high,
+		Low:        low,
+		Close:      closePrice,
+		Volume:     volume,
+		IsComplete: true,
+	}, nil
+}
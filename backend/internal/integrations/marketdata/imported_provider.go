@@ -0,0 +1,319 @@
+package marketdata
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	marketDAO "tradesimulator/internal/dao/market"
+	"tradesimulator/internal/integrations/binance"
+	"tradesimulator/internal/models"
+)
+
+// importedQuoteAsset is the quote asset imported symbols must use, as portfolios are held in it
+const importedQuoteAsset = "USDT"
+
+// ImportedMarketDataProvider serves candle series imported into the database and passes every other symbol
+// to the upstream provider. A symbol's imported intervals are served as stored; larger intervals are aggregated
+// from the longest imported interval that divides them
+type ImportedMarketDataProvider struct {
+	upstream MarketDataProvider
+	dao      marketDAO.ImportedCandleDAOInterface
+
+	mu       sync.Mutex
+	imported map[string][]marketDAO.ImportedSeries // Imported series per symbol, shortest interval first
+	series   map[string][]models.OHLCV             // Loaded and aggregated candles keyed by symbol and interval
+}
+
+var _ MarketDataProvider = (*ImportedMarketDataProvider)(nil)
+
+// NewImportedMarketDataProvider layers the imported series stored through dao over upstream
+func NewImportedMarketDataProvider(upstream MarketDataProvider, dao marketDAO.ImportedCandleDAOInterface) (*ImportedMarketDataProvider, error) {
+	stored, err := dao.ListSeries()
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &ImportedMarketDataProvider{
+		upstream: upstream,
+		dao:      dao,
+		imported: make(map[string][]marketDAO.ImportedSeries),
+		series:   make(map[string][]models.OHLCV),
+	}
+	for _, series := range stored {
+		provider.addSeriesUnsafe(series)
+	}
+	return provider, nil
+}
+
+// Upstream returns the provider serving symbols that weren't imported
+func (p *ImportedMarketDataProvider) Upstream() MarketDataProvider {
+	return p.upstream
+}
+
+// Import stores candles as the symbol's series at interval, replacing an earlier import of the same series
+// The symbol must be a USDT pair the upstream provider doesn't serve
+func (p *ImportedMarketDataProvider) Import(symbol, interval string, candles []models.OHLCV) (*marketDAO.ImportedSeries, error) {
+	symbol = models.NormalizeSymbol(symbol)
+	if err := p.validateImportSymbol(symbol); err != nil {
+		return nil, err
+	}
+	if !isFixedInterval(interval) {
+		return nil, fmt.Errorf("%w: unsupported interval %q", ErrInvalidImport, interval)
+	}
+	if len(candles) == 0 {
+		return nil, fmt.Errorf("%w: no candle rows", ErrInvalidImport)
+	}
+
+	rows := make([]models.ImportedCandle, 0, len(candles))
+	for _, candle := range candles {
+		rows = append(rows, models.ImportedCandle{
+			Symbol:    symbol,
+			Interval:  interval,
+			StartTime: candle.StartTime,
+			Open:      candle.Open,
+			High:      candle.High,
+			Low:       candle.Low,
+			Close:     candle.Close,
+			Volume:    candle.Volume,
+		})
+	}
+
+	if err := p.dao.ReplaceSeries(symbol, interval, rows); err != nil {
+		return nil, err
+	}
+
+	series := marketDAO.ImportedSeries{
+		Symbol:      symbol,
+		Interval:    interval,
+		CandleCount: len(candles),
+		StartTime:   candles[0].StartTime,
+		EndTime:     candles[len(candles)-1].StartTime,
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.addSeriesUnsafe(series)
+	// Aggregated series of the symbol may have been built from the replaced candles
+	for key := range p.series {
+		if strings.HasPrefix(key, symbol+"|") {
+			delete(p.series, key)
+		}
+	}
+	return &series, nil
+}
+
+// validateImportSymbol checks an import targets a USDT pair that won't shadow upstream data
+func (p *ImportedMarketDataProvider) validateImportSymbol(symbol string) error {
+	if !strings.HasSuffix(symbol, importedQuoteAsset) || symbol == importedQuoteAsset || len(symbol) > 20 {
+		return fmt.Errorf("%w: symbol %q must be a %s pair of at most 20 characters", ErrInvalidImport, symbol, importedQuoteAsset)
+	}
+	for _, served := range p.upstream.GetSupportedSymbols() {
+		if served == symbol {
+			return fmt.Errorf("%w: symbol %s is served by the market data provider", ErrInvalidImport, symbol)
+		}
+	}
+	return nil
+}
+
+// addSeriesUnsafe records an imported series, replacing the entry for the same interval (caller must hold lock)
+func (p *ImportedMarketDataProvider) addSeriesUnsafe(series marketDAO.ImportedSeries) {
+	// Build a new slice, earlier ones may still be read outside the lock
+	entries := make([]marketDAO.ImportedSeries, 0, len(p.imported[series.Symbol])+1)
+	for _, entry := range p.imported[series.Symbol] {
+		if entry.Interval != series.Interval {
+			entries = append(entries, entry)
+		}
+	}
+	entries = append(entries, series)
+	sort.Slice(entries, func(i, j int) bool {
+		return models.GetIntervalDurationMs(entries[i].Interval) < models.GetIntervalDurationMs(entries[j].Interval)
+	})
+	p.imported[series.Symbol] = entries
+}
+
+// importedSeries returns the imported series of a symbol, or nil if the symbol wasn't imported
+func (p *ImportedMarketDataProvider) importedSeries(symbol string) []marketDAO.ImportedSeries {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.imported[symbol]
+}
+
+// candles returns a symbol's candles at interval and the series they were built from, loading and aggregating
+// them on first use
+func (p *ImportedMarketDataProvider) candles(symbol, interval string) (series, source []models.OHLCV, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Pick the interval itself, or else the longest imported interval it's a whole multiple of
+	durationMs := models.GetIntervalDurationMs(interval)
+	sourceInterval := ""
+	for _, imported := range p.imported[symbol] {
+		importedMs := models.GetIntervalDurationMs(imported.Interval)
+		if isFixedInterval(interval) && importedMs <= durationMs && durationMs%importedMs == 0 {
+			sourceInterval = imported.Interval
+		}
+	}
+	if sourceInterval == "" {
+		return nil, nil, fmt.Errorf("no imported %s data at %s or an interval that divides it", symbol, interval)
+	}
+
+	source, err = p.loadUnsafe(symbol, sourceInterval)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sourceInterval == interval {
+		return source, source, nil
+	}
+
+	key := symbol + "|" + interval
+	if cached, ok := p.series[key]; ok {
+		return cached, source, nil
+	}
+	series = aggregateCandles(source, sourceInterval, interval)
+	p.series[key] = series
+	return series, source, nil
+}
+
+// loadUnsafe returns an imported series as stored, reading it from the database on first use (caller must hold lock)
+func (p *ImportedMarketDataProvider) loadUnsafe(symbol, interval string) ([]models.OHLCV, error) {
+	key := symbol + "|" + interval
+	if cached, ok := p.series[key]; ok {
+		return cached, nil
+	}
+
+	rows, err := p.dao.GetSeries(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+	series := make([]models.OHLCV, 0, len(rows))
+	for i := range rows {
+		series = append(series, rows[i].ToOHLCV())
+	}
+	p.series[key] = series
+	return series, nil
+}
+
+// GetHistoricalData returns imported candles with the same range semantics as Binance, or the upstream's
+// candles for symbols that weren't imported
+func (p *ImportedMarketDataProvider) GetHistoricalData(symbol, interval string, limit int, startTime, endTime *int64, enableIncomplete bool) ([]models.OHLCV, error) {
+	if p.importedSeries(symbol) == nil {
+		return p.upstream.GetHistoricalData(symbol, interval, limit, startTime, endTime, enableIncomplete)
+	}
+
+	series, source, err := p.candles(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+	result := selectCandles(series, limit, startTime, endTime)
+	if enableIncomplete {
+		cutOffLastCandle(result, source, interval, endTime)
+	}
+	return result, nil
+}
+
+// GetHistoricalDataWithSource fetches historical data and reports where it was served from
+// Imported candles count as served from the source
+func (p *ImportedMarketDataProvider) GetHistoricalDataWithSource(symbol, interval string, limit int, startTime, endTime *int64, enableIncomplete bool) ([]models.OHLCV, binance.DataSource, error) {
+	if upstream, ok := p.upstream.(SourceReportingProvider); ok && p.importedSeries(symbol) == nil {
+		return upstream.GetHistoricalDataWithSource(symbol, interval, limit, startTime, endTime, enableIncomplete)
+	}
+	data, err := p.GetHistoricalData(symbol, interval, limit, startTime, endTime, enableIncomplete)
+	return data, binance.DataSourceUpstream, err
+}
+
+// GetKlines returns candles in Binance's kline format
+func (p *ImportedMarketDataProvider) GetKlines(symbol, interval string, limit int, startTime, endTime *int64) ([]models.Kline, error) {
+	if p.importedSeries(symbol) == nil {
+		return p.upstream.GetKlines(symbol, interval, limit, startTime, endTime)
+	}
+
+	candles, err := p.GetHistoricalData(symbol, interval, limit, startTime, endTime, false)
+	if err != nil {
+		return nil, err
+	}
+	return toKlines(candles), nil
+}
+
+// GetEarliestAvailableTime returns the start time of a symbol's first imported candle across its intervals
+func (p *ImportedMarketDataProvider) GetEarliestAvailableTime(symbol string) (int64, error) {
+	imported := p.importedSeries(symbol)
+	if imported == nil {
+		return p.upstream.GetEarliestAvailableTime(symbol)
+	}
+
+	earliest := imported[0].StartTime
+	for _, series := range imported[1:] {
+		if series.StartTime < earliest {
+			earliest = series.StartTime
+		}
+	}
+	return earliest, nil
+}
+
+// GetLatestPrices returns the last imported close of imported symbols and asks the upstream for the rest
+func (p *ImportedMarketDataProvider) GetLatestPrices(symbols []string) (map[string]float64, error) {
+	prices := make(map[string]float64, len(symbols))
+	var upstreamSymbols []string
+	for _, symbol := range symbols {
+		imported := p.importedSeries(symbol)
+		if imported == nil {
+			upstreamSymbols = append(upstreamSymbols, symbol)
+			continue
+		}
+
+		// Use the series whose last candle closes latest
+		latest := imported[0]
+		for _, series := range imported[1:] {
+			if seriesCloseTime(series) > seriesCloseTime(latest) {
+				latest = series
+			}
+		}
+		series, _, err := p.candles(symbol, latest.Interval)
+		if err != nil {
+			return nil, err
+		}
+		prices[symbol] = series[len(series)-1].Close
+	}
+
+	if len(upstreamSymbols) > 0 {
+		upstreamPrices, err := p.upstream.GetLatestPrices(upstreamSymbols)
+		if err != nil {
+			return nil, err
+		}
+		for symbol, price := range upstreamPrices {
+			prices[symbol] = price
+		}
+	}
+	return prices, nil
+}
+
+// seriesCloseTime returns the close time of an imported series' last candle
+func seriesCloseTime(series marketDAO.ImportedSeries) int64 {
+	return series.EndTime + models.GetIntervalDurationMs(series.Interval) - 1
+}
+
+// GetSupportedSymbols returns the upstream's symbols followed by the imported ones
+func (p *ImportedMarketDataProvider) GetSupportedSymbols() []string {
+	symbols := p.upstream.GetSupportedSymbols()
+
+	p.mu.Lock()
+	imported := make([]string, 0, len(p.imported))
+	for symbol := range p.imported {
+		imported = append(imported, symbol)
+	}
+	p.mu.Unlock()
+
+	sort.Strings(imported)
+	return append(symbols, imported...)
+}
+
+// GetSupportedIntervals returns the upstream's intervals
+func (p *ImportedMarketDataProvider) GetSupportedIntervals() []string {
+	return p.upstream.GetSupportedIntervals()
+}
+
+// ValidateInterval checks the interval with the upstream; imported symbols report missing intervals when fetched
+func (p *ImportedMarketDataProvider) ValidateInterval(interval string) bool {
+	return p.upstream.ValidateInterval(interval)
+}
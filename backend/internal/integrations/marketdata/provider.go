@@ -0,0 +1,58 @@
+package marketdata
+
+import (
+	"fmt"
+
+	"tradesimulator/internal/integrations/binance"
+	"tradesimulator/internal/models"
+)
+
+// Provider names accepted by the MARKET_DATA_PROVIDER setting
+const (
+	ProviderBinance = "binance"
+	ProviderCSV     = "csv"
+)
+
+// MarketDataProvider supplies the candles and symbol metadata simulations run on
+type MarketDataProvider interface {
+	GetHistoricalData(symbol, interval string, limit int, startTime, endTime *int64, enableIncomplete bool) ([]models.OHLCV, error)
+	GetKlines(symbol, interval string, limit int, startTime, endTime *int64) ([]models.Kline, error)
+	GetEarliestAvailableTime(symbol string) (int64, error)
+	GetLatestPrices(symbols []string) (map[string]float64, error)
+	GetSupportedSymbols() []string
+	GetSupportedIntervals() []string
+	ValidateInterval(interval string) bool
+}
+
+var _ MarketDataProvider = (*binance.BinanceService)(nil)
+
+// SourceReportingProvider is implemented by providers that can tell whether candles came from a cache
+type SourceReportingProvider interface {
+	GetHistoricalDataWithSource(symbol, interval string, limit int, startTime, endTime *int64, enableIncomplete bool) ([]models.OHLCV, binance.DataSource, error)
+}
+
+// layeredProvider is implemented by providers that serve some data themselves and pass the rest to another provider
+type layeredProvider interface {
+	Upstream() MarketDataProvider
+}
+
+// Underlying returns the provider at the bottom of any layered providers, e.g. the Binance client under imported data
+func Underlying(provider MarketDataProvider) MarketDataProvider {
+	for {
+		layered, ok := provider.(layeredProvider)
+		if !ok {
+			return provider
+		}
+		provider = layered.Upstream()
+	}
+}
+
+// ParseProvider validates a market data provider setting
+func ParseProvider(value string) (string, error) {
+	switch value {
+	case ProviderBinance, ProviderCSV:
+		return value, nil
+	default:
+		return "", fmt.Errorf("unknown market data provider %q (expected binance or csv)", value)
+	}
+}
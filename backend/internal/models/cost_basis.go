@@ -0,0 +1,152 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// CostBasisMethod selects how sells are matched against earlier buys when realizing P&L
+type CostBasisMethod string
+
+const (
+	CostBasisAverage CostBasisMethod = "average" // Weighted average cost of the open position
+	CostBasisFIFO    CostBasisMethod = "fifo"    // Oldest open lots are closed first
+)
+
+// lotQuantityEpsilon treats smaller remaining quantities as fully closed
+const lotQuantityEpsilon = 1e-9
+
+var (
+	costBasisMu     sync.RWMutex
+	costBasisMethod = CostBasisAverage
+)
+
+// ParseCostBasisMethod validates a cost basis method name
+func ParseCostBasisMethod(value string) (CostBasisMethod, error) {
+	switch method := CostBasisMethod(value); method {
+	case CostBasisAverage, CostBasisFIFO:
+		return method, nil
+	default:
+		return "", fmt.Errorf("unknown cost basis method %q (expected average or fifo)", value)
+	}
+}
+
+// SetCostBasisMethod sets the method used to realize P&L
+func SetCostBasisMethod(method CostBasisMethod) {
+	costBasisMu.Lock()
+	defer costBasisMu.Unlock()
+	costBasisMethod = method
+}
+
+// GetCostBasisMethod returns the method used to realize P&L
+func GetCostBasisMethod() CostBasisMethod {
+	costBasisMu.RLock()
+	defer costBasisMu.RUnlock()
+	return costBasisMethod
+}
+
+// Lot is an open quantity of a symbol acquired by a single trade
+// Quantity is negative for short lots; Price is the per unit cost including the opening fee
+type Lot struct {
+	Quantity float64 `json:"quantity"`
+	Price    float64 `json:"price"`
+	OpenedAt int64   `json:"openedAt"` // Simulation time in milliseconds
+}
+
+// CostBasisLedger tracks open lots per symbol and realizes P&L as trades reduce them
+type CostBasisLedger struct {
+	method CostBasisMethod
+	lots   map[string][]Lot // Oldest first, all lots of a symbol share one direction
+}
+
+// NewCostBasisLedger creates a ledger using the given method
+func NewCostBasisLedger(method CostBasisMethod) *CostBasisLedger {
+	return &CostBasisLedger{
+		method: method,
+		lots:   make(map[string][]Lot),
+	}
+}
+
+// Method returns the cost basis method of the ledger
+func (l *CostBasisLedger) Method() CostBasisMethod {
+	return l.method
+}
+
+// Apply records a trade and returns the P&L it realized, net of fees
+// closing reports whether the trade reduced an open position
+func (l *CostBasisLedger) Apply(trade *Trade) (realized float64, closing bool) {
+	if trade == nil || trade.Quantity <= 0 {
+		return 0, false
+	}
+
+	// Fees are folded into the per unit price: buys cost more, sells receive less
+	signedQuantity := trade.Quantity
+	effectivePrice := trade.Price + trade.Fee/trade.Quantity
+	if trade.Side == OrderSideSell {
+		signedQuantity = -trade.Quantity
+		effectivePrice = trade.Price - trade.Fee/trade.Quantity
+	}
+
+	lots := l.lots[trade.Symbol]
+	remaining := signedQuantity
+
+	// Close lots in the opposite direction, oldest first
+	for len(lots) > 0 && math.Abs(remaining) > lotQuantityEpsilon && (lots[0].Quantity > 0) != (remaining > 0) {
+		closing = true
+		closed := math.Min(math.Abs(remaining), math.Abs(lots[0].Quantity))
+		if lots[0].Quantity > 0 {
+			realized += closed * (effectivePrice - lots[0].Price)
+			lots[0].Quantity -= closed
+			remaining += closed
+		} else {
+			realized += closed * (lots[0].Price - effectivePrice)
+			lots[0].Quantity += closed
+			remaining -= closed
+		}
+		if math.Abs(lots[0].Quantity) <= lotQuantityEpsilon {
+			lots = lots[1:]
+		}
+	}
+
+	// Any quantity left opens a new lot, merged into one averaged lot under the average method
+	if math.Abs(remaining) > lotQuantityEpsilon {
+		lot := Lot{Quantity: remaining, Price: effectivePrice, OpenedAt: trade.ExecutedAt}
+		if l.method == CostBasisAverage && len(lots) > 0 {
+			merged := lots[0]
+			totalQuantity := merged.Quantity + lot.Quantity
+			merged.Price = (merged.Quantity*merged.Price + lot.Quantity*lot.Price) / totalQuantity
+			merged.Quantity = totalQuantity
+			lots = []Lot{merged}
+		} else {
+			lots = append(lots, lot)
+		}
+	}
+
+	if len(lots) == 0 {
+		delete(l.lots, trade.Symbol)
+	} else {
+		l.lots[trade.Symbol] = lots
+	}
+	return realized, closing
+}
+
+// OpenLots returns a copy of the open lots of a symbol, oldest first
+func (l *CostBasisLedger) OpenLots(symbol string) []Lot {
+	return append([]Lot(nil), l.lots[symbol]...)
+}
+
+// UnrealizedPnL values the open lots at the given prices, symbols without a price are skipped
+func (l *CostBasisLedger) UnrealizedPnL(prices map[string]float64) float64 {
+	var total float64
+	for symbol, lots := range l.lots {
+		price, exists := prices[symbol]
+		if !exists {
+			continue
+		}
+		for _, lot := range lots {
+			total += lot.Quantity * (price - lot.Price)
+		}
+	}
+	return total
+}
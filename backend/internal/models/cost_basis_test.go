@@ -0,0 +1,155 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func costBasisTrade(side OrderSide, quantity, price, fee float64) *Trade {
+	return &Trade{Symbol: "BTCUSDT", Side: side, Quantity: quantity, Price: price, Fee: fee}
+}
+
+func TestCostBasisLedger(t *testing.T) {
+	tests := []struct {
+		name         string
+		trades       []*Trade
+		wantRealized map[CostBasisMethod]float64 // Realized by the last trade
+		wantLots     map[CostBasisMethod][]Lot
+	}{
+		{
+			name: "sell after two buys",
+			trades: []*Trade{
+				costBasisTrade(OrderSideBuy, 1, 100, 0),
+				costBasisTrade(OrderSideBuy, 1, 200, 0),
+				costBasisTrade(OrderSideSell, 1, 250, 0),
+			},
+			wantRealized: map[CostBasisMethod]float64{CostBasisFIFO: 150, CostBasisAverage: 100},
+			wantLots: map[CostBasisMethod][]Lot{
+				CostBasisFIFO:    {{Quantity: 1, Price: 200}},
+				CostBasisAverage: {{Quantity: 1, Price: 150}},
+			},
+		},
+		{
+			name: "sell spanning lots",
+			trades: []*Trade{
+				costBasisTrade(OrderSideBuy, 1, 100, 0),
+				costBasisTrade(OrderSideBuy, 2, 130, 0),
+				costBasisTrade(OrderSideSell, 2, 150, 0),
+			},
+			wantRealized: map[CostBasisMethod]float64{CostBasisFIFO: 70, CostBasisAverage: 60},
+			wantLots: map[CostBasisMethod][]Lot{
+				CostBasisFIFO:    {{Quantity: 1, Price: 130}},
+				CostBasisAverage: {{Quantity: 1, Price: 120}},
+			},
+		},
+		{
+			name: "fees are part of the cost and the proceeds",
+			trades: []*Trade{
+				costBasisTrade(OrderSideBuy, 2, 100, 2),
+				costBasisTrade(OrderSideSell, 1, 110, 1),
+			},
+			wantRealized: map[CostBasisMethod]float64{CostBasisFIFO: 8, CostBasisAverage: 8},
+			wantLots: map[CostBasisMethod][]Lot{
+				CostBasisFIFO:    {{Quantity: 1, Price: 101}},
+				CostBasisAverage: {{Quantity: 1, Price: 101}},
+			},
+		},
+		{
+			name: "covering shorts",
+			trades: []*Trade{
+				costBasisTrade(OrderSideSell, 1, 100, 0),
+				costBasisTrade(OrderSideSell, 1, 80, 0),
+				costBasisTrade(OrderSideBuy, 1, 90, 0),
+			},
+			wantRealized: map[CostBasisMethod]float64{CostBasisFIFO: 10, CostBasisAverage: 0},
+			wantLots: map[CostBasisMethod][]Lot{
+				CostBasisFIFO:    {{Quantity: -1, Price: 80}},
+				CostBasisAverage: {{Quantity: -1, Price: 90}},
+			},
+		},
+		{
+			name: "selling through a long opens a short",
+			trades: []*Trade{
+				costBasisTrade(OrderSideBuy, 1, 100, 0),
+				costBasisTrade(OrderSideSell, 3, 120, 0),
+			},
+			wantRealized: map[CostBasisMethod]float64{CostBasisFIFO: 20, CostBasisAverage: 20},
+			wantLots: map[CostBasisMethod][]Lot{
+				CostBasisFIFO:    {{Quantity: -2, Price: 120}},
+				CostBasisAverage: {{Quantity: -2, Price: 120}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, method := range []CostBasisMethod{CostBasisFIFO, CostBasisAverage} {
+			t.Run(tt.name+"/"+string(method), func(t *testing.T) {
+				ledger := NewCostBasisLedger(method)
+				var realized float64
+				var closing bool
+				for _, trade := range tt.trades {
+					realized, closing = ledger.Apply(trade)
+				}
+
+				if !closing {
+					t.Error("last trade not reported as closing")
+				}
+				if want := tt.wantRealized[method]; math.Abs(realized-want) > 1e-9 {
+					t.Errorf("realized = %g, want %g", realized, want)
+				}
+				lots := ledger.OpenLots("BTCUSDT")
+				want := tt.wantLots[method]
+				if len(lots) != len(want) {
+					t.Fatalf("open lots = %+v, want %+v", lots, want)
+				}
+				for i := range lots {
+					if math.Abs(lots[i].Quantity-want[i].Quantity) > 1e-9 || math.Abs(lots[i].Price-want[i].Price) > 1e-9 {
+						t.Errorf("lot %d = %+v, want %+v", i, lots[i], want[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestCostBasisLedgerUnrealizedPnL(t *testing.T) {
+	for _, method := range []CostBasisMethod{CostBasisFIFO, CostBasisAverage} {
+		ledger := NewCostBasisLedger(method)
+		ledger.Apply(costBasisTrade(OrderSideBuy, 1, 100, 0))
+		ledger.Apply(costBasisTrade(OrderSideBuy, 1, 200, 0))
+		ledger.Apply(&Trade{Symbol: "ETHUSDT", Side: OrderSideSell, Quantity: 2, Price: 50})
+
+		// Both methods value the same open quantity at the same total cost until a sell splits them
+		prices := map[string]float64{"BTCUSDT": 180, "ETHUSDT": 40}
+		if got := ledger.UnrealizedPnL(prices); math.Abs(got-80) > 1e-9 {
+			t.Errorf("%s: unrealized = %g, want 80", method, got)
+		}
+		if got := ledger.UnrealizedPnL(map[string]float64{"BTCUSDT": 180}); math.Abs(got-60) > 1e-9 {
+			t.Errorf("%s: unrealized without an ETH price = %g, want 60", method, got)
+		}
+	}
+}
+
+func TestCostBasisLedgerIgnoresEmptyTrades(t *testing.T) {
+	ledger := NewCostBasisLedger(CostBasisFIFO)
+	if realized, closing := ledger.Apply(nil); realized != 0 || closing {
+		t.Errorf("nil trade realized %g, closing %t", realized, closing)
+	}
+	if realized, closing := ledger.Apply(costBasisTrade(OrderSideSell, 0, 100, 0)); realized != 0 || closing {
+		t.Errorf("empty trade realized %g, closing %t", realized, closing)
+	}
+	if lots := ledger.OpenLots("BTCUSDT"); len(lots) != 0 {
+		t.Errorf("open lots = %+v, want none", lots)
+	}
+}
+
+func TestParseCostBasisMethod(t *testing.T) {
+	for _, value := range []string{"average", "fifo"} {
+		if method, err := ParseCostBasisMethod(value); err != nil || string(method) != value {
+			t.Errorf("ParseCostBasisMethod(%q) = %q, %v", value, method, err)
+		}
+	}
+	if _, err := ParseCostBasisMethod("lifo"); err == nil {
+		t.Error("ParseCostBasisMethod(\"lifo\") succeeded")
+	}
+}
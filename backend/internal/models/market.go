@@ -3,6 +3,7 @@ package models
 import (
 	"regexp"
 	"strconv"
+	"time"
 )
 
 // Kline represents a single candlestick/kline data point
@@ -82,8 +83,11 @@ type HistoricalDataRequest struct {
 
 // HistoricalDataResponse represents the response structure
 type HistoricalDataResponse struct {
-	Symbol string  `json:"symbol"`
-	Data   []OHLCV `json:"data"`
+	Symbol    string  `json:"symbol"`
+	Data      []OHLCV `json:"data"`
+	FromCache bool    `json:"fromCache"`        // Served from the kline cache instead of Binance
+	Stale     bool    `json:"stale"`            // Binance was unreachable, data may not cover the full range
+	Cutoff    *int64  `json:"cutoff,omitempty"` // Set when an active simulation limited data to this time
 }
 
 // EarliestTimeResponse represents the response for earliest available time
@@ -93,6 +97,15 @@ type EarliestTimeResponse struct {
 	EarliestTimeISO string `json:"earliestTimeISO"`
 }
 
+// RandomStartResponse represents a randomly selected simulation start time
+type RandomStartResponse struct {
+	Symbol        string `json:"symbol"`
+	StartTime     int64  `json:"startTime"`
+	StartTimeISO  string `json:"startTimeISO"`
+	MinDuration   int64  `json:"minDuration"`   // Guaranteed replay duration after StartTime in milliseconds
+	ExcludeRecent int64  `json:"excludeRecent"` // Most recent period excluded from selection in milliseconds
+}
+
 // CreateIncompleteCandle creates an incomplete OHLCV from base candles
 func CreateIncompleteCandle(startTime int64, targetEndTime int64, interval string, baseCandles []OHLCV) OHLCV {
 	if len(baseCandles) == 0 {
@@ -178,3 +191,35 @@ func CalculateCandleStartTime(timestamp int64, interval string) int64 {
 	durationMs := GetIntervalDurationMs(interval)
 	return (timestamp / durationMs) * durationMs
 }
+
+// ImportedCandle is a candle of a custom series imported for backtesting on data Binance doesn't have
+type ImportedCandle struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Symbol    string    `json:"symbol" gorm:"not null"`
+	Interval  string    `json:"interval" gorm:"not null"`
+	StartTime int64     `json:"start_time" gorm:"not null"` // Start time of the candle in milliseconds
+	Open      float64   `json:"open" gorm:"not null"`
+	High      float64   `json:"high" gorm:"not null"`
+	Low       float64   `json:"low" gorm:"not null"`
+	Close     float64   `json:"close" gorm:"not null"`
+	Volume    float64   `json:"volume" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (ImportedCandle) TableName() string {
+	return "imported_candles"
+}
+
+// ToOHLCV converts the imported candle to OHLCV format
+func (c *ImportedCandle) ToOHLCV() OHLCV {
+	return OHLCV{
+		StartTime:  c.StartTime,
+		EndTime:    c.StartTime + GetIntervalDurationMs(c.Interval) - 1,
+		Open:       c.Open,
+		High:       c.High,
+		Low:        c.Low,
+		Close:      c.Close,
+		Volume:     c.Volume,
+		IsComplete: true,
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 type OrderSide string
@@ -15,17 +17,43 @@ const (
 	OrderSideBuy  OrderSide = "buy"
 	OrderSideSell OrderSide = "sell"
 	
-	OrderTypeMarket    OrderType = "market"
-	OrderTypeLimit     OrderType = "limit"
-	OrderTypeStopLimit OrderType = "stop_limit"
-	// Future order types: stop_market, take_profit, etc.
+	OrderTypeMarket       OrderType = "market"
+	OrderTypeLimit        OrderType = "limit"
+	OrderTypeStopLimit    OrderType = "stop_limit"
+	OrderTypeStopLoss     OrderType = "stop_loss"     // Executes at market once price moves against the position
+	OrderTypeTakeProfit   OrderType = "take_profit"   // Executes at market once price moves in favour of the position
+	OrderTypeTrailingStop OrderType = "trailing_stop" // Stop-loss whose stop follows favourable price moves
+	// Future order types: stop_market, etc.
 	
-	OrderStatusPending   OrderStatus = "pending"
-	OrderStatusExecuted  OrderStatus = "executed"
-	OrderStatusFailed    OrderStatus = "failed"
-	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusPending         OrderStatus = "pending"
+	OrderStatusPartiallyFilled OrderStatus = "partially_filled" // Some quantity filled, the remainder still rests
+	OrderStatusExecuted        OrderStatus = "executed"
+	OrderStatusFailed          OrderStatus = "failed"
+	OrderStatusCancelled       OrderStatus = "cancelled"
 )
 
+// OpenOrderStatuses are the statuses of orders that can still fill
+var OpenOrderStatuses = []OrderStatus{OrderStatusPending, OrderStatusPartiallyFilled}
+
+// Time in force values for limit orders, GTC when unset
+const (
+	TimeInForceGTC = "GTC" // Good till cancelled, or until ExpireTime when set
+	TimeInForceIOC = "IOC" // Immediate or cancel: fill at placement, cancel anything left
+	TimeInForceFOK = "FOK" // Fill or kill: fill entirely at placement or reject the order
+)
+
+// MaxOrderTagLength is the longest tag an order can carry
+const MaxOrderTagLength = 32
+
+// NormalizeOrderTag trims an order tag and checks it fits the tag column
+func NormalizeOrderTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if utf8.RuneCountInString(tag) > MaxOrderTagLength {
+		return "", fmt.Errorf("tag must be at most %d characters", MaxOrderTagLength)
+	}
+	return tag, nil
+}
+
 // Order represents a trading order with flexible type-specific parameters
 type Order struct {
 	ID           uint        `json:"id" gorm:"primaryKey"`
@@ -36,10 +64,12 @@ type Order struct {
 	Side         OrderSide   `json:"side" gorm:"not null"`
 	Type         OrderType   `json:"type" gorm:"not null"`
 	Quantity     float64     `json:"quantity" gorm:"not null"`
+	FilledQuantity float64   `json:"filled_quantity" gorm:"not null;default:0"` // Quantity filled so far, across all trades
 	Status       OrderStatus `json:"status" gorm:"not null;default:'pending'"`
 	PlacedAt     int64       `json:"placed_at" gorm:"not null"` // Simulation time in milliseconds
-	ExecutedAt   *int64      `json:"executed_at,omitempty"` // Simulation time in milliseconds
-	ExecutedPrice *float64    `json:"executed_price,omitempty"`
+	ExecutedAt   *int64      `json:"executed_at,omitempty"` // Simulation time in milliseconds of the latest fill
+	ExecutedPrice *float64    `json:"executed_price,omitempty"` // Average fill price
+	Tag          string      `json:"tag,omitempty" gorm:"not null;default:''"` // Free-text label attributing the order to a strategy
 	
 	// Flexible order parameters stored as JSON for different order types
 	OrderParams  OrderParameters `json:"order_params" gorm:"type:json"`
@@ -56,25 +86,36 @@ func (Order) TableName() string {
 type OrderParameters struct {
 	// Limit Order Parameters
 	LimitPrice *float64 `json:"limit_price,omitempty"` // Price for limit orders
+
+	// Quantity originally requested when the engine adjusted the order quantity (e.g. clamped to affordable)
+	RequestedQuantity *float64 `json:"requested_quantity,omitempty"`
+
+	// Price a market order was placed at, compared with its fill to measure slippage
+	ExpectedPrice *float64 `json:"expected_price,omitempty"`
 	
 	// Stop Limit Order Parameters
 	StopPrice     *float64 `json:"stop_price,omitempty"`     // Trigger price for stop orders
 	StopLimitPrice *float64 `json:"stop_limit_price,omitempty"` // Limit price after stop is triggered
 	
-	// Take Profit / Stop Loss Parameters (future)
+	// Take Profit / Stop Loss Parameters, on a bracket entry the prices of the exits placed once it fills
 	TakeProfitPrice *float64 `json:"take_profit_price,omitempty"` // Take profit trigger price
 	StopLossPrice   *float64 `json:"stop_loss_price,omitempty"`   // Stop loss trigger price
+
+	// Trailing Stop Parameters, the stop trails the high-water mark by either an absolute or a percentage distance
+	TrailingDelta   *float64 `json:"trailing_delta,omitempty"`   // Absolute trailing distance in quote currency
+	TrailingPercent *float64 `json:"trailing_percent,omitempty"` // Trailing distance as % of the high-water mark
+	HighWaterMark   *float64 `json:"high_water_mark,omitempty"`  // Best price seen since placement: highest for sells, lowest for buys
 	
-	// Time in Force Parameters (future)
+	// Time in Force Parameters
 	TimeInForce   *string `json:"time_in_force,omitempty"`   // GTC, IOC, FOK, etc.
-	ExpireTime    *int64  `json:"expire_time,omitempty"`     // Expiration time in milliseconds
+	ExpireTime    *int64  `json:"expire_time,omitempty"`     // Expiration simulation time in milliseconds
 	
 	// Advanced Parameters (future)
 	ReduceOnly    *bool   `json:"reduce_only,omitempty"`     // Reduce position only flag
 	PostOnly      *bool   `json:"post_only,omitempty"`       // Post-only flag for makers
 	
-	// Conditional Parameters (future)
-	ParentOrderID *uint   `json:"parent_order_id,omitempty"` // For OCO, bracket orders
+	// Conditional Parameters
+	ParentOrderID *uint   `json:"parent_order_id,omitempty"` // Entry of the bracket an exit order belongs to
 	TriggerCondition *string `json:"trigger_condition,omitempty"` // Condition for activation
 }
 
@@ -113,6 +154,14 @@ func (o *Order) GetLimitPrice() *float64 {
 	return o.OrderParams.LimitPrice
 }
 
+// IntendedPrice returns the price an order meant to fill at: the limit price, or the expected price of a market order
+func (o *Order) IntendedPrice() *float64 {
+	if o.OrderParams.LimitPrice != nil {
+		return o.OrderParams.LimitPrice
+	}
+	return o.OrderParams.ExpectedPrice
+}
+
 // SetLimitPrice sets the limit price for limit orders
 func (o *Order) SetLimitPrice(price float64) {
 	o.OrderParams.LimitPrice = &price
@@ -148,6 +197,81 @@ func (o *Order) IsStopOrder() bool {
 	return o.Type == OrderTypeStopLimit && o.OrderParams.StopPrice != nil
 }
 
+// IsProtectiveOrder checks if this is a stop-loss, take-profit or trailing stop order
+func (o *Order) IsProtectiveOrder() bool {
+	return o.GetTriggerPrice() != nil
+}
+
+// GetTriggerPrice returns the price that triggers a stop-loss, take-profit or trailing stop order
+func (o *Order) GetTriggerPrice() *float64 {
+	switch o.Type {
+	case OrderTypeStopLoss:
+		return o.OrderParams.StopLossPrice
+	case OrderTypeTakeProfit:
+		return o.OrderParams.TakeProfitPrice
+	case OrderTypeTrailingStop:
+		return o.TrailingStopPrice()
+	default:
+		return nil
+	}
+}
+
+// TrailingStopPrice returns the current stop of a trailing stop order, derived from its high-water mark
+func (o *Order) TrailingStopPrice() *float64 {
+	params := o.OrderParams
+	if params.HighWaterMark == nil {
+		return nil
+	}
+
+	var distance float64
+	switch {
+	case params.TrailingDelta != nil:
+		distance = *params.TrailingDelta
+	case params.TrailingPercent != nil:
+		distance = *params.HighWaterMark * *params.TrailingPercent / 100
+	default:
+		return nil
+	}
+
+	stopPrice := *params.HighWaterMark + distance // Buy stops trail above the low-water mark
+	if o.Side == OrderSideSell {
+		stopPrice = *params.HighWaterMark - distance
+	}
+	return &stopPrice
+}
+
+// TriggersOnDrop reports whether a protective order triggers when the price falls to its trigger price
+// Sell stops and buy take-profits wait for a drop, sell take-profits and buy stops for a rise
+func (o *Order) TriggersOnDrop() bool {
+	if o.Type == OrderTypeStopLoss || o.Type == OrderTypeTrailingStop {
+		return o.Side == OrderSideSell
+	}
+	return o.Side == OrderSideBuy
+}
+
+// RemainingQuantity returns the quantity still to be filled
+func (o *Order) RemainingQuantity() float64 {
+	return o.Quantity - o.FilledQuantity
+}
+
+// IsOpen checks if the order can still fill
+func (o *Order) IsOpen() bool {
+	return o.Status == OrderStatusPending || o.Status == OrderStatusPartiallyFilled
+}
+
+// GetTimeInForce returns the order's time in force, defaulting to GTC
+func (o *Order) GetTimeInForce() string {
+	if o.OrderParams.TimeInForce == nil || *o.OrderParams.TimeInForce == "" {
+		return TimeInForceGTC
+	}
+	return *o.OrderParams.TimeInForce
+}
+
+// IsExpiredAt checks if the order's expire time has been reached at the given simulation time
+func (o *Order) IsExpiredAt(simulationTime int64) bool {
+	return o.OrderParams.ExpireTime != nil && *o.OrderParams.ExpireTime <= simulationTime
+}
+
 // GetEffectivePrice returns the relevant price for order execution
 func (o *Order) GetEffectivePrice() *float64 {
 	switch o.Type {
@@ -172,6 +296,7 @@ type Trade struct {
 	Quantity     float64   `json:"quantity" gorm:"not null"`
 	Price        float64   `json:"price" gorm:"not null"`
 	Fee          float64   `json:"fee" gorm:"default:0"`
+	Slippage     float64   `json:"slippage" gorm:"not null;default:0"` // Per unit price move against the trade from its reference price
 	ExecutedAt   int64     `json:"executed_at" gorm:"not null"` // Simulation time in milliseconds
 	CreatedAt    time.Time `json:"created_at"`
 	
@@ -179,6 +304,19 @@ type Trade struct {
 	Order Order `json:"order,omitempty" gorm:"foreignKey:OrderID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
 
+// PriceImprovement returns how much better a trade filled than its order's intended price, in quote currency
+// Positive values are price improvement and negative values slippage; false when the order has no intended price
+func (t *Trade) PriceImprovement(order *Order) (float64, bool) {
+	intended := order.IntendedPrice()
+	if intended == nil || *intended <= 0 {
+		return 0, false
+	}
+	if t.Side == OrderSideBuy {
+		return (*intended - t.Price) * t.Quantity, true
+	}
+	return (t.Price - *intended) * t.Quantity, true
+}
+
 func (Trade) TableName() string {
 	return "trades"
 }
@@ -188,16 +326,76 @@ func (Trade) TableName() string {
 type Position struct {
 	ID           uint      `json:"id" gorm:"primaryKey"`
 	UserID       uint      `json:"user_id" gorm:"not null;default:1;uniqueIndex:idx_user_symbol_base_sim"`
-	SimulationID *uint     `json:"simulation_id" gorm:"index;uniqueIndex:idx_user_symbol_base_sim"` // Link to simulation record
+	SimulationID *uint     `json:"simulation_id" gorm:"not null;index;uniqueIndex:idx_user_symbol_base_sim"` // Link to simulation record (required)
 	Symbol       string    `json:"symbol" gorm:"not null;uniqueIndex:idx_user_symbol_base_sim"` // ETH, USDT, etc.
 	BaseCurrency string    `json:"base_currency" gorm:"not null;uniqueIndex:idx_user_symbol_base_sim;default:USDT"` // USDT, USD, etc.
 	Quantity     float64   `json:"quantity" gorm:"not null;default:0"` // Can be negative for short positions
 	AveragePrice float64   `json:"average_price" gorm:"not null;default:0"` // Always 1 for base currency positions
 	TotalCost    float64   `json:"total_cost" gorm:"not null;default:0"` // Total cost basis including fees
+	RealizedPnL  float64   `json:"realized_pnl" gorm:"not null;default:0"` // Gains and losses locked in by reducing the position, net of fees
 	UpdatedAt    time.Time `json:"updated_at"`
 	CreatedAt    time.Time `json:"created_at"`
+
+	// Futures mode: a leveraged long pays Margin in cash and borrows the rest of its notional value,
+	// and is force-closed once the price falls to LiquidationPrice
+	Leverage         float64  `json:"leverage" gorm:"not null;default:1"`
+	Margin           float64  `json:"margin" gorm:"not null;default:0"`
+	LiquidationPrice *float64 `json:"liquidation_price,omitempty"`
 }
 
 func (Position) TableName() string {
 	return "positions"
+}
+
+// PositionSnapshot records a position's state after a fill changed it, so its evolution over a run can be reviewed
+type PositionSnapshot struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	SimulationID uint      `json:"simulation_id" gorm:"not null;index:idx_position_snapshots_history"`
+	Symbol       string    `json:"symbol" gorm:"not null;index:idx_position_snapshots_history"`
+	SimTime      int64     `json:"sim_time" gorm:"not null;index:idx_position_snapshots_history"` // Simulation time of the fill in milliseconds
+	OrderID      uint      `json:"order_id" gorm:"not null"`                                      // Order whose fill changed the position
+	Quantity     float64   `json:"quantity" gorm:"not null"`
+	AveragePrice float64   `json:"average_price" gorm:"not null"`
+	TotalCost    float64   `json:"total_cost" gorm:"not null"`
+	RealizedPnL  float64   `json:"realized_pnl" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (PositionSnapshot) TableName() string {
+	return "position_snapshots"
+}
+
+// PositionHistoryResponse is the recorded position history of a simulation
+type PositionHistoryResponse struct {
+	SimulationID uint               `json:"simulationId"`
+	Symbol       string             `json:"symbol,omitempty"` // Empty when every symbol is included
+	Snapshots    []PositionSnapshot `json:"snapshots"`
+}
+
+// IsFlat reports whether the position holds nothing; closed positions are kept flat to retain their realized P&L
+func (p *Position) IsFlat() bool {
+	return p.Quantity == 0
+}
+
+// Borrowed returns the part of a leveraged position's notional value not paid for by its margin
+func (p *Position) Borrowed() float64 {
+	if p.Leverage <= 1 {
+		return 0
+	}
+	return p.Margin * (p.Leverage - 1)
+}
+
+// MarketValue returns what the position is worth at price, net of the amount borrowed to open it
+// Shorts have a negative quantity and are valued as the liability of buying them back
+func (p *Position) MarketValue(price float64) float64 {
+	return p.Quantity*price - p.Borrowed()
+}
+
+// LongLiquidationPrice returns the price at which a leveraged long's equity, its value at that price less
+// the borrowed amount, falls to the maintenance margin share of its notional value
+func LongLiquidationPrice(quantity, borrowed, maintenanceMarginRate float64) float64 {
+	if quantity <= 0 || borrowed <= 0 {
+		return 0
+	}
+	return borrowed / (quantity * (1 - maintenanceMarginRate))
 }
\ No newline at end of file
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	// DefaultValueDecimals is the number of decimals monetary values are rounded to for display
+	DefaultValueDecimals = 2
+	// PercentDecimals is the number of decimals percentages are rounded to for display
+	PercentDecimals = 2
+)
+
+var (
+	precisionMu        sync.RWMutex
+	defaultDecimals    = DefaultValueDecimals
+	quoteAssetDecimals = map[string]int{} // Per quote asset overrides of defaultDecimals
+)
+
+// SetValueDecimals sets the default number of decimals for monetary values
+func SetValueDecimals(decimals int) {
+	if decimals < 0 {
+		return
+	}
+	precisionMu.Lock()
+	defer precisionMu.Unlock()
+	defaultDecimals = decimals
+}
+
+// SetQuoteAssetDecimals overrides the number of decimals for values denominated in a quote asset
+func SetQuoteAssetDecimals(asset string, decimals int) {
+	if decimals < 0 {
+		return
+	}
+	precisionMu.Lock()
+	defer precisionMu.Unlock()
+	quoteAssetDecimals[asset] = decimals
+}
+
+// ValueDecimals returns the number of decimals used for values denominated in a quote asset
+func ValueDecimals(quoteAsset string) int {
+	precisionMu.RLock()
+	defer precisionMu.RUnlock()
+	if decimals, exists := quoteAssetDecimals[quoteAsset]; exists {
+		return decimals
+	}
+	return defaultDecimals
+}
+
+// RoundValue rounds a monetary value in the given quote asset for responses
+// Calculations should keep full precision and only round values being returned
+func RoundValue(quoteAsset string, value float64) float64 {
+	return roundTo(value, ValueDecimals(quoteAsset))
+}
+
+// RoundPercent rounds a percentage for responses
+func RoundPercent(value float64) float64 {
+	return roundTo(value, PercentDecimals)
+}
+
+// roundTo rounds value half away from zero to the given number of decimals
+func roundTo(value float64, decimals int) float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return value
+	}
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(value*factor) / factor
+}
@@ -27,15 +27,80 @@ type Simulation struct {
 	Symbol         string           `json:"symbol" gorm:"not null;index"`
 	StartSimTime   int64            `json:"start_sim_time" gorm:"not null"` // Simulation start time in milliseconds
 	EndSimTime     int64            `json:"end_sim_time" gorm:"not null"`   // Simulation end time in milliseconds
+	LastSimTime    int64            `json:"last_sim_time" gorm:"not null"`  // Latest simulation time reached, checkpointed while playing
 	InitialFunding float64          `json:"initial_funding" gorm:"not null"`
 	Mode           SimulationMode   `json:"mode" gorm:"not null;default:spot"`
 	ExtraConfigs   string           `json:"extra_configs" gorm:"type:text"` // JSON format for additional configs
 	Status         SimulationStatus `json:"status" gorm:"not null;default:running"`
-	TotalValue     *float64         `json:"total_value,omitempty"` // Final portfolio value when completed
+	TotalValue     *float64         `json:"total_value,omitempty"`           // Final portfolio value when completed
+	PnL            *float64         `json:"pnl,omitempty" gorm:"column:pnl"` // TotalValue minus InitialFunding, stored with TotalValue
+	ReturnPct      *float64         `json:"return_pct,omitempty"`            // PnL as a percentage of InitialFunding
 	CreatedAt      time.Time        `json:"created_at"`
 	UpdatedAt      time.Time        `json:"updated_at"`
 }
 
 func (Simulation) TableName() string {
 	return "simulations"
-}
\ No newline at end of file
+}
+
+// EquitySample is a portfolio total value recorded at a point in simulation time
+// Samples recorded before the cash split was stored have no cash or position value
+type EquitySample struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	SimulationID  uint      `json:"simulation_id" gorm:"not null;index:idx_equity_samples_simulation_time"`
+	SimTime       int64     `json:"sim_time" gorm:"not null;index:idx_equity_samples_simulation_time"` // Simulation time in milliseconds
+	TotalValue    float64   `json:"total_value" gorm:"not null"`
+	Cash          *float64  `json:"cash,omitempty"`           // USDT balance
+	PositionValue *float64  `json:"position_value,omitempty"` // Market value of non-USDT holdings
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (EquitySample) TableName() string {
+	return "equity_samples"
+}
+
+// EquityCurvePoint is a single point of a simulation's equity curve
+type EquityCurvePoint struct {
+	Time          int64    `json:"time"` // Simulation time in milliseconds
+	TotalValue    float64  `json:"totalValue"`
+	Cash          *float64 `json:"cash,omitempty"`          // USDT balance, absent for older samples
+	PositionValue *float64 `json:"positionValue,omitempty"` // Market value of non-USDT holdings, absent for older samples
+}
+
+// EquityCurveResponse is the recorded equity curve of a simulation
+type EquityCurveResponse struct {
+	SimulationID uint               `json:"simulationId"`
+	Points       []EquityCurvePoint `json:"points"`
+}
+
+// CandleArchive is the candle series a finished simulation ran on, stored so it can be reviewed
+// without refetching from Binance
+type CandleArchive struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	SimulationID uint      `json:"simulation_id" gorm:"not null;uniqueIndex"`
+	Symbol       string    `json:"symbol" gorm:"not null"`
+	Interval     string    `json:"interval" gorm:"not null"`
+	StartTime    int64     `json:"start_time" gorm:"not null"` // Start time of the first candle in milliseconds
+	EndTime      int64     `json:"end_time" gorm:"not null"`   // Simulation end time in milliseconds
+	CandleCount  int       `json:"candle_count" gorm:"not null"`
+	Candles      string    `json:"-" gorm:"type:text;not null"` // JSON encoded []OHLCV
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (CandleArchive) TableName() string {
+	return "candle_archives"
+}
+
+// Annotation is a user note attached to a simulation time or trade, e.g. "entered on breakout"
+type Annotation struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	SimulationID uint      `json:"simulation_id" gorm:"not null;index"`
+	SimTime      int64     `json:"sim_time" gorm:"not null"` // Simulation time in milliseconds the note refers to
+	TradeID      *uint     `json:"trade_id,omitempty" gorm:"index"`
+	Text         string    `json:"text" gorm:"type:text;not null"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (Annotation) TableName() string {
+	return "annotations"
+}
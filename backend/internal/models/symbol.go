@@ -0,0 +1,25 @@
+package models
+
+import "strings"
+
+// symbolSeparators are stripped from user-supplied symbols, so "BTC/USDT" and "btc-usdt" match BTCUSDT
+var symbolSeparators = strings.NewReplacer("/", "", "-", "", "_", "", " ", "")
+
+// symbolAliases maps common alternative names to canonical trading pairs
+var symbolAliases = map[string]string{
+	"BTC":     "BTCUSDT",
+	"XBT":     "BTCUSDT",
+	"XBTUSDT": "BTCUSDT",
+	"ETH":     "ETHUSDT",
+}
+
+// NormalizeSymbol returns the canonical form of a user-supplied trading symbol: trimmed, uppercased,
+// without pair separators and with aliases resolved, e.g. " btc/usdt " and "xbt" become BTCUSDT
+// Unknown symbols are normalized the same way and left for validation to reject
+func NormalizeSymbol(symbol string) string {
+	normalized := symbolSeparators.Replace(strings.ToUpper(strings.TrimSpace(symbol)))
+	if canonical, ok := symbolAliases[normalized]; ok {
+		return canonical
+	}
+	return normalized
+}
@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+func TestNormalizeSymbol(t *testing.T) {
+	tests := []struct {
+		symbol string
+		want   string
+	}{
+		{symbol: "BTCUSDT", want: "BTCUSDT"},
+		{symbol: "btcusdt", want: "BTCUSDT"},
+		{symbol: "  ethusdt\t", want: "ETHUSDT"},
+		{symbol: " Btc/Usdt ", want: "BTCUSDT"},
+		{symbol: "eth-usdt", want: "ETHUSDT"},
+		{symbol: "btc_usdt", want: "BTCUSDT"},
+		{symbol: "xbt", want: "BTCUSDT"},
+		{symbol: " eth ", want: "ETHUSDT"},
+		{symbol: "dogeusdt", want: "DOGEUSDT"},
+		{symbol: "   ", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeSymbol(tt.symbol); got != tt.want {
+			t.Errorf("NormalizeSymbol(%q) = %q, want %q", tt.symbol, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	simulationDAO "tradesimulator/internal/dao/simulation"
+	tradingDAO "tradesimulator/internal/dao/trading"
+	"tradesimulator/internal/models"
+)
+
+// MaxAnnotationLength is the longest annotation text accepted, in characters
+const MaxAnnotationLength = 1000
+
+var (
+	// ErrAnnotationSimulationNotFound is returned when the annotated simulation does not exist
+	ErrAnnotationSimulationNotFound = errors.New("simulation not found")
+	// ErrAnnotationNotFound is returned when the annotation does not exist in the simulation
+	ErrAnnotationNotFound = errors.New("annotation not found")
+	// ErrInvalidAnnotation is returned when an annotation request fails validation
+	ErrInvalidAnnotation = errors.New("invalid annotation")
+)
+
+// CreateAnnotationRequest describes a note to attach to a simulation
+// Either SimTime or TradeID must be set; a trade's execution time is used when SimTime is omitted
+type CreateAnnotationRequest struct {
+	SimTime int64  `json:"simTime"`
+	TradeID *uint  `json:"tradeId"`
+	Text    string `json:"text"`
+}
+
+// AnnotationService manages user notes attached to simulations
+type AnnotationService struct {
+	annotationDAO simulationDAO.AnnotationDAOInterface
+	simulationDAO simulationDAO.SimulationDAOInterface
+	tradeDAO      tradingDAO.TradeDAOInterface
+}
+
+// NewAnnotationService creates a new annotation service
+func NewAnnotationService(annotationDAO simulationDAO.AnnotationDAOInterface, simulationDAO simulationDAO.SimulationDAOInterface, tradeDAO tradingDAO.TradeDAOInterface) *AnnotationService {
+	return &AnnotationService{
+		annotationDAO: annotationDAO,
+		simulationDAO: simulationDAO,
+		tradeDAO:      tradeDAO,
+	}
+}
+
+// CreateAnnotation validates and stores a note on a simulation
+func (as *AnnotationService) CreateAnnotation(simulationID uint, request CreateAnnotationRequest) (*models.Annotation, error) {
+	if _, err := as.simulationDAO.GetSimulationByID(simulationID); err != nil {
+		return nil, ErrAnnotationSimulationNotFound
+	}
+
+	text := strings.TrimSpace(request.Text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: text is required", ErrInvalidAnnotation)
+	}
+	if len([]rune(text)) > MaxAnnotationLength {
+		return nil, fmt.Errorf("%w: text must be at most %d characters", ErrInvalidAnnotation, MaxAnnotationLength)
+	}
+
+	simTime := request.SimTime
+	if request.TradeID != nil {
+		trade, err := as.tradeDAO.GetByID(*request.TradeID)
+		if err != nil || trade.SimulationID == nil || *trade.SimulationID != simulationID {
+			return nil, fmt.Errorf("%w: trade %d not found in simulation", ErrInvalidAnnotation, *request.TradeID)
+		}
+		if simTime == 0 {
+			simTime = trade.ExecutedAt
+		}
+	}
+	if simTime <= 0 {
+		return nil, fmt.Errorf("%w: simTime or tradeId is required", ErrInvalidAnnotation)
+	}
+
+	annotation := &models.Annotation{
+		SimulationID: simulationID,
+		SimTime:      simTime,
+		TradeID:      request.TradeID,
+		Text:         text,
+	}
+	if err := as.annotationDAO.Create(annotation); err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
+// GetAnnotations returns the notes of a simulation ordered by simulation time
+func (as *AnnotationService) GetAnnotations(simulationID uint) ([]models.Annotation, error) {
+	if _, err := as.simulationDAO.GetSimulationByID(simulationID); err != nil {
+		return nil, ErrAnnotationSimulationNotFound
+	}
+	return as.annotationDAO.GetBySimulation(simulationID)
+}
+
+// DeleteAnnotation removes a note from a simulation
+func (as *AnnotationService) DeleteAnnotation(simulationID, annotationID uint) error {
+	deleted, err := as.annotationDAO.Delete(simulationID, annotationID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAnnotationNotFound
+	}
+	return nil
+}
@@ -1,52 +1,188 @@
 package market
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"time"
+
+	marketDAO "tradesimulator/internal/dao/market"
 	"tradesimulator/internal/integrations/binance"
+	"tradesimulator/internal/integrations/marketdata"
 	"tradesimulator/internal/models"
 )
 
+// ErrNoValidStartTime is returned when the symbol's history is too short for the requested duration
+var ErrNoValidStartTime = errors.New("not enough history for the requested duration")
+
 // MarketDataService provides market data functionality
 type MarketDataService struct {
-	binanceClient *binance.BinanceService
+	provider  marketdata.MarketDataProvider
+	prefetch  *prefetchManager
+	watchlist *watchlistCache
+}
+
+// upstreamLoggingProvider is implemented by providers that log their upstream requests and cache klines
+type upstreamLoggingProvider interface {
+	GetRequestLogSnapshot() (*binance.RequestLogSnapshot, bool)
+	Cache() *binance.KlineCache
 }
 
 // MarketDataServiceInterface defines the contract for market data services
 type MarketDataServiceInterface interface {
 	GetKlines(symbol, interval string, limit int, startTime, endTime *int64) ([]models.Kline, error)
 	GetHistoricalData(symbol, interval string, limit int, startTime, endTime *int64, enableIncomplete bool) ([]models.OHLCV, error)
+	GetHistoricalDataWithSource(symbol, interval string, limit int, startTime, endTime *int64, enableIncomplete bool) ([]models.OHLCV, binance.DataSource, error)
 	GetSupportedSymbols() []string
 	ValidateInterval(interval string) bool
 	GetEarliestAvailableTime(symbol string) (int64, error)
+	GetCandleAt(symbol, interval string, timestamp int64) (*models.OHLCV, error)
+	GetLatestPrices(symbols []string) (map[string]float64, error)
+	GetWatchlist() (*Watchlist, error)
+	GetRandomStartTime(symbol string, minDurationMs, excludeRecentMs int64) (int64, error)
+	StartPrefetch(req PrefetchRequest) (*PrefetchJob, error)
+	GetPrefetchJob(jobID string) (*PrefetchJob, bool)
+	GetUpstreamRequestLog() (*binance.RequestLogSnapshot, bool)
+	GetKlineCacheStats() binance.KlineCacheStats
+	ImportCandles(symbol, interval string, r io.Reader) (*marketDAO.ImportedSeries, error)
+}
+
+// ErrImportUnavailable is returned when the market data provider can't store imported candles
+var ErrImportUnavailable = errors.New("candle imports are not available")
+
+// candleImporter is implemented by providers that can store and replay imported candle series
+type candleImporter interface {
+	Import(symbol, interval string, candles []models.OHLCV) (*marketDAO.ImportedSeries, error)
 }
 
 // NewMarketDataService creates a new market data service
-func NewMarketDataService(binanceClient *binance.BinanceService) MarketDataServiceInterface {
+func NewMarketDataService(provider marketdata.MarketDataProvider) MarketDataServiceInterface {
 	return &MarketDataService{
-		binanceClient: binanceClient,
+		provider:  provider,
+		prefetch:  newPrefetchManager(provider),
+		watchlist: &watchlistCache{},
 	}
 }
 
 // GetKlines fetches historical kline data for a symbol
 func (mds *MarketDataService) GetKlines(symbol, interval string, limit int, startTime, endTime *int64) ([]models.Kline, error) {
-	return mds.binanceClient.GetKlines(symbol, interval, limit, startTime, endTime)
+	return mds.provider.GetKlines(symbol, interval, limit, startTime, endTime)
 }
 
 // GetHistoricalData fetches historical data with optional incomplete candle support
 func (mds *MarketDataService) GetHistoricalData(symbol, interval string, limit int, startTime, endTime *int64, enableIncomplete bool) ([]models.OHLCV, error) {
-	return mds.binanceClient.GetHistoricalData(symbol, interval, limit, startTime, endTime, enableIncomplete)
+	return mds.provider.GetHistoricalData(symbol, interval, limit, startTime, endTime, enableIncomplete)
+}
+
+// GetHistoricalDataWithSource fetches historical data and reports whether it was served from cache
+// Providers without a cache always serve from their source
+func (mds *MarketDataService) GetHistoricalDataWithSource(symbol, interval string, limit int, startTime, endTime *int64, enableIncomplete bool) ([]models.OHLCV, binance.DataSource, error) {
+	if provider, ok := mds.provider.(marketdata.SourceReportingProvider); ok {
+		return provider.GetHistoricalDataWithSource(symbol, interval, limit, startTime, endTime, enableIncomplete)
+	}
+	data, err := mds.provider.GetHistoricalData(symbol, interval, limit, startTime, endTime, enableIncomplete)
+	return data, binance.DataSourceUpstream, err
 }
 
 // GetSupportedSymbols returns list of supported trading pairs
 func (mds *MarketDataService) GetSupportedSymbols() []string {
-	return mds.binanceClient.GetSupportedSymbols()
+	return mds.provider.GetSupportedSymbols()
 }
 
 // ValidateInterval checks if the interval is valid
 func (mds *MarketDataService) ValidateInterval(interval string) bool {
-	return mds.binanceClient.ValidateInterval(interval)
+	return mds.provider.ValidateInterval(interval)
 }
 
 // GetEarliestAvailableTime fetches the earliest available data point for a symbol
 func (mds *MarketDataService) GetEarliestAvailableTime(symbol string) (int64, error) {
-	return mds.binanceClient.GetEarliestAvailableTime(symbol)
-}
\ No newline at end of file
+	return mds.provider.GetEarliestAvailableTime(symbol)
+}
+
+// GetCandleAt returns the candle of the given interval containing timestamp, or nil if no data covers it
+func (mds *MarketDataService) GetCandleAt(symbol, interval string, timestamp int64) (*models.OHLCV, error) {
+	candleStartTime := models.CalculateCandleStartTime(timestamp, interval)
+
+	data, err := mds.provider.GetHistoricalData(symbol, interval, 1, &candleStartTime, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 || data[0].StartTime > timestamp || data[0].EndTime < timestamp {
+		return nil, nil
+	}
+
+	return &data[0], nil
+}
+
+// GetLatestPrices returns the current price for each symbol using a single upstream request
+func (mds *MarketDataService) GetLatestPrices(symbols []string) (map[string]float64, error) {
+	return mds.provider.GetLatestPrices(symbols)
+}
+
+// GetRandomStartTime picks a random minute-aligned start time between the symbol's earliest data
+// and the present, leaving at least minDurationMs of data after it and never entering the most
+// recent excludeRecentMs
+func (mds *MarketDataService) GetRandomStartTime(symbol string, minDurationMs, excludeRecentMs int64) (int64, error) {
+	if minDurationMs < 0 || excludeRecentMs < 0 {
+		return 0, fmt.Errorf("durations must not be negative")
+	}
+
+	earliestTime, err := mds.provider.GetEarliestAvailableTime(symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	latestStart := time.Now().UnixMilli() - excludeRecentMs - minDurationMs
+	if latestStart <= earliestTime {
+		return 0, ErrNoValidStartTime
+	}
+
+	startTime := earliestTime + rand.Int64N(latestStart-earliestTime)
+	return models.CalculateCandleStartTime(startTime, "1m"), nil
+}
+
+// StartPrefetch starts loading a candle range into the cache in the background
+func (mds *MarketDataService) StartPrefetch(req PrefetchRequest) (*PrefetchJob, error) {
+	return mds.prefetch.start(req)
+}
+
+// GetPrefetchJob returns the current status of a prefetch job
+func (mds *MarketDataService) GetPrefetchJob(jobID string) (*PrefetchJob, bool) {
+	return mds.prefetch.get(jobID)
+}
+
+// GetUpstreamRequestLog returns logged Binance request statistics, or false if logging is disabled
+// or the provider doesn't call Binance
+func (mds *MarketDataService) GetUpstreamRequestLog() (*binance.RequestLogSnapshot, bool) {
+	provider, ok := marketdata.Underlying(mds.provider).(upstreamLoggingProvider)
+	if !ok {
+		return nil, false
+	}
+	return provider.GetRequestLogSnapshot()
+}
+
+// GetKlineCacheStats returns the hit and miss counters and size of the kline cache, all zero if the provider has none
+func (mds *MarketDataService) GetKlineCacheStats() binance.KlineCacheStats {
+	provider, ok := marketdata.Underlying(mds.provider).(upstreamLoggingProvider)
+	if !ok {
+		return binance.KlineCacheStats{}
+	}
+	return provider.Cache().Stats()
+}
+
+// ImportCandles parses a CSV of candle rows and stores them as the symbol's series at interval, replacing
+// an earlier import of the series. Errors caused by the data wrap marketdata.ErrInvalidImport
+func (mds *MarketDataService) ImportCandles(symbol, interval string, r io.Reader) (*marketDAO.ImportedSeries, error) {
+	importer, ok := mds.provider.(candleImporter)
+	if !ok {
+		return nil, ErrImportUnavailable
+	}
+
+	candles, err := marketdata.ParseImportCSV(r, interval)
+	if err != nil {
+		return nil, err
+	}
+	return importer.Import(symbol, interval, candles)
+}
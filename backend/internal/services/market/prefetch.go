@@ -0,0 +1,210 @@
+package market
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"tradesimulator/internal/integrations/marketdata"
+)
+
+type PrefetchStatus string
+
+const (
+	PrefetchStatusPending   PrefetchStatus = "pending"
+	PrefetchStatusRunning   PrefetchStatus = "running"
+	PrefetchStatusCompleted PrefetchStatus = "completed"
+	PrefetchStatusFailed    PrefetchStatus = "failed"
+
+	prefetchBatchSize = 1000 // Binance maximum klines per request
+
+	// Finished jobs stay available for polling for prefetchJobRetention, and at most maxFinishedPrefetchJobs of
+	// them are kept, dropping the oldest first
+	prefetchJobRetention    = time.Hour
+	maxFinishedPrefetchJobs = 100
+)
+
+// PrefetchRequest describes a candle range to load into the cache ahead of a simulation
+type PrefetchRequest struct {
+	Symbol    string `json:"symbol" binding:"required"`
+	Interval  string `json:"interval" binding:"required"`
+	StartTime int64  `json:"startTime" binding:"required"`
+	EndTime   int64  `json:"endTime" binding:"required"`
+}
+
+// PrefetchJob reports the progress of a background prefetch
+type PrefetchJob struct {
+	ID             string         `json:"id"`
+	Symbol         string         `json:"symbol"`
+	Interval       string         `json:"interval"`
+	StartTime      int64          `json:"startTime"`
+	EndTime        int64          `json:"endTime"`
+	Status         PrefetchStatus `json:"status"`
+	Progress       float64        `json:"progress"` // 0-100%
+	CandlesFetched int            `json:"candlesFetched"`
+	LoadedUntil    int64          `json:"loadedUntil"` // Close time of the last loaded candle
+	Error          string         `json:"error,omitempty"`
+	CreatedAt      time.Time      `json:"createdAt"`
+	FinishedAt     *time.Time     `json:"finishedAt,omitempty"`
+}
+
+// prefetchManager runs prefetch jobs and keeps their status for polling
+type prefetchManager struct {
+	mu       sync.RWMutex
+	provider marketdata.MarketDataProvider
+	jobs     map[string]*PrefetchJob
+	nextID   int64
+}
+
+func newPrefetchManager(provider marketdata.MarketDataProvider) *prefetchManager {
+	return &prefetchManager{
+		provider: provider,
+		jobs:     make(map[string]*PrefetchJob),
+	}
+}
+
+// start validates the request and launches the prefetch in the background
+func (pm *prefetchManager) start(req PrefetchRequest) (*PrefetchJob, error) {
+	if req.EndTime <= req.StartTime {
+		return nil, fmt.Errorf("endTime must be after startTime")
+	}
+	if !pm.provider.ValidateInterval(req.Interval) {
+		return nil, fmt.Errorf("invalid interval: %s", req.Interval)
+	}
+
+	pm.mu.Lock()
+	pm.pruneUnsafe(time.Now())
+	pm.nextID++
+	job := &PrefetchJob{
+		ID:        fmt.Sprintf("prefetch_%d", pm.nextID),
+		Symbol:    req.Symbol,
+		Interval:  req.Interval,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Status:    PrefetchStatusPending,
+		CreatedAt: time.Now(),
+	}
+	pm.jobs[job.ID] = job
+	snapshot := *job
+	pm.mu.Unlock()
+
+	go pm.run(job.ID)
+
+	return &snapshot, nil
+}
+
+// get returns a copy of the job's current state
+func (pm *prefetchManager) get(id string) (*PrefetchJob, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	job, exists := pm.jobs[id]
+	if !exists {
+		return nil, false
+	}
+	snapshot := *job
+	return &snapshot, true
+}
+
+// run pages through the requested range, letting the provider populate its cache, if it keeps one
+func (pm *prefetchManager) run(id string) {
+	pm.update(id, func(job *PrefetchJob) {
+		job.Status = PrefetchStatusRunning
+	})
+
+	job, _ := pm.get(id)
+	log.Printf("Prefetch %s started: %s %s from %d to %d", id, job.Symbol, job.Interval, job.StartTime, job.EndTime)
+
+	cursor := job.StartTime
+	endTime := job.EndTime
+	for cursor <= endTime {
+		klines, err := pm.provider.GetKlines(job.Symbol, job.Interval, prefetchBatchSize, &cursor, &endTime)
+		if err != nil {
+			pm.finish(id, err)
+			return
+		}
+		if len(klines) == 0 {
+			break // No more data available in range
+		}
+
+		lastCloseTime := klines[len(klines)-1].CloseTime
+		pm.update(id, func(job *PrefetchJob) {
+			job.CandlesFetched += len(klines)
+			job.LoadedUntil = lastCloseTime
+			job.Progress = prefetchProgress(job.StartTime, job.EndTime, lastCloseTime)
+		})
+
+		cursor = lastCloseTime + 1
+		if len(klines) < prefetchBatchSize {
+			break
+		}
+	}
+
+	pm.finish(id, nil)
+}
+
+// update applies a mutation to a job under lock
+func (pm *prefetchManager) update(id string, mutate func(job *PrefetchJob)) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if job, exists := pm.jobs[id]; exists {
+		mutate(job)
+	}
+}
+
+// finish marks a job completed or failed
+func (pm *prefetchManager) finish(id string, err error) {
+	pm.update(id, func(job *PrefetchJob) {
+		now := time.Now()
+		job.FinishedAt = &now
+		if err != nil {
+			job.Status = PrefetchStatusFailed
+			job.Error = err.Error()
+			log.Printf("Prefetch %s failed after %d candles: %v", id, job.CandlesFetched, err)
+			return
+		}
+		job.Status = PrefetchStatusCompleted
+		job.Progress = 100
+		log.Printf("Prefetch %s completed: %d candles cached", id, job.CandlesFetched)
+	})
+}
+
+// pruneUnsafe drops finished jobs past the retention window, then the oldest finished jobs over the cap
+// Running jobs are always kept (caller must hold lock)
+func (pm *prefetchManager) pruneUnsafe(now time.Time) {
+	var finished []*PrefetchJob
+	for id, job := range pm.jobs {
+		if job.FinishedAt == nil {
+			continue
+		}
+		if now.Sub(*job.FinishedAt) > prefetchJobRetention {
+			delete(pm.jobs, id)
+			continue
+		}
+		finished = append(finished, job)
+	}
+
+	if excess := len(finished) - maxFinishedPrefetchJobs; excess > 0 {
+		sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt.Before(*finished[j].FinishedAt) })
+		for _, job := range finished[:excess] {
+			delete(pm.jobs, job.ID)
+		}
+	}
+}
+
+// prefetchProgress converts the loaded position into a percentage of the requested range
+func prefetchProgress(startTime, endTime, loadedUntil int64) float64 {
+	if endTime <= startTime {
+		return 100
+	}
+	progress := float64(loadedUntil-startTime) / float64(endTime-startTime) * 100
+	if progress > 100 {
+		return 100
+	}
+	if progress < 0 {
+		return 0
+	}
+	return progress
+}
@@ -0,0 +1,42 @@
+package market
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPrefetchJobsPruned(t *testing.T) {
+	now := time.Now()
+	finishedAgo := func(age time.Duration) *time.Time {
+		finished := now.Add(-age)
+		return &finished
+	}
+
+	pm := newPrefetchManager(nil)
+	pm.jobs["running"] = &PrefetchJob{ID: "running", Status: PrefetchStatusRunning}
+	pm.jobs["expired"] = &PrefetchJob{ID: "expired", Status: PrefetchStatusFailed, FinishedAt: finishedAgo(prefetchJobRetention + time.Second)}
+	for i := 0; i <= maxFinishedPrefetchJobs; i++ {
+		id := fmt.Sprintf("finished_%d", i)
+		// finished_0 finished longest ago
+		pm.jobs[id] = &PrefetchJob{ID: id, Status: PrefetchStatusCompleted, FinishedAt: finishedAgo(time.Duration(maxFinishedPrefetchJobs-i) * time.Second)}
+	}
+
+	pm.pruneUnsafe(now)
+
+	if _, kept := pm.jobs["running"]; !kept {
+		t.Error("running job was dropped")
+	}
+	if _, kept := pm.jobs["expired"]; kept {
+		t.Error("job finished before the retention window was kept")
+	}
+	if _, kept := pm.jobs["finished_0"]; kept {
+		t.Error("oldest finished job over the cap was kept")
+	}
+	if _, kept := pm.jobs[fmt.Sprintf("finished_%d", maxFinishedPrefetchJobs)]; !kept {
+		t.Error("latest finished job was dropped")
+	}
+	if len(pm.jobs) != maxFinishedPrefetchJobs+1 {
+		t.Errorf("kept %d jobs, want %d finished and the running one", len(pm.jobs), maxFinishedPrefetchJobs)
+	}
+}
@@ -0,0 +1,107 @@
+package market
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"tradesimulator/internal/integrations/binance"
+	"tradesimulator/internal/integrations/marketdata"
+)
+
+const watchlistCacheTTL = 30 * time.Second // Keeps symbol pickers from hitting Binance on every request
+
+// WatchlistEntry describes a supported symbol for the symbol switcher
+type WatchlistEntry struct {
+	Symbol             string  `json:"symbol"`
+	Price              float64 `json:"price"`
+	PriceChange        float64 `json:"priceChange"`        // Absolute change over the last 24 hours
+	PriceChangePercent float64 `json:"priceChangePercent"` // Percentage change over the last 24 hours
+	Status             string  `json:"status"`             // Exchange trading status, empty when not listed
+	DataAvailable      bool    `json:"dataAvailable"`      // Whether the exchange has candle data to simulate
+}
+
+// Watchlist is the cached set of watchlist entries
+type Watchlist struct {
+	Symbols   []WatchlistEntry `json:"symbols"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+}
+
+// watchlistCache holds the most recently built watchlist
+type watchlistCache struct {
+	mu        sync.Mutex
+	watchlist *Watchlist
+}
+
+// GetWatchlist returns the supported symbols with their latest price, 24h change and data availability
+// Results are cached briefly; concurrent requests share one refresh
+func (mds *MarketDataService) GetWatchlist() (*Watchlist, error) {
+	mds.watchlist.mu.Lock()
+	defer mds.watchlist.mu.Unlock()
+
+	if cached := mds.watchlist.watchlist; cached != nil && time.Since(cached.UpdatedAt) < watchlistCacheTTL {
+		return cached, nil
+	}
+
+	watchlist, err := buildWatchlist(mds.provider)
+	if err != nil {
+		return nil, err
+	}
+	mds.watchlist.watchlist = watchlist
+	return watchlist, nil
+}
+
+// exchangeStatusProvider is implemented by providers backed by a live exchange
+type exchangeStatusProvider interface {
+	GetPriceChanges24h(symbols []string) (map[string]binance.PriceChange24h, error)
+	GetSymbolStatuses(symbols []string) (map[string]string, error)
+}
+
+// buildWatchlist composes the watchlist from the batch price, 24h ticker and exchange info endpoints
+// Symbols not served by a live exchange, e.g. imported ones, report no price change or status and have data
+func buildWatchlist(provider marketdata.MarketDataProvider) (*Watchlist, error) {
+	symbols := provider.GetSupportedSymbols()
+
+	prices, err := provider.GetLatestPrices(symbols)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch watchlist prices: %w", err)
+	}
+
+	changes := map[string]binance.PriceChange24h{}
+	statuses := map[string]string{}
+	liveSymbols := map[string]bool{}
+	underlying := marketdata.Underlying(provider)
+	if exchange, ok := underlying.(exchangeStatusProvider); ok {
+		exchangeSymbols := underlying.GetSupportedSymbols()
+		for _, symbol := range exchangeSymbols {
+			liveSymbols[symbol] = true
+		}
+		changes, err = exchange.GetPriceChanges24h(exchangeSymbols)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch watchlist price changes: %w", err)
+		}
+		statuses, err = exchange.GetSymbolStatuses(exchangeSymbols)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch watchlist symbol statuses: %w", err)
+		}
+	}
+
+	entries := make([]WatchlistEntry, 0, len(symbols))
+	for _, symbol := range symbols {
+		status, listed := statuses[symbol]
+		if !liveSymbols[symbol] {
+			listed = true
+		}
+		change := changes[symbol]
+		entries = append(entries, WatchlistEntry{
+			Symbol:             symbol,
+			Price:              prices[symbol],
+			PriceChange:        change.PriceChange,
+			PriceChangePercent: change.PriceChangePercent,
+			Status:             status,
+			DataAvailable:      listed,
+		})
+	}
+
+	return &Watchlist{Symbols: entries, UpdatedAt: time.Now()}, nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"sync"
+)
+
+// MarketCutoffRegistry tracks simulations that restrict market data and the market time each has reached
+// While any of them is active, market data endpoints must not return data past the earliest of
+// their cutoffs, so a client cannot fetch ahead of the replay
+type MarketCutoffRegistry struct {
+	mu      sync.RWMutex
+	cutoffs map[uint]int64 // Simulation ID -> latest market time the client may see, in milliseconds
+}
+
+// NewMarketCutoffRegistry creates an empty market cutoff registry
+func NewMarketCutoffRegistry() *MarketCutoffRegistry {
+	return &MarketCutoffRegistry{
+		cutoffs: make(map[uint]int64),
+	}
+}
+
+// Activate registers a simulation with its initial cutoff
+func (r *MarketCutoffRegistry) Activate(simulationID uint, cutoff int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cutoffs[simulationID] = cutoff
+}
+
+// Advance moves a simulation's cutoff forward; it never moves backwards
+func (r *MarketCutoffRegistry) Advance(simulationID uint, cutoff int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if current, exists := r.cutoffs[simulationID]; exists && cutoff > current {
+		r.cutoffs[simulationID] = cutoff
+	}
+}
+
+// Deactivate removes a simulation from the registry
+func (r *MarketCutoffRegistry) Deactivate(simulationID uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.cutoffs, simulationID)
+}
+
+// Cutoff returns the earliest cutoff across registered simulations, or false if none are registered
+func (r *MarketCutoffRegistry) Cutoff() (int64, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var earliest int64
+	found := false
+	for _, cutoff := range r.cutoffs {
+		if !found || cutoff < earliest {
+			earliest = cutoff
+			found = true
+		}
+	}
+	return earliest, found
+}
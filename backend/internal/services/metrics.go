@@ -0,0 +1,255 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	simulationDAO "tradesimulator/internal/dao/simulation"
+	tradingDAO "tradesimulator/internal/dao/trading"
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/services/market"
+)
+
+// msPerYear annualizes the Sharpe ratio of equity curve returns
+const msPerYear = 365 * 24 * 60 * 60 * 1000
+
+// ErrMetricsSimulationNotFound is returned when the simulation does not exist
+var ErrMetricsSimulationNotFound = errors.New("simulation not found")
+
+// PerformanceMetrics holds the risk and trade metrics of a simulation
+// Equity curve metrics are zero when the simulation has no recorded time range or fewer than two curve points
+type PerformanceMetrics struct {
+	SimulationID   uint    `json:"simulationId"`
+	Interval       string  `json:"interval,omitempty"` // Candle interval the equity curve was rebuilt at
+	EquityPoints   int     `json:"equityPoints"`       // Points of the rebuilt equity curve, including the initial funding
+	FinalValue     float64 `json:"finalValue"`         // Portfolio value at the last curve point, or the stored total value without a curve
+	MaxDrawdown    float64 `json:"maxDrawdown"`        // Largest fall from a curve peak, in the quote asset
+	MaxDrawdownPct float64 `json:"maxDrawdownPct"`     // Largest fall from a curve peak as a percentage of that peak
+	SharpeRatio    float64 `json:"sharpeRatio"`        // Annualized mean over standard deviation of per-candle returns, with no risk-free rate
+	TradeCount     int     `json:"tradeCount"`
+	ClosingTrades  int     `json:"closingTrades"` // Trades that reduced or closed a position
+	WinningTrades  int     `json:"winningTrades"` // Closing trades with positive realized P&L after fees
+	LosingTrades   int     `json:"losingTrades"`  // Closing trades with negative realized P&L after fees
+	WinRate        float64 `json:"winRate"`       // Percentage of closing trades that were winners
+	AverageWin     float64 `json:"averageWin"`    // Mean realized P&L of winning trades
+	AverageLoss    float64 `json:"averageLoss"`   // Mean realized P&L of losing trades, negative
+	RealizedPnL    float64 `json:"realizedPnl"`
+	TotalFees      float64 `json:"totalFees"`
+}
+
+// MetricsService computes performance metrics from a simulation's trades and market data
+type MetricsService struct {
+	simulationDAO     simulationDAO.SimulationDAOInterface
+	tradeDAO          tradingDAO.TradeDAOInterface
+	marketDataService market.MarketDataServiceInterface
+}
+
+// NewMetricsService creates a new metrics service
+func NewMetricsService(simulationDAO simulationDAO.SimulationDAOInterface, tradeDAO tradingDAO.TradeDAOInterface, marketDataService market.MarketDataServiceInterface) *MetricsService {
+	return &MetricsService{
+		simulationDAO:     simulationDAO,
+		tradeDAO:          tradeDAO,
+		marketDataService: marketDataService,
+	}
+}
+
+// GetPerformanceMetrics computes trade metrics from the simulation's trades, and drawdown and Sharpe ratio
+// from an equity curve rebuilt by replaying those trades against candle closes over the simulation's range
+func (ms *MetricsService) GetPerformanceMetrics(userID, simulationID uint) (*PerformanceMetrics, error) {
+	simulation, err := ms.simulationDAO.GetSimulationByID(simulationID)
+	if err != nil {
+		return nil, ErrMetricsSimulationNotFound
+	}
+
+	trades, err := ms.tradeDAO.GetUserTrades(userID, simulationID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metrics trades: %w", err)
+	}
+	sort.SliceStable(trades, func(i, j int) bool {
+		if trades[i].ExecutedAt != trades[j].ExecutedAt {
+			return trades[i].ExecutedAt < trades[j].ExecutedAt
+		}
+		return trades[i].ID < trades[j].ID
+	})
+
+	metrics := &PerformanceMetrics{
+		SimulationID: simulationID,
+		FinalValue:   simulation.InitialFunding,
+	}
+	if simulation.TotalValue != nil {
+		metrics.FinalValue = *simulation.TotalValue
+	}
+	applyTradeMetrics(metrics, trades)
+
+	if simulation.EndSimTime > simulation.StartSimTime {
+		interval, curve, err := ms.rebuildEquityCurve(simulation, trades)
+		if err != nil {
+			return nil, err
+		}
+		metrics.Interval = interval
+		applyEquityMetrics(metrics, curve, models.GetIntervalDurationMs(interval))
+	}
+
+	const quoteAsset = "USDT"
+	metrics.FinalValue = models.RoundValue(quoteAsset, metrics.FinalValue)
+	metrics.MaxDrawdown = models.RoundValue(quoteAsset, metrics.MaxDrawdown)
+	metrics.MaxDrawdownPct = models.RoundPercent(metrics.MaxDrawdownPct)
+	metrics.SharpeRatio = math.Round(metrics.SharpeRatio*1e4) / 1e4
+	metrics.WinRate = models.RoundPercent(metrics.WinRate)
+	metrics.AverageWin = models.RoundValue(quoteAsset, metrics.AverageWin)
+	metrics.AverageLoss = models.RoundValue(quoteAsset, metrics.AverageLoss)
+	metrics.RealizedPnL = models.RoundValue(quoteAsset, metrics.RealizedPnL)
+	metrics.TotalFees = models.RoundValue(quoteAsset, metrics.TotalFees)
+	return metrics, nil
+}
+
+// applyTradeMetrics matches the trades, oldest first, against open lots to count winners and losers
+func applyTradeMetrics(metrics *PerformanceMetrics, trades []models.Trade) {
+	ledger := models.NewCostBasisLedger(models.GetCostBasisMethod())
+	var totalWins, totalLosses float64
+	for i := range trades {
+		metrics.TradeCount++
+		metrics.TotalFees += trades[i].Fee
+
+		realized, closing := ledger.Apply(&trades[i])
+		if !closing {
+			continue
+		}
+		metrics.ClosingTrades++
+		metrics.RealizedPnL += realized
+		switch {
+		case realized > 0:
+			metrics.WinningTrades++
+			totalWins += realized
+		case realized < 0:
+			metrics.LosingTrades++
+			totalLosses += realized
+		}
+	}
+
+	if metrics.ClosingTrades > 0 {
+		metrics.WinRate = float64(metrics.WinningTrades) / float64(metrics.ClosingTrades) * 100
+	}
+	if metrics.WinningTrades > 0 {
+		metrics.AverageWin = totalWins / float64(metrics.WinningTrades)
+	}
+	if metrics.LosingTrades > 0 {
+		metrics.AverageLoss = totalLosses / float64(metrics.LosingTrades)
+	}
+}
+
+// rebuildEquityCurve values the portfolio at the initial funding and then at every candle close of the
+// simulation's symbol, with cash and holdings replayed from the trades filled by that close
+// Other traded symbols are priced at their latest candle close, or their latest fill before their first candle
+func (ms *MetricsService) rebuildEquityCurve(simulation *models.Simulation, trades []models.Trade) (string, []float64, error) {
+	interval, _, _ := selectReplayInterval("", simulation.StartSimTime, simulation.EndSimTime, MaxReplayCandles)
+	startTime := models.CalculateCandleStartTime(simulation.StartSimTime, interval)
+	endTime := simulation.EndSimTime
+
+	candles := make(map[string][]models.OHLCV)
+	symbols := []string{simulation.Symbol}
+	for _, trade := range trades {
+		if _, seen := candles[trade.Symbol]; !seen && trade.Symbol != simulation.Symbol {
+			symbols = append(symbols, trade.Symbol)
+		}
+		candles[trade.Symbol] = nil
+	}
+	for _, symbol := range symbols {
+		data, err := ms.marketDataService.GetHistoricalData(symbol, interval, MaxReplayCandles, &startTime, &endTime, false)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to get %s candles for metrics: %w", symbol, err)
+		}
+		candles[symbol] = data
+	}
+
+	cash := simulation.InitialFunding
+	holdings := make(map[string]float64)
+	prices := make(map[string]float64)
+	nextCandle := make(map[string]int)
+	nextTrade := 0
+
+	curve := []float64{simulation.InitialFunding}
+	for _, candle := range candles[simulation.Symbol] {
+		closeTime := candle.EndTime
+		if closeTime > endTime {
+			closeTime = endTime
+		}
+
+		for ; nextTrade < len(trades) && trades[nextTrade].ExecutedAt <= closeTime; nextTrade++ {
+			trade := trades[nextTrade]
+			value := trade.Quantity * trade.Price
+			if trade.Side == models.OrderSideBuy {
+				cash -= value + trade.Fee
+				holdings[trade.Symbol] += trade.Quantity
+			} else {
+				cash += value - trade.Fee
+				holdings[trade.Symbol] -= trade.Quantity
+			}
+			prices[trade.Symbol] = trade.Price
+		}
+
+		// Closes at or before this candle's close are known at this point of the run
+		for _, symbol := range symbols {
+			series := candles[symbol]
+			index := nextCandle[symbol]
+			for ; index < len(series) && series[index].EndTime <= candle.EndTime; index++ {
+				prices[symbol] = series[index].Close
+			}
+			nextCandle[symbol] = index
+		}
+
+		value := cash
+		for symbol, quantity := range holdings {
+			value += quantity * prices[symbol]
+		}
+		curve = append(curve, value)
+	}
+	return interval, curve, nil
+}
+
+// applyEquityMetrics measures the drawdown and Sharpe ratio of an equity curve sampled every intervalMs
+func applyEquityMetrics(metrics *PerformanceMetrics, curve []float64, intervalMs int64) {
+	metrics.EquityPoints = len(curve)
+	if len(curve) == 0 {
+		return
+	}
+	metrics.FinalValue = curve[len(curve)-1]
+
+	peak := curve[0]
+	returns := make([]float64, 0, len(curve)-1)
+	for i, value := range curve {
+		if value > peak {
+			peak = value
+		}
+		drawdown := peak - value
+		if drawdown > metrics.MaxDrawdown {
+			metrics.MaxDrawdown = drawdown
+		}
+		if peak > 0 && drawdown/peak*100 > metrics.MaxDrawdownPct {
+			metrics.MaxDrawdownPct = drawdown / peak * 100
+		}
+		if i > 0 && curve[i-1] > 0 {
+			returns = append(returns, value/curve[i-1]-1)
+		}
+	}
+
+	if len(returns) < 2 || intervalMs <= 0 {
+		return
+	}
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	stdDev := math.Sqrt(variance / float64(len(returns)-1))
+	if stdDev == 0 {
+		return
+	}
+	metrics.SharpeRatio = mean / stdDev * math.Sqrt(float64(msPerYear)/float64(intervalMs))
+}
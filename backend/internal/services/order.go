@@ -1,10 +1,19 @@
 package services
 
 import (
+	"errors"
+	"sort"
+
 	tradingDAO "tradesimulator/internal/dao/trading"
 	"tradesimulator/internal/models"
 )
 
+// ErrOrderNotFound is returned when an order does not exist or belongs to another user
+var ErrOrderNotFound = errors.New("order not found")
+
+// ErrOrderNotOpen is returned when an order has already been executed, cancelled or failed
+var ErrOrderNotOpen = errors.New("order is no longer open")
+
 // OrderService handles order orchestration and business logic
 type OrderService struct {
 	orderDAO tradingDAO.OrderDAOInterface
@@ -24,7 +33,170 @@ func (os *OrderService) GetUserOrders(userID uint, simulationID uint, limit int)
 	return os.orderDAO.GetUserOrders(userID, simulationID, limit)
 }
 
+// GetUserOrdersByTag gets a user's orders carrying a tag
+func (os *OrderService) GetUserOrdersByTag(userID uint, simulationID uint, tag string, limit int) ([]models.Order, error) {
+	return os.orderDAO.GetUserOrdersByTag(userID, simulationID, tag, limit)
+}
+
+// GetOpenOrders gets a user's pending orders in a simulation
+func (os *OrderService) GetOpenOrders(userID uint, simulationID uint) ([]models.Order, error) {
+	return os.orderDAO.GetPendingOrders(userID, simulationID)
+}
+
+// GetOpenOrder gets a user's order, failing if it can no longer fill
+func (os *OrderService) GetOpenOrder(userID uint, orderID uint) (*models.Order, error) {
+	order, err := os.orderDAO.GetByID(orderID)
+	if err != nil || order.UserID != userID {
+		return nil, ErrOrderNotFound
+	}
+	if !order.IsOpen() {
+		return nil, ErrOrderNotOpen
+	}
+	return order, nil
+}
+
+// CancelStoredOrder cancels an open order directly in the database
+// Used when no engine holds the order in memory, e.g. its simulation isn't running
+func (os *OrderService) CancelStoredOrder(userID uint, orderID uint) (*models.Order, error) {
+	order, err := os.GetOpenOrder(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	order.Status = models.OrderStatusCancelled
+	if err := os.orderDAO.Update(order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
 // GetUserTrades gets all trades for a user
 func (os *OrderService) GetUserTrades(userID uint, simulationID uint, limit int) ([]models.Trade, error) {
-	return os.tradeDAO.GetUserTrades(userID, simulationID, limit)
+	trades, err := os.tradeDAO.GetUserTrades(userID, simulationID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return roundTradeFees(trades), nil
+}
+
+// GetUserTradesByTag gets a user's trades whose order carries a tag
+func (os *OrderService) GetUserTradesByTag(userID uint, simulationID uint, tag string, limit int) ([]models.Trade, error) {
+	trades, err := os.tradeDAO.GetUserTradesByTag(userID, simulationID, tag, limit)
+	if err != nil {
+		return nil, err
+	}
+	return roundTradeFees(trades), nil
+}
+
+// GetLastTradePrice gets the price of a user's most recent trade of a symbol, 0 when the symbol was never traded
+func (os *OrderService) GetLastTradePrice(userID uint, simulationID uint, symbol string) (float64, error) {
+	trade, err := os.tradeDAO.GetLatestTrade(userID, simulationID, symbol)
+	if err != nil || trade == nil {
+		return 0, err
+	}
+	return trade.Price, nil
+}
+
+// GetOrderTrades gets the trades resulting from a user's order
+func (os *OrderService) GetOrderTrades(userID uint, orderID uint) ([]models.Trade, error) {
+	order, err := os.orderDAO.GetByID(orderID)
+	if err != nil || order.UserID != userID {
+		return nil, ErrOrderNotFound
+	}
+	trades, err := os.tradeDAO.GetTradesByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	return roundTradeFees(trades), nil
+}
+
+// roundTradeFees rounds computed fees for display; prices and quantities are returned as executed
+func roundTradeFees(trades []models.Trade) []models.Trade {
+	for i := range trades {
+		trades[i].Fee = models.RoundValue(trades[i].BaseCurrency, trades[i].Fee)
+	}
+	return trades
+}
+
+// NearestOrder is an open order with the price move needed before it can fill
+type NearestOrder struct {
+	Order        models.Order `json:"order"`
+	TriggerPrice float64      `json:"triggerPrice"` // Limit price, or stop price for stop-limit, stop-loss and take-profit orders
+	Distance     float64      `json:"distance"`     // Price move towards the trigger still needed; 0 or less means already reachable
+	DistancePct  float64      `json:"distancePct"`  // Distance as % of the current price
+}
+
+// NearestOrders lists open orders per side, closest to filling first
+type NearestOrders struct {
+	CurrentPrice float64        `json:"currentPrice"`
+	Buy          []NearestOrder `json:"buy"`
+	Sell         []NearestOrder `json:"sell"`
+}
+
+// GetNearestOrders sorts a user's open orders in a simulation by how far the price must move before each fills
+// Orders without a trigger price, such as market orders waiting for the next candle, are left out
+func (os *OrderService) GetNearestOrders(userID uint, simulationID uint, currentPrice float64) (*NearestOrders, error) {
+	orders, err := os.orderDAO.GetPendingOrders(userID, simulationID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &NearestOrders{
+		CurrentPrice: currentPrice,
+		Buy:          []NearestOrder{},
+		Sell:         []NearestOrder{},
+	}
+	for _, order := range orders {
+		nearest, ok := nearestOrder(order, currentPrice)
+		if !ok {
+			continue
+		}
+		if order.Side == models.OrderSideBuy {
+			result.Buy = append(result.Buy, nearest)
+		} else {
+			result.Sell = append(result.Sell, nearest)
+		}
+	}
+
+	for _, side := range [][]NearestOrder{result.Buy, result.Sell} {
+		sort.SliceStable(side, func(i, j int) bool {
+			return side[i].Distance < side[j].Distance
+		})
+	}
+	return result, nil
+}
+
+// nearestOrder computes the distance of an order's trigger from the current price
+// Buy limits and sell stops wait for the price to fall, sell limits and buy stops for it to rise
+func nearestOrder(order models.Order, currentPrice float64) (NearestOrder, bool) {
+	var triggerPrice float64
+	var waitsForDrop bool
+	switch {
+	case order.IsLimitOrder():
+		triggerPrice = *order.GetLimitPrice()
+		waitsForDrop = order.Side == models.OrderSideBuy
+	case order.IsStopOrder():
+		triggerPrice = *order.GetStopPrice()
+		waitsForDrop = order.Side == models.OrderSideSell
+	case order.IsProtectiveOrder():
+		triggerPrice = *order.GetTriggerPrice()
+		waitsForDrop = order.TriggersOnDrop()
+	default:
+		return NearestOrder{}, false
+	}
+
+	distance := triggerPrice - currentPrice
+	if waitsForDrop {
+		distance = currentPrice - triggerPrice
+	}
+
+	nearest := NearestOrder{
+		Order:        order,
+		TriggerPrice: triggerPrice,
+		Distance:     distance,
+	}
+	if currentPrice > 0 {
+		nearest.DistancePct = models.RoundPercent(distance / currentPrice * 100)
+	}
+	return nearest, true
 }
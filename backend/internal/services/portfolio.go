@@ -2,31 +2,35 @@ package services
 
 import (
 	"fmt"
+	"math"
 
-	"tradesimulator/internal/database"
+	tradingDAO "tradesimulator/internal/dao/trading"
 	"tradesimulator/internal/models"
-	"gorm.io/gorm"
 )
 
 // PortfolioService handles portfolio and position management
+// Every query is scoped to one simulation, so portfolios of different simulations never mix
 type PortfolioService struct {
-	db *gorm.DB
+	positionDAO tradingDAO.PositionDAOInterface
 }
 
 // NewPortfolioService creates a new portfolio service
-func NewPortfolioService() *PortfolioService {
+func NewPortfolioService(positionDAO tradingDAO.PositionDAOInterface) *PortfolioService {
 	return &PortfolioService{
-		db: database.GetDB(),
+		positionDAO: positionDAO,
 	}
 }
 
 
 // PortfolioSummary represents complete portfolio information using unified Position model
 type PortfolioSummary struct {
-	Positions   []PositionSummary `json:"positions"`
-	TotalValue  float64           `json:"totalValue"`
-	TotalPnL    float64           `json:"totalPnL"`
-	CashBalance float64           `json:"cash_balance"` // USDT position quantity
+	Positions     []PositionSummary `json:"positions"`
+	TotalValue    float64           `json:"totalValue"`
+	RealizedPnL   float64           `json:"realizedPnL"`   // Locked in by reducing or closing positions, net of fees
+	UnrealizedPnL float64           `json:"unrealizedPnL"` // Open positions at current prices against their cost basis
+	TotalPnL      float64           `json:"totalPnL"`      // Realized plus unrealized
+	CashBalance   float64           `json:"cash_balance"`  // USDT position quantity
+	Exposure      ExposureSummary   `json:"exposure"`
 	// Legacy portfolio structure for backward compatibility with frontend
 	Portfolio struct {
 		ID          uint    `json:"id"`
@@ -47,8 +51,77 @@ type PositionSummary struct {
 	TotalReturn   float64          `json:"totalReturn"` // Percentage return
 }
 
+// ExposureSummary describes market exposure of non-cash positions
+// Gross exposure can exceed 100% of portfolio value when positions are leveraged
+type ExposureSummary struct {
+	LongExposure     float64          `json:"longExposure"`     // Market value of long positions
+	ShortExposure    float64          `json:"shortExposure"`    // Absolute market value of short positions
+	NetExposure      float64          `json:"netExposure"`      // Long minus short
+	GrossExposure    float64          `json:"grossExposure"`    // Long plus short
+	NetExposurePct   float64          `json:"netExposurePct"`   // Net exposure as % of total portfolio value
+	GrossExposurePct float64          `json:"grossExposurePct"` // Gross exposure as % of total portfolio value
+	BySymbol         []SymbolExposure `json:"bySymbol"`
+}
+
+// SymbolExposure represents net exposure in a single symbol
+type SymbolExposure struct {
+	Symbol         string  `json:"symbol"`
+	Direction      string  `json:"direction"`      // "long", "short" or "flat"
+	NetValue       float64 `json:"netValue"`       // Signed market value (negative for shorts)
+	PctOfPortfolio float64 `json:"pctOfPortfolio"` // Signed % of total portfolio value
+}
+
+// calculateExposure builds exposure figures from position summaries and total portfolio value
+func calculateExposure(positions []PositionSummary, totalValue float64) ExposureSummary {
+	exposure := ExposureSummary{
+		BySymbol: []SymbolExposure{},
+	}
+
+	for _, summary := range positions {
+		if summary.Position.Symbol == "USDT" {
+			continue // Cash carries no market exposure
+		}
+
+		// Exposure is the notional value, which exceeds the market value of leveraged positions
+		netValue := summary.Position.Quantity * summary.CurrentPrice
+		direction := "flat"
+		if netValue > 0 {
+			direction = "long"
+			exposure.LongExposure += netValue
+		} else if netValue < 0 {
+			direction = "short"
+			exposure.ShortExposure += -netValue
+		}
+
+		symbolExposure := SymbolExposure{
+			Symbol:    summary.Position.Symbol,
+			Direction: direction,
+			NetValue:  netValue,
+		}
+		if totalValue != 0 {
+			symbolExposure.PctOfPortfolio = (netValue / totalValue) * 100
+		}
+		exposure.BySymbol = append(exposure.BySymbol, symbolExposure)
+	}
+
+	exposure.NetExposure = exposure.LongExposure - exposure.ShortExposure
+	exposure.GrossExposure = exposure.LongExposure + exposure.ShortExposure
+	if totalValue != 0 {
+		exposure.NetExposurePct = (exposure.NetExposure / totalValue) * 100
+		exposure.GrossExposurePct = (exposure.GrossExposure / totalValue) * 100
+	}
+
+	return exposure
+}
+
 // GetUserPortfolio gets complete portfolio summary for a user using unified Position model
 func (ps *PortfolioService) GetUserPortfolio(userID uint, simulationID uint, symbol string, currentPrice float64) (*PortfolioSummary, error) {
+	return ps.GetUserPortfolioAtPrices(userID, simulationID, map[string]float64{symbol: currentPrice})
+}
+
+// GetUserPortfolioAtPrices gets the portfolio summary with each held symbol valued at its price in prices
+// Symbols without a price are valued at their average price
+func (ps *PortfolioService) GetUserPortfolioAtPrices(userID uint, simulationID uint, prices map[string]float64) (*PortfolioSummary, error) {
 	// Get all positions for the user
 	positions, err := ps.GetUserPositions(userID, simulationID)
 	if err != nil {
@@ -59,29 +132,38 @@ func (ps *PortfolioService) GetUserPortfolio(userID uint, simulationID uint, sym
 	var positionSummaries []PositionSummary
 	var totalMarketValue float64
 	var totalUnrealizedPnL float64
+	var totalRealizedPnL float64
 	var cashBalance float64
 
 	for _, position := range positions {
+		totalRealizedPnL += position.RealizedPnL
+		if position.Symbol != "USDT" && position.IsFlat() {
+			continue // Closed positions only contribute their realized P&L
+		}
+
 		var positionPrice float64
 		
 		if position.Symbol == "USDT" {
 			// USDT always has price = 1
 			positionPrice = 1.0
 			cashBalance = position.Quantity
-		} else if position.Symbol == symbol {
-			// Use current simulation price for the main trading symbol
-			positionPrice = currentPrice
+		} else if price := prices[position.Symbol]; price > 0 {
+			// Use the simulation's current price for the symbol
+			positionPrice = price
 		} else {
 			// For other symbols, use average price as fallback
 			positionPrice = position.AveragePrice
 		}
 
-		marketValue := position.Quantity * positionPrice
-		unrealizedPnL := marketValue - position.TotalCost
+		// Leveraged positions are worth their notional value less what was borrowed to open them
+		marketValue := position.MarketValue(positionPrice)
+		unrealizedPnL := position.Quantity*positionPrice - position.TotalCost
 		
+		// Returns of leveraged positions are measured against the margin and fees paid, and returns of
+		// shorts against their proceeds
 		var totalReturn float64
-		if position.TotalCost != 0 {
-			totalReturn = (unrealizedPnL / position.TotalCost) * 100
+		if paid := position.TotalCost - position.Borrowed(); paid != 0 {
+			totalReturn = (unrealizedPnL / math.Abs(paid)) * 100
 		}
 
 		positionSummary := PositionSummary{
@@ -115,25 +197,68 @@ func (ps *PortfolioService) GetUserPortfolio(userID uint, simulationID uint, sym
 	}
 
 	summary := &PortfolioSummary{
-		Portfolio:   legacyPortfolio,
-		Positions:   positionSummaries,
-		TotalValue:  totalMarketValue,
-		TotalPnL:    totalUnrealizedPnL,
-		CashBalance: cashBalance,
+		Portfolio:     legacyPortfolio,
+		Positions:     positionSummaries,
+		TotalValue:    totalMarketValue,
+		RealizedPnL:   totalRealizedPnL,
+		UnrealizedPnL: totalUnrealizedPnL,
+		TotalPnL:      totalRealizedPnL + totalUnrealizedPnL,
+		CashBalance:   cashBalance,
+		Exposure:      calculateExposure(positionSummaries, totalMarketValue),
 	}
 
+	roundPortfolioSummary(summary)
+
 	return summary, nil
 }
 
+// roundPortfolioSummary rounds monetary values and percentages in a summary for display
+// Totals are calculated at full precision before rounding so they don't accumulate rounding error
+func roundPortfolioSummary(summary *PortfolioSummary) {
+	const quoteAsset = "USDT"
 
+	for i := range summary.Positions {
+		position := &summary.Positions[i]
+		positionQuote := position.Position.BaseCurrency
+		if positionQuote == "" {
+			positionQuote = quoteAsset
+		}
+		position.MarketValue = models.RoundValue(positionQuote, position.MarketValue)
+		position.UnrealizedPnL = models.RoundValue(positionQuote, position.UnrealizedPnL)
+		position.TotalReturn = models.RoundPercent(position.TotalReturn)
+	}
 
-// GetUserPositionsLockFree gets all positions for a user without calling GetStatus (to avoid deadlocks)
-func (ps *PortfolioService) GetUserPositions(userID uint, simulationID uint) ([]models.Position, error) {
-	var positions []models.Position
-	if err := ps.db.Where("user_id = ? AND simulation_id = ?", userID, simulationID).Find(&positions).Error; err != nil {
-		return nil, err
+	summary.TotalValue = models.RoundValue(quoteAsset, summary.TotalValue)
+	summary.RealizedPnL = models.RoundValue(quoteAsset, summary.RealizedPnL)
+	summary.UnrealizedPnL = models.RoundValue(quoteAsset, summary.UnrealizedPnL)
+	summary.TotalPnL = models.RoundValue(quoteAsset, summary.TotalPnL)
+	summary.CashBalance = models.RoundValue(quoteAsset, summary.CashBalance)
+	summary.Portfolio.CashBalance = models.RoundValue(quoteAsset, summary.Portfolio.CashBalance)
+	summary.Portfolio.TotalValue = models.RoundValue(quoteAsset, summary.Portfolio.TotalValue)
+
+	exposure := &summary.Exposure
+	exposure.LongExposure = models.RoundValue(quoteAsset, exposure.LongExposure)
+	exposure.ShortExposure = models.RoundValue(quoteAsset, exposure.ShortExposure)
+	exposure.NetExposure = models.RoundValue(quoteAsset, exposure.NetExposure)
+	exposure.GrossExposure = models.RoundValue(quoteAsset, exposure.GrossExposure)
+	exposure.NetExposurePct = models.RoundPercent(exposure.NetExposurePct)
+	exposure.GrossExposurePct = models.RoundPercent(exposure.GrossExposurePct)
+	for i := range exposure.BySymbol {
+		exposure.BySymbol[i].NetValue = models.RoundValue(quoteAsset, exposure.BySymbol[i].NetValue)
+		exposure.BySymbol[i].PctOfPortfolio = models.RoundPercent(exposure.BySymbol[i].PctOfPortfolio)
 	}
-	return positions, nil
 }
 
 
+
+// GetPositionHistory gets the position snapshots of a simulation in simulation time order, for one symbol
+// when symbol is not empty
+func (ps *PortfolioService) GetPositionHistory(simulationID uint, symbol string) ([]models.PositionSnapshot, error) {
+	return ps.positionDAO.GetSnapshots(simulationID, symbol)
+}
+
+// GetUserPositions gets all positions of a user in one simulation without calling GetStatus (to avoid deadlocks)
+// Cash is only ever held in the simulation-scoped USDT position, so it is counted exactly once
+func (ps *PortfolioService) GetUserPositions(userID uint, simulationID uint) ([]models.Position, error) {
+	return ps.positionDAO.GetUserPositions(userID, simulationID)
+}
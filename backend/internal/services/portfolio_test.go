@@ -0,0 +1,84 @@
+package services
+
+import (
+	"testing"
+
+	"tradesimulator/internal/testutil"
+)
+
+// Cash is counted once: each simulation's portfolio holds only its own funding position, and funding can only be
+// created for a simulation
+func TestPortfolioCountsCashOnce(t *testing.T) {
+	positionDAO := testutil.NewPositionDAO()
+	first, second := uint(1), uint(2)
+	for _, simulationID := range []*uint{&first, &second} {
+		if err := positionDAO.CreateInitialUSDTPosition(1, simulationID, 10_000); err != nil {
+			t.Fatalf("CreateInitialUSDTPosition: %v", err)
+		}
+	}
+	if err := positionDAO.CreateInitialUSDTPosition(1, nil, 10_000); err == nil {
+		t.Error("created a USDT position without a simulation")
+	}
+	if err := positionDAO.CreateInitialUSDTPosition(1, &first, 10_000); err == nil {
+		t.Error("funded a simulation twice")
+	}
+
+	portfolio, err := NewPortfolioService(positionDAO).GetUserPortfolio(1, first, "BTCUSDT", 100)
+	if err != nil {
+		t.Fatalf("GetUserPortfolio: %v", err)
+	}
+	if len(portfolio.Positions) != 1 {
+		t.Errorf("got %d positions, want the simulation's USDT position only", len(portfolio.Positions))
+	}
+	if portfolio.CashBalance != 10_000 || portfolio.TotalValue != 10_000 {
+		t.Errorf("cash %g and total value %g, want 10000 each", portfolio.CashBalance, portfolio.TotalValue)
+	}
+}
+
+// Positions in one simulation never show up in another simulation's portfolio
+func TestPortfolioScopedBySimulation(t *testing.T) {
+	positionDAO := testutil.NewPositionDAO()
+	first, second := uint(1), uint(2)
+	fills := []struct {
+		simulationID *uint
+		funding      float64
+		quantity     float64
+		price        float64
+	}{
+		{simulationID: &first, funding: 10_000, quantity: 10, price: 100},
+		{simulationID: &second, funding: 5_000, quantity: 2, price: 200},
+	}
+	for _, fill := range fills {
+		if err := positionDAO.CreateInitialUSDTPosition(1, fill.simulationID, fill.funding); err != nil {
+			t.Fatalf("CreateInitialUSDTPosition: %v", err)
+		}
+		if err := positionDAO.UpdateOrCreatePosition(nil, 1, fill.simulationID, "BTCUSDT", "USDT", fill.quantity, fill.price, 0); err != nil {
+			t.Fatalf("UpdateOrCreatePosition: %v", err)
+		}
+		if err := positionDAO.UpdateOrCreatePosition(nil, 1, fill.simulationID, "USDT", "USDT", -fill.quantity*fill.price, 1, 0); err != nil {
+			t.Fatalf("UpdateOrCreatePosition: %v", err)
+		}
+	}
+
+	tests := []struct {
+		simulationID uint
+		wantCash     float64
+		wantTotal    float64
+	}{
+		{simulationID: first, wantCash: 9_000, wantTotal: 10_500},
+		{simulationID: second, wantCash: 4_600, wantTotal: 4_900},
+	}
+	for _, tt := range tests {
+		portfolio, err := NewPortfolioService(positionDAO).GetUserPortfolio(1, tt.simulationID, "BTCUSDT", 150)
+		if err != nil {
+			t.Fatalf("GetUserPortfolio: %v", err)
+		}
+		if len(portfolio.Positions) != 2 {
+			t.Errorf("simulation %d has %d positions, want its USDT and BTC positions", tt.simulationID, len(portfolio.Positions))
+		}
+		if portfolio.CashBalance != tt.wantCash || portfolio.TotalValue != tt.wantTotal {
+			t.Errorf("simulation %d cash %g and total value %g, want %g and %g",
+				tt.simulationID, portfolio.CashBalance, portfolio.TotalValue, tt.wantCash, tt.wantTotal)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	simulationDAO "tradesimulator/internal/dao/simulation"
+	tradingDAO "tradesimulator/internal/dao/trading"
+	"tradesimulator/internal/models"
+	"tradesimulator/internal/services/market"
+)
+
+const (
+	// DefaultReplayMaxCandles is the candle budget used when the caller does not set one
+	DefaultReplayMaxCandles = 500
+	// MaxReplayCandles is the largest candle budget, matching a single Binance klines request
+	MaxReplayCandles = 1000
+)
+
+// replayIntervals lists supported intervals from finest to coarsest for downsampling
+var replayIntervals = []string{"1m", "3m", "5m", "15m", "30m", "1h", "2h", "4h", "6h", "8h", "12h", "1d", "3d", "1w", "1M"}
+
+var (
+	// ErrReplaySimulationNotFound is returned when the simulation does not exist
+	ErrReplaySimulationNotFound = errors.New("simulation not found")
+	// ErrReplayInvalidInterval is returned when the requested interval is not supported
+	ErrReplayInvalidInterval = errors.New("invalid interval")
+	// ErrReplayNoTimeRange is returned when the simulation has not recorded an end time yet
+	ErrReplayNoTimeRange = errors.New("simulation has no recorded time range")
+)
+
+// ReplayTradeMarker marks a trade on the replay chart
+type ReplayTradeMarker struct {
+	TradeID    uint             `json:"tradeId"`
+	Time       int64            `json:"time"`       // Simulation time of the fill in milliseconds
+	CandleTime int64            `json:"candleTime"` // Start time of the replay candle containing the fill
+	Price      float64          `json:"price"`
+	Quantity   float64          `json:"quantity"`
+	Side       models.OrderSide `json:"side"`
+}
+
+// ReplayData bundles candles and trade markers for animating a finished simulation
+type ReplayData struct {
+	SimulationID uint                `json:"simulationId"`
+	Symbol       string              `json:"symbol"`
+	Interval     string              `json:"interval"`
+	StartTime    int64               `json:"startTime"`
+	EndTime      int64               `json:"endTime"`
+	Downsampled  bool                `json:"downsampled"` // True if a coarser interval than requested was used
+	Archived     bool                `json:"archived"`    // True if the candles were served from the simulation's candle archive
+	Candles      []models.OHLCV      `json:"candles"`
+	Trades       []ReplayTradeMarker `json:"trades"`
+	Annotations  []models.Annotation `json:"annotations"` // User notes ordered by simulation time
+}
+
+// ReplayService aggregates simulation, trade and market data for replays
+type ReplayService struct {
+	simulationDAO     simulationDAO.SimulationDAOInterface
+	tradeDAO          tradingDAO.TradeDAOInterface
+	annotationDAO     simulationDAO.AnnotationDAOInterface
+	marketDataService market.MarketDataServiceInterface
+}
+
+// NewReplayService creates a new replay service
+func NewReplayService(simulationDAO simulationDAO.SimulationDAOInterface, tradeDAO tradingDAO.TradeDAOInterface, annotationDAO simulationDAO.AnnotationDAOInterface, marketDataService market.MarketDataServiceInterface) *ReplayService {
+	return &ReplayService{
+		simulationDAO:     simulationDAO,
+		tradeDAO:          tradeDAO,
+		annotationDAO:     annotationDAO,
+		marketDataService: marketDataService,
+	}
+}
+
+// GetReplayData returns candles covering the simulation's time range with its trades aligned to them
+// If interval is empty, or would produce more than maxCandles, the finest interval that fits is used
+func (rs *ReplayService) GetReplayData(userID, simulationID uint, interval string, maxCandles int) (*ReplayData, error) {
+	if maxCandles <= 0 {
+		maxCandles = DefaultReplayMaxCandles
+	}
+	if maxCandles > MaxReplayCandles {
+		maxCandles = MaxReplayCandles
+	}
+
+	simulation, err := rs.simulationDAO.GetSimulationByID(simulationID)
+	if err != nil {
+		return nil, ErrReplaySimulationNotFound
+	}
+	if simulation.EndSimTime <= simulation.StartSimTime {
+		return nil, ErrReplayNoTimeRange
+	}
+
+	replayInterval, downsampled, ok := selectReplayInterval(interval, simulation.StartSimTime, simulation.EndSimTime, maxCandles)
+	if !ok {
+		return nil, ErrReplayInvalidInterval
+	}
+
+	candles, archived, err := rs.archivedCandles(simulation, replayInterval, maxCandles)
+	if err != nil {
+		return nil, err
+	}
+	if !archived {
+		startTime := models.CalculateCandleStartTime(simulation.StartSimTime, replayInterval)
+		endTime := simulation.EndSimTime
+		candles, err = rs.marketDataService.GetHistoricalData(simulation.Symbol, replayInterval, maxCandles, &startTime, &endTime, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get replay candles: %w", err)
+		}
+	}
+
+	trades, err := rs.tradeDAO.GetUserTrades(userID, simulationID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replay trades: %w", err)
+	}
+
+	annotations, err := rs.annotationDAO.GetBySimulation(simulationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replay annotations: %w", err)
+	}
+
+	return &ReplayData{
+		SimulationID: simulationID,
+		Symbol:       simulation.Symbol,
+		Interval:     replayInterval,
+		StartTime:    simulation.StartSimTime,
+		EndTime:      simulation.EndSimTime,
+		Downsampled:  downsampled,
+		Archived:     archived,
+		Candles:      candles,
+		Trades:       buildTradeMarkers(trades, candles),
+		Annotations:  annotations,
+	}, nil
+}
+
+// ArchiveCandles stores the candles a finished simulation is replayed with by default, so later
+// reviews are served from the database even if Binance is unavailable or its data changes
+// Candles fetched during the run are served from the kline cache, so this rarely reaches Binance
+func (rs *ReplayService) ArchiveCandles(simulationID uint) error {
+	simulation, err := rs.simulationDAO.GetSimulationByID(simulationID)
+	if err != nil {
+		return ErrReplaySimulationNotFound
+	}
+	if simulation.EndSimTime <= simulation.StartSimTime {
+		return ErrReplayNoTimeRange
+	}
+
+	interval, _, _ := selectReplayInterval("", simulation.StartSimTime, simulation.EndSimTime, DefaultReplayMaxCandles)
+	startTime := models.CalculateCandleStartTime(simulation.StartSimTime, interval)
+	endTime := simulation.EndSimTime
+	candles, err := rs.marketDataService.GetHistoricalData(simulation.Symbol, interval, DefaultReplayMaxCandles, &startTime, &endTime, false)
+	if err != nil {
+		return fmt.Errorf("failed to get candles to archive: %w", err)
+	}
+
+	encoded, err := json.Marshal(candles)
+	if err != nil {
+		return fmt.Errorf("failed to encode candles: %w", err)
+	}
+
+	archive := &models.CandleArchive{
+		SimulationID: simulationID,
+		Symbol:       simulation.Symbol,
+		Interval:     interval,
+		StartTime:    startTime,
+		EndTime:      endTime,
+		CandleCount:  len(candles),
+		Candles:      string(encoded),
+	}
+	if err := rs.simulationDAO.SaveCandleArchive(archive); err != nil {
+		return err
+	}
+
+	log.Printf("Archived %d %s %s candles for simulation %d", len(candles), simulation.Symbol, interval, simulationID)
+	return nil
+}
+
+// archivedCandles returns the archived candles of a simulation if the archive matches the replay request
+// The archive is only used when it covers the simulation's current time range at the chosen interval
+func (rs *ReplayService) archivedCandles(simulation *models.Simulation, interval string, maxCandles int) ([]models.OHLCV, bool, error) {
+	archive, err := rs.simulationDAO.GetCandleArchive(simulation.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if archive == nil || archive.Interval != interval || archive.EndTime != simulation.EndSimTime || archive.CandleCount > maxCandles {
+		return nil, false, nil
+	}
+
+	var candles []models.OHLCV
+	if err := json.Unmarshal([]byte(archive.Candles), &candles); err != nil {
+		return nil, false, fmt.Errorf("failed to decode candle archive: %w", err)
+	}
+	return candles, true, nil
+}
+
+// selectReplayInterval picks the requested interval, or the finest one keeping the range within maxCandles
+func selectReplayInterval(requested string, startTime, endTime int64, maxCandles int) (string, bool, bool) {
+	span := endTime - startTime
+	fits := func(interval string) bool {
+		return span/models.GetIntervalDurationMs(interval) < int64(maxCandles)
+	}
+
+	startIndex := -1
+	for i, interval := range replayIntervals {
+		if interval == requested {
+			startIndex = i
+			break
+		}
+	}
+	if startIndex < 0 {
+		if requested != "" {
+			return "", false, false
+		}
+		startIndex = 0
+	}
+
+	for _, interval := range replayIntervals[startIndex:] {
+		if fits(interval) {
+			return interval, requested != "" && interval != requested, true
+		}
+	}
+	coarsest := replayIntervals[len(replayIntervals)-1]
+	return coarsest, requested != "" && requested != coarsest, true
+}
+
+// buildTradeMarkers converts trades into chronological markers aligned to the candle containing each fill
+func buildTradeMarkers(trades []models.Trade, candles []models.OHLCV) []ReplayTradeMarker {
+	markers := make([]ReplayTradeMarker, 0, len(trades))
+	for _, trade := range trades {
+		marker := ReplayTradeMarker{
+			TradeID:  trade.ID,
+			Time:     trade.ExecutedAt,
+			Price:    trade.Price,
+			Quantity: trade.Quantity,
+			Side:     trade.Side,
+		}
+
+		// Last candle starting at or before the fill
+		index := sort.Search(len(candles), func(i int) bool {
+			return candles[i].StartTime > trade.ExecutedAt
+		}) - 1
+		if index >= 0 {
+			marker.CandleTime = candles[index].StartTime
+		}
+
+		markers = append(markers, marker)
+	}
+
+	sort.Slice(markers, func(i, j int) bool {
+		return markers[i].Time < markers[j].Time
+	})
+	return markers
+}
@@ -0,0 +1,58 @@
+package testutil
+
+import (
+	"sync"
+
+	"tradesimulator/internal/types"
+)
+
+// Message is one message sent to a Client
+type Message struct {
+	Type types.MessageType
+	Data interface{}
+}
+
+// Client records the messages and errors engines send to a WebSocket client
+type Client struct {
+	mu       sync.Mutex
+	messages []Message
+	errors   []string
+}
+
+// NewClient creates a client without messages
+func NewClient() *Client {
+	return &Client{}
+}
+
+func (c *Client) SendMessage(messageType types.MessageType, data interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, Message{Type: messageType, Data: data})
+}
+
+func (c *Client) SendError(message string, errorDetails string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errors = append(c.errors, message)
+}
+
+// Messages returns the messages of a type sent so far, all of them when messageType is empty
+func (c *Client) Messages(messageType types.MessageType) []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var messages []Message
+	for _, message := range c.messages {
+		if messageType == "" || message.Type == messageType {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// Errors returns the error messages sent so far
+func (c *Client) Errors() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.errors...)
+}
@@ -0,0 +1,687 @@
+package testutil
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	simulationDAO "tradesimulator/internal/dao/simulation"
+	tradingDAO "tradesimulator/internal/dao/trading"
+	"tradesimulator/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SimulationDAO keeps simulation records, equity samples and candle archives in memory
+type SimulationDAO struct {
+	mu            sync.Mutex
+	nextID        uint
+	simulations   map[uint]*models.Simulation
+	equitySamples map[uint][]models.EquitySample
+	archives      map[uint]*models.CandleArchive
+}
+
+// NewSimulationDAO creates an empty in-memory simulation DAO
+func NewSimulationDAO() *SimulationDAO {
+	return &SimulationDAO{
+		simulations:   make(map[uint]*models.Simulation),
+		equitySamples: make(map[uint][]models.EquitySample),
+		archives:      make(map[uint]*models.CandleArchive),
+	}
+}
+
+var _ simulationDAO.SimulationDAOInterface = (*SimulationDAO)(nil)
+
+func (s *SimulationDAO) CreateSimulationRecord(userID uint, symbol string, startSimTime, endSimTime int64, initialFunding float64, mode models.SimulationMode, extraConfig *simulationDAO.ExtraConfig) (*models.Simulation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	simulation := &models.Simulation{
+		ID:             s.nextID,
+		UserID:         userID,
+		Symbol:         symbol,
+		StartSimTime:   startSimTime,
+		EndSimTime:     endSimTime,
+		InitialFunding: initialFunding,
+		Mode:           mode,
+		ExtraConfigs:   "{}",
+		Status:         models.SimulationStatusRunning,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	s.simulations[simulation.ID] = simulation
+
+	stored := *simulation
+	return &stored, nil
+}
+
+func (s *SimulationDAO) UpdateSimulationStatus(simulationID uint, status models.SimulationStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	simulation, ok := s.simulations[simulationID]
+	if !ok {
+		return fmt.Errorf("simulation record not found: %d", simulationID)
+	}
+	simulation.Status = status
+	return nil
+}
+
+func (s *SimulationDAO) UpdateSimulationStatusWithDetails(simulationID uint, status models.SimulationStatus, endSimTime int64, totalValue *float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	simulation, ok := s.simulations[simulationID]
+	if !ok {
+		return fmt.Errorf("simulation record not found: %d", simulationID)
+	}
+	simulation.Status = status
+	if endSimTime != 0 {
+		simulation.EndSimTime = endSimTime
+		simulation.LastSimTime = endSimTime
+	}
+	if totalValue != nil {
+		value := *totalValue
+		pnl := value - simulation.InitialFunding
+		simulation.TotalValue = &value
+		simulation.PnL = &pnl
+		if simulation.InitialFunding != 0 {
+			returnPct := pnl / simulation.InitialFunding * 100
+			simulation.ReturnPct = &returnPct
+		}
+	}
+	return nil
+}
+
+func (s *SimulationDAO) UpdateLastSimTime(simulationID uint, lastSimTime int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	simulation, ok := s.simulations[simulationID]
+	if !ok {
+		return fmt.Errorf("simulation record not found: %d", simulationID)
+	}
+	simulation.LastSimTime = lastSimTime
+	return nil
+}
+
+func (s *SimulationDAO) GetSimulationByID(simulationID uint) (*models.Simulation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	simulation, ok := s.simulations[simulationID]
+	if !ok {
+		return nil, fmt.Errorf("simulation not found: %w", gorm.ErrRecordNotFound)
+	}
+	stored := *simulation
+	return &stored, nil
+}
+
+// GetUserSimulations returns the user's simulations newest first; the sort is applied by the database DAO only
+func (s *SimulationDAO) GetUserSimulations(userID uint, sortBy simulationDAO.SimulationSort, limit, offset int) ([]models.Simulation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var simulations []models.Simulation
+	for _, simulation := range s.simulations {
+		if simulation.UserID == userID {
+			simulations = append(simulations, *simulation)
+		}
+	}
+	sort.Slice(simulations, func(i, j int) bool { return simulations[i].ID > simulations[j].ID })
+
+	if offset >= len(simulations) {
+		return []models.Simulation{}, nil
+	}
+	simulations = simulations[offset:]
+	if limit > 0 && limit < len(simulations) {
+		simulations = simulations[:limit]
+	}
+	return simulations, nil
+}
+
+func (s *SimulationDAO) GetRunningSimulation(userID uint) (*models.Simulation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, simulation := range s.simulations {
+		if simulation.UserID == userID &&
+			(simulation.Status == models.SimulationStatusRunning || simulation.Status == models.SimulationStatusPaused) {
+			stored := *simulation
+			return &stored, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *SimulationDAO) DeleteSimulation(simulationID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.simulations[simulationID]; !ok {
+		return fmt.Errorf("simulation record not found: %d", simulationID)
+	}
+	delete(s.simulations, simulationID)
+	delete(s.equitySamples, simulationID)
+	delete(s.archives, simulationID)
+	return nil
+}
+
+func (s *SimulationDAO) GetSimulationStats(simulationID uint) (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+func (s *SimulationDAO) RecordEquitySample(simulationID uint, simTime int64, cash, positionValue float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.equitySamples[simulationID] = append(s.equitySamples[simulationID], models.EquitySample{
+		SimulationID:  simulationID,
+		SimTime:       simTime,
+		Cash:          &cash,
+		PositionValue: &positionValue,
+		TotalValue:    cash + positionValue,
+	})
+	return nil
+}
+
+func (s *SimulationDAO) GetEquitySamples(simulationID uint) ([]models.EquitySample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.EquitySample(nil), s.equitySamples[simulationID]...), nil
+}
+
+func (s *SimulationDAO) GetEquitySampleSummary(simulationID uint) (int, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	samples := s.equitySamples[simulationID]
+	if len(samples) == 0 {
+		return 0, 0, nil
+	}
+	return len(samples), samples[len(samples)-1].SimTime, nil
+}
+
+// ThinEquitySamples drops every other sample, keeping the first and the latest
+func (s *SimulationDAO) ThinEquitySamples(simulationID uint) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	samples := s.equitySamples[simulationID]
+	var kept []models.EquitySample
+	for i, sample := range samples {
+		if i%2 == 0 || i == len(samples)-1 {
+			kept = append(kept, sample)
+		}
+	}
+	s.equitySamples[simulationID] = kept
+	return len(samples) - len(kept), nil
+}
+
+func (s *SimulationDAO) SaveCandleArchive(archive *models.CandleArchive) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *archive
+	s.archives[archive.SimulationID] = &stored
+	return nil
+}
+
+func (s *SimulationDAO) GetCandleArchive(simulationID uint) (*models.CandleArchive, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	archive, ok := s.archives[simulationID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	stored := *archive
+	return &stored, nil
+}
+
+// OrderDAO keeps orders in memory; transactions are ignored
+type OrderDAO struct {
+	mu     sync.Mutex
+	nextID uint
+	orders map[uint]models.Order
+}
+
+// NewOrderDAO creates an empty in-memory order DAO
+func NewOrderDAO() *OrderDAO {
+	return &OrderDAO{orders: make(map[uint]models.Order)}
+}
+
+var _ tradingDAO.OrderDAOInterface = (*OrderDAO)(nil)
+
+func (dao *OrderDAO) Create(order *models.Order) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	dao.nextID++
+	order.ID = dao.nextID
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+	dao.orders[order.ID] = *order
+	return nil
+}
+
+func (dao *OrderDAO) Update(order *models.Order) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if _, ok := dao.orders[order.ID]; !ok {
+		return fmt.Errorf("failed to update order: %w", gorm.ErrRecordNotFound)
+	}
+	order.UpdatedAt = time.Now()
+	dao.orders[order.ID] = *order
+	return nil
+}
+
+func (dao *OrderDAO) GetByID(orderID uint) (*models.Order, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	order, ok := dao.orders[orderID]
+	if !ok {
+		return nil, fmt.Errorf("failed to get order: %w", gorm.ErrRecordNotFound)
+	}
+	return &order, nil
+}
+
+// GetUserOrders returns the simulation's orders newest first
+func (dao *OrderDAO) GetUserOrders(userID, simulationID uint, limit int) ([]models.Order, error) {
+	return dao.find(func(order models.Order) bool {
+		return order.UserID == userID && sameSimulation(order.SimulationID, simulationID)
+	}, true, limit), nil
+}
+
+func (dao *OrderDAO) GetUserOrdersByTag(userID, simulationID uint, tag string, limit int) ([]models.Order, error) {
+	return dao.find(func(order models.Order) bool {
+		return order.UserID == userID && sameSimulation(order.SimulationID, simulationID) && order.Tag == tag
+	}, true, limit), nil
+}
+
+// GetPendingOrders returns the simulation's open orders oldest first
+func (dao *OrderDAO) GetPendingOrders(userID, simulationID uint) ([]models.Order, error) {
+	return dao.find(func(order models.Order) bool {
+		return order.UserID == userID && sameSimulation(order.SimulationID, simulationID) && order.IsOpen()
+	}, false, 0), nil
+}
+
+// GetSimulationOpenOrders returns every user's open orders in the simulation, or in all simulations for 0, oldest
+// first
+func (dao *OrderDAO) GetSimulationOpenOrders(simulationID uint) ([]models.Order, error) {
+	return dao.find(func(order models.Order) bool {
+		return (simulationID == 0 || sameSimulation(order.SimulationID, simulationID)) && order.IsOpen()
+	}, false, 0), nil
+}
+
+func (dao *OrderDAO) CreateWithTx(tx *gorm.DB, order *models.Order) error {
+	return dao.Create(order)
+}
+
+func (dao *OrderDAO) UpdateWithTx(tx *gorm.DB, order *models.Order) error {
+	return dao.Update(order)
+}
+
+// find returns matching orders by ID, which is creation order
+func (dao *OrderDAO) find(match func(models.Order) bool, newestFirst bool, limit int) []models.Order {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	orders := []models.Order{}
+	for _, order := range dao.orders {
+		if match(order) {
+			orders = append(orders, order)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if newestFirst {
+			return orders[i].ID > orders[j].ID
+		}
+		return orders[i].ID < orders[j].ID
+	})
+	if limit > 0 && limit < len(orders) {
+		orders = orders[:limit]
+	}
+	return orders
+}
+
+// TradeDAO keeps trades in memory; transactions are ignored
+type TradeDAO struct {
+	mu     sync.Mutex
+	nextID uint
+	trades []models.Trade
+	orders *OrderDAO // Orders the trades belong to, for filtering by tag
+}
+
+// NewTradeDAO creates an empty in-memory trade DAO
+func NewTradeDAO() *TradeDAO {
+	return &TradeDAO{}
+}
+
+var _ tradingDAO.TradeDAOInterface = (*TradeDAO)(nil)
+
+func (dao *TradeDAO) Create(trade *models.Trade) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	dao.nextID++
+	trade.ID = dao.nextID
+	trade.CreatedAt = time.Now()
+	stored := *trade
+	stored.Order = models.Order{}
+	dao.trades = append(dao.trades, stored)
+	return nil
+}
+
+func (dao *TradeDAO) GetByID(tradeID uint) (*models.Trade, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	for _, trade := range dao.trades {
+		if trade.ID == tradeID {
+			return &trade, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to get trade: %w", gorm.ErrRecordNotFound)
+}
+
+// GetUserTrades returns the simulation's trades latest first
+func (dao *TradeDAO) GetUserTrades(userID, simulationID uint, limit int) ([]models.Trade, error) {
+	return dao.find(func(trade models.Trade) bool {
+		return trade.UserID == userID && sameSimulation(trade.SimulationID, simulationID)
+	}, true, limit), nil
+}
+
+// SetOrderDAO links the DAO holding the trades' orders, which GetUserTradesByTag reads their tags from
+func (dao *TradeDAO) SetOrderDAO(orders *OrderDAO) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+	dao.orders = orders
+}
+
+// GetUserTradesByTag returns the simulation's trades whose order carries tag latest first
+func (dao *TradeDAO) GetUserTradesByTag(userID, simulationID uint, tag string, limit int) ([]models.Trade, error) {
+	dao.mu.Lock()
+	orders := dao.orders
+	dao.mu.Unlock()
+	if orders == nil {
+		return nil, fmt.Errorf("trades by tag need the order DAO, see SetOrderDAO")
+	}
+
+	tagged := map[uint]bool{}
+	for _, order := range orders.find(func(order models.Order) bool {
+		return sameSimulation(order.SimulationID, simulationID) && order.Tag == tag
+	}, false, 0) {
+		tagged[order.ID] = true
+	}
+	return dao.find(func(trade models.Trade) bool {
+		return trade.UserID == userID && sameSimulation(trade.SimulationID, simulationID) && tagged[trade.OrderID]
+	}, true, limit), nil
+}
+
+func (dao *TradeDAO) GetTradesByOrderID(orderID uint) ([]models.Trade, error) {
+	return dao.find(func(trade models.Trade) bool { return trade.OrderID == orderID }, false, 0), nil
+}
+
+func (dao *TradeDAO) GetLatestTrade(userID, simulationID uint, symbol string) (*models.Trade, error) {
+	trades := dao.find(func(trade models.Trade) bool {
+		return trade.UserID == userID && sameSimulation(trade.SimulationID, simulationID) && trade.Symbol == symbol
+	}, true, 1)
+	if len(trades) == 0 {
+		return nil, nil
+	}
+	return &trades[0], nil
+}
+
+func (dao *TradeDAO) CreateWithTx(tx *gorm.DB, trade *models.Trade) error {
+	return dao.Create(trade)
+}
+
+// find returns matching trades by execution time, then ID
+func (dao *TradeDAO) find(match func(models.Trade) bool, latestFirst bool, limit int) []models.Trade {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	trades := []models.Trade{}
+	for _, trade := range dao.trades {
+		if match(trade) {
+			trades = append(trades, trade)
+		}
+	}
+	sort.SliceStable(trades, func(i, j int) bool {
+		before := trades[i].ExecutedAt < trades[j].ExecutedAt ||
+			(trades[i].ExecutedAt == trades[j].ExecutedAt && trades[i].ID < trades[j].ID)
+		if latestFirst {
+			return !before
+		}
+		return before
+	})
+	if limit > 0 && limit < len(trades) {
+		trades = trades[:limit]
+	}
+	return trades
+}
+
+// PositionDAO keeps positions and their snapshots in memory, applying fills with the same arithmetic as the
+// database DAO; transactions are ignored
+type PositionDAO struct {
+	mu        sync.Mutex
+	nextID    uint
+	positions map[uint]models.Position
+	snapshots []models.PositionSnapshot
+}
+
+// NewPositionDAO creates an empty in-memory position DAO
+func NewPositionDAO() *PositionDAO {
+	return &PositionDAO{positions: make(map[uint]models.Position)}
+}
+
+var _ tradingDAO.PositionDAOInterface = (*PositionDAO)(nil)
+
+func (dao *PositionDAO) Create(position *models.Position) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+	return dao.createUnsafe(position)
+}
+
+func (dao *PositionDAO) Update(position *models.Position) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if _, ok := dao.positions[position.ID]; !ok {
+		return fmt.Errorf("failed to update position: %w", gorm.ErrRecordNotFound)
+	}
+	position.UpdatedAt = time.Now()
+	dao.positions[position.ID] = *position
+	return nil
+}
+
+func (dao *PositionDAO) Delete(position *models.Position) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	delete(dao.positions, position.ID)
+	return nil
+}
+
+func (dao *PositionDAO) GetByID(positionID uint) (*models.Position, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	position, ok := dao.positions[positionID]
+	if !ok {
+		return nil, fmt.Errorf("failed to get position: %w", gorm.ErrRecordNotFound)
+	}
+	return &position, nil
+}
+
+// GetUserPositions returns the simulation's positions by ID
+func (dao *PositionDAO) GetUserPositions(userID, simulationID uint) ([]models.Position, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	positions := []models.Position{}
+	for _, position := range dao.positions {
+		if position.UserID == userID && sameSimulation(position.SimulationID, simulationID) {
+			positions = append(positions, position)
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].ID < positions[j].ID })
+	return positions, nil
+}
+
+func (dao *PositionDAO) GetPosition(userID, simulationID uint, symbol, baseCurrency string) (*models.Position, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	position, ok := dao.findUnsafe(userID, simulationID, symbol, baseCurrency)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &position, nil
+}
+
+func (dao *PositionDAO) CreateWithTx(tx *gorm.DB, position *models.Position) error {
+	return dao.Create(position)
+}
+
+func (dao *PositionDAO) UpdateWithTx(tx *gorm.DB, position *models.Position) error {
+	return dao.Update(position)
+}
+
+func (dao *PositionDAO) DeleteWithTx(tx *gorm.DB, position *models.Position) error {
+	return dao.Delete(position)
+}
+
+func (dao *PositionDAO) GetPositionWithTx(tx *gorm.DB, userID, simulationID uint, symbol, baseCurrency string) (*models.Position, error) {
+	return dao.GetPosition(userID, simulationID, symbol, baseCurrency)
+}
+
+func (dao *PositionDAO) UpdateOrCreatePosition(tx *gorm.DB, userID uint, simulationID *uint, symbol string, baseCurrency string, quantityChange, price, fee float64) error {
+	if simulationID == nil {
+		return fmt.Errorf("simulation ID is required to update %s position", symbol)
+	}
+
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	position, ok := dao.findUnsafe(userID, *simulationID, symbol, baseCurrency)
+	if !ok {
+		created := tradingDAO.NewPositionFromFill(userID, simulationID, symbol, baseCurrency, quantityChange, price, fee)
+		return dao.createUnsafe(&created)
+	}
+
+	if !tradingDAO.ApplyPositionChange(&position, quantityChange, price, fee) {
+		delete(dao.positions, position.ID)
+		return nil
+	}
+	position.UpdatedAt = time.Now()
+	dao.positions[position.ID] = position
+	return nil
+}
+
+func (dao *PositionDAO) RecordSnapshotWithTx(tx *gorm.DB, userID uint, simulationID *uint, symbol, baseCurrency string, orderID uint, simTime int64) error {
+	if simulationID == nil {
+		return fmt.Errorf("simulation ID is required to record %s position snapshot", symbol)
+	}
+
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	position, ok := dao.findUnsafe(userID, *simulationID, symbol, baseCurrency)
+	if !ok {
+		return nil
+	}
+	dao.snapshots = append(dao.snapshots, models.PositionSnapshot{
+		ID:           uint(len(dao.snapshots) + 1),
+		SimulationID: *simulationID,
+		Symbol:       symbol,
+		SimTime:      simTime,
+		OrderID:      orderID,
+		Quantity:     position.Quantity,
+		AveragePrice: position.AveragePrice,
+		TotalCost:    position.TotalCost,
+		RealizedPnL:  position.RealizedPnL,
+		CreatedAt:    time.Now(),
+	})
+	return nil
+}
+
+func (dao *PositionDAO) GetSnapshots(simulationID uint, symbol string) ([]models.PositionSnapshot, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	snapshots := []models.PositionSnapshot{}
+	for _, snapshot := range dao.snapshots {
+		if snapshot.SimulationID == simulationID && (symbol == "" || snapshot.Symbol == symbol) {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].SimTime < snapshots[j].SimTime })
+	return snapshots, nil
+}
+
+func (dao *PositionDAO) CreateInitialUSDTPosition(userID uint, simulationID *uint, initialFunding float64) error {
+	if simulationID == nil {
+		return fmt.Errorf("simulation ID is required to create initial USDT position")
+	}
+
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	return dao.createUnsafe(&models.Position{
+		UserID:       userID,
+		SimulationID: simulationID,
+		Symbol:       "USDT",
+		BaseCurrency: "USDT",
+		Quantity:     initialFunding,
+		AveragePrice: 1.0,
+		TotalCost:    initialFunding,
+	})
+}
+
+// createUnsafe stores a new position, rejecting a duplicate of the unique index (caller must hold lock)
+func (dao *PositionDAO) createUnsafe(position *models.Position) error {
+	if position.SimulationID == nil {
+		return fmt.Errorf("simulation ID is required to create %s position", position.Symbol)
+	}
+	if _, exists := dao.findUnsafe(position.UserID, *position.SimulationID, position.Symbol, position.BaseCurrency); exists {
+		return fmt.Errorf("duplicate %s/%s position in simulation %d", position.Symbol, position.BaseCurrency, *position.SimulationID)
+	}
+
+	dao.nextID++
+	position.ID = dao.nextID
+	position.CreatedAt = time.Now()
+	position.UpdatedAt = position.CreatedAt
+	stored := *position
+	stored.SimulationID = copyID(position.SimulationID)
+	dao.positions[position.ID] = stored
+	return nil
+}
+
+// findUnsafe looks up a position by its unique key (caller must hold lock)
+func (dao *PositionDAO) findUnsafe(userID, simulationID uint, symbol, baseCurrency string) (models.Position, bool) {
+	for _, position := range dao.positions {
+		if position.UserID == userID && sameSimulation(position.SimulationID, simulationID) &&
+			position.Symbol == symbol && position.BaseCurrency == baseCurrency {
+			return position, true
+		}
+	}
+	return models.Position{}, false
+}
+
+func sameSimulation(id *uint, simulationID uint) bool {
+	return id != nil && *id == simulationID
+}
+
+func copyID(id *uint) *uint {
+	if id == nil {
+		return nil
+	}
+	value := *id
+	return &value
+}
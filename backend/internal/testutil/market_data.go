@@ -0,0 +1,213 @@
+package testutil
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"tradesimulator/internal/models"
+)
+
+// DefaultVolume is the volume of generated candles
+const DefaultVolume = 1000
+
+// HistoricalRequest records one GetHistoricalData call
+type HistoricalRequest struct {
+	Symbol    string
+	Interval  string
+	Limit     int
+	StartTime *int64
+	EndTime   *int64
+}
+
+// MarketData serves fixed candles per symbol and interval the way Binance pages klines: by open time, from
+// startTime inclusive up to endTime inclusive, at most limit at a time
+type MarketData struct {
+	mu        sync.Mutex
+	candles   map[string][]models.OHLCV
+	requests  []HistoricalRequest
+	err       error
+	failAfter int // Successful requests left before err is returned
+}
+
+// NewMarketData creates market data without candles
+func NewMarketData() *MarketData {
+	return &MarketData{candles: make(map[string][]models.OHLCV)}
+}
+
+// SetCandles replaces the candles served for a symbol and interval
+func (m *MarketData) SetCandles(symbol, interval string, candles []models.OHLCV) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sorted := append([]models.OHLCV(nil), candles...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].StartTime < sorted[j].StartTime })
+	m.candles[symbol+"/"+interval] = sorted
+}
+
+// FailAfter makes historical data requests return err once successes requests have succeeded
+// A nil err makes every request succeed again
+func (m *MarketData) FailAfter(successes int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+	m.failAfter = successes
+}
+
+// Requests returns the historical data requests made so far
+func (m *MarketData) Requests() []HistoricalRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]HistoricalRequest(nil), m.requests...)
+}
+
+func (m *MarketData) GetHistoricalData(symbol, interval string, limit int, startTime, endTime *int64, enableIncomplete bool) ([]models.OHLCV, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests = append(m.requests, HistoricalRequest{
+		Symbol:    symbol,
+		Interval:  interval,
+		Limit:     limit,
+		StartTime: copyTime(startTime),
+		EndTime:   copyTime(endTime),
+	})
+	if m.err != nil {
+		if m.failAfter <= 0 {
+			return nil, m.err
+		}
+		m.failAfter--
+	}
+
+	var matched []models.OHLCV
+	for _, candle := range m.candles[symbol+"/"+interval] {
+		if startTime != nil && candle.StartTime < *startTime {
+			continue
+		}
+		if endTime != nil && candle.StartTime > *endTime {
+			break
+		}
+		matched = append(matched, candle)
+	}
+
+	if len(matched) > limit {
+		if startTime == nil {
+			// Without a start time Binance returns the most recent candles
+			matched = matched[len(matched)-limit:]
+		} else {
+			matched = matched[:limit]
+		}
+	}
+	return matched, nil
+}
+
+func (m *MarketData) GetKlines(symbol, interval string, limit int, startTime, endTime *int64) ([]models.Kline, error) {
+	candles, err := m.GetHistoricalData(symbol, interval, limit, startTime, endTime, false)
+	if err != nil {
+		return nil, err
+	}
+
+	klines := make([]models.Kline, 0, len(candles))
+	for _, candle := range candles {
+		klines = append(klines, models.Kline{
+			OpenTime:  candle.StartTime,
+			Open:      strconv.FormatFloat(candle.Open, 'f', -1, 64),
+			High:      strconv.FormatFloat(candle.High, 'f', -1, 64),
+			Low:       strconv.FormatFloat(candle.Low, 'f', -1, 64),
+			Close:     strconv.FormatFloat(candle.Close, 'f', -1, 64),
+			Volume:    strconv.FormatFloat(candle.Volume, 'f', -1, 64),
+			CloseTime: candle.EndTime,
+		})
+	}
+	return klines, nil
+}
+
+func (m *MarketData) GetEarliestAvailableTime(symbol string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	earliest := int64(math.MaxInt64)
+	for key, candles := range m.candles {
+		if len(candles) > 0 && strings.HasPrefix(key, symbol+"/") && candles[0].StartTime < earliest {
+			earliest = candles[0].StartTime
+		}
+	}
+	if earliest == math.MaxInt64 {
+		return 0, fmt.Errorf("no data for %s", symbol)
+	}
+	return earliest, nil
+}
+
+func (m *MarketData) GetLatestPrices(symbols []string) (map[string]float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prices := make(map[string]float64)
+	for _, symbol := range symbols {
+		for key, candles := range m.candles {
+			if len(candles) > 0 && strings.HasPrefix(key, symbol+"/") {
+				prices[symbol] = candles[len(candles)-1].Close
+			}
+		}
+	}
+	return prices, nil
+}
+
+func (m *MarketData) GetSupportedSymbols() []string {
+	return []string{"BTCUSDT", "ETHUSDT"}
+}
+
+func (m *MarketData) GetSupportedIntervals() []string {
+	return []string{"1m", "3m", "5m", "15m", "30m", "1h", "2h", "4h", "6h", "8h", "12h", "1d", "3d", "1w", "1M"}
+}
+
+func (m *MarketData) ValidateInterval(interval string) bool {
+	for _, supported := range m.GetSupportedIntervals() {
+		if supported == interval {
+			return true
+		}
+	}
+	return false
+}
+
+// Candles builds consecutive complete candles from startTime, one per close; each opens at the previous close
+// (the first at its own close) and its high and low span the open and close
+func Candles(startTime int64, interval string, closes ...float64) []models.OHLCV {
+	candles := make([]models.OHLCV, 0, len(closes))
+	open := 0.0
+	for i, close := range closes {
+		if i == 0 {
+			open = close
+		}
+		start := startTime + int64(i)*models.GetIntervalDurationMs(interval)
+		candles = append(candles, Candle(start, interval, open, math.Max(open, close), math.Min(open, close), close))
+		open = close
+	}
+	return candles
+}
+
+// Candle builds one complete candle starting at startTime with DefaultVolume
+func Candle(startTime int64, interval string, open, high, low, close float64) models.OHLCV {
+	return models.OHLCV{
+		StartTime:  startTime,
+		EndTime:    startTime + models.GetIntervalDurationMs(interval) - 1,
+		Open:       open,
+		High:       high,
+		Low:        low,
+		Close:      close,
+		Volume:     DefaultVolume,
+		IsComplete: true,
+	}
+}
+
+func copyTime(t *int64) *int64 {
+	if t == nil {
+		return nil
+	}
+	value := *t
+	return &value
+}
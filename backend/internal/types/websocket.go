@@ -16,12 +16,29 @@ const (
 	SimulationSetSpeed  MessageType = "simulation_control_set_speed"
 	SimulationSetTimeframe MessageType = "simulation_control_set_timeframe"
 	SimulationGetStatus MessageType = "simulation_control_get_status"
+	SimulationResync    MessageType = "simulation_control_resync"
+	SimulationSeek      MessageType = "simulation_control_seek"
+	SimulationStepBack  MessageType = "simulation_control_step_back"
+	SimulationGetPrice  MessageType = "simulation_control_get_price"
+	ResyncState         MessageType = "resync_state"
+	WarmupComplete      MessageType = "warmup_complete"
+	WarmupBars          MessageType = "warmup_bars"
+	TradingStats        MessageType = "trading_stats"
+	IntervalUpdate      MessageType = "interval_update"
+	CurrentPrice        MessageType = "current_price"
+	GetMetadata         MessageType = "get_metadata"
+	Metadata            MessageType = "metadata"
+	ReplayRestarted     MessageType = "replay_restarted"
 	// Order control messages
 	OrderPlace          MessageType = "order_place"
 	OrderCancel         MessageType = "order_cancel"
 	OrderPlaced         MessageType = "order_placed"
 	OrderExecuted       MessageType = "order_executed"
+	TradeExecuted       MessageType = "trade_executed"
 	OrderCancelled      MessageType = "order_cancelled"
+	OrderFailed         MessageType = "order_failed"
+	OrderCloseAll       MessageType = "order_close_all"
+	OrderCloseAllResult MessageType = "order_close_all_result"
 )
 
 // WebSocketMessage represents a WebSocket message
@@ -74,7 +74,8 @@ function AppContent() {
 
   // Handle simulation updates from WebSocket
   useEffect(() => {
-    if (lastSimulationUpdate) {
+    // Multi-symbol simulations also stream their extra symbols, the chart only shows the main one
+    if (lastSimulationUpdate && lastSimulationUpdate.symbol === symbol) {
       setSimulationState(prev => ({
         ...prev,
         state: lastSimulationUpdate.state as 'stopped' | 'playing' | 'paused',
@@ -85,7 +86,7 @@ function AppContent() {
         lastCandle: lastSimulationUpdate.baseCandle // Always update candle from backend
       }));
     }
-  }, [lastSimulationUpdate]);
+  }, [lastSimulationUpdate, symbol]);
 
   // Handle status updates from WebSocket (like when simulation stops due to end of data)
   useEffect(() => {
@@ -98,7 +98,7 @@ const PositionsList: React.FC<PositionsListProps> = ({ onRefreshReady }) => {
   }
 
   // Filter out USDT positions since they're shown as cash balance in portfolio summary
-  const tradingPositions = calculatedPositions?.filter(pos => pos.position.symbol !== 'USDT') || [];
+  const tradingPositions = calculatedPositions?.filter(pos => pos.position.symbol !== 'USDT' && pos.position.quantity !== 0) || [];
   
   if (tradingPositions.length === 0) {
     return (
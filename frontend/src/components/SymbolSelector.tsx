@@ -1,4 +1,4 @@
-import React from 'react';
+import React, { useEffect, useState } from 'react';
 
 interface SymbolSelectorProps {
   symbol: string;
@@ -11,7 +11,19 @@ const SymbolSelector: React.FC<SymbolSelectorProps> = ({
   onSymbolChange,
   disabled = false
 }) => {
-  const symbols = ['BTCUSDT', 'ETHUSDT', 'ADAUSDT', 'DOTUSDT', 'LINKUSDT'];
+  // Supported symbols are configured on the server, keep the defaults until they load
+  const [symbols, setSymbols] = useState<string[]>(['BTCUSDT', 'ETHUSDT']);
+
+  useEffect(() => {
+    fetch('/api/v1/market/symbols')
+      .then(response => (response.ok ? response.json() : Promise.reject(response.statusText)))
+      .then((data: { symbols?: string[] }) => {
+        if (data.symbols && data.symbols.length > 0) {
+          setSymbols(data.symbols);
+        }
+      })
+      .catch(err => console.error('Failed to load supported symbols:', err));
+  }, []);
 
   return (
     <div style={{ display: 'flex', flexDirection: 'column', gap: '6px', width: '100%' }}>
@@ -0,0 +1,18 @@
+-- Migration: Require simulation_id on positions
+-- Date: 2026-10-18
+-- Description: Positions are always scoped to a simulation. Rows with a NULL simulation_id are
+-- unreachable and bypass the (user_id, symbol, base_currency, simulation_id) unique index
+
+-- Begin transaction
+BEGIN;
+
+-- Remove orphaned positions that were created without a simulation
+DELETE FROM positions
+WHERE simulation_id IS NULL;
+
+-- Prevent new positions without a simulation
+ALTER TABLE positions
+ALTER COLUMN simulation_id SET NOT NULL;
+
+-- Commit the transaction
+COMMIT;
@@ -0,0 +1,21 @@
+-- Migration: Add equity samples
+-- Date: 2026-10-18
+-- Description: Portfolio total value sampled at simulation time intervals during a run,
+-- read by the equity curve endpoint instead of reconstructing it from trades
+
+-- Begin transaction
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS equity_samples (
+    id BIGSERIAL PRIMARY KEY,
+    simulation_id BIGINT NOT NULL REFERENCES simulations(id) ON DELETE CASCADE,
+    sim_time BIGINT NOT NULL,
+    total_value DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_equity_samples_simulation_time
+ON equity_samples (simulation_id, sim_time);
+
+-- Commit the transaction
+COMMIT;
@@ -0,0 +1,24 @@
+-- Migration: Add simulation annotations
+-- Date: 2026-10-18
+-- Description: User notes attached to a simulation time or trade, returned with replay data
+
+-- Begin transaction
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS annotations (
+    id BIGSERIAL PRIMARY KEY,
+    simulation_id BIGINT NOT NULL REFERENCES simulations(id) ON DELETE CASCADE,
+    sim_time BIGINT NOT NULL,
+    trade_id BIGINT REFERENCES trades(id) ON DELETE SET NULL,
+    text TEXT NOT NULL,
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_annotations_simulation_id
+ON annotations (simulation_id);
+
+CREATE INDEX IF NOT EXISTS idx_annotations_trade_id
+ON annotations (trade_id);
+
+-- Commit the transaction
+COMMIT;
@@ -0,0 +1,24 @@
+-- Migration: Add simulation candle archives
+-- Date: 2026-10-18
+-- Description: Candle series of finished simulations, served by replay data instead of refetching from Binance
+
+-- Begin transaction
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS candle_archives (
+    id BIGSERIAL PRIMARY KEY,
+    simulation_id BIGINT NOT NULL REFERENCES simulations(id) ON DELETE CASCADE,
+    symbol VARCHAR(20) NOT NULL,
+    interval VARCHAR(10) NOT NULL,
+    start_time BIGINT NOT NULL,
+    end_time BIGINT NOT NULL,
+    candle_count INTEGER NOT NULL,
+    candles TEXT NOT NULL,
+    created_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_candle_archives_simulation_id
+ON candle_archives (simulation_id);
+
+-- Commit the transaction
+COMMIT;
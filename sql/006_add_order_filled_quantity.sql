@@ -0,0 +1,15 @@
+-- Migration: Track filled quantity for partially filled orders
+-- Date: 2026-10-18
+-- Description: Limit orders can fill over several candles when volume participation is capped
+
+-- Begin transaction
+BEGIN;
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS filled_quantity DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- Orders executed before partial fills existed were filled in full
+UPDATE orders SET filled_quantity = quantity WHERE status = 'executed';
+
+-- Commit the transaction
+COMMIT;
@@ -0,0 +1,12 @@
+-- Migration: Track realized P&L on positions
+-- Date: 2026-10-18
+-- Description: Reducing a position realizes P&L against its average price; closed positions are kept flat to retain it
+
+-- Begin transaction
+BEGIN;
+
+ALTER TABLE positions
+ADD COLUMN IF NOT EXISTS realized_pnl DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- Commit the transaction
+COMMIT;
@@ -0,0 +1,26 @@
+-- Migration: Add position snapshots
+-- Date: 2026-10-18
+-- Description: A position's quantity, average price and realized P&L recorded after every fill that changes it,
+-- read by the position history endpoint to show scaling in and out over a run
+
+-- Begin transaction
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS position_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    simulation_id BIGINT NOT NULL REFERENCES simulations(id) ON DELETE CASCADE,
+    symbol VARCHAR(20) NOT NULL,
+    sim_time BIGINT NOT NULL,
+    order_id BIGINT NOT NULL,
+    quantity DOUBLE PRECISION NOT NULL,
+    average_price DOUBLE PRECISION NOT NULL,
+    total_cost DOUBLE PRECISION NOT NULL,
+    realized_pnl DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_position_snapshots_history
+ON position_snapshots (simulation_id, symbol, sim_time);
+
+-- Commit the transaction
+COMMIT;
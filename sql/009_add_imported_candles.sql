@@ -0,0 +1,26 @@
+-- Migration: Add imported candles
+-- Date: 2026-10-18
+-- Description: Custom candle series imported through POST /api/v1/market/import, replayed by simulations
+-- like Binance data for symbols Binance doesn't serve
+
+-- Begin transaction
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS imported_candles (
+    id BIGSERIAL PRIMARY KEY,
+    symbol VARCHAR(20) NOT NULL,
+    interval VARCHAR(10) NOT NULL,
+    start_time BIGINT NOT NULL,
+    open DOUBLE PRECISION NOT NULL,
+    high DOUBLE PRECISION NOT NULL,
+    low DOUBLE PRECISION NOT NULL,
+    close DOUBLE PRECISION NOT NULL,
+    volume DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_imported_candles_series
+ON imported_candles (symbol, interval, start_time);
+
+-- Commit the transaction
+COMMIT;
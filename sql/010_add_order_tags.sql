@@ -0,0 +1,16 @@
+-- Migration: Add order tags
+-- Date: 2026-10-18
+-- Description: Free-text strategy label on orders, so several strategies trading in one simulation
+-- can be told apart when listing orders and trades
+
+-- Begin transaction
+BEGIN;
+
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS tag VARCHAR(32) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_orders_simulation_tag
+ON orders (simulation_id, tag);
+
+-- Commit the transaction
+COMMIT;
@@ -0,0 +1,23 @@
+-- Migration: Store simulation P&L and return
+-- Date: 2026-10-18
+-- Description: P&L and return percentage are written along with the total value so simulation lists
+-- can be sorted by performance without recomputing them
+
+-- Begin transaction
+BEGIN;
+
+ALTER TABLE simulations
+ADD COLUMN IF NOT EXISTS pnl DOUBLE PRECISION,
+ADD COLUMN IF NOT EXISTS return_pct DOUBLE PRECISION;
+
+-- Backfill records that already have a total value
+UPDATE simulations
+SET pnl = total_value - initial_funding,
+    return_pct = (total_value - initial_funding) / NULLIF(initial_funding, 0) * 100
+WHERE total_value IS NOT NULL AND pnl IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_simulations_user_pnl ON simulations (user_id, pnl);
+CREATE INDEX IF NOT EXISTS idx_simulations_user_return_pct ON simulations (user_id, return_pct);
+
+-- Commit the transaction
+COMMIT;
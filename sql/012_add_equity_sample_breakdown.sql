@@ -0,0 +1,14 @@
+-- Migration: Add cash and position value to equity samples
+-- Date: 2026-10-18
+-- Description: Split each equity sample's total value into the USDT balance and the market value
+-- of held positions. Samples recorded earlier keep NULL for both
+
+-- Begin transaction
+BEGIN;
+
+ALTER TABLE equity_samples
+ADD COLUMN IF NOT EXISTS cash DOUBLE PRECISION,
+ADD COLUMN IF NOT EXISTS position_value DOUBLE PRECISION;
+
+-- Commit the transaction
+COMMIT;
@@ -0,0 +1,15 @@
+-- Migration: Add margin fields to positions
+-- Date: 2026-10-18
+-- Description: Futures mode simulations open leveraged longs that pay part of their notional value
+-- as margin and are force-closed at a liquidation price. Existing positions are unleveraged
+
+-- Begin transaction
+BEGIN;
+
+ALTER TABLE positions
+ADD COLUMN IF NOT EXISTS leverage DOUBLE PRECISION NOT NULL DEFAULT 1,
+ADD COLUMN IF NOT EXISTS margin DOUBLE PRECISION NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS liquidation_price DOUBLE PRECISION;
+
+-- Commit the transaction
+COMMIT;
@@ -0,0 +1,13 @@
+-- Migration: Add slippage to trades
+-- Date: 2026-10-18
+-- Description: Market fills can slip from their reference price under a per simulation slippage model.
+-- The per unit price move applied is stored on each trade; existing trades had none
+
+-- Begin transaction
+BEGIN;
+
+ALTER TABLE trades
+ADD COLUMN IF NOT EXISTS slippage DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- Commit the transaction
+COMMIT;
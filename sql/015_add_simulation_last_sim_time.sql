@@ -0,0 +1,23 @@
+-- Migration: Add last simulation time to simulations
+-- Date: 2026-10-18
+-- Description: Playing simulations checkpoint how far they have got in last_sim_time instead of end_sim_time,
+-- which is only written when a simulation pauses, stops or completes. Recovery after a restart resumes from it
+
+-- Begin transaction
+BEGIN;
+
+ALTER TABLE simulations
+ADD COLUMN IF NOT EXISTS last_sim_time BIGINT NOT NULL DEFAULT 0;
+
+-- Every end time written so far was the latest simulation time reached
+UPDATE simulations
+SET last_sim_time = end_sim_time
+WHERE end_sim_time > 0 AND last_sim_time = 0;
+
+-- Running simulations only had an end time from checkpoints, which now live in last_sim_time
+UPDATE simulations
+SET end_sim_time = 0
+WHERE status = 'running';
+
+-- Commit the transaction
+COMMIT;